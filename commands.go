@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"trollfish/uci"
+)
+
+// runCommand runs one of the command-line tools instead of the UCI loop.
+// It returns false if name isn't a command.
+func runCommand(ctx context.Context, name string, args []string) (bool, error) {
+	switch name {
+	case "puzzles":
+		return true, runPuzzles(ctx, args)
+	case "sts":
+		return true, runSTS(ctx, args)
+	case "bench":
+		return true, runBench(ctx, args)
+	case "serve":
+		return true, runServe(ctx, args)
+	case "analyzeset":
+		return true, runAnalyzeSet(ctx, args)
+	case "check":
+		return true, runCheck(ctx, args)
+	case "pgnbook":
+		return true, runPGNBook(args)
+	case "fetch-engine":
+		return true, runFetchEngine(ctx, args)
+	case "replay":
+		return true, runReplay(ctx, args)
+	case "games":
+		return true, runGames(args)
+	}
+	return false, nil
+}
+
+func runPuzzles(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("puzzles", flag.ExitOnError)
+	humanized := fs.Bool("humanized", false, "score the troll move selector instead of raw Stockfish")
+	nodes := fs.Int("nodes", 0, "nodes per search (overrides -movetime)")
+	moveTime := fs.Int("movetime", 1000, "milliseconds per search")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: trollfish puzzles [flags] <file.epd|file.csv>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts := uci.PuzzleOptions{Humanized: *humanized, Nodes: *nodes, MoveTime: *moveTime}
+	_, err := uci.RunPuzzles(ctx, fs.Arg(0), opts, os.Stdout)
+	return err
+}
+
+func runSTS(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sts", flag.ExitOnError)
+	humanized := fs.Bool("humanized", false, "score the troll move selector instead of raw Stockfish")
+	nodes := fs.Int("nodes", 1_000_000, "nodes per position")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: trollfish sts [flags] <STS.epd>...")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts := uci.PuzzleOptions{Humanized: *humanized, Nodes: *nodes}
+	for _, path := range fs.Args() {
+		if _, err := uci.RunSTS(ctx, path, opts, os.Stdout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runBench follows the engine convention of "<engine> bench [depth]" so
+// OpenBench-style tools can run it.
+func runBench(ctx context.Context, args []string) error {
+	var depth int
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("bench: invalid depth '%s'", args[0])
+		}
+		depth = n
+	}
+
+	_, err := uci.Bench(ctx, depth, os.Stdout)
+	return err
+}
+
+func runAnalyzeSet(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("analyzeset", flag.ExitOnError)
+	depth := fs.Int("depth", 0, "depth per position (overrides -nodes and -movetime)")
+	nodes := fs.Int("nodes", 0, "nodes per position (overrides -movetime)")
+	moveTime := fs.Int("movetime", 1000, "milliseconds per position")
+	engines := fs.Int("engines", 1, "engines analyzing in parallel")
+	format := fs.String("format", "csv", "output format, csv or json (one object per line)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: trollfish analyzeset [flags] <file>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts := uci.AnalyzeOptions{Depth: *depth, Nodes: *nodes, MoveTime: *moveTime, Engines: *engines, Format: *format}
+	_, err := uci.RunAnalyzeSet(ctx, fs.Arg(0), opts, os.Stdout)
+	return err
+}
+
+// runCheck validates the configuration before a game, so a missing engine or
+// tablebase shows up now rather than mid-game.
+func runCheck(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	engine := fs.String("engine", "", "engine binary (default $TROLLFISH_ENGINE, the config file's engine or the built-in Stockfish path)")
+	variantEngine := fs.String("variant-engine", "", "variant engine binary (VariantEngine)")
+	syzygyPath := fs.String("syzygy", "", "tablebase directories (SyzygyPath)")
+	repertoire := fs.String("antichess-repertoire", "", "antichess repertoire file (AntichessRepertoire)")
+	book := fs.String("opening-book", "", "opening book file (OpeningBook)")
+	polyglot := fs.String("polyglot-book", "", "Polyglot .bin book (PolyglotBook)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: trollfish check [flags]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	cfg := uci.CheckConfig{
+		Engine:              *engine,
+		VariantEngine:       *variantEngine,
+		SyzygyPath:          *syzygyPath,
+		AntichessRepertoire: *repertoire,
+		OpeningBook:         *book,
+		PolyglotBook:        *polyglot,
+		LogSinks:            os.Getenv("TROLLFISH_LOG"),
+		Options:             engineOptions,
+	}
+	_, err := uci.RunCheck(ctx, cfg, os.Stdout)
+	return err
+}
+
+// runPGNBook compiles PGN prep into a book for the OpeningBook option.
+func runPGNBook(args []string) error {
+	fs := flag.NewFlagSet("pgnbook", flag.ExitOnError)
+	color := fs.String("color", "", "keep only white's or black's moves (default both)")
+	plies := fs.Int("plies", 0, "leave out moves past this many plies (default all)")
+	out := fs.String("o", "", "output file (default stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: trollfish pgnbook [flags] <file.pgn>...")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		fp, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+		w = fp
+	}
+
+	opts := uci.PGNBookOptions{Color: *color, Plies: *plies}
+	return uci.RunPGNBook(fs.Args(), opts, w)
+}
+
+// runFetchEngine installs Stockfish for this platform and points the config
+// file at it, so there's no path to set up by hand.
+func runFetchEngine(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("fetch-engine", flag.ExitOnError)
+	version := fs.String("version", "", "Stockfish release, for example 17 (default the latest)")
+	dir := fs.String("dir", "", "install directory (default $TROLLFISH_DATA or trollfish in the user's data directory)")
+	sha := fs.String("sha256", "", "the build's checksum, for a release that doesn't publish one")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: trollfish fetch-engine [flags]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts := uci.FetchEngineOptions{Version: *version, Dir: *dir, SHA256: *sha}
+	_, err := uci.FetchEngine(ctx, opts, os.Stdout)
+	return err
+}
+
+// runServe keeps the engine running and accepts UCI sessions over TCP or a
+// Unix socket, one client at a time.
+// runReplay plays a logged session again to check the same moves come out,
+// after a change to the selector or to find where a game went differently.
+func runReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	session := fs.Int("session", 0, "session in the log to replay, from 1 (default the last)")
+	verbose := fs.Bool("v", false, "report every move, not just the ones that diverge")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: trollfish replay [flags] <trollfish.log>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts := uci.ReplayOptions{Session: *session, Verbose: *verbose}
+	_, err := uci.RunReplay(ctx, fs.Arg(0), opts, os.Stdout)
+	return err
+}
+
+// runGames lists the games in a GameArchive database.
+func runGames(args []string) error {
+	fs := flag.NewFlagSet("games", flag.ExitOnError)
+	opponent := fs.String("opponent", "", "only games against this opponent")
+	result := fs.String("result", "", "only games with this result: 1-0, 0-1, 1/2-1/2 or *")
+	variant := fs.String("variant", "", "only games of this variant")
+	since := fs.String("since", "", "only games ended on or after this date, YYYY-MM-DD")
+	limit := fs.Int("n", 20, "the most recent games to list, 0 for all")
+	moves := fs.Bool("moves", false, "list each game's moves and evals")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: trollfish games [flags] <archive.db>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	q := uci.ArchiveQuery{Opponent: *opponent, Result: *result, Variant: uci.Variant(strings.ToLower(*variant)), Limit: *limit, Moves: *moves}
+	if *since != "" {
+		t, err := time.ParseInLocation("2006-01-02", *since, time.Local)
+		if err != nil {
+			return fmt.Errorf("games: invalid -since '%s'", *since)
+		}
+		q.Since = t
+	}
+	return uci.RunArchiveQuery(fs.Arg(0), q, os.Stdout)
+}
+
+func runServe(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:7777", "TCP address to listen on")
+	socket := fs.String("unix", "", "Unix socket to listen on instead of TCP")
+	logSinks := fs.String("log", "", "log sinks: file=<path>, json=<path> (JSON lines), syslog and stderr, each with an optional :debug, :info, :warn or :error (default $TROLLFISH_LOG or \"file=trollfish.log:debug,stderr:info\")")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: trollfish serve [flags]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	network, address := "tcp", *addr
+	if *socket != "" {
+		network, address = "unix", *socket
+
+		// clean up after a server that didn't exit cleanly
+		if fi, err := os.Stat(*socket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(*socket)
+		}
+	}
+
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	p, err := newUCI()
+	if err != nil {
+		_ = ln.Close()
+		return err
+	}
+	spec := *logSinks
+	if spec == "" && os.Getenv("TROLLFISH_LOG") == "" {
+		spec = "file=trollfish.log:debug,stderr:info"
+	}
+	if spec != "" {
+		if err := p.SetLogSinks(spec); err != nil {
+			_ = ln.Close()
+			return err
+		}
+	}
+	shutdownOnSignal(p)
+
+	return p.Serve(ctx, ln)
+}
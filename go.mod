@@ -1,3 +1,5 @@
 module trollfish
 
 go 1.18
+
+require github.com/mattn/go-sqlite3 v1.14.17
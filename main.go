@@ -7,8 +7,10 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
-	"runtime"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 	"trollfish/uci"
 )
@@ -62,13 +64,64 @@ func createDownloadScripts() {
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
-	p := uci.New("trollfish 15", "the trollfish developers",
-		uci.Option{Name: "Threads", Type: uci.OptionTypeSpin, Default: "1", Min: 1, Max: runtime.NumCPU()},
-		uci.Option{Name: "MultiPV", Type: uci.OptionTypeString, Default: "8"},
-		uci.Option{Name: "PlayBad", Type: uci.OptionTypeString, Default: "false"},
-		uci.Option{Name: "StartAgro", Type: uci.OptionTypeString, Default: "false"},
-		uci.Option{Name: "SyzygyPath", Type: uci.OptionTypeString, Default: ""},
-	)
-	ctx, _ := p.Start(context.Background())
+	if len(os.Args) > 1 {
+		ok, err := runCommand(context.Background(), os.Args[1], os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if ok {
+			return
+		}
+	}
+
+	p, err := newUCI()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, _, err := p.Start(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownOnSignal(p)
+	reloadOnSignal(p)
+
 	<-ctx.Done()
 }
+
+// engineOptions are the options trollfish declares on top of the built-in
+// ones.
+var engineOptions = []uci.Option{
+	{Name: "MultiPV", Type: uci.OptionTypeSpin, Default: "1", Min: 1, Max: 500},
+	{Name: "PlayBad", Type: uci.OptionTypeString, Default: "false"},
+	{Name: "StartAgro", Type: uci.OptionTypeString, Default: "false"},
+	{Name: "SyzygyPath", Type: uci.OptionTypeString, Default: ""},
+}
+
+// newUCI creates the engine. TROLLFISH_LOG configures the log sinks, for
+// example "file=trollfish.log:debug,syslog:error".
+func newUCI() (*uci.UCI, error) {
+	p, err := uci.New("trollfish 15", "the trollfish developers", engineOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec := os.Getenv("TROLLFISH_LOG"); spec != "" {
+		if err := p.SetLogSinks(spec); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// shutdownOnSignal shuts down gracefully on SIGINT/SIGTERM. Without it a
+// supervisor's SIGTERM leaves SF running.
+func shutdownOnSignal(p *uci.UCI) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		p.Shutdown()
+	}()
+}
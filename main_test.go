@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+	"trollfish/uci"
+)
+
+// signalEngine is an engine that answers uci and isready, and notes when it's
+// asked to quit.
+type signalEngine struct {
+	output chan string
+	exited chan struct{}
+	quit   sync.Once
+}
+
+func newSignalEngine() *signalEngine {
+	return &signalEngine{output: make(chan string, 16), exited: make(chan struct{})}
+}
+
+func (e *signalEngine) Write(s string) {
+	switch s {
+	case "uci":
+		e.output <- "uciok"
+	case "isready":
+		e.output <- "readyok"
+	}
+}
+
+func (e *signalEngine) Output() <-chan string   { return e.output }
+func (e *signalEngine) Quit()                   { e.quit.Do(func() { close(e.exited) }) }
+func (e *signalEngine) Exited() <-chan struct{} { return e.exited }
+func (e *signalEngine) Err() error              { return nil }
+
+func (e *signalEngine) Running() bool {
+	select {
+	case <-e.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func TestShutdownOnSignal(t *testing.T) {
+	// the game in progress is saved to the working directory
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+	t.Setenv("TROLLFISH_STATE", filepath.Join(dir, "options.json"))
+	t.Setenv("TROLLFISH_LOG", "file="+filepath.Join(dir, "trollfish.log"))
+
+	p, err := newUCI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf := newSignalEngine()
+	p.SetEngineStarter(func(context.Context, string, func(string)) (uci.EngineBackend, error) {
+		return sf, nil
+	})
+	in, w := io.Pipe()
+	p.SetIO(in, io.Discard)
+
+	ctx, _, err := p.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shutdownOnSignal(p)
+
+	_, _ = io.WriteString(w, "setoption name MultiPV value 3\nposition startpos moves e2e4\n")
+	gamePath := filepath.Join(dir, "trollfish-game.json")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(gamePath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the game wasn't saved")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	proc, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		t.Skipf("can't signal ourselves here: %v", err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no shutdown after SIGTERM")
+	}
+
+	if sf.Running() {
+		t.Error("the engine wasn't quit")
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "options.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var options map[string]string
+	if err := json.Unmarshal(b, &options); err != nil || options["MultiPV"] != "3" {
+		t.Errorf("options: %s (%v)", b, err)
+	}
+	if _, err := os.Stat(gamePath); err != nil {
+		t.Errorf("the saved game is gone after shutdown: %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+package main
+
+import "trollfish/uci"
+
+// reloadOnSignal does nothing, js has no SIGHUP. The ReloadConfig button
+// still reloads the config file.
+func reloadOnSignal(p *uci.UCI) {}
@@ -0,0 +1,25 @@
+//go:build !js
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"trollfish/uci"
+)
+
+// reloadOnSignal reloads the config file on SIGHUP, keeping the game in
+// progress.
+func reloadOnSignal(p *uci.UCI) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	go func() {
+		for range sigs {
+			if err := p.ReloadConfig(); err != nil {
+				p.WriteLine(fmt.Sprintf("info string ERR: config: %v", err))
+			}
+		}
+	}()
+}
@@ -0,0 +1,79 @@
+package stockfish
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// stderrBurst is how many stderr lines are logged per stderrInterval; the
+	// rest are counted and reported as dropped.
+	stderrBurst    = 50
+	stderrInterval = time.Second
+)
+
+// lineLimiter allows up to burst lines per interval.
+type lineLimiter struct {
+	burst    int
+	interval time.Duration
+
+	windowStart time.Time
+	n           int
+	dropped     int
+}
+
+// allow reports whether a line seen at now should be logged, and how many
+// lines were dropped in the window before it, if it's the first in a new one.
+func (l *lineLimiter) allow(now time.Time) (ok bool, dropped int) {
+	if now.Sub(l.windowStart) >= l.interval {
+		dropped = l.dropped
+		l.windowStart, l.n, l.dropped = now, 0, 0
+	}
+
+	if l.n >= l.burst {
+		l.dropped++
+		return false, dropped
+	}
+	l.n++
+	return true, dropped
+}
+
+// logStderr logs the engine's stderr, tagged with the process so it can be
+// told apart from trollfish's own output. Blank lines are skipped and a noisy
+// engine is rate limited.
+func (sf *StockFish) logStderr(r io.Reader, pid int) {
+	tag := fmt.Sprintf("SF STDERR [pid %d]:", pid)
+	limiter := lineLimiter{burst: stderrBurst, interval: stderrInterval}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		select {
+		case <-sf.Ctx.Done():
+			return
+		default:
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		ok, dropped := limiter.allow(time.Now())
+		if dropped > 0 {
+			sf.logInfo(fmt.Sprintf("%s dropped %d lines", tag, dropped))
+		}
+		if ok {
+			sf.logInfo(fmt.Sprintf("%s %s", tag, line))
+		}
+	}
+
+	if limiter.dropped > 0 {
+		sf.logInfo(fmt.Sprintf("%s dropped %d lines", tag, limiter.dropped))
+	}
+	if err := scanner.Err(); err != nil {
+		sf.logInfo(fmt.Sprintf("SF ERR: stderr: %v", err))
+	}
+}
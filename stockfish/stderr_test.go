@@ -0,0 +1,24 @@
+package stockfish
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLineLimiter(t *testing.T) {
+	l := lineLimiter{burst: 2, interval: time.Second}
+	start := time.Now()
+
+	for i, want := range []bool{true, true, false, false} {
+		ok, dropped := l.allow(start.Add(time.Duration(i) * time.Millisecond))
+		if ok != want || dropped != 0 {
+			t.Errorf("line %d: want %v, 0 got %v, %d", i, want, ok, dropped)
+		}
+	}
+
+	// the next window reports what the last one dropped
+	ok, dropped := l.allow(start.Add(time.Second))
+	if !ok || dropped != 2 {
+		t.Errorf("next window: want true, 2 got %v, %d", ok, dropped)
+	}
+}
@@ -3,31 +3,66 @@ package stockfish
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// quitTimeout is how long Quit waits for the engine to exit on its own before
+// killing it.
+const quitTimeout = 2 * time.Second
+
+// NotFoundError is returned by Start when the engine binary doesn't exist.
+type NotFoundError struct {
+	Path string
+
+	// OnPath is set when Path is a bare name that was looked for on $PATH.
+	OnPath bool
+}
+
+func (e *NotFoundError) Error() string {
+	if e.OnPath {
+		return fmt.Sprintf("'%s' not found on $PATH, set the engine's full path", e.Path)
+	}
+	return fmt.Sprintf("'%s' not found", e.Path)
+}
+
 type StockFish struct {
 	Ctx    context.Context
 	Output <-chan string
 
-	cancel  context.CancelFunc
-	writer  io.WriteCloser
-	logInfo func(string)
+	cancel   context.CancelFunc
+	writer   io.WriteCloser
+	logInfo  func(string)
+	exited   chan struct{}
+	exitErr  error
+	quit     sync.Once
+	quitting int32
 }
 
 func Start(ctx context.Context, binary string, logInfo func(string)) (*StockFish, error) {
+	// a bare name is looked for on $PATH, as a shell would
+	if filepath.Base(binary) == binary {
+		path, err := exec.LookPath(binary)
+		if err != nil {
+			return nil, &NotFoundError{Path: binary, OnPath: true}
+		}
+		binary = path
+	}
+
 	_, err := os.Stat(binary)
 	if err != nil && os.IsNotExist(err) {
-		return nil, fmt.Errorf("'%s' not found", binary)
+		return nil, &NotFoundError{Path: binary}
 	}
 
-	dir := filepath.Base(binary)
+	// run from the binary's own directory, where it looks for its nets
+	dir := filepath.Dir(binary)
 
 	output := make(chan string, 512)
 
@@ -35,6 +70,7 @@ func Start(ctx context.Context, binary string, logInfo func(string)) (*StockFish
 	sf.Ctx, sf.cancel = context.WithCancel(ctx)
 	sf.Output = output
 	sf.logInfo = logInfo
+	sf.exited = make(chan struct{})
 
 	cmd := exec.CommandContext(sf.Ctx, binary)
 	cmd.Dir = dir
@@ -57,7 +93,11 @@ func Start(ctx context.Context, binary string, logInfo func(string)) (*StockFish
 	}
 
 	if err := cmd.Start(); err != nil {
-		log.Fatalf("cmd.Start: %v\n", err)
+		sf.cancel()
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, &NotFoundError{Path: binary}
+		}
+		return nil, fmt.Errorf("start '%s': %w", binary, err)
 	}
 
 	var wg sync.WaitGroup
@@ -66,24 +106,13 @@ func Start(ctx context.Context, binary string, logInfo func(string)) (*StockFish
 	// stderr loop
 	go func() {
 		defer wg.Done()
-		r := bufio.NewScanner(stderr)
-		for r.Scan() {
-			select {
-			case <-sf.Ctx.Done():
-				return
-			default:
-				line := r.Text()
-				logInfo(fmt.Sprintf("SF STDERR: %s", line))
-			}
-		}
-		if err := r.Err(); err != nil {
-			logInfo(fmt.Sprintf("SF ERR: stderr: %v", err))
-		}
+		sf.logStderr(stderr, cmd.Process.Pid)
 	}()
 
-	// stdout loop
+	// stdout loop, Output is closed when it ends
 	go func() {
 		defer wg.Done()
+		defer close(output)
 		r := bufio.NewScanner(stdout)
 		for r.Scan() {
 			select {
@@ -98,15 +127,29 @@ func Start(ctx context.Context, binary string, logInfo func(string)) (*StockFish
 	}()
 
 	go func() {
-		if err := cmd.Wait(); err != nil {
+		defer close(sf.exited)
+		err := cmd.Wait()
+		if err != nil {
 			logInfo(fmt.Sprintf("SF ERR: %v\n", err))
 		}
+		if atomic.LoadInt32(&sf.quitting) == 0 {
+			if err == nil {
+				err = errors.New("engine exited unexpectedly")
+			}
+			sf.exitErr = err
+		}
 	}()
 
 	return &sf, nil
 }
 
+// Write sends a command to the engine. Writes to a nil StockFish, when there's
+// no engine, are dropped.
 func (sf *StockFish) Write(s string) {
+	if sf == nil {
+		return
+	}
+
 	sf.logInfo(fmt.Sprintf("SF: -> %s", s))
 
 	b := []byte(s)
@@ -115,6 +158,53 @@ func (sf *StockFish) Write(s string) {
 	_, _ = sf.writer.Write(b)
 }
 
+// Quit asks the engine to exit and waits for it, killing the process if it
+// doesn't exit within quitTimeout. It's safe to call more than once.
 func (sf *StockFish) Quit() {
-	sf.cancel()
+	if sf == nil {
+		return
+	}
+
+	sf.quit.Do(func() {
+		atomic.StoreInt32(&sf.quitting, 1)
+		sf.Write("quit")
+
+		select {
+		case <-sf.exited:
+		case <-time.After(quitTimeout):
+			sf.logInfo("SF ERR: engine didn't exit after quit, killing it")
+		}
+
+		sf.cancel()
+		<-sf.exited
+	})
+}
+
+// Running reports whether the engine process is up. A nil StockFish isn't.
+func (sf *StockFish) Running() bool {
+	if sf == nil {
+		return false
+	}
+	select {
+	case <-sf.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// Exited is closed once the engine process has exited.
+func (sf *StockFish) Exited() <-chan struct{} {
+	return sf.exited
+}
+
+// Err returns why the engine exited when it wasn't asked to. It's nil while
+// the engine is running and after Quit.
+func (sf *StockFish) Err() error {
+	select {
+	case <-sf.exited:
+		return sf.exitErr
+	default:
+		return nil
+	}
 }
@@ -0,0 +1,46 @@
+package stockfish
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestStartNotFound(t *testing.T) {
+	for _, binary := range []string{filepath.Join(t.TempDir(), "stockfish"), "trollfish-no-such-engine"} {
+		_, err := Start(context.Background(), binary, func(string) {})
+
+		var notFound *NotFoundError
+		if !errors.As(err, &notFound) {
+			t.Errorf("%s: want a NotFoundError got: %v", binary, err)
+			continue
+		}
+		if notFound.Path != binary {
+			t.Errorf("Path want: %s got: %s", binary, notFound.Path)
+		}
+		if onPath := filepath.Base(binary) == binary; notFound.OnPath != onPath {
+			t.Errorf("%s: OnPath want: %v got: %v", binary, onPath, notFound.OnPath)
+		}
+	}
+}
+
+func TestStartOnPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script engine")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\nwhile read -r line; do [ \"$line\" = quit ] && exit; done\n"
+	if err := os.WriteFile(filepath.Join(dir, "trollfish-test-engine"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	sf, err := Start(context.Background(), "trollfish-test-engine", func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf.Quit()
+}
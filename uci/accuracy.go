@@ -0,0 +1,123 @@
+package uci
+
+import (
+	"fmt"
+	"math"
+)
+
+// sideAccuracy is one side's play in a game, scored the way lichess does:
+// each move's accuracy from the win chance it gave up, the game's from those,
+// weighted towards the sharp parts of the game.
+type sideAccuracy struct {
+	color  string
+	loss   int
+	before []float64 // the mover's win % before each move
+	after  []float64 // and after it
+}
+
+func (s *sideAccuracy) add(before, after int) {
+	s.loss += min(max(before-after, 0), maxCPLoss)
+	s.before = append(s.before, winPercent(before))
+	s.after = append(s.after, winPercent(after))
+}
+
+func (s sideAccuracy) moves() int {
+	return len(s.before)
+}
+
+func (s sideAccuracy) acpl() float64 {
+	if s.moves() == 0 {
+		return 0
+	}
+	return float64(s.loss) / float64(s.moves())
+}
+
+// accuracy is lichess' game accuracy: the mean of the moves' accuracies
+// weighted by how volatile the game was around each, averaged with their
+// harmonic mean so a few bad moves count for more than a good run.
+func (s sideAccuracy) accuracy() float64 {
+	n := s.moves()
+	if n == 0 {
+		return 0
+	}
+
+	// the windows are over the win % before each move and after the last
+	wins := append(append([]float64(nil), s.before...), s.after[n-1])
+	size := min(max(len(wins)/10, 2), 8)
+	weights := make([]float64, n)
+	for i := range weights {
+		lo := max(min(i-size/2, len(wins)-size), 0)
+		weights[i] = math.Min(math.Max(stdDev(wins[lo:min(lo+size, len(wins))]), 0.5), 12)
+	}
+
+	var weighted, weightSum, harmonic float64
+	for i := 0; i < n; i++ {
+		a := moveAccuracy(s.before[i], s.after[i])
+		weighted += a * weights[i]
+		weightSum += weights[i]
+		harmonic += 1 / math.Max(a, 1)
+	}
+	return (weighted/weightSum + float64(n)/harmonic) / 2
+}
+
+func (s sideAccuracy) String() string {
+	return fmt.Sprintf("%s accuracy %0.1f%% acpl %0.1f moves %d", colorName(s.color), s.accuracy(), s.acpl(), s.moves())
+}
+
+// winPercent is lichess' win chance for a centipawn eval, 0 to 100.
+func winPercent(cp int) float64 {
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(cp)))-1)
+}
+
+// moveAccuracy is lichess' accuracy for a move from the mover's win chance
+// before and after it, 100 for a move that gave nothing up.
+func moveAccuracy(before, after float64) float64 {
+	if after >= before {
+		return 100
+	}
+	raw := 103.1668100711649*math.Exp(-0.04354415386753951*(before-after)) - 3.166924740191411
+	return math.Min(math.Max(raw+1, 0), 100)
+}
+
+func stdDev(v []float64) float64 {
+	var mean float64
+	for _, x := range v {
+		mean += x
+	}
+	mean /= float64(len(v))
+	var variance float64
+	for _, x := range v {
+		variance += (x - mean) * (x - mean)
+	}
+	return math.Sqrt(variance / float64(len(v)))
+}
+
+// gameAccuracy scores both sides' moves from our evals. Ours are SF's best
+// line against the one we played. The opponent's are our last line against
+// SF's eval after their reply, so only the replies between two of our
+// searches count.
+func gameAccuracy(evals []moveEval) (ours, theirs sideAccuracy) {
+	if len(evals) == 0 {
+		return ours, theirs
+	}
+	ours.color = evals[0].color
+	theirs.color = "w"
+	if ours.color == "w" {
+		theirs.color = "b"
+	}
+
+	for i, m := range evals {
+		ours.add(m.sfScore, m.playedScore)
+
+		if i == 0 {
+			continue
+		}
+		prev := evals[i-1]
+		if gamePly(m.moveNumber, m.color) != gamePly(prev.moveNumber, prev.color)+2 {
+			continue
+		}
+		// from their side, the line we played is what they faced
+		theirs.add(-prev.playedScore, -m.sfScore)
+	}
+	return ours, theirs
+}
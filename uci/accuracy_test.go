@@ -0,0 +1,69 @@
+package uci
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMoveAccuracy(t *testing.T) {
+	cases := []struct {
+		before, after int
+		want          float64
+	}{
+		{0, 0, 100},
+		{0, 50, 100},
+		{50, 0, 82.3},
+		{300, -300, 9.4},
+		{maxCPLoss, -maxCPLoss, 0},
+	}
+	for _, c := range cases {
+		got := moveAccuracy(winPercent(c.before), winPercent(c.after))
+		if math.Abs(c.want-got) > 0.1 {
+			t.Errorf("%d to %d, want: %0.1f got: %0.1f", c.before, c.after, c.want, got)
+		}
+	}
+}
+
+func TestGameAccuracy(t *testing.T) {
+	// arrange
+	evals := []moveEval{
+		{moveNumber: 1, color: "w", sfScore: 30, playedScore: 30},
+		// their reply dropped 30 to -300 for them, we gave 200 of it back
+		{moveNumber: 2, color: "w", sfScore: 300, playedScore: 100},
+		// their move 3 reply isn't between two of our searches
+		{moveNumber: 4, color: "w", sfScore: 100, playedScore: 100},
+	}
+
+	// act
+	ours, theirs := gameAccuracy(evals)
+
+	// assert
+	if ours.color != "w" || theirs.color != "b" {
+		t.Fatalf("want: w b got: %s %s", ours.color, theirs.color)
+	}
+	checks := []struct {
+		name      string
+		want, got float64
+	}{
+		{"our moves", 3, float64(ours.moves())},
+		{"our acpl", 200.0 / 3, ours.acpl()},
+		{"their moves", 1, float64(theirs.moves())},
+		{"their acpl", 270, theirs.acpl()},
+	}
+	for _, c := range checks {
+		if math.Abs(c.want-c.got) > 0.001 {
+			t.Errorf("%s, want: %0.2f got: %0.2f", c.name, c.want, c.got)
+		}
+	}
+	if a := theirs.accuracy(); a >= ours.accuracy() || a <= 0 || ours.accuracy() >= 100 {
+		t.Errorf("want 0 < theirs < ours < 100 got: %0.1f %0.1f", a, ours.accuracy())
+	}
+
+	perfect, _ := gameAccuracy(evals[:1])
+	if a := perfect.accuracy(); a != 100 {
+		t.Errorf("no loss, want: 100 got: %0.1f", a)
+	}
+	if s := perfect.String(); s != "white accuracy 100.0% acpl 0.0 moves 1" {
+		t.Errorf("got: %s", s)
+	}
+}
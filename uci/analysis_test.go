@@ -0,0 +1,211 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoInfinite(t *testing.T) {
+	u, out := newTestUCI(t)
+
+	eng := newFakeBackend()
+	u.sf = eng
+	loopDone := make(chan struct{})
+	go func() {
+		u.stockFishReadLoop(eng)
+		close(loopDone)
+	}()
+
+	u.SetPosition("startpos")
+	u.Go("infinite")
+	if sent := eng.sent(); len(sent) == 0 || sent[len(sent)-1] != "go infinite" {
+		t.Fatalf("want go infinite sent, got %q", sent)
+	}
+
+	info := "info depth 12 seldepth 15 multipv 1 score cp 30 nodes 5000 nps 1000 time 5 pv e2e4 e7e5"
+	eng.output <- "info depth 12 currmove g1f3 currmovenumber 3"
+	eng.output <- info
+	u.parseLine("stop")
+	eng.output <- "bestmove b1c3 ponder e7e5"
+	eng.Quit()
+	<-loopDone
+
+	got := out.String()
+	for _, want := range []string{
+		"info depth 12 currmove g1f3 currmovenumber 3\n",
+		info + "\n",
+		"bestmove b1c3 ponder e7e5\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "agro") {
+		t.Errorf("troll selection ran:\n%s", got)
+	}
+}
+
+func TestGoInfiniteNoEngine(t *testing.T) {
+	u, out := newTestUCI(t)
+
+	u.SetPosition("startpos", "moves", "e2e4")
+	out.Reset()
+	u.Go("infinite")
+	if strings.Contains(out.String(), "bestmove") {
+		t.Fatalf("bestmove before stop:\n%s", out.String())
+	}
+
+	u.parseLine("stop")
+	if !strings.Contains(out.String(), "bestmove ") {
+		t.Errorf("no bestmove after stop:\n%s", out.String())
+	}
+}
+
+// TestGoInfiniteEngineDies has SF die during a search that waits for the GUI:
+// the built-in engine's bestmove waits for it too.
+func TestGoInfiniteEngineDies(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		cmd  string
+	}{
+		{name: "infinite", args: []string{"infinite"}, cmd: "stop"},
+		{name: "ponder stop", args: []string{"ponder", "wtime", "60000", "btime", "60000"}, cmd: "stop"},
+		{name: "ponderhit", args: []string{"ponder", "wtime", "60000", "btime", "60000"}, cmd: "ponderhit"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u, out := newTestUCI(t)
+
+			started := make(chan *fakeBackend, 2)
+			u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+				e := newFakeBackend()
+				started <- e
+				return e, nil
+			})
+			if err := u.switchEngine("/engines/sf"); err != nil {
+				t.Fatal(err)
+			}
+			sf := <-started
+
+			u.SetPosition("startpos", "moves", "e2e4", "c7c5", "g1f3", "d7d6", "d2d4")
+			u.Go(c.args...)
+			sf.crash(errors.New("signal: segmentation fault"))
+			select {
+			case <-started:
+			case <-time.After(5 * time.Second):
+				t.Fatal("engine not restarted")
+			}
+
+			if strings.Contains(out.String(), "bestmove") {
+				t.Fatalf("bestmove before %s:\n%s", c.cmd, out.String())
+			}
+			u.parseLine(c.cmd)
+			if got := strings.Count(out.String(), "bestmove "); got != 1 {
+				t.Errorf("want 1 bestmove after %s, got %d:\n%s", c.cmd, got, out.String())
+			}
+		})
+	}
+}
+
+func TestGoLimitPassthrough(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		want     string
+		analysis bool
+	}{
+		{name: "depth", args: []string{"depth", "12"}, want: "go depth 12", analysis: true},
+		{name: "nodes", args: []string{"nodes", "50000"}, want: "go nodes 50000", analysis: true},
+		{name: "movetime with a clock", args: []string{"wtime", "60000", "btime", "60000", "movetime", "300"}, want: "go wtime 60000 btime 60000 movetime 300"},
+		{name: "depth with a clock", args: []string{"wtime", "60000", "btime", "60000", "depth", "6"}, want: "go wtime 60000 btime 60000 depth 6"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u, _ := newTestUCI(t)
+
+			eng := newFakeBackend()
+			u.sf = eng
+
+			// out of book, so the search goes to the engine
+			u.SetPosition("startpos", "moves", "e2e4", "c7c5", "g1f3", "d7d6", "d2d4")
+			u.Go(c.args...)
+
+			if sent := eng.sent(); len(sent) == 0 || sent[len(sent)-1] != c.want {
+				t.Errorf("want %q sent, got %q", c.want, sent)
+			}
+			u.moveListMtx.Lock()
+			analysis := u.analysis
+			u.moveListMtx.Unlock()
+			if analysis != c.analysis {
+				t.Errorf("analysis want: %v got: %v", c.analysis, analysis)
+			}
+		})
+	}
+}
+
+func TestGoSearchMoves(t *testing.T) {
+	u, out := newTestUCI(t)
+
+	eng := newFakeBackend()
+	u.sf = eng
+	loopDone := make(chan struct{})
+	go func() {
+		u.stockFishReadLoop(eng)
+		close(loopDone)
+	}()
+
+	u.SetPosition("fen", "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4")
+	u.Go("wtime", "60000", "btime", "60000", "searchmoves", "d2d3", "b1c3")
+	sent := eng.sent()
+	if len(sent) == 0 || !strings.HasPrefix(sent[len(sent)-1], "go movetime ") || !strings.HasSuffix(sent[len(sent)-1], " searchmoves d2d3 b1c3") {
+		t.Fatalf("want searchmoves forwarded, got %q", sent)
+	}
+
+	// a line outside searchmoves can't be played
+	eng.output <- "info depth 10 seldepth 12 multipv 1 score cp 60 nodes 5000 nps 1000 time 5 pv f3g5 d7d5"
+	eng.output <- "info depth 10 seldepth 12 multipv 2 score cp 30 nodes 5000 nps 1000 time 5 pv d2d3 f8c5"
+	eng.output <- "info depth 10 seldepth 12 multipv 3 score cp 25 nodes 5000 nps 1000 time 5 pv b1c3 f8c5"
+	eng.output <- "bestmove f3g5 ponder d7d5"
+	eng.Quit()
+	<-loopDone
+
+	var bestMove string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "bestmove ") {
+			bestMove = strings.Fields(line)[1]
+		}
+	}
+	if bestMove != "d2d3" && bestMove != "b1c3" {
+		t.Errorf("want d2d3 or b1c3 got: '%s'\n%s", bestMove, out.String())
+	}
+}
+
+func TestGoMoveOverhead(t *testing.T) {
+	u, _ := newTestUCI(t)
+
+	eng := newFakeBackend()
+	u.sf = eng
+
+	u.SetOption("Move Overhead", "1400")
+	u.SetPosition("fen", "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4")
+	u.Go("wtime", "1500", "btime", "60000")
+
+	sent := eng.sent()
+	if sent[0] != "setoption name Move Overhead value 1400" {
+		t.Errorf("want Move Overhead forwarded, got %q", sent)
+	}
+	p, err := ParseGoParams(strings.Fields(strings.TrimPrefix(sent[len(sent)-1], "go ")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 100ms left after the overhead
+	if p.MoveTime > 100 {
+		t.Errorf("movetime want: <= 100 got: %d", p.MoveTime)
+	}
+}
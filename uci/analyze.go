@@ -0,0 +1,92 @@
+package uci
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Analyzer runs searches on engine instances of its own, for Go programs that
+// embed trollfish as an analysis library. Nothing goes through stdin or
+// stdout and the troll policy isn't involved: results are SF's own. Analyze
+// can be called from more than one goroutine; the searches run in parallel,
+// one per engine, and queue for an engine past that.
+type Analyzer struct {
+	pool *enginePool
+}
+
+// AnalyzeResult is the outcome of a search.
+type AnalyzeResult struct {
+	BestMove string
+	Lines    []Info // last line reported for each multipv, in multipv order
+	Err      error
+}
+
+// Best returns SF's main line, or just the best move if no PV was reported.
+func (r AnalyzeResult) Best() Info {
+	return searchResult{BestMove: r.BestMove, Lines: r.Lines}.Best()
+}
+
+// NewAnalyzer starts the engine at path, or the configured SF if it's
+// empty. The engine quits when ctx is done or Close is called.
+func NewAnalyzer(ctx context.Context, path string) (*Analyzer, error) {
+	return NewAnalyzerPool(ctx, path, 1)
+}
+
+// NewAnalyzerPool is NewAnalyzer with n engines, for n searches at a time.
+// Each engine has its own Threads and Hash, set with SetOption.
+func NewAnalyzerPool(ctx context.Context, path string, n int) (*Analyzer, error) {
+	if path == "" {
+		path = defaultEnginePath()
+	}
+
+	pool, err := newEnginePool(ctx, path, n, func(string) {})
+	if err != nil {
+		return nil, err
+	}
+	return &Analyzer{pool: pool}, nil
+}
+
+// SetOption sets an option on every engine, for example MultiPV or Threads.
+// It waits for the running searches to finish first.
+func (a *Analyzer) SetOption(name, value string) error {
+	return a.pool.setOption(name, value)
+}
+
+// Analyze searches fen within limits, which take the same fields as a "go"
+// command; with none set the search runs until ctx is done. Each PV line is
+// sent on the first channel as SF reports it, and the first channel is closed
+// before the result is sent on the second. Cancelling ctx stops the search
+// early and still produces a result.
+//
+// The caller has to drain the Info channel or cancel ctx, otherwise the
+// search blocks on sending updates.
+func (a *Analyzer) Analyze(ctx context.Context, fen string, limits GoParams) (<-chan Info, <-chan AnalyzeResult) {
+	updates := make(chan Info, 64)
+	done := make(chan AnalyzeResult, 1)
+
+	limits.Ponder = false
+	if limits.Depth == 0 && limits.Nodes == 0 && limits.MoveTime == 0 && limits.Mate == 0 && !limits.HasClock() {
+		limits.Infinite = true
+	}
+
+	go func() {
+		defer close(done)
+
+		r, err := a.pool.search(ctx, fen, strings.Fields(limits.String()), updates)
+
+		close(updates)
+		if err != nil {
+			done <- AnalyzeResult{Err: fmt.Errorf("analyze '%s': %w", fen, err)}
+			return
+		}
+		done <- AnalyzeResult{BestMove: r.BestMove, Lines: r.Lines}
+	}()
+
+	return updates, done
+}
+
+// Close waits for the running searches and quits the engines.
+func (a *Analyzer) Close() {
+	a.pool.close()
+}
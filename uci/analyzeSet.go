@@ -0,0 +1,232 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// AnalyzeOptions configures an analyzeset run.
+type AnalyzeOptions struct {
+	// Depth, Nodes or MoveTime (ms) limits each search, the first one set wins.
+	Depth    int
+	Nodes    int
+	MoveTime int
+
+	// Engines is how many SF instances analyze positions in parallel.
+	Engines int
+
+	// Format is "csv" or "json" (one object per line).
+	Format string
+}
+
+func (o AnalyzeOptions) goArgs() []string {
+	switch {
+	case o.Depth > 0:
+		return []string{"depth", strconv.Itoa(o.Depth)}
+	case o.Nodes > 0:
+		return []string{"nodes", strconv.Itoa(o.Nodes)}
+	case o.MoveTime > 0:
+		return []string{"movetime", strconv.Itoa(o.MoveTime)}
+	}
+	return []string{"movetime", "1000"}
+}
+
+// Analysis is the result for one position of an analyzeset run. Exactly one of
+// CP and Mate is set, unless the search failed.
+type Analysis struct {
+	FEN      string   `json:"fen"`
+	Depth    int      `json:"depth"`
+	CP       *int     `json:"cp,omitempty"`
+	Mate     *int     `json:"mate,omitempty"`
+	BestMove string   `json:"bestmove"`
+	PV       []string `json:"pv"`
+	Nodes    int64    `json:"nodes"`
+	Error    string   `json:"error,omitempty"`
+}
+
+func newAnalysis(fen string, r searchResult) Analysis {
+	best := r.Best()
+	a := Analysis{
+		FEN:      fen,
+		Depth:    best.Depth,
+		BestMove: r.BestMove,
+		PV:       strings.Fields(best.PV),
+		Nodes:    best.Nodes,
+	}
+	if best.Mate != 0 {
+		a.Mate = &best.Mate
+	} else {
+		a.CP = &best.Score
+	}
+	if a.PV == nil {
+		a.PV = []string{}
+	}
+	return a
+}
+
+var analysisCSVHeader = []string{"fen", "depth", "cp", "mate", "bestmove", "pv", "nodes", "error"}
+
+func (a Analysis) csvRecord() []string {
+	optional := func(n *int) string {
+		if n == nil {
+			return ""
+		}
+		return strconv.Itoa(*n)
+	}
+	return []string{
+		a.FEN, strconv.Itoa(a.Depth), optional(a.CP), optional(a.Mate),
+		a.BestMove, strings.Join(a.PV, " "), strconv.FormatInt(a.Nodes, 10), a.Error,
+	}
+}
+
+// analysisWriter writes results in the requested format.
+type analysisWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func newAnalysisWriter(w io.Writer, format string) (*analysisWriter, error) {
+	switch format {
+	case "", "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(analysisCSVHeader); err != nil {
+			return nil, err
+		}
+		return &analysisWriter{csv: cw}, nil
+	case "json":
+		return &analysisWriter{json: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown format '%s', expected csv or json", format)
+}
+
+func (aw *analysisWriter) write(a Analysis) error {
+	if aw.json != nil {
+		return aw.json.Encode(a)
+	}
+	if err := aw.csv.Write(a.csvRecord()); err != nil {
+		return err
+	}
+	aw.csv.Flush()
+	return aw.csv.Error()
+}
+
+// readAnalyzeSet reads positions, one per line: full FENs, or EPD lines whose
+// operations are ignored.
+func readAnalyzeSet(r io.Reader) ([]string, error) {
+	var fens []string
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 6 {
+			if _, err := strconv.Atoi(fields[4]); err == nil {
+				fens = append(fens, line)
+				continue
+			}
+		}
+
+		rec, err := parseEPD(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		fens = append(fens, rec.FEN)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return fens, nil
+}
+
+// RunAnalyzeSet analyzes every position in a file of FENs on a pool of engines
+// and writes the eval, best move and PV for each to w, in file order. A failed
+// search is reported in the position's error field and doesn't stop the run.
+func RunAnalyzeSet(ctx context.Context, path string, opts AnalyzeOptions, w io.Writer) (int, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	fens, err := readAnalyzeSet(fp)
+	_ = fp.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	aw, err := newAnalysisWriter(w, opts.Format)
+	if err != nil {
+		return 0, err
+	}
+
+	engines := min(max(opts.Engines, 1), max(len(fens), 1))
+	pool, err := newEnginePool(ctx, defaultEnginePath(), engines, func(string) {})
+	if err != nil {
+		return 0, err
+	}
+	defer pool.close()
+
+	// workers take positions in order; results are written in file order as
+	// soon as everything before them is done
+	results := make([]chan Analysis, len(fens))
+	for i := range results {
+		results[i] = make(chan Analysis, 1)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < engines; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r, err := pool.search(context.Background(), fens[i], opts.goArgs(), nil)
+				if err != nil {
+					results[i] <- Analysis{FEN: fens[i], PV: []string{}, Error: err.Error()}
+					continue
+				}
+				results[i] <- newAnalysis(fens[i], r)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := range fens {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var written int
+	for i := range fens {
+		var a Analysis
+		select {
+		case a = <-results[i]:
+		case <-ctx.Done():
+			wg.Wait()
+			return written, ctx.Err()
+		}
+		if err := aw.write(a); err != nil {
+			return written, err
+		}
+		written++
+	}
+	wg.Wait()
+
+	return written, nil
+}
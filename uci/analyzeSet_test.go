@@ -0,0 +1,77 @@
+package uci
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadAnalyzeSet(t *testing.T) {
+	const file = `# positions
+rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1
+
+1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; id "BK.01";
+`
+	got, err := readAnalyzeSet(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - 0 1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nwant: %v\ngot:  %v", want, got)
+	}
+}
+
+func TestAnalysisWriter(t *testing.T) {
+	results := []Analysis{
+		newAnalysis(startPosFEN, searchResult{
+			BestMove: "e2e4",
+			Lines:    []Info{{Depth: 12, MultiPV: 1, Score: 31, Nodes: 5000, PV: "e2e4 e7e5"}},
+		}),
+		newAnalysis("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", searchResult{
+			BestMove: "a1a8",
+			Lines:    []Info{{Depth: 3, MultiPV: 1, Mate: 1, Nodes: 40, PV: "a1a8"}},
+		}),
+	}
+
+	var csvOut bytes.Buffer
+	aw, err := newAnalysisWriter(&csvOut, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range results {
+		if err := aw.write(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wantCSV := "fen,depth,cp,mate,bestmove,pv,nodes,error\n" +
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1,12,31,,e2e4,e2e4 e7e5,5000,\n" +
+		"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1,3,,1,a1a8,a1a8,40,\n"
+	if csvOut.String() != wantCSV {
+		t.Errorf("csv\nwant: %q\ngot:  %q", wantCSV, csvOut.String())
+	}
+
+	var jsonOut bytes.Buffer
+	aw, err = newAnalysisWriter(&jsonOut, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aw.write(results[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	wantJSON := `{"fen":"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1","depth":3,"mate":1,"bestmove":"a1a8","pv":["a1a8"],"nodes":40}` + "\n"
+	if jsonOut.String() != wantJSON {
+		t.Errorf("json\nwant: %s\ngot:  %s", wantJSON, jsonOut.String())
+	}
+
+	if _, err := newAnalysisWriter(&jsonOut, "xml"); err == nil {
+		t.Error("xml: expected an error")
+	}
+}
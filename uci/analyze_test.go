@@ -0,0 +1,58 @@
+package uci
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeEngine answers enough UCI for a search: two PV updates and a bestmove.
+const fakeEngine = `#!/bin/sh
+while read -r cmd rest; do
+	case "$cmd" in
+	uci) echo "id name fake"; echo "uciok" ;;
+	isready) echo "readyok" ;;
+	go)
+		echo "info depth 1 multipv 1 score cp 20 nodes 10 pv e2e4"
+		echo "info depth 2 multipv 1 score cp 35 nodes 40 pv d2d4 d7d5"
+		echo "bestmove d2d4 ponder d7d5"
+		;;
+	quit) exit 0 ;;
+	esac
+done
+`
+
+func TestAnalyze(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "engine")
+	if err := os.WriteFile(path, []byte(fakeEngine), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := NewAnalyzer(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	updates, result := a.Analyze(ctx, startPosFEN, GoParams{Depth: 2})
+
+	var pvs []string
+	for info := range updates {
+		pvs = append(pvs, info.PV)
+	}
+	if len(pvs) != 2 || pvs[0] != "e2e4" || pvs[1] != "d2d4 d7d5" {
+		t.Errorf("updates: %q", pvs)
+	}
+
+	r := <-result
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if r.BestMove != "d2d4" || r.Best().Score != 35 || r.Best().Depth != 2 {
+		t.Errorf("result: %+v", r)
+	}
+}
@@ -0,0 +1,113 @@
+package uci
+
+import "fmt"
+
+const (
+	// antiDrawMinScore is how far ahead, in centipawns, we have to be before
+	// the selector starts steering away from draws.
+	antiDrawMinScore = 150
+
+	// antiDrawTolerance is how much eval we'll give up for a line that keeps
+	// winning chances alive.
+	antiDrawTolerance = 40
+
+	// antiDrawHalfmoves is the halfmove clock past which we'd rather reset
+	// it; the fifty-move rule draws at 100.
+	antiDrawHalfmoves = 60
+
+	// antiDrawMultiPV gives the selector a few more lines to choose from once
+	// a draw is in sight.
+	antiDrawMultiPV = 3
+)
+
+// drawRisk scores how much playing a line risks letting the game slip into a
+// draw: repeating a position, letting the fifty-move count drift on, or
+// simplifying into a drawn ending in the built-in tables. 0 is no risk.
+func drawRisk(h *History, line Info) int {
+	b := h.Board()
+	b.Moves(pvMove(line.PV))
+
+	var risk int
+	if n := h.count(repetitionKey(&b)); n > 0 {
+		risk += 2 * n
+	}
+	if atoi(b.HalfmoveClock) >= antiDrawHalfmoves {
+		risk++
+	}
+	if b.Variant.isChess() {
+		if score, ok := probeEndgame(b); ok && score == 0 {
+			risk += 4
+		}
+	}
+	return risk
+}
+
+// drawInSight is true when the game is getting close to a draw we could
+// avoid: a position has already come up twice, the fifty-move count is
+// getting on, or few enough pieces are left to trade down into a table draw.
+func drawInSight(h *History) bool {
+	if h == nil {
+		return false
+	}
+
+	b := h.Board()
+	if h.Repetitions() >= 2 || atoi(b.HalfmoveClock) >= antiDrawHalfmoves {
+		return true
+	}
+
+	var pieces int
+	for _, c := range b.Pos {
+		if c != ' ' {
+			pieces++
+		}
+	}
+	return pieces <= 4
+}
+
+// avoidDraw picks, among the lines nearly as good as SF's, the one least
+// likely to let a winning game end in a draw. SF's line wins ties. Callers
+// must hold moveListMtx.
+func (u *UCI) avoidDraw(moveList []Info, engineMove Info) Info {
+	if u.history == nil || engineMove.Mate != 0 || engineMove.Score < antiDrawMinScore {
+		return engineMove
+	}
+
+	best, bestRisk := engineMove, drawRisk(u.history, engineMove)
+	if bestRisk == 0 {
+		return engineMove
+	}
+
+	for _, line := range moveList {
+		if line.Mate != 0 || engineMove.Score-line.Score > antiDrawTolerance || pvMove(line.PV) == pvMove(engineMove.PV) {
+			continue
+		}
+		if risk := drawRisk(u.history, line); risk < bestRisk {
+			best, bestRisk = line, risk
+		}
+	}
+
+	if pvMove(best.PV) != pvMove(engineMove.PV) {
+		u.logInfo(fmt.Sprintf("anti_draw: sf_move: %s score: %d risk: %d played_move: %s score: %d risk: %d",
+			pvMove(engineMove.PV), engineMove.Score, drawRisk(u.history, engineMove),
+			pvMove(best.PV), best.Score, bestRisk,
+		))
+	}
+	return best
+}
+
+// contemptBias is how much further from equal the selector counts a line
+// that's a 0.00 repetition. Contempt shies away from the draw for a decisive
+// game, negative contempt heads for it when playing up. Callers must hold
+// moveListMtx.
+func (u *UCI) contemptBias(move Info) int {
+	if u.contempt == 0 || u.history == nil || move.Score != 0 || move.Mate != 0 {
+		return 0
+	}
+
+	b := u.history.Board()
+	b.Moves(pvMove(move.PV))
+	if u.history.count(repetitionKey(&b)) == 0 {
+		return 0
+	}
+	return u.contempt
+}
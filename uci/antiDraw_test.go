@@ -0,0 +1,114 @@
+package uci
+
+import (
+	"testing"
+)
+
+func TestAvoidDraw(t *testing.T) {
+	buildEndgameTables()
+
+	tests := []struct {
+		name     string
+		fen      string
+		moves    []string
+		moveList []Info
+		want     string
+	}{
+		{
+			name:  "repetition",
+			fen:   "4k3/8/8/8/8/8/8/R3K1N1 w - - 0 1",
+			moves: []string{"g1f3", "e8d8", "f3g1", "d8e8"},
+			moveList: []Info{
+				{MultiPV: 1, Score: 500, PV: "g1f3 e8d8"},
+				{MultiPV: 2, Score: 480, PV: "a1a7 e8d8"},
+				{MultiPV: 3, Score: 400, PV: "e1e2 e8d8"},
+			},
+			want: "a1a7",
+		},
+		{
+			name: "table draw",
+			fen:  "4k3/8/8/8/8/5p2/8/4K1N1 w - - 0 1",
+			moveList: []Info{
+				{MultiPV: 1, Score: 300, PV: "g1f3 e8d7"},
+				{MultiPV: 2, Score: 280, PV: "e1f2 e8d7"},
+			},
+			want: "e1f2",
+		},
+		{
+			name:  "too much to give up",
+			fen:   "4k3/8/8/8/8/8/8/R3K1N1 w - - 0 1",
+			moves: []string{"g1f3", "e8d8", "f3g1", "d8e8"},
+			moveList: []Info{
+				{MultiPV: 1, Score: 500, PV: "g1f3 e8d8"},
+				{MultiPV: 2, Score: 300, PV: "a1a7 e8d8"},
+			},
+			want: "g1f3",
+		},
+		{
+			name: "not winning",
+			fen:  "4k3/8/8/8/8/5p2/8/4K1N1 w - - 0 1",
+			moveList: []Info{
+				{MultiPV: 1, Score: 100, PV: "g1f3 e8d7"},
+				{MultiPV: 2, Score: 90, PV: "e1f2 e8d7"},
+			},
+			want: "g1f3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}}
+			u.history, _ = (*History)(nil).sync(FENtoBoard(tt.fen), tt.moves)
+
+			got := u.avoidDraw(tt.moveList, tt.moveList[0])
+			if pvMove(got.PV) != tt.want {
+				t.Errorf("want: %s got: %s", tt.want, pvMove(got.PV))
+			}
+		})
+	}
+}
+
+func TestContempt(t *testing.T) {
+	tests := []struct {
+		name     string
+		contempt string
+		moveList []Info
+		want     string
+	}{
+		{
+			name:     "no contempt takes the repetition",
+			contempt: "0",
+			moveList: []Info{{MultiPV: 1, Score: 0, PV: "g1f3 g8f6"}, {MultiPV: 2, Score: 30, PV: "e2e4"}},
+			want:     "g1f3",
+		},
+		{
+			name:     "contempt avoids the repetition",
+			contempt: "50",
+			moveList: []Info{{MultiPV: 1, Score: 0, PV: "g1f3 g8f6"}, {MultiPV: 2, Score: 30, PV: "e2e4"}},
+			want:     "e2e4",
+		},
+		{
+			name:     "negative contempt heads for the repetition",
+			contempt: "-50",
+			moveList: []Info{{MultiPV: 1, Score: 0, PV: "e2e4"}, {MultiPV: 2, Score: 0, PV: "g1f3 g8f6"}},
+			want:     "g1f3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := newTestUCI(t)
+
+			// g1f3 goes back to a position that's been on the board
+			u.SetPosition("startpos", "moves", "g1f3", "g8f6", "f3g1", "f6g8")
+			u.SetOption("Contempt", tt.contempt)
+
+			u.moveListMtx.Lock()
+			got := u.selectMove(tt.moveList, tt.moveList[0])
+			u.moveListMtx.Unlock()
+			if pvMove(got.PV) != tt.want {
+				t.Errorf("want: %s got: %s", tt.want, got.PV)
+			}
+		})
+	}
+}
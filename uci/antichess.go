@@ -0,0 +1,230 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const antichessStartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+
+// isAntichess is true for the variants where the goal is to lose all of your
+// pieces: captures are mandatory and the king is an ordinary piece.
+func (v Variant) isAntichess() bool {
+	return v == VariantAntichess || v == VariantGiveaway
+}
+
+// antichessMoves returns the legal antichess moves. There's no check, so every
+// pseudo-legal move counts, but if any capture is possible one must be made.
+func (b *Board) antichessMoves() []string {
+	moves := b.pseudoLegalMoves()
+
+	var captures []string
+	for _, move := range moves {
+		if b.isCapture(move) {
+			captures = append(captures, move)
+		}
+	}
+
+	if len(captures) != 0 {
+		return captures
+	}
+	return moves
+}
+
+// isCapture reports whether the UCI move takes a piece, en passant included.
+func (b *Board) isCapture(move string) bool {
+	from, to := uciToIndex(move[:2]), uciToIndex(move[2:4])
+	if b.Pos[to] != ' ' {
+		return true
+	}
+	p := b.Pos[from]
+	return (p == 'P' || p == 'p') && move[2:4] == b.EnPassantSquare
+}
+
+// antichessResult returns the winner once a side has given away all of its
+// pieces or has no move left, both of which win.
+func (b *Board) antichessResult() string {
+	var white, black bool
+	for _, c := range b.Pos {
+		white = white || isWhitePiece(c)
+		black = black || isBlackPiece(c)
+	}
+
+	switch {
+	case !white:
+		return "1-0"
+	case !black:
+		return "0-1"
+	case len(b.antichessMoves()) == 0:
+		if b.ActiveColor == "w" {
+			return "1-0"
+		}
+		return "0-1"
+	}
+	return ""
+}
+
+// antichessRepertoire holds the replies trollfish plays from known antichess
+// positions, keyed by piece placement and side to move so transpositions
+// share replies.
+type antichessRepertoire struct {
+	replies map[string][]repertoireReply
+}
+
+type repertoireReply struct {
+	move   string
+	weight int
+}
+
+// loadAntichessRepertoire reads a repertoire file. Each line is the moves
+// from the start position, a ':', then the replies to choose from, each with
+// an optional '*weight' (default 1):
+//
+//	# comment
+//	: e2e3*3 b2b3
+//	e2e3 : b7b5*2 c7c5
+//	e2e3 b7b5 : f1b5
+//
+// Every move is checked against the antichess rules.
+func loadAntichessRepertoire(filename string) (*antichessRepertoire, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return parseAntichessRepertoire(fp)
+}
+
+func parseAntichessRepertoire(r io.Reader) (*antichessRepertoire, error) {
+	rep := &antichessRepertoire{replies: make(map[string][]repertoireReply)}
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if idx := strings.IndexRune(line, '#'); idx != -1 {
+			line = line[:idx]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if err := rep.addLine(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rep, nil
+}
+
+func (rep *antichessRepertoire) addLine(line string) error {
+	moves, replies, ok := strings.Cut(line, ":")
+	if !ok {
+		return fmt.Errorf("missing ':' between moves and replies")
+	}
+
+	b := FENtoBoard(antichessStartFEN)
+	b.Variant = VariantAntichess
+	for _, move := range strings.Fields(moves) {
+		if !b.IsLegal(move) {
+			return fmt.Errorf("illegal move '%s' in '%s'", move, b.FEN())
+		}
+		b.Moves(move)
+	}
+
+	fields := strings.Fields(replies)
+	if len(fields) == 0 {
+		return fmt.Errorf("no replies")
+	}
+
+	key := repertoireKey(b.FEN())
+	for _, field := range fields {
+		move, weight := field, 1
+		if m, w, ok := strings.Cut(field, "*"); ok {
+			n, err := strconv.Atoi(w)
+			if err != nil || n < 0 {
+				return fmt.Errorf("bad weight in '%s'", field)
+			}
+			move, weight = m, n
+		}
+		if !b.IsLegal(move) {
+			return fmt.Errorf("illegal reply '%s' in '%s'", move, b.FEN())
+		}
+		rep.replies[key] = append(rep.replies[key], repertoireReply{move: move, weight: weight})
+	}
+
+	return nil
+}
+
+// Move picks a reply for the position at random, by weight, or returns "" if
+// the position isn't in the repertoire.
+func (rep *antichessRepertoire) Move(fen string) string {
+	return pickReply(rep.replies[repertoireKey(fen)])
+}
+
+// pickReply picks a reply at random, by weight. It returns "" if there are no
+// replies with a weight.
+func pickReply(replies []repertoireReply) string {
+	var total int
+	for _, r := range replies {
+		total += r.weight
+	}
+	if total == 0 {
+		return ""
+	}
+
+	n := rand.Intn(total)
+	for _, r := range replies {
+		if n < r.weight {
+			return r.move
+		}
+		n -= r.weight
+	}
+	return ""
+}
+
+func repertoireKey(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) < 2 {
+		return fen
+	}
+	return fields[0] + " " + fields[1]
+}
+
+// setAntichessRepertoire handles the AntichessRepertoire option.
+func (u *UCI) setAntichessRepertoire(filename string) {
+	var rep *antichessRepertoire
+	if filename != "" {
+		var err error
+		if rep, err = loadAntichessRepertoire(filename); err != nil {
+			u.WriteLine(fmt.Sprintf("info string ERR: antichess repertoire '%s': %v", filename, err))
+			return
+		}
+		u.logInfo(fmt.Sprintf("antichess repertoire: %s, %d positions", filename, len(rep.replies)))
+	}
+
+	u.moveListMtx.Lock()
+	u.antichessRepertoire = rep
+	u.moveListMtx.Unlock()
+}
+
+// antichessBookMove returns a repertoire reply for the current position, or ""
+// if there isn't one.
+func (u *UCI) antichessBookMove() string {
+	u.moveListMtx.Lock()
+	rep := u.antichessRepertoire
+	u.moveListMtx.Unlock()
+
+	if rep == nil || u.fen == "" {
+		return ""
+	}
+	return rep.Move(u.fen)
+}
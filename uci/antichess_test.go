@@ -0,0 +1,110 @@
+package uci
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAntichessMoves(t *testing.T) {
+	cases := []struct {
+		name    string
+		variant Variant
+		fen     string
+		want    []string
+	}{
+		{
+			name:    "capture is mandatory",
+			variant: VariantAntichess,
+			fen:     "rnbqkbnr/p1pppppp/8/1p6/4P3/8/PPPP1PPP/RNBQKBNR w - - 0 2",
+			want:    []string{"f1b5"},
+		},
+		{
+			name:    "king captures like any piece",
+			variant: VariantAntichess,
+			fen:     "8/8/8/8/8/8/2k5/K1R5 b - - 0 30",
+			want:    []string{"c2c1"},
+		},
+		{
+			name:    "promotion to king",
+			variant: VariantAntichess,
+			fen:     "8/P7/8/8/8/8/8/7k w - - 0 40",
+			want:    []string{"a7a8b", "a7a8k", "a7a8n", "a7a8q", "a7a8r"},
+		},
+		{
+			name:    "giveaway castles through attacked squares",
+			variant: VariantGiveaway,
+			fen:     "4k3/8/8/8/8/8/8/4K2R w K - 0 1",
+			want: []string{"e1d1", "e1d2", "e1e2", "e1f1", "e1f2", "e1g1",
+				"h1f1", "h1g1", "h1h2", "h1h3", "h1h4", "h1h5", "h1h6", "h1h7", "h1h8"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+			b.Variant = c.variant
+
+			got := b.LegalMoves()
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("\nwant: %v\ngot:  %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestAntichessResult(t *testing.T) {
+	cases := []struct {
+		fen  string
+		want string
+	}{
+		{fen: antichessStartFEN, want: ""},
+		{fen: "8/8/8/8/8/8/8/7k w - - 0 40", want: "1-0"},
+		{fen: "8/8/8/8/8/8/8/7K b - - 0 40", want: "0-1"},
+		{fen: "8/8/8/8/8/p7/P7/8 w - - 0 40", want: "1-0"}, // blocked, no moves
+	}
+
+	for _, c := range cases {
+		b := FENtoBoard(c.fen)
+		b.Variant = VariantAntichess
+		if got := b.VariantResult(); got != c.want {
+			t.Errorf("%s: want '%s' got '%s'", c.fen, c.want, got)
+		}
+	}
+}
+
+func TestParseAntichessRepertoire(t *testing.T) {
+	const file = `# troll lines
+: e2e3
+e2e3 b7b5 : f1b5*2 # forced anyway
+e2e3 : b7b5
+`
+	rep, err := parseAntichessRepertoire(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rep.Move(antichessStartFEN); got != "e2e3" {
+		t.Errorf("start: want e2e3 got '%s'", got)
+	}
+
+	b := FENtoBoard(antichessStartFEN)
+	b.Variant = VariantAntichess
+	b.Moves("e2e3", "b7b5")
+	if got := rep.Move(b.FEN()); got != "f1b5" {
+		t.Errorf("after 1. e3 b5: want f1b5 got '%s'", got)
+	}
+
+	b.Moves("f1b5")
+	if got := rep.Move(b.FEN()); got != "" {
+		t.Errorf("unknown position: want '' got '%s'", got)
+	}
+
+	for _, bad := range []string{"e2e4", ": e2e5", "e2e3 b7b5 : e3e4", ": e2e3*x"} {
+		if _, err := parseAntichessRepertoire(strings.NewReader(bad)); err == nil {
+			t.Errorf("'%s': expected an error", bad)
+		}
+	}
+}
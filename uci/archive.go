@@ -0,0 +1,243 @@
+package uci
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// archiveTime is how the archive keeps times: UTC and fixed width, so they
+// sort as text.
+const archiveTime = "2006-01-02T15:04:05.000000000Z"
+
+// archiveSchema is the GameArchive database. Scores are two columns, cp and
+// mate, as in Score.
+const archiveSchema = `
+CREATE TABLE IF NOT EXISTS games (
+	id                INTEGER PRIMARY KEY,
+	ended             TEXT NOT NULL,
+	variant           TEXT NOT NULL,
+	start_fen         TEXT NOT NULL,
+	moves             TEXT NOT NULL,
+	result            TEXT NOT NULL,
+	reason            TEXT NOT NULL,
+	color             TEXT NOT NULL,
+	opponent_name     TEXT NOT NULL,
+	opponent_title    TEXT NOT NULL,
+	opponent_rating   INTEGER NOT NULL,
+	opponent_computer INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS games_ended ON games (ended);
+CREATE INDEX IF NOT EXISTS games_opponent ON games (opponent_name COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS evals (
+	game_id      INTEGER NOT NULL REFERENCES games (id),
+	move_number  INTEGER NOT NULL,
+	color        TEXT NOT NULL,
+	fen          TEXT NOT NULL,
+	played       TEXT NOT NULL,
+	eval_cp      INTEGER NOT NULL,
+	eval_mate    INTEGER NOT NULL,
+	sf_best      TEXT NOT NULL,
+	sf_eval_cp   INTEGER NOT NULL,
+	sf_eval_mate INTEGER NOT NULL,
+	movetime     INTEGER NOT NULL,
+	clock        INTEGER NOT NULL,
+	opp_clock    INTEGER NOT NULL,
+	agro         INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS evals_game ON evals (game_id);
+
+CREATE TABLE IF NOT EXISTS agro_changes (
+	game_id     INTEGER NOT NULL REFERENCES games (id),
+	move_number INTEGER NOT NULL,
+	color       TEXT NOT NULL,
+	agro        INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS agro_changes_game ON agro_changes (game_id);
+`
+
+// opponent is who we're playing, from the GUI's UCI_Opponent:
+// "<title> <rating> <computer|human> <name>", with "none" for an unknown
+// title or rating.
+type opponent struct {
+	title    string
+	rating   int
+	computer bool
+	name     string
+}
+
+func parseOpponent(s string) opponent {
+	fields := strings.SplitN(strings.TrimSpace(s), " ", 4)
+	if len(fields) < 4 {
+		// not the standard form, keep what we were given
+		return opponent{name: strings.TrimSpace(s)}
+	}
+	o := opponent{computer: fields[2] == "computer", name: fields[3]}
+	if fields[0] != "none" {
+		o.title = fields[0]
+	}
+	o.rating, _ = strconv.Atoi(fields[1])
+	return o
+}
+
+func (o opponent) String() string {
+	if o.name == "" {
+		return "?"
+	}
+	s := o.name
+	if o.title != "" {
+		s = o.title + " " + s
+	}
+	if o.rating > 0 {
+		s += fmt.Sprintf(" (%d)", o.rating)
+	}
+	if o.computer {
+		s += " [computer]"
+	}
+	return s
+}
+
+// agroChange is a move agro was turned on or off with.
+type agroChange struct {
+	moveNumber int
+	color      string
+	agro       bool
+}
+
+// agroChanges finds the moves agro changed with, from the game's start
+// without it.
+func agroChanges(evals []moveEval) []agroChange {
+	var changes []agroChange
+	var agro bool
+	for _, m := range evals {
+		if m.agro != agro {
+			changes = append(changes, agroChange{moveNumber: m.moveNumber, color: m.color, agro: m.agro})
+			agro = m.agro
+		}
+	}
+	return changes
+}
+
+// archivedGame is a game as it's kept in the archive.
+type archivedGame struct {
+	id       int64
+	record   gameRecord
+	color    string // ours, "" if we never searched
+	opponent opponent
+	agro     []agroChange
+}
+
+// ArchiveQuery picks games out of a GameArchive database. Zero values match
+// every game.
+type ArchiveQuery struct {
+	Opponent string // name, in any case
+	Result   string // "1-0", "0-1", "1/2-1/2" or "*"
+	Variant  Variant
+	Since    time.Time // ended at or after
+	Limit    int       // the most recent games, all of them if 0
+
+	// Moves includes each game's moves and our evals.
+	Moves bool
+}
+
+// archiveGame adds the game that just ended to GameArchive, if the option's
+// set and a move was played.
+func (u *UCI) archiveGame(evals []moveEval) {
+	path := u.options.String("GameArchive")
+
+	u.moveListMtx.Lock()
+	h := u.history
+	variant := u.variant
+	result := u.gameResult
+	opp := u.gameOpponent
+	u.moveListMtx.Unlock()
+
+	if path == "" || h == nil || h.Len() == 0 {
+		return
+	}
+
+	g := archivedGame{
+		record:   newGameRecord(h, variant, result, evals),
+		opponent: opp,
+		agro:     agroChanges(evals),
+	}
+	if len(evals) > 0 {
+		g.color = evals[0].color
+	}
+
+	a, err := openArchive(path)
+	if err != nil {
+		u.logInfo(fmt.Sprintf("ERR: archive game: %v", err))
+		return
+	}
+	defer a.Close()
+
+	id, err := a.save(g)
+	if err != nil {
+		u.logInfo(fmt.Sprintf("ERR: archive game: %v", err))
+		return
+	}
+	u.logInfo(fmt.Sprintf("game archived to %s as %d", path, id))
+}
+
+// RunArchiveQuery writes the games in a GameArchive database matching q to w,
+// one line each, followed by their moves and evals if q.Moves is set.
+func RunArchiveQuery(path string, q ArchiveQuery, w io.Writer) error {
+	// opening it would create it, a mistyped path would look like no games
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return fmt.Errorf("%s: no such archive", path)
+	}
+
+	a, err := openArchive(path)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	games, err := a.games(q)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, g := range games {
+		r := g.record
+		line := fmt.Sprintf("%d %s %s %s vs %s: %s", g.id, r.Ended.Local().Format("2006-01-02 15:04"),
+			r.Variant, colorName(g.color), g.opponent, r.Result)
+		if r.Reason != "" {
+			line += " (" + r.Reason + ")"
+		}
+		line += fmt.Sprintf(", %d moves", len(r.Moves))
+		for _, c := range g.agro {
+			state := "off"
+			if c.agro {
+				state = "on"
+			}
+			line += fmt.Sprintf(", agro %s at %d%s", state, c.moveNumber, c.color)
+		}
+		_, _ = fmt.Fprintln(w, line)
+
+		if q.Moves {
+			_, _ = fmt.Fprintf(w, "  %s moves %s\n", r.Start, strings.Join(r.Moves, " "))
+			for _, m := range r.Evals {
+				_, _ = fmt.Fprintf(w, "  %d%s %s %s sf %s %s %dms\n", m.MoveNumber, m.Color,
+					m.Played, m.Eval, m.SFBest, m.SFEval, m.MoveTime)
+			}
+		}
+	}
+	_, _ = fmt.Fprintf(w, "%d games\n", len(games))
+	return nil
+}
+
+func colorName(color string) string {
+	switch color {
+	case "w":
+		return "white"
+	case "b":
+		return "black"
+	}
+	return "?"
+}
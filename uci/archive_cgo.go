@@ -0,0 +1,177 @@
+//go:build cgo
+
+package uci
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// archive is a GameArchive database.
+type archive struct {
+	db *sql.DB
+}
+
+// openArchive opens the archive at path, creating it if it doesn't exist.
+func openArchive(path string) (*archive, error) {
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(archiveSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &archive{db: db}, nil
+}
+
+func (a *archive) Close() error {
+	return a.db.Close()
+}
+
+// save adds a game, its evals and agro changes in one transaction, and
+// returns its id.
+func (a *archive) save(g archivedGame) (int64, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	r := g.record
+	res, err := tx.Exec(`INSERT INTO games (ended, variant, start_fen, moves, result, reason, color,
+		opponent_name, opponent_title, opponent_rating, opponent_computer)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Ended.UTC().Format(archiveTime), string(r.Variant), r.Start, strings.Join(r.Moves, " "),
+		r.Result, r.Reason, g.color,
+		g.opponent.name, g.opponent.title, g.opponent.rating, g.opponent.computer)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range r.Evals {
+		if _, err := tx.Exec(`INSERT INTO evals (game_id, move_number, color, fen, played, eval_cp, eval_mate,
+			sf_best, sf_eval_cp, sf_eval_mate, movetime, clock, opp_clock, agro)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, m.MoveNumber, m.Color, m.FEN, m.Played, m.Eval.CP, m.Eval.Mate,
+			m.SFBest, m.SFEval.CP, m.SFEval.Mate, m.MoveTime, m.Clock, m.OppClock, m.Agro); err != nil {
+			return 0, err
+		}
+	}
+	for _, c := range g.agro {
+		if _, err := tx.Exec(`INSERT INTO agro_changes (game_id, move_number, color, agro) VALUES (?, ?, ?, ?)`,
+			id, c.moveNumber, c.color, c.agro); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// games returns the games matching q, the most recent first.
+func (a *archive) games(q ArchiveQuery) ([]archivedGame, error) {
+	var where []string
+	var args []interface{}
+	if q.Opponent != "" {
+		where, args = append(where, "opponent_name = ? COLLATE NOCASE"), append(args, q.Opponent)
+	}
+	if q.Result != "" {
+		where, args = append(where, "result = ?"), append(args, q.Result)
+	}
+	if q.Variant != "" {
+		where, args = append(where, "variant = ?"), append(args, string(q.Variant))
+	}
+	if !q.Since.IsZero() {
+		where, args = append(where, "ended >= ?"), append(args, q.Since.UTC().Format(archiveTime))
+	}
+
+	query := `SELECT id, ended, variant, start_fen, moves, result, reason, color,
+		opponent_name, opponent_title, opponent_rating, opponent_computer FROM games`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY ended DESC, id DESC"
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	rows, err := a.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var games []archivedGame
+	for rows.Next() {
+		var g archivedGame
+		var ended, variant, moves string
+		if err := rows.Scan(&g.id, &ended, &variant, &g.record.Start, &moves, &g.record.Result, &g.record.Reason, &g.color,
+			&g.opponent.name, &g.opponent.title, &g.opponent.rating, &g.opponent.computer); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		g.record.Ended, _ = time.Parse(archiveTime, ended)
+		g.record.Variant = Variant(variant)
+		g.record.Moves = strings.Fields(moves)
+		games = append(games, g)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range games {
+		if err := a.loadAgro(&games[i]); err != nil {
+			return nil, err
+		}
+		if q.Moves {
+			if err := a.loadEvals(&games[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return games, nil
+}
+
+func (a *archive) loadAgro(g *archivedGame) error {
+	rows, err := a.db.Query(`SELECT move_number, color, agro FROM agro_changes WHERE game_id = ? ORDER BY rowid`, g.id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c agroChange
+		if err := rows.Scan(&c.moveNumber, &c.color, &c.agro); err != nil {
+			return err
+		}
+		g.agro = append(g.agro, c)
+	}
+	return rows.Err()
+}
+
+func (a *archive) loadEvals(g *archivedGame) error {
+	rows, err := a.db.Query(`SELECT move_number, color, fen, played, eval_cp, eval_mate,
+		sf_best, sf_eval_cp, sf_eval_mate, movetime, clock, opp_clock, agro
+		FROM evals WHERE game_id = ? ORDER BY rowid`, g.id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m moveRecord
+		if err := rows.Scan(&m.MoveNumber, &m.Color, &m.FEN, &m.Played, &m.Eval.CP, &m.Eval.Mate,
+			&m.SFBest, &m.SFEval.CP, &m.SFEval.Mate, &m.MoveTime, &m.Clock, &m.OppClock, &m.Agro); err != nil {
+			return err
+		}
+		g.record.Evals = append(g.record.Evals, m)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,146 @@
+//go:build cgo
+
+package uci
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.db")
+	a, err := openArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	start := FENtoBoard(startPosFEN)
+	h := NewHistory(start)
+	for _, move := range []string{"e2e4", "e7e5", "g1f3", "b8c6"} {
+		h.Push(move)
+	}
+	evals := []moveEval{
+		{moveNumber: 1, color: "w", fen: startPosFEN, sfMove: "e2e4", sfEval: Score{CP: 30}, playedMove: "e2e4", playedEval: Score{CP: 30}},
+		{moveNumber: 2, color: "w", sfMove: "g1f3", sfEval: Score{CP: 40}, playedMove: "g1f3", playedEval: Score{CP: 35}, agro: true, moveTime: 120},
+	}
+	ended := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	save := func(opp string, result GameResult, ended time.Time) {
+		t.Helper()
+		g := archivedGame{
+			record:   newGameRecord(h, VariantChess, result, evals),
+			color:    "w",
+			opponent: parseOpponent(opp),
+			agro:     agroChanges(evals),
+		}
+		g.record.Ended = ended
+		if _, err := a.save(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save("IM 2400 human Someone", GameResult{Result: "1-0", Reason: "checkmate"}, ended)
+	save("none 1800 computer other-bot", GameResult{Result: "0-1", Reason: "resignation"}, ended.Add(time.Hour))
+	save("IM 2450 human Someone", GameResult{}, ended.Add(2*time.Hour))
+
+	games, err := a.games(ArchiveQuery{Opponent: "someone", Moves: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("want 2 games got %d", len(games))
+	}
+	g := games[0]
+	if g.record.Result != "*" || g.opponent.rating != 2450 || !g.record.Ended.Equal(ended.Add(2*time.Hour)) {
+		t.Errorf("want the latest game first got %+v", g)
+	}
+	if strings.Join(g.record.Moves, " ") != "e2e4 e7e5 g1f3 b8c6" || g.record.Start != startPosFEN || g.color != "w" {
+		t.Errorf("game: %+v", g.record)
+	}
+	if len(g.record.Evals) != 2 || g.record.Evals[1].Eval != (Score{CP: 35}) || g.record.Evals[1].MoveTime != 120 || !g.record.Evals[1].Agro {
+		t.Errorf("evals: %+v", g.record.Evals)
+	}
+	if len(g.agro) != 1 || g.agro[0] != (agroChange{moveNumber: 2, color: "w", agro: true}) {
+		t.Errorf("agro changes: %+v", g.agro)
+	}
+
+	for _, c := range []struct {
+		q    ArchiveQuery
+		want int
+	}{
+		{ArchiveQuery{}, 3},
+		{ArchiveQuery{Result: "0-1"}, 1},
+		{ArchiveQuery{Since: ended.Add(time.Minute)}, 2},
+		{ArchiveQuery{Limit: 1}, 1},
+		{ArchiveQuery{Variant: VariantAtomic}, 0},
+	} {
+		games, err := a.games(c.q)
+		if err != nil || len(games) != c.want {
+			t.Errorf("%+v: want %d games got %d (%v)", c.q, c.want, len(games), err)
+		}
+		if !c.q.Moves && len(games) > 0 && games[0].record.Evals != nil {
+			t.Errorf("%+v: evals without Moves", c.q)
+		}
+	}
+
+	var out strings.Builder
+	if err := RunArchiveQuery(path, ArchiveQuery{Result: "1-0"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "white vs IM Someone (2400): 1-0 (checkmate), 4 moves, agro on at 2w\n1 games\n"
+	if !strings.HasSuffix(out.String(), want) {
+		t.Errorf("want: ...%s got: %s", want, out.String())
+	}
+}
+
+func TestArchiveGame(t *testing.T) {
+	u, out := newTestUCI(t)
+
+	path := filepath.Join(t.TempDir(), "games.db")
+	u.SetOption("GameArchive", path)
+	u.SetOption("UCI_Opponent", "none 1500 human someone")
+	u.SetOption("OwnBook", "false")
+
+	eng := newScriptedEngine([]string{
+		"info depth 18 seldepth 24 multipv 1 score cp 42 nodes 900000 nps 900000 time 1000 pv g1f3 b8c6",
+		"bestmove g1f3 ponder b8c6",
+	})
+	u.setEngine(eng, "")
+	go u.stockFishReadLoop(eng)
+
+	u.ResetGame()
+	u.SetPosition("startpos", "moves", "e2e4", "e7e5")
+	u.Go("wtime", "60000", "btime", "60000")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "bestmove ") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no bestmove:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	u.SetPosition("startpos", "moves", "e2e4", "e7e5", "g1f3", "b8c6")
+	// the next game's opponent, set before the ucinewgame ending this one
+	u.SetOption("UCI_Opponent", "none 2000 computer other")
+	u.ResetGame()
+
+	var archived strings.Builder
+	if err := RunArchiveQuery(path, ArchiveQuery{}, &archived); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(archived.String(), "white vs someone (1500): *, 4 moves\n1 games\n") {
+		t.Errorf("archive:\n%s", archived.String())
+	}
+
+	// nothing played, nothing archived
+	u.ResetGame()
+	archived.Reset()
+	if err := RunArchiveQuery(path, ArchiveQuery{}, &archived); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(archived.String(), "1 games\n") {
+		t.Errorf("archive:\n%s", archived.String())
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !cgo
+
+package uci
+
+import "errors"
+
+// errNoArchive is what a GameArchive does without cgo, the SQLite driver is
+// a C library.
+var errNoArchive = errors.New("the game archive needs a cgo build")
+
+type archive struct{}
+
+func openArchive(path string) (*archive, error) {
+	return nil, errNoArchive
+}
+
+func (a *archive) Close() error {
+	return errNoArchive
+}
+
+func (a *archive) save(g archivedGame) (int64, error) {
+	return 0, errNoArchive
+}
+
+func (a *archive) games(q ArchiveQuery) ([]archivedGame, error) {
+	return nil, errNoArchive
+}
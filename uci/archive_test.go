@@ -0,0 +1,34 @@
+package uci
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseOpponent(t *testing.T) {
+	cases := map[string]opponent{
+		"GM 2800 human Magnus Carlsen": {title: "GM", rating: 2800, name: "Magnus Carlsen"},
+		"none none computer stockfish": {computer: true, name: "stockfish"},
+		"BOT 1500 computer some-bot":   {title: "BOT", rating: 1500, computer: true, name: "some-bot"},
+		"someone":                      {name: "someone"},
+		"":                             {},
+	}
+	for s, want := range cases {
+		if got := parseOpponent(s); got != want {
+			t.Errorf("'%s': want: %+v got: %+v", s, want, got)
+		}
+	}
+}
+
+func TestRunArchiveQueryMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gmaes.db")
+	err := RunArchiveQuery(path, ArchiveQuery{}, &strings.Builder{})
+	if err == nil || !strings.HasSuffix(err.Error(), "no such archive") {
+		t.Errorf("want: no such archive got: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the query created %s", path)
+	}
+}
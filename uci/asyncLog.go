@@ -0,0 +1,165 @@
+package uci
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// asyncLogQueue is how many log lines can wait for a slow disk before new
+// ones are dropped.
+const asyncLogQueue = 4096
+
+// logEntry is a queued log line, or fn to run in order with the lines.
+type logEntry struct {
+	level LogLevel
+	line  string
+	raw   bool // Write's bytes, for the sinks to classify
+	fn    func()
+}
+
+// asyncLog writes the log from a goroutine of its own, so a slow disk never
+// holds up the GUI's output: WriteLine logs while it holds mtxStdout. When
+// the queue is full lines are dropped and the count is logged once it has
+// room.
+type asyncLog struct {
+	w io.WriteCloser
+
+	mtx     sync.Mutex
+	queue   chan logEntry
+	closed  bool
+	dropped int
+	done    chan struct{}
+}
+
+func newAsyncLog(w io.WriteCloser) *asyncLog {
+	a := &asyncLog{w: w, queue: make(chan logEntry, asyncLogQueue), done: make(chan struct{})}
+	go a.run()
+	return a
+}
+
+func (a *asyncLog) run() {
+	defer close(a.done)
+
+	for e := range a.queue {
+		switch {
+		case e.fn != nil:
+			// a Sync waits for the count too
+			a.reportDropped()
+			e.fn()
+		case e.raw:
+			_, _ = a.w.Write([]byte(e.line))
+		default:
+			a.writeLine(e.level, e.line)
+		}
+
+		if len(a.queue) == 0 {
+			a.reportDropped()
+		}
+	}
+}
+
+// reportDropped logs how many lines were dropped since it last did.
+func (a *asyncLog) reportDropped() {
+	a.mtx.Lock()
+	dropped := a.dropped
+	a.dropped = 0
+	a.mtx.Unlock()
+
+	if dropped > 0 {
+		a.writeLine(LogError, fmt.Sprintf("%s ERR: log: dropped %d lines", ts(), dropped))
+	}
+}
+
+func (a *asyncLog) writeLine(level LogLevel, line string) {
+	if w, ok := a.w.(interface{ write(LogLevel, string) }); ok {
+		w.write(level, line)
+		return
+	}
+	_, _ = a.w.Write([]byte(line + "\n"))
+}
+
+// enqueue queues e, dropping it if the queue is full unless wait is set. It
+// returns false once the log is closed.
+func (a *asyncLog) enqueue(e logEntry, wait bool) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.closed {
+		return false
+	}
+	if wait {
+		a.queue <- e
+		return true
+	}
+	select {
+	case a.queue <- e:
+	default:
+		a.dropped++
+	}
+	return true
+}
+
+func (a *asyncLog) Write(p []byte) (int, error) {
+	a.enqueue(logEntry{line: string(p), raw: true}, false)
+	return len(p), nil
+}
+
+func (a *asyncLog) write(level LogLevel, line string) {
+	a.enqueue(logEntry{level: level, line: line}, false)
+}
+
+// do runs fn in order with the queued lines, for changes that have to apply
+// from a point in the log on, like the json sinks' context.
+func (a *asyncLog) do(fn func()) {
+	a.enqueue(logEntry{fn: fn}, true)
+}
+
+// Sync waits for the queued lines to be written, then flushes the files.
+func (a *asyncLog) Sync() error {
+	written := make(chan struct{})
+	if !a.enqueue(logEntry{fn: func() { close(written) }}, true) {
+		return nil
+	}
+	<-written
+
+	if s, ok := a.w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close writes the queued lines and closes the log. It's safe to call more
+// than once.
+func (a *asyncLog) Close() error {
+	a.mtx.Lock()
+	if a.closed {
+		a.mtx.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.mtx.Unlock()
+
+	<-a.done
+	return a.w.Close()
+}
+
+// logSinks returns the log sinks behind the log, if it's made of them.
+func (u *UCI) logSinks() (*logSinks, bool) {
+	switch l := u.log.(type) {
+	case *logSinks:
+		return l, true
+	case *asyncLog:
+		s, ok := l.w.(*logSinks)
+		return s, ok
+	}
+	return nil, false
+}
+
+// syncLog flushes the log, before the process might exit.
+func (u *UCI) syncLog() {
+	if s, ok := u.log.(interface{ Sync() error }); ok {
+		_ = s.Sync()
+	}
+}
@@ -0,0 +1,64 @@
+package uci
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingLog is a log on a disk that hangs until release is closed.
+type blockingLog struct {
+	release chan struct{}
+
+	mtx   sync.Mutex
+	lines []string
+}
+
+func (l *blockingLog) Write(p []byte) (int, error) {
+	<-l.release
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.lines = append(l.lines, stripTimestamp(strings.TrimRight(string(p), "\n")))
+	return len(p), nil
+}
+
+func (l *blockingLog) Close() error { return nil }
+
+func TestAsyncLog(t *testing.T) {
+	slow := &blockingLog{release: make(chan struct{})}
+	a := newAsyncLog(slow)
+
+	// a hung disk doesn't hold up the writer, the overflow is dropped
+	start := time.Now()
+	for i := 0; i < asyncLogQueue+10; i++ {
+		a.write(LogInfo, "[2026-10-17 10:00:00] line")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("writes took %v with the disk hung", d)
+	}
+
+	close(slow.release)
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	slow.mtx.Lock()
+	n, last := len(slow.lines), slow.lines[len(slow.lines)-1]
+	slow.mtx.Unlock()
+	// the first line was taken off the queue before it filled
+	if n < asyncLogQueue || n > asyncLogQueue+2 {
+		t.Errorf("want about %d lines, got %d", asyncLogQueue, n)
+	}
+	if !strings.HasPrefix(last, "ERR: log: dropped ") {
+		t.Errorf("last line want the dropped count, got '%s'", last)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	a.write(LogInfo, "after close")
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,112 @@
+package uci
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const defaultBenchDepth = 13
+
+// benchPositions is a subset of Stockfish's bench positions.
+var benchPositions = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 10",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 11",
+	"4rrk1/pp1n3p/3q2pQ/2p1pb2/2PP4/2P3N1/P2B2PP/4RRK1 b - - 7 19",
+	"rq3rk1/ppp2ppp/1bnpb3/3N2B1/3NP3/7P/PPPQ1PP1/2KR3R w - - 7 14",
+	"r1bq1r1k/1pp1n1pp/1p1p4/4p2Q/4Pp2/1BNP4/PPP2PPP/3R1RK1 w - - 2 14",
+	"r3r1k1/2p2ppp/p1p1bn2/8/1q2P3/2NPQN2/PPP3PP/R4RK1 b - - 2 15",
+	"r1bbk1nr/pp3p1p/2n5/1N4p1/2Np1B2/8/PPP2PPP/2KR1B1R w kq - 0 13",
+	"r1bq1rk1/ppp1nppp/4n3/3p3Q/3P4/1BP1B3/PP1N2PP/R4RK1 w - - 1 16",
+	"4r1k1/r1q2ppp/ppp2n2/4P3/5Rb1/1N1BQ3/PPP3PP/R5K1 w - - 1 17",
+	"2rqkb1r/ppp2p2/2npb1p1/1N1Nn2p/2P1PP2/8/PP2B1PP/R1BQK2R b KQ - 0 11",
+	"r1bq1r1k/b1p1npp1/p2p3p/1p6/3PP3/1B2NN2/PP3PPP/R2Q1RK1 w - - 1 16",
+	"3r1rk1/p5pp/bpp1pp2/8/q1PP1P2/b3P3/P2NQRPP/1R2B1K1 b - - 6 22",
+	"r1q2rk1/2p1bppp/2Pp4/p6b/Q1PNp3/4B3/PP1R1PPP/2K4R w - - 2 18",
+	"8/8/8/8/5kp1/P7/8/1K1N4 w - - 0 1",
+	"8/3k4/8/8/8/4B3/4KB2/2B5 w - - 0 1",
+}
+
+// BenchResult is the total work done by a bench run.
+type BenchResult struct {
+	Nodes int64
+	Time  time.Duration
+}
+
+// NPS returns nodes per second over the whole run.
+func (r BenchResult) NPS() int64 {
+	ms := r.Time.Milliseconds()
+	if ms == 0 {
+		ms = 1
+	}
+	return r.Nodes * 1000 / ms
+}
+
+// Bench searches the bench positions to a fixed depth with one thread and a
+// fresh hash per position, so the node count is deterministic for a given
+// engine build. The report follows Stockfish's format, which is what
+// OpenBench-style tools parse.
+func Bench(ctx context.Context, depth int, w io.Writer) (BenchResult, error) {
+	if depth <= 0 {
+		depth = defaultBenchDepth
+	}
+
+	s, err := startSearcher(ctx, func(string) {})
+	if err != nil {
+		return BenchResult{}, err
+	}
+	defer s.quit()
+
+	return s.bench(depth, w)
+}
+
+// bench runs Bench on the searcher's engine.
+func (s *searcher) bench(depth int, w io.Writer) (BenchResult, error) {
+	s.setOption("Threads", 1)
+	s.setOption("Hash", 16)
+
+	var res BenchResult
+	for i, fen := range benchPositions {
+		s.sf.Write("ucinewgame")
+		if err := s.ready(); err != nil {
+			return res, err
+		}
+
+		start := time.Now()
+		sr, err := s.search(fen, "depth", fmt.Sprintf("%d", depth))
+		if err != nil {
+			return res, err
+		}
+		res.Time += time.Since(start)
+		res.Nodes += sr.Best().Nodes
+
+		_, _ = fmt.Fprintf(w, "Position: %d/%d (%s) bestmove %s nodes %d\n", i+1, len(benchPositions), fen, sr.BestMove, sr.Best().Nodes)
+	}
+
+	_, _ = fmt.Fprintf(w, "\n===========================\n")
+	_, _ = fmt.Fprintf(w, "Total time (ms) : %d\n", res.Time.Milliseconds())
+	_, _ = fmt.Fprintf(w, "Nodes searched  : %d\n", res.Nodes)
+	_, _ = fmt.Fprintf(w, "Nodes/second    : %d\n", res.NPS())
+	_, _ = fmt.Fprintf(w, "%d nodes %d nps\n", res.Nodes, res.NPS())
+
+	return res, nil
+}
+
+// bench runs the "bench [depth]" command from the GUI on its own engine
+// instance, leaving the game engine untouched.
+func (u *UCI) bench(v ...string) {
+	depth := defaultBenchDepth
+	if len(v) > 0 {
+		depth = atoi(v[0])
+	}
+
+	var sb strings.Builder
+	if _, err := Bench(u.ctx, depth, &sb); err != nil {
+		u.WriteLine(fmt.Sprintf("info string ERR: bench: %v", err))
+		return
+	}
+	u.WriteLines(strings.Split(strings.TrimRight(sb.String(), "\n"), "\n")...)
+}
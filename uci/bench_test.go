@@ -0,0 +1,67 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestBenchDeterministic runs bench twice and wants the same node count. With
+// no SF to run, a scripted engine answers, which still checks each run sets
+// one thread and starts every position with a fresh hash.
+func TestBenchDeterministic(t *testing.T) {
+	var scripted []*scriptedEngine
+	run := func() BenchResult {
+		t.Helper()
+
+		s, err := startSearcher(context.Background(), func(string) {})
+		var notFound *EngineNotFoundError
+		if errors.As(err, &notFound) {
+			searches := make([][]string, len(benchPositions))
+			for i := range searches {
+				searches[i] = []string{
+					fmt.Sprintf("info depth 5 seldepth 7 multipv 1 score cp 20 nodes %d nps 100000 time 10 pv e2e4", 1000+i),
+					"bestmove e2e4",
+				}
+			}
+			eng := newScriptedEngine(searches...)
+			scripted = append(scripted, eng)
+			s, err = startSearcherBackend(eng, func(string) {})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.quit()
+
+		var sb strings.Builder
+		res, err := s.bench(5, &sb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Nodes == 0 || !strings.Contains(sb.String(), fmt.Sprintf("Nodes searched  : %d\n", res.Nodes)) {
+			t.Fatalf("nodes %d, report:\n%s", res.Nodes, sb.String())
+		}
+		return res
+	}
+
+	first, second := run(), run()
+	if first.Nodes != second.Nodes {
+		t.Errorf("nodes: first run %d second %d", first.Nodes, second.Nodes)
+	}
+
+	for _, eng := range scripted {
+		eng.mtx.Lock()
+		sent := strings.Join(eng.lines, "\n")
+		eng.mtx.Unlock()
+		for _, want := range []string{"setoption name Threads value 1", "setoption name Hash value 16"} {
+			if !strings.Contains(sent, want) {
+				t.Errorf("want %q in:\n%s", want, sent)
+			}
+		}
+		if got := strings.Count(sent, "ucinewgame\nisready"); got != len(benchPositions) {
+			t.Errorf("fresh hash for %d of %d positions:\n%s", got, len(benchPositions), sent)
+		}
+	}
+}
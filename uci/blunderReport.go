@@ -0,0 +1,32 @@
+package uci
+
+import "fmt"
+
+// defaultBlunderReport is the cp a move of ours gives up to SF's best to be
+// in the blunder report. It's well under blunderTolerance, so the moves the
+// filter came close to catching are listed too.
+const defaultBlunderReport = 100
+
+// blunderReport lists our moves that gave up more than threshold cp to SF's
+// best, with the position they were played from, to tune the blunder filter
+// against. tolerance is the filter's for the game. It returns nothing for a
+// game without such a move.
+func blunderReport(evals []moveEval, threshold, tolerance int) []string {
+	var lines []string
+	for _, m := range evals {
+		loss := m.cpLoss()
+		if m.playedMove == m.sfMove || m.playedMove == "" || m.sfMove == "" || loss <= threshold {
+			continue
+		}
+		b := FENtoBoard(m.fen)
+		lines = append(lines, fmt.Sprintf("blunder: %d%s played %s (%s) sf %s (%s) loss %d fen %s",
+			m.moveNumber, m.color, pgnSAN(&b, m.playedMove), m.playedEval, pgnSAN(&b, m.sfMove), m.sfEval, loss, m.fen))
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	header := fmt.Sprintf("blunder report: %d of %d moves gave up more than %d cp, the filter's at %d",
+		len(lines), len(evals), threshold, tolerance)
+	return append([]string{header}, lines...)
+}
@@ -0,0 +1,35 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBlunderReport(t *testing.T) {
+	// arrange
+	const fen = "r1bqkb1r/1ppp1ppp/p1n2n2/4p3/B3P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 2 5"
+	evals := []moveEval{
+		// SF's move, whatever it cost
+		{moveNumber: 5, color: "w", fen: fen, sfMove: "e1g1", sfScore: 40, playedMove: "e1g1", playedScore: -300},
+		// under the threshold
+		{moveNumber: 5, color: "w", fen: fen, sfMove: "e1g1", sfScore: 40, playedMove: "d2d3", playedScore: 0},
+		{moveNumber: 5, color: "w", fen: fen, sfMove: "e1g1", sfScore: 40, sfEval: Score{CP: 40},
+			playedMove: "f3e5", playedScore: -120, playedEval: Score{CP: -120}},
+	}
+
+	// act
+	lines := blunderReport(evals, 100, 250)
+
+	// assert
+	want := []string{
+		"blunder report: 1 of 3 moves gave up more than 100 cp, the filter's at 250",
+		"blunder: 5w played Nxe5 (-1.20) sf O-O (0.40) loss 160 fen " + fen,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(lines, "\n"))
+	}
+
+	if lines := blunderReport(evals[:2], 100, 250); lines != nil {
+		t.Errorf("want no report got %q", lines)
+	}
+}
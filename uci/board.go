@@ -14,6 +14,20 @@ type Board struct {
 	EnPassantSquare string
 	HalfmoveClock   string
 	FullMove        string
+
+	// Variant is empty for standard chess.
+	Variant Variant
+
+	// Pocket holds crazyhouse pieces in hand, white's upper case.
+	Pocket string
+
+	// Promoted marks crazyhouse pieces that were promoted, one bit per
+	// square; they go back to the pocket as pawns when captured.
+	Promoted uint64
+
+	// Chess960 castles as the king taking its own rook, "e1h1", and keeps
+	// castling rights for rooks that may start anywhere.
+	Chess960 bool
 }
 
 func (b *Board) FEN() string {
@@ -38,6 +52,9 @@ func (b *Board) FEN() string {
 			}
 
 			fen.WriteRune(b.Pos[offset+j])
+			if b.Promoted&(1<<(offset+j)) != 0 {
+				fen.WriteRune('~')
+			}
 		}
 
 		if blanks != 0 {
@@ -46,6 +63,10 @@ func (b *Board) FEN() string {
 		}
 	}
 
+	if b.Variant == VariantCrazyhouse {
+		fen.WriteString(fmt.Sprintf("[%s]", b.Pocket))
+	}
+
 	fen.WriteString(fmt.Sprintf(" %s %s %s %s %s", b.ActiveColor, b.Castling, b.EnPassantSquare, b.HalfmoveClock, b.FullMove))
 
 	return fen.String()
@@ -64,6 +85,11 @@ func (b *Board) Moves(moves ...string) {
 		activeColor = 1
 	}
 
+	var rights []castleRight
+	if b.Chess960 {
+		rights = b.castleRights()
+	}
+
 	var wk, wq, bk, bq bool
 	for _, c := range b.Castling {
 		switch c {
@@ -86,6 +112,24 @@ func (b *Board) Moves(moves ...string) {
 			activeColor = 1
 		}
 
+		if len(move) >= 4 && move[1] == '@' {
+			b.drop(move, activeColor == 1)
+			b.EnPassantSquare = "-"
+			halfMoveClock++
+			continue
+		}
+
+		if b.Chess960 {
+			from, to := uciToIndex(move[:2]), uciToIndex(move[2:4])
+			rights = updateCastleRights(rights, b.Pos[from], from, to)
+			if b.isCastle960(from, to) {
+				b.castle960(from, to)
+				b.EnPassantSquare = "-"
+				halfMoveClock++
+				continue
+			}
+		}
+
 		fromUCI := move[:2]
 		toUCI := move[2:4]
 		var promote string
@@ -93,16 +137,20 @@ func (b *Board) Moves(moves ...string) {
 			promote = string(move[4])
 		}
 
-		// castling privileges
-		if fromUCI == "a1" || toUCI == "a1" {
-			wq = false
-		} else if fromUCI == "h1" || toUCI == "h1" {
-			wk = false
-		} else if fromUCI == "a8" || toUCI == "a8" {
-			bq = false
-		} else if fromUCI == "h8" || toUCI == "h8" {
-			bk = false
-		} else if fromUCI == "e1" {
+		// castling privileges; a rook taking a rook loses both
+		for _, sq := range []string{fromUCI, toUCI} {
+			switch sq {
+			case "a1":
+				wq = false
+			case "h1":
+				wk = false
+			case "a8":
+				bq = false
+			case "h8":
+				bk = false
+			}
+		}
+		if fromUCI == "e1" {
 			wk, wq = false, false
 		} else if fromUCI == "e8" {
 			bk, bq = false, false
@@ -110,10 +158,20 @@ func (b *Board) Moves(moves ...string) {
 
 		from, to := uciToIndex(fromUCI), uciToIndex(toUCI)
 		piece := b.Pos[from]
-		isCapture := b.Pos[to] != ' '
+		captured := b.Pos[to]
+		isCapture := captured != ' '
 		b.Pos[to] = b.Pos[from]
 		b.Pos[from] = ' '
 
+		if b.Variant == VariantCrazyhouse {
+			b.pocketCapture(captured, b.Promoted&(1<<to) != 0)
+			b.Promoted &^= 1 << to
+			if b.Promoted&(1<<from) != 0 {
+				b.Promoted &^= 1 << from
+				b.Promoted |= 1 << to
+			}
+		}
+
 		if toUCI == b.EnPassantSquare && (piece == 'P' || piece == 'p') {
 			var captureOn int
 			if activeColor == 0 {
@@ -121,6 +179,9 @@ func (b *Board) Moves(moves ...string) {
 			} else {
 				captureOn = to + 8
 			}
+			if b.Variant == VariantCrazyhouse {
+				b.pocketCapture(b.Pos[captureOn], false)
+			}
 			b.Pos[captureOn] = ' '
 			isCapture = true
 		}
@@ -129,7 +190,8 @@ func (b *Board) Moves(moves ...string) {
 		b.EnPassantSquare = "-"
 		if piece == 'P' || piece == 'p' {
 			halfMoveClock = 0
-			if int(math.Abs(float64(to-from))) == 16 {
+			// horde pawns can double step from the first rank, but can't be taken en passant
+			if int(math.Abs(float64(to-from))) == 16 && (fromUCI[1] == '2' || fromUCI[1] == '7') {
 				var file rune
 				if activeColor == 0 {
 					file = '6' // next move is white's, so the target is in black's position
@@ -153,6 +215,23 @@ func (b *Board) Moves(moves ...string) {
 			} else {
 				b.Pos[to] = rune(promote[0] - 32)
 			}
+			if b.Variant == VariantCrazyhouse {
+				b.Promoted |= 1 << to
+			}
+		}
+
+		if b.Variant == VariantAtomic && isCapture {
+			b.explode(to)
+
+			// castling rights go with an exploded king or rook
+			wk = wk && b.Pos[60] == 'K' && b.Pos[63] == 'R'
+			wq = wq && b.Pos[60] == 'K' && b.Pos[56] == 'R'
+			bk = bk && b.Pos[4] == 'k' && b.Pos[7] == 'r'
+			bq = bq && b.Pos[4] == 'k' && b.Pos[0] == 'r'
+		}
+
+		if b.Chess960 {
+			continue
 		}
 
 		// white king castle
@@ -188,6 +267,13 @@ func (b *Board) Moves(moves ...string) {
 		b.ActiveColor = "b"
 	}
 
+	if b.Chess960 {
+		b.setCastleRights(b.validCastleRights(rights))
+		b.HalfmoveClock = fmt.Sprintf("%d", halfMoveClock)
+		b.FullMove = fmt.Sprintf("%d", fullMove)
+		return
+	}
+
 	// castling
 	var cstl strings.Builder
 	if wk {
@@ -216,7 +302,25 @@ func (b *Board) Moves(moves ...string) {
 
 func FENtoBoard(fen string) Board {
 	parts := strings.Split(fen, " ")
-	ranks := strings.Split(parts[0], "/")
+	// the clocks are optional, to SF too
+	switch len(parts) {
+	case 4:
+		parts = append(parts, "0", "1")
+	case 5:
+		parts = append(parts, "1")
+	}
+	placement := parts[0]
+
+	// crazyhouse: "rnbqkbnr/.../RNBQKBNR[Qp]"
+	var pocket string
+	var crazyhouse bool
+	if idx := strings.IndexByte(placement, '['); idx != -1 {
+		pocket = strings.TrimSuffix(placement[idx+1:], "]")
+		placement = placement[:idx]
+		crazyhouse = true
+	}
+
+	ranks := strings.Split(placement, "/")
 	b := Board{
 		ActiveColor:     parts[1],
 		Castling:        parts[2],
@@ -224,7 +328,13 @@ func FENtoBoard(fen string) Board {
 		HalfmoveClock:   parts[4],
 		FullMove:        parts[5],
 		Pos:             make([]rune, 64),
+		Pocket:          pocket,
+	}
+	if crazyhouse {
+		b.Variant = VariantCrazyhouse
 	}
+	// Shredder-FEN names the rooks' files, which only Chess960 needs
+	b.Chess960 = strings.ContainsAny(b.Castling, "ABCDEFGHabcdefgh")
 
 	for i := 7; i >= 0; i-- {
 		rank := ranks[i]
@@ -236,6 +346,8 @@ func FENtoBoard(fen string) Board {
 					b.Pos[offset] = ' '
 					offset++
 				}
+			} else if c == '~' {
+				b.Promoted |= 1 << (offset - 1)
 			} else {
 				b.Pos[offset] = c
 				offset++
@@ -246,9 +358,89 @@ func FENtoBoard(fen string) Board {
 	return b
 }
 
+// checkFEN reports a FEN FENtoBoard can't read: it needs 8 ranks of 8
+// squares, the side to move, castling and en passant. The clocks can be left
+// off.
+func checkFEN(fen string) error {
+	parts := strings.Split(fen, " ")
+	if len(parts) < 4 || len(parts) > 6 {
+		return fmt.Errorf("FEN '%s' has %d fields, want 4 to 6", fen, len(parts))
+	}
+
+	placement := parts[0]
+	if idx := strings.IndexByte(placement, '['); idx != -1 {
+		placement = placement[:idx]
+	}
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return fmt.Errorf("FEN '%s' has %d ranks, want 8", fen, len(ranks))
+	}
+	for _, rank := range ranks {
+		var squares int
+		for _, c := range rank {
+			switch {
+			case c >= '1' && c <= '8':
+				squares += int(c - '0')
+			case c == '~':
+			case unicode.IsLetter(c):
+				squares++
+			default:
+				return fmt.Errorf("FEN '%s': '%c' in rank '%s'", fen, c, rank)
+			}
+		}
+		if squares != 8 {
+			return fmt.Errorf("FEN '%s': rank '%s' has %d squares, want 8", fen, rank, squares)
+		}
+	}
+	if parts[1] != "w" && parts[1] != "b" {
+		return fmt.Errorf("FEN '%s': side to move '%s', want w or b", fen, parts[1])
+	}
+	return nil
+}
+
 func uciToIndex(uci string) int {
 	file := int(uci[0]) - 'a'
 	rank := int(uci[1]) - '0' - 1
 	idx := (7-rank)*8 + file
 	return idx
 }
+
+// PolyglotKey returns the position's Zobrist key as Polyglot books compute it.
+// The en passant file only counts when a pawn of the side to move is next to
+// the pawn that just moved, so it could actually take en passant.
+func (b *Board) PolyglotKey() uint64 {
+	const kinds = "pPnNbBrRqQkK"
+
+	var key uint64
+	for i, c := range b.Pos {
+		kind := strings.IndexRune(kinds, c)
+		if kind == -1 {
+			continue
+		}
+		rank, file := 7-i/8, i%8
+		key ^= polyglotRandom[64*kind+8*rank+file]
+	}
+
+	for i, c := range "KQkq" {
+		if strings.ContainsRune(b.Castling, c) {
+			key ^= polyglotRandom[768+i]
+		}
+	}
+
+	if len(b.EnPassantSquare) == 2 {
+		ep := uciToIndex(b.EnPassantSquare)
+		pawn, from := 'P', ep+8 // white to move takes from the 5th rank
+		if b.ActiveColor == "b" {
+			pawn, from = 'p', ep-8
+		}
+		file := ep % 8
+		if (file > 0 && b.Pos[from-1] == pawn) || (file < 7 && b.Pos[from+1] == pawn) {
+			key ^= polyglotRandom[772+file]
+		}
+	}
+
+	if b.ActiveColor == "w" {
+		key ^= polyglotRandom[780]
+	}
+	return key
+}
@@ -218,3 +218,65 @@ func TestMakeMoves(t *testing.T) {
 		})
 	}
 }
+
+// The positions and keys from the Polyglot book format's description.
+func TestPolyglotKey(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want uint64
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0x463b96181691fc9c},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 0x823c9b50fd114196},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", 0x0756b94461c50fb0},
+		{"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2", 0x662fafb965db29d4},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", 0x22a48b5a8e47ff78},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR b kq - 0 3", 0x652a607ca3f242c1},
+		{"rnbq1bnr/ppp1pkpp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR w - - 0 4", 0x00fdd303c946bdd9},
+		{"rnbqkbnr/p1pppppp/8/8/PpP4P/8/1P1PPPP1/RNBQKBNR b KQkq c3 0 3", 0x3c8123ea7b067637},
+		{"rnbqkbnr/p1pppppp/8/8/P6P/R1p5/1P1PPPP1/1NBQKBNR b Kkq - 0 4", 0x5c3f9b829b279560},
+	}
+	for _, tt := range tests {
+		b := FENtoBoard(tt.fen)
+		if got := b.PolyglotKey(); got != tt.want {
+			t.Errorf("%s: want: %016x got: %016x", tt.fen, tt.want, got)
+		}
+	}
+
+	// the key follows the board through moves
+	b := FENtoBoard(startPosFEN)
+	b.Moves("e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7")
+	if got := b.PolyglotKey(); got != 0x00fdd303c946bdd9 {
+		t.Errorf("after moves: want: %016x got: %016x", uint64(0x00fdd303c946bdd9), got)
+	}
+}
+
+func TestCheckFEN(t *testing.T) {
+	cases := []struct {
+		fen  string
+		want string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ""},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -", ""},
+		{"rnbqkb1r/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[Nn] w KQkq - 0 1", ""},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w", "FEN 'rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w' has 2 fields, want 4 to 6"},
+		{"8/8/8 w - - 0 1", "FEN '8/8/8 w - - 0 1' has 3 ranks, want 8"},
+		{"9/8/8/8/8/8/8/8 w - - 0 1", "FEN '9/8/8/8/8/8/8/8 w - - 0 1': '9' in rank '9'"},
+		{"ppppppppp/8/8/8/8/8/8/8 w - - 0 1", "FEN 'ppppppppp/8/8/8/8/8/8/8 w - - 0 1': rank 'ppppppppp' has 9 squares, want 8"},
+		{"8/8/8/8/8/8/8/8 x - - 0 1", "FEN '8/8/8/8/8/8/8/8 x - - 0 1': side to move 'x', want w or b"},
+	}
+
+	for _, c := range cases {
+		var got string
+		if err := checkFEN(c.fen); err != nil {
+			got = err.Error()
+		}
+		if got != c.want {
+			t.Errorf("%s\nwant: '%s'\ngot:  '%s'", c.fen, c.want, got)
+		}
+	}
+
+	// the clocks default as they do for SF
+	if b := FENtoBoard("8/8/8/8/8/8/8/K6k b - -"); b.HalfmoveClock != "0" || b.FullMove != "1" {
+		t.Errorf("clocks want: 0 1 got: %s %s", b.HalfmoveClock, b.FullMove)
+	}
+}
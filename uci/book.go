@@ -0,0 +1,232 @@
+package uci
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+)
+
+// defaultBook is the built-in casual book: the gambits trollfish likes to
+// play when it isn't playing to win.
+//
+//go:embed book.json
+var defaultBook []byte
+
+// openingBook is a list of book lines. When several lines match the position
+// one is picked at random by line weight, then one of its replies by reply
+// weight.
+type openingBook struct {
+	lines []bookLine
+
+	// fallback is checked when none of the lines match, so a user's book
+	// can override the built-in lines.
+	fallback *openingBook
+}
+
+type bookLine struct {
+	prefix  string // FEN prefix the position has to start with
+	weight  int
+	replies []repertoireReply
+}
+
+// bookFile is the JSON form of a book:
+//
+//	{"lines": [
+//	  {"fen": "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w", "replies": [{"move": "d1h5"}]},
+//	  {"moves": "d2d4 e7e5", "weight": 2, "replies": [{"move": "d4e5", "weight": 3}, {"move": "g1f3"}]}
+//	]}
+//
+// A line is keyed by a FEN, which matches any position whose FEN starts with
+// its fields (so castling rights and the rest can be left off), or by the
+// moves from the start position. Lines and replies are picked at random by
+// weight, 1 if not given; a line with weight 0 is never played.
+type bookFile struct {
+	Lines []bookFileLine `json:"lines"`
+}
+
+type bookFileLine struct {
+	Comment string          `json:"comment,omitempty"`
+	FEN     string          `json:"fen,omitempty"`
+	Moves   string          `json:"moves,omitempty"`
+	Weight  *int            `json:"weight,omitempty"`
+	Replies []bookFileReply `json:"replies"`
+}
+
+type bookFileReply struct {
+	Move   string `json:"move"`
+	Weight *int   `json:"weight,omitempty"`
+}
+
+func loadOpeningBook(filename string) (*openingBook, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return parseOpeningBook(fp)
+}
+
+// parseOpeningBook reads a JSON book. Every reply is checked for legality.
+func parseOpeningBook(r io.Reader) (*openingBook, error) {
+	var f bookFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+
+	var book openingBook
+	for i, l := range f.Lines {
+		var b Board
+		var prefix string
+		switch {
+		case l.FEN != "" && l.Moves != "":
+			return nil, fmt.Errorf("line %d: has both fen and moves", i+1)
+		case l.FEN != "":
+			prefix = strings.TrimSpace(l.FEN)
+			if len(strings.Fields(prefix)) < 2 {
+				return nil, fmt.Errorf("line %d: fen '%s' needs at least the placement and side to move", i+1, prefix)
+			}
+			b = FENtoBoard(completeFEN(prefix))
+		case l.Moves != "":
+			b = FENtoBoard(startPosFEN)
+			for _, move := range strings.Fields(l.Moves) {
+				if !b.IsLegal(move) {
+					return nil, fmt.Errorf("line %d: illegal move '%s' in '%s'", i+1, move, b.FEN())
+				}
+				b.Moves(move)
+			}
+			prefix = repertoireKey(b.FEN())
+		default:
+			return nil, fmt.Errorf("line %d: needs a fen or moves", i+1)
+		}
+
+		if len(l.Replies) == 0 {
+			return nil, fmt.Errorf("line %d: no replies", i+1)
+		}
+
+		line := bookLine{prefix: prefix, weight: 1}
+		if l.Weight != nil {
+			line.weight = *l.Weight
+		}
+		if line.weight < 0 {
+			return nil, fmt.Errorf("line %d: negative weight", i+1)
+		}
+		for _, reply := range l.Replies {
+			weight := 1
+			if reply.Weight != nil {
+				weight = *reply.Weight
+			}
+			if weight < 0 {
+				return nil, fmt.Errorf("line %d: negative weight for '%s'", i+1, reply.Move)
+			}
+			if !b.IsLegal(reply.Move) {
+				return nil, fmt.Errorf("line %d: illegal reply '%s' in '%s'", i+1, reply.Move, b.FEN())
+			}
+			line.replies = append(line.replies, repertoireReply{move: reply.Move, weight: weight})
+		}
+		book.lines = append(book.lines, line)
+	}
+
+	if len(book.lines) == 0 {
+		return nil, errors.New("no lines")
+	}
+	return &book, nil
+}
+
+// completeFEN fills in the fields a FEN prefix leaves off.
+func completeFEN(prefix string) string {
+	fields := strings.Fields(prefix)
+	defaults := []string{"", "w", "KQkq", "-", "0", "1"}
+	for len(fields) < len(defaults) {
+		fields = append(fields, defaults[len(fields)])
+	}
+	return strings.Join(fields, " ")
+}
+
+// Move picks a reply for the position, or returns "" if no line matches.
+func (book *openingBook) Move(fen string) string {
+	for ; book != nil; book = book.fallback {
+		var matches []bookLine
+		var total int
+		for _, l := range book.lines {
+			if fenHasPrefix(fen, l.prefix) {
+				matches = append(matches, l)
+				total += l.weight
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if total == 0 {
+			return ""
+		}
+
+		n := rand.Intn(total)
+		for _, l := range matches {
+			if n < l.weight {
+				return pickReply(l.replies)
+			}
+			n -= l.weight
+		}
+	}
+	return ""
+}
+
+// fenHasPrefix reports whether fen starts with prefix's fields; "w KQ" isn't
+// a prefix of "w KQkq".
+func fenHasPrefix(fen, prefix string) bool {
+	return strings.HasPrefix(fen, prefix) && (len(fen) == len(prefix) || fen[len(prefix)] == ' ')
+}
+
+// with returns a book that checks other's lines before falling back to the
+// book's own.
+func (book *openingBook) with(other *openingBook) *openingBook {
+	if other == nil {
+		return book
+	}
+	return &openingBook{lines: other.lines, fallback: book}
+}
+
+var (
+	builtinBookOnce  sync.Once
+	builtinBookLines *openingBook
+)
+
+// builtinBook parses the built-in book the first time it's needed; the move
+// generator it checks replies with isn't set up until the package's init
+// functions have run.
+func builtinBook() *openingBook {
+	builtinBookOnce.Do(func() {
+		book, err := parseOpeningBook(strings.NewReader(string(defaultBook)))
+		if err != nil {
+			panic(fmt.Sprintf("built-in book: %v", err))
+		}
+		builtinBookLines = book
+	})
+	return builtinBookLines
+}
+
+// setOpeningBook handles the OpeningBook option. The file's lines are checked
+// before the built-in ones, so they can add lines or override its replies.
+func (u *UCI) setOpeningBook(filename string) {
+	book := builtinBook()
+	if filename != "" {
+		user, err := loadOpeningBook(filename)
+		if err != nil {
+			u.WriteLine(fmt.Sprintf("info string ERR: opening book '%s': %v", filename, err))
+			return
+		}
+		u.logInfo(fmt.Sprintf("opening book: %s, %d lines", filename, len(user.lines)))
+		book = builtinBook().with(user)
+	}
+
+	u.moveListMtx.Lock()
+	u.book = book
+	u.moveListMtx.Unlock()
+}
@@ -0,0 +1,117 @@
+package uci
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultBookDelayMin = 300  // ms
+	defaultBookDelayMax = 1500 // ms
+
+	// bookDelayInstantTime is the clock below which book moves are played
+	// instantly, same as the time manager's very low time.
+	bookDelayInstantTime = 5_000
+
+	// defaultBookDepth is the last move number book moves are played on;
+	// 0 plays them as long as the book has them.
+	defaultBookDepth = 0
+
+	// defaultBookExitTime is the least we think on the first move out of
+	// book, before the clock caps it.
+	defaultBookExitTime = 3000 // ms
+)
+
+// bookDelayRange returns the range to pick a book move's delay from. The first
+// moves come quickly and later book moves take up to the full delay, like a
+// human who knows the first few moves cold. The delay is capped to a small
+// fraction of the clock so bullet games don't lose time to it.
+func bookDelayRange(minDelay, maxDelay, moveNumber int, p GoParams, color string) (lo, hi int) {
+	if !p.HasClock() || maxDelay <= 0 {
+		return 0, 0
+	}
+
+	ourTime, ourInc, _, _ := p.Clock(color)
+	if ourTime < bookDelayInstantTime {
+		return 0, 0
+	}
+
+	// 1. -> 50%, 8. and later -> 100%
+	scale := 0.5 + 0.5*float64(min(max(moveNumber-1, 0), 7))/7
+
+	lo = int(float64(minDelay) * scale)
+	hi = int(float64(maxDelay) * scale)
+
+	limit := ourTime/60 + ourInc/2
+	hi = min(hi, limit)
+	lo = min(lo, hi)
+
+	return lo, hi
+}
+
+// playBookMove sends a book move after a randomized delay. "stop" sends it
+// right away.
+func (u *UCI) playBookMove(move string, p GoParams) {
+	lo, hi := bookDelayRange(u.options.Int("BookDelayMin"), u.options.Int("BookDelayMax"), u.gameMoveCount, p, u.gameActiveColor)
+	delay := lo
+	if hi > lo {
+		delay += rand.Intn(hi - lo + 1)
+	}
+	u.moveListMtx.Lock()
+	scramble := u.scramble
+	u.moveListMtx.Unlock()
+	if scramble {
+		// every ms counts when both flags are about to fall
+		delay = 0
+	}
+
+	u.logInfo(fmt.Sprintf("book_move: %s delay: %dms", move, delay))
+
+	u.moveListMtx.Lock()
+	u.session.bookMoves++
+	u.bookExit = true
+	u.moveListMtx.Unlock()
+
+	if delay == 0 {
+		u.WriteLine("bestmove " + move)
+		u.finishSearch()
+		return
+	}
+
+	stop := make(chan struct{})
+
+	u.moveListMtx.Lock()
+	u.bookStop = stop
+	u.searchDone = make(chan struct{})
+	u.moveListMtx.Unlock()
+
+	go func() {
+		t := time.NewTimer(time.Duration(delay) * time.Millisecond)
+		defer t.Stop()
+
+		select {
+		case <-t.C:
+		case <-stop:
+		case <-u.ctx.Done():
+		}
+
+		u.moveListMtx.Lock()
+		u.bookStop = nil
+		u.moveListMtx.Unlock()
+
+		u.WriteLine("bestmove " + move)
+		u.finishSearch()
+	}()
+}
+
+// stopBookDelay cuts short a delayed book move, if there is one.
+func (u *UCI) stopBookDelay() {
+	u.moveListMtx.Lock()
+	defer u.moveListMtx.Unlock()
+
+	if u.bookStop != nil {
+		close(u.bookStop)
+		u.bookStop = nil
+	}
+}
@@ -0,0 +1,47 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBookDelayRange(t *testing.T) {
+	clock := GoParams{WTime: 180_000, BTime: 180_000, WInc: 2000, BInc: 2000}
+
+	cases := []struct {
+		name       string
+		moveNumber int
+		p          GoParams
+		color      string
+		wantLo     int
+		wantHi     int
+	}{
+		{name: "first move", moveNumber: 1, p: clock, color: "w", wantLo: 150, wantHi: 750},
+		{name: "later move", moveNumber: 10, p: clock, color: "b", wantLo: 300, wantHi: 1500},
+		{name: "bullet", moveNumber: 8, p: GoParams{WTime: 30_000, BTime: 60_000}, color: "w", wantLo: 300, wantHi: 500},
+		{name: "time trouble", moveNumber: 8, p: GoParams{WTime: 60_000, BTime: 4_000}, color: "b", wantLo: 0, wantHi: 0},
+		{name: "no clock", moveNumber: 8, p: GoParams{MoveTime: 1000}, color: "w", wantLo: 0, wantHi: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lo, hi := bookDelayRange(300, 1500, c.moveNumber, c.p, c.color)
+			if lo != c.wantLo || hi != c.wantHi {
+				t.Errorf("want: %d-%d got: %d-%d", c.wantLo, c.wantHi, lo, hi)
+			}
+		})
+	}
+}
+
+func TestBookExit(t *testing.T) {
+	u, out := newTestUCI(t)
+	u.fen = startPosFEN
+
+	u.Go("movetime", "100")
+	if !strings.HasPrefix(out.String(), "bestmove ") {
+		t.Fatalf("want a book move, got %q", out.String())
+	}
+	if !u.bookExit {
+		t.Error("want the next search marked as the book exit")
+	}
+}
@@ -0,0 +1,151 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinBook(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want []string
+	}{
+		// mostly the Wayward Queen
+		{"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2", []string{"d1h5", "g1f3", "f1c4"}},
+		// the Englund and 1. d4 Nf6 share the position, see
+		// TestBuiltinBookEnglund
+		{"rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq d3 0 1", []string{"e7e5", "g8f6"}},
+		// lines can key on castling rights and the en passant square
+		{"rnbqkbnr/pp2pppp/3p4/8/4P3/2N5/PP3PPP/R1BQKBNR w KQkq - 0 5", []string{"f1c4"}},
+		{"rnbqkbnr/pp2pppp/3p4/8/4P3/2N5/PP3PPP/R1BQKBNR w - - 0 5", []string{""}},
+		{startPosFEN, []string{""}},
+	}
+	for _, tt := range tests {
+		seen := make(map[string]bool)
+		for i := 0; i < 200; i++ {
+			seen[builtinBook().Move(tt.fen)] = true
+		}
+		for _, want := range tt.want {
+			if !seen[want] {
+				t.Errorf("%s: want: %q in %v", tt.fen, want, seen)
+			}
+		}
+		if len(seen) != len(tt.want) {
+			t.Errorf("%s: want: %v got: %v", tt.fen, tt.want, seen)
+		}
+	}
+}
+
+// TestBuiltinBookEnglund checks the Englund stays the usual answer to 1. d4.
+func TestBuiltinBookEnglund(t *testing.T) {
+	counts := make(map[string]int)
+	const n = 4000
+	for i := 0; i < n; i++ {
+		counts[builtinBook().Move("rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq d3 0 1")]++
+	}
+	// 3:1, with plenty of room for chance
+	if counts["e7e5"] < n*65/100 || counts["e7e5"] > n*85/100 {
+		t.Errorf("e7e5: want about %d got %d", n*3/4, counts["e7e5"])
+	}
+	if counts["e7e5"]+counts["g8f6"] != n {
+		t.Errorf("want only e7e5 and g8f6, got %v", counts)
+	}
+}
+
+func TestOpeningBookLineWeights(t *testing.T) {
+	const file = `{"lines": [
+		{"moves": "e2e4", "weight": 3, "replies": [{"move": "c7c5"}]},
+		{"fen": "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b", "replies": [{"move": "e7e5"}]},
+		{"fen": "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq", "weight": 0, "replies": [{"move": "e7e6"}]},
+		{"fen": "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQ", "replies": [{"move": "d7d5"}]}
+	]}`
+
+	book, err := parseOpeningBook(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+	const n = 4000
+	for i := 0; i < n; i++ {
+		counts[book.Move("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")]++
+	}
+	if counts["e7e6"] != 0 {
+		t.Errorf("e7e6 has line weight 0, played %d times", counts["e7e6"])
+	}
+	// 3:1, with plenty of room for chance
+	if counts["c7c5"] < n*65/100 || counts["c7c5"] > n*85/100 {
+		t.Errorf("c7c5: want about %d got %d", n*3/4, counts["c7c5"])
+	}
+
+	// fields match whole: "KQ" isn't a prefix of "KQkq"
+	if got := book.Move("rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq d3 0 1"); got != "" {
+		t.Errorf("want no move, got %q", got)
+	}
+	if got := book.Move("rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQ d3 0 1"); got != "d7d5" {
+		t.Errorf("want d7d5, got %q", got)
+	}
+}
+
+func TestParseOpeningBook(t *testing.T) {
+	const file = `{"lines": [
+		{"moves": "e2e4 e7e5", "replies": [{"move": "f2f4", "weight": 0}, {"move": "d2d4"}]},
+		{"fen": "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b", "replies": [{"move": "e7e5"}]}
+	]}`
+
+	book, err := parseOpeningBook(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := builtinBook().with(book)
+	for _, tt := range []struct {
+		fen  string
+		want string
+	}{
+		// overrides the Wayward Queen, f4 has no weight
+		{"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2", "d2d4"},
+		// overrides 1. e4 c5
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "e7e5"},
+		// still in the built-in book
+		{"rnbqkbnr/pp1ppppp/8/2p5/3PP3/8/PPP2PPP/RNBQKBNR b KQkq d3 0 2", "c5d4"},
+	} {
+		if got := user.Move(tt.fen); got != tt.want {
+			t.Errorf("%s: want: %q got: %q", tt.fen, tt.want, got)
+		}
+	}
+
+	bad := map[string]string{
+		`{"lines": []}`: "no lines",
+		`{"lines": [{"moves": "e2e5", "replies": [{"move": "e7e5"}]}]}`:               "illegal move",
+		`{"lines": [{"moves": "e2e4", "replies": [{"move": "e2e4"}]}]}`:               "illegal reply",
+		`{"lines": [{"fen": "8/8/8/8/8/8/8/8", "replies": [{"move": "e2e4"}]}]}`:      "needs at least",
+		`{"lines": [{"moves": "e2e4", "replies": []}]}`:                               "no replies",
+		`{"lines": [{"moves": "e2e4", "replies": [{"move": "e7e5", "weight": -1}]}]}`: "negative weight",
+		`{"lines": [{"moves": "e2e4", "weight": -1, "replies": [{"move": "e7e5"}]}]}`: "negative weight",
+		`{"lines": [{"fen": "x w", "moves": "e2e4", "replies": [{"move": "e7e5"}]}]}`: "both",
+		`{"lines": [{"replies": [{"move": "e7e5"}]}]}`:                                "needs a fen or moves",
+	}
+	for file, want := range bad {
+		_, err := parseOpeningBook(strings.NewReader(file))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: want error containing %q got %v", file, want, err)
+		}
+	}
+}
+
+func TestOwnBookOption(t *testing.T) {
+	u, _ := newTestUCI(t)
+	if !u.ownBook {
+		t.Fatal("OwnBook should default to true")
+	}
+
+	u.SetOption("OwnBook", "false")
+	if u.ownBook {
+		t.Error("OwnBook false: want the book off")
+	}
+	u.SetOption("OwnBook", "TRUE")
+	if !u.ownBook {
+		t.Error("OwnBook TRUE: want the book on")
+	}
+}
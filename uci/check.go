@@ -0,0 +1,277 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// checkEngineTimeout is how long an engine has to answer "uci".
+const checkEngineTimeout = 10 * time.Second
+
+// CheckConfig is the configuration the check command validates. Empty fields
+// aren't checked, except Engine which defaults to the configured SF path.
+type CheckConfig struct {
+	Engine              string
+	VariantEngine       string
+	SyzygyPath          string
+	AntichessRepertoire string
+	OpeningBook         string
+	PolyglotBook        string
+	LogSinks            string
+
+	// Options are the engine's declared options, checked the same way New
+	// does.
+	Options []Option
+}
+
+// CheckResult is the outcome of one check.
+type CheckResult struct {
+	Name   string
+	Detail string
+	Err    error
+}
+
+func (r CheckResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("FAIL %s: %v", r.Name, r.Err)
+	}
+	return fmt.Sprintf("ok   %s: %s", r.Name, r.Detail)
+}
+
+// RunCheck validates the configuration and writes a line per check to w. It
+// returns an error if anything failed, so it can gate starting a rated game.
+func RunCheck(ctx context.Context, cfg CheckConfig, w io.Writer) ([]CheckResult, error) {
+	engine := cfg.Engine
+	if engine == "" {
+		engine = defaultEnginePath()
+	}
+
+	var results []CheckResult
+	add := func(r CheckResult) {
+		results = append(results, r)
+		_, _ = fmt.Fprintln(w, r)
+	}
+
+	add(checkConfig(configPath()))
+	add(checkEngine(ctx, "engine", engine))
+	if cfg.VariantEngine != "" {
+		add(checkEngine(ctx, "variant engine", cfg.VariantEngine))
+	}
+	add(checkOptions(cfg.Options))
+	add(checkOpeningBook(cfg.OpeningBook))
+	if cfg.PolyglotBook != "" {
+		add(checkPolyglotBook(cfg.PolyglotBook))
+	}
+	if cfg.AntichessRepertoire != "" {
+		add(checkAntichessRepertoire(cfg.AntichessRepertoire))
+	}
+	if cfg.SyzygyPath != "" {
+		add(checkSyzygyPath(cfg.SyzygyPath))
+	}
+	add(checkEndgameTables())
+	if cfg.LogSinks != "" {
+		add(checkLogSinks(cfg.LogSinks))
+	}
+
+	var failed int
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	_, _ = fmt.Fprintf(w, "all %d checks passed\n", len(results))
+	return results, nil
+}
+
+// checkEngine starts the engine and waits for it to answer "uci".
+func checkEngine(ctx context.Context, name, path string) CheckResult {
+	r := CheckResult{Name: name}
+
+	ctx, cancel := context.WithTimeout(ctx, checkEngineTimeout)
+	defer cancel()
+
+	sf, err := StartStockfish(ctx, path, func(string) {})
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	defer sf.Quit()
+
+	s := searcher{sf: sf, logInfo: func(string) {}}
+	sf.Write("uci")
+	lines, err := s.waitFor("uciok")
+	if err != nil {
+		r.Err = fmt.Errorf("'%s' didn't answer uci: %w", path, err)
+		return r
+	}
+
+	var caps engineCaps
+	for _, line := range lines {
+		caps.parse(line)
+	}
+	r.Detail = path
+	if caps.name != "" {
+		r.Detail = fmt.Sprintf("%s (%s)", caps.name, path)
+	}
+	return r
+}
+
+// checkOptions declares the options along with the built-in ones.
+func checkOptions(options []Option) CheckResult {
+	r := CheckResult{Name: "options"}
+
+	u, err := New("check", "check", options...)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Detail = fmt.Sprintf("%d options", len(u.options.All()))
+	return r
+}
+
+// checkConfig loads the config file, if there is one.
+func checkConfig(path string) CheckResult {
+	r := CheckResult{Name: "config"}
+
+	if _, err := os.Stat(path); err != nil {
+		r.Detail = fmt.Sprintf("no config file (%s), using defaults", path)
+		return r
+	}
+	if _, err := LoadConfig(path); err != nil {
+		r.Err = err
+		return r
+	}
+	r.Detail = path
+	return r
+}
+
+// checkOpeningBook plays every built-in first move from the start position
+// and loads the book file, if there is one.
+func checkOpeningBook(path string) CheckResult {
+	r := CheckResult{Name: "opening book"}
+
+	b := FENtoBoard(startPosFEN)
+	for _, item := range firstMoveMap {
+		if !b.IsLegal(item.uci) {
+			r.Err = fmt.Errorf("first move '%s' is illegal", item.uci)
+			return r
+		}
+	}
+	r.Detail = fmt.Sprintf("%d first moves, %d built-in lines", len(firstMoveMap), len(builtinBook().lines))
+
+	if path != "" {
+		book, err := loadOpeningBook(path)
+		if err != nil {
+			r.Err = fmt.Errorf("'%s': %w", path, err)
+			return r
+		}
+		r.Detail += fmt.Sprintf(", %d lines (%s)", len(book.lines), path)
+	}
+	return r
+}
+
+// checkPolyglotBook loads the book and looks up the start position, which any
+// useful book has.
+func checkPolyglotBook(path string) CheckResult {
+	r := CheckResult{Name: "polyglot book"}
+
+	book, err := loadPolyglotBook(path)
+	if err != nil {
+		r.Err = fmt.Errorf("'%s': %w", path, err)
+		return r
+	}
+	r.Detail = fmt.Sprintf("%d entries (%s)", len(book.entries), path)
+	if book.Move(FENtoBoard(startPosFEN)) == "" {
+		r.Detail += ", no start position moves"
+	}
+	return r
+}
+
+func checkAntichessRepertoire(path string) CheckResult {
+	r := CheckResult{Name: "antichess repertoire"}
+
+	rep, err := loadAntichessRepertoire(path)
+	if err != nil {
+		r.Err = fmt.Errorf("'%s': %w", path, err)
+		return r
+	}
+	r.Detail = fmt.Sprintf("%d positions (%s)", len(rep.replies), path)
+	return r
+}
+
+// checkSyzygyPath checks each directory of a SyzygyPath (':' separated, as
+// SF takes it) exists and has tables in it.
+func checkSyzygyPath(path string) CheckResult {
+	r := CheckResult{Name: "tablebases"}
+
+	var wdl, dtz int
+	for _, dir := range filepath.SplitList(path) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			r.Err = err
+			return r
+		}
+
+		var tables int
+		for _, e := range entries {
+			switch filepath.Ext(e.Name()) {
+			case ".rtbw":
+				wdl++
+				tables++
+			case ".rtbz":
+				dtz++
+				tables++
+			}
+		}
+		if tables == 0 {
+			r.Err = fmt.Errorf("no .rtbw/.rtbz files in '%s'", dir)
+			return r
+		}
+	}
+	r.Detail = fmt.Sprintf("%d wdl %d dtz", wdl, dtz)
+	return r
+}
+
+// checkEndgameTables builds the built-in endgame tables the policy plays
+// trivial endings from.
+func checkEndgameTables() CheckResult {
+	r := CheckResult{Name: "endgame tables"}
+
+	buildEndgameTables()
+	move, _, ok := EndgameMove(FENtoBoard("8/8/8/4k3/8/8/8/4K2Q w - - 0 1"))
+	if !ok {
+		r.Err = errors.New("no move for KQK")
+		return r
+	}
+	r.Detail = fmt.Sprintf("KQK plays %s", move)
+	return r
+}
+
+func checkLogSinks(spec string) CheckResult {
+	r := CheckResult{Name: "log sinks"}
+
+	configs, err := parseLogSinks(spec)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	names := make([]string, 0, len(configs))
+	for _, c := range configs {
+		name := c.kind
+		if c.target != "" {
+			name += "=" + c.target
+		}
+		names = append(names, fmt.Sprintf("%s (%s)", name, c.level))
+	}
+	r.Detail = strings.Join(names, ", ")
+	return r
+}
@@ -0,0 +1,60 @@
+package uci
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecks(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	tables := filepath.Join(dir, "tables")
+	for _, d := range []string{empty, tables} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"KQvK.rtbw", "KQvK.rtbz", "KRvK.rtbw"} {
+		if err := os.WriteFile(filepath.Join(tables, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repertoire := filepath.Join(dir, "antichess.txt")
+	if err := os.WriteFile(repertoire, []byte(": e2e3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	badRepertoire := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(badRepertoire, []byte(": e2e5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		result CheckResult
+		ok     bool
+	}{
+		{name: "tablebases", result: checkSyzygyPath(tables), ok: true},
+		{name: "empty tablebase dir", result: checkSyzygyPath(tables + string(filepath.ListSeparator) + empty), ok: false},
+		{name: "missing tablebase dir", result: checkSyzygyPath(filepath.Join(dir, "nope")), ok: false},
+		{name: "repertoire", result: checkAntichessRepertoire(repertoire), ok: true},
+		{name: "bad repertoire", result: checkAntichessRepertoire(badRepertoire), ok: false},
+		{name: "options", result: checkOptions([]Option{{Name: "Threads", Type: OptionTypeSpin, Default: "1", Min: 1, Max: 8}}), ok: true},
+		{name: "bad option", result: checkOptions([]Option{{Name: "Threads", Type: OptionTypeSpin, Default: "1", Min: 8, Max: 1}}), ok: false},
+		{name: "opening book", result: checkOpeningBook(""), ok: true},
+		{name: "log sinks", result: checkLogSinks("file=trollfish.log:debug,syslog:error"), ok: true},
+		{name: "bad log sinks", result: checkLogSinks("kafka"), ok: false},
+		{name: "missing engine", result: checkEngine(context.Background(), "engine", filepath.Join(dir, "stockfish")), ok: false},
+	}
+
+	for _, c := range cases {
+		if ok := c.result.Err == nil; ok != c.ok {
+			t.Errorf("%s: want ok %v got %s", c.name, c.ok, c.result)
+		}
+	}
+
+	if got, want := checkSyzygyPath(tables).Detail, "2 wdl 1 dtz"; got != want {
+		t.Errorf("tablebases: want '%s' got '%s'", want, got)
+	}
+}
@@ -0,0 +1,217 @@
+package uci
+
+import (
+	"strings"
+	"unicode"
+)
+
+// castleRight is a Chess960 castling right, named by its rook's square.
+type castleRight struct {
+	white bool
+	rook  int
+}
+
+// backRank is the first square of the color's back rank.
+func backRank(white bool) int {
+	if white {
+		return 56
+	}
+	return 0
+}
+
+// castleRights reads the castling field, either Shredder-FEN ("HAha", the
+// rooks' files) or X-FEN ("KQkq", the outermost rook on that side of the
+// king).
+func (b *Board) castleRights() []castleRight {
+	var rights []castleRight
+	for _, c := range b.Castling {
+		white := unicode.IsUpper(c)
+		rank := backRank(white)
+		rook := 'r'
+		if white {
+			rook = 'R'
+		}
+
+		king := b.kingSquare(white)
+		if king < rank || king >= rank+8 {
+			continue
+		}
+
+		sq := -1
+		switch unicode.ToLower(c) {
+		case 'k':
+			for s := rank + 7; s > king; s-- {
+				if b.Pos[s] == rook {
+					sq = s
+					break
+				}
+			}
+		case 'q':
+			for s := rank; s < king; s++ {
+				if b.Pos[s] == rook {
+					sq = s
+					break
+				}
+			}
+		case 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h':
+			sq = rank + int(unicode.ToLower(c)-'a')
+		}
+		if sq != -1 {
+			rights = append(rights, castleRight{white: white, rook: sq})
+		}
+	}
+	return rights
+}
+
+// setCastleRights writes the castling field as X-FEN: K or Q when the rook is
+// the outermost on its side of the king, as in standard chess, otherwise the
+// rook's file.
+func (b *Board) setCastleRights(rights []castleRight) {
+	var sb strings.Builder
+	for _, white := range []bool{true, false} {
+		for _, r := range rights {
+			if r.white != white {
+				continue
+			}
+			sb.WriteRune(b.castleRightRune(r))
+		}
+	}
+	if sb.Len() == 0 {
+		b.Castling = "-"
+		return
+	}
+	b.Castling = sb.String()
+}
+
+func (b *Board) castleRightRune(r castleRight) rune {
+	rank := backRank(r.white)
+	king := b.kingSquare(r.white)
+	rook := b.Pos[r.rook]
+
+	c := rune('a' + r.rook%8)
+	outermost := true
+	if r.rook > king {
+		c = 'k'
+		for s := r.rook + 1; s < rank+8; s++ {
+			outermost = outermost && b.Pos[s] != rook
+		}
+	} else {
+		c = 'q'
+		for s := rank; s < r.rook; s++ {
+			outermost = outermost && b.Pos[s] != rook
+		}
+	}
+	if !outermost {
+		c = rune('a' + r.rook%8)
+	}
+	if r.white {
+		c = unicode.ToUpper(c)
+	}
+	return c
+}
+
+// validCastleRights drops rights whose rook or king is gone, for example
+// exploded in atomic.
+func (b *Board) validCastleRights(rights []castleRight) []castleRight {
+	var kept []castleRight
+	for _, r := range rights {
+		king := b.kingSquare(r.white)
+		rank := backRank(r.white)
+		rook := 'r'
+		if r.white {
+			rook = 'R'
+		}
+		if b.Pos[r.rook] == rook && king >= rank && king < rank+8 {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// castle960Targets returns where the king and rook end up when castling with
+// the rook on rook: the g and f files on the king side, c and d on the
+// queen side, as in standard chess.
+func castle960Targets(king, rook int) (kingTo, rookTo int) {
+	rank := king / 8 * 8
+	if rook > king {
+		return rank + 6, rank + 5
+	}
+	return rank + 2, rank + 3
+}
+
+// isCastle960 reports whether from-to is a Chess960 castle, written as the
+// king taking its own rook.
+func (b *Board) isCastle960(from, to int) bool {
+	king, rook := b.Pos[from], b.Pos[to]
+	switch king {
+	case 'K':
+		return rook == 'R'
+	case 'k':
+		return rook == 'r'
+	}
+	return false
+}
+
+// castle960 moves the king and rook for a Chess960 castle.
+func (b *Board) castle960(from, to int) {
+	king, rook := b.Pos[from], b.Pos[to]
+	kingTo, rookTo := castle960Targets(from, to)
+	b.Pos[from], b.Pos[to] = ' ', ' '
+	b.Pos[kingTo], b.Pos[rookTo] = king, rook
+}
+
+// castlingMoves960 returns the side to move's Chess960 castles. Every square
+// the king and rook cross or land on must be empty but for the two of them,
+// and the king can't pass through check.
+func (b *Board) castlingMoves960() []string {
+	white := b.ActiveColor == "w"
+	king := b.kingSquare(white)
+	if king == -1 {
+		return nil
+	}
+
+	var moves []string
+rightLoop:
+	for _, r := range b.castleRights() {
+		if r.white != white {
+			continue
+		}
+		kingTo, rookTo := castle960Targets(king, r.rook)
+
+		lo, hi := min(min(king, kingTo), min(r.rook, rookTo)), max(max(king, kingTo), max(r.rook, rookTo))
+		for sq := lo; sq <= hi; sq++ {
+			if sq != king && sq != r.rook && b.Pos[sq] != ' ' {
+				continue rightLoop
+			}
+		}
+
+		// with the rook lifted, since it may be what blocks an attack along
+		// the back rank
+		pos := b.Clone()
+		pos.Pos[r.rook] = ' '
+		lo, hi = min(king, kingTo), max(king, kingTo)
+		for sq := lo; sq <= hi; sq++ {
+			if pos.IsAttacked(sq, !white) {
+				continue rightLoop
+			}
+		}
+		moves = append(moves, indexToUCI(king)+indexToUCI(r.rook))
+	}
+	return moves
+}
+
+// updateCastleRights drops the rights a move from-to loses: all of a side's
+// when its king moves, and a rook's when it moves or is taken.
+func updateCastleRights(rights []castleRight, piece rune, from, to int) []castleRight {
+	kept := rights[:0]
+	for _, r := range rights {
+		if unicode.ToLower(piece) == 'k' && isWhitePiece(piece) == r.white {
+			continue
+		}
+		if r.rook == from || r.rook == to {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
@@ -0,0 +1,130 @@
+package uci
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestChess960Moves(t *testing.T) {
+	cases := []struct {
+		name     string
+		fen      string
+		chess960 bool
+		moves    []string
+		want     string
+	}{
+		{
+			name:  "king side",
+			fen:   "4k3/8/8/8/8/8/8/1R3KR1 w GB - 0 1",
+			moves: []string{"f1g1"},
+			want:  "4k3/8/8/8/8/8/8/1R3RK1 b - - 1 1",
+		},
+		{
+			name:  "queen side",
+			fen:   "4k3/8/8/8/8/8/8/1R3KR1 w GB - 0 1",
+			moves: []string{"f1b1"},
+			want:  "4k3/8/8/8/8/8/8/2KR2R1 b - - 1 1",
+		},
+		{
+			name:  "a rook move loses its right, the outermost rook is written as X-FEN",
+			fen:   "4k3/8/8/8/8/8/8/1R3KR1 w GB - 0 1",
+			moves: []string{"g1g2"},
+			want:  "4k3/8/8/8/8/8/6R1/1R3K2 b Q - 1 1",
+		},
+		{
+			name:     "the standard position castles as king takes rook",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			chess960: true,
+			moves:    []string{"e1h1", "e8a8"},
+			want:     "2kr3r/8/8/8/8/8/8/R4RK1 w - - 2 2",
+		},
+		{
+			name:  "an inner rook keeps its file",
+			fen:   "rk2r3/8/8/8/8/8/8/RK2R3 w EAea - 0 1",
+			moves: []string{"a1a2"},
+			want:  "rk2r3/8/8/8/8/8/R7/1K2R3 b Kkq - 1 1",
+		},
+		{
+			name:  "a rook with another beyond it is written as its file",
+			fen:   "4k3/8/8/8/8/8/8/1K2R2R b E - 0 1",
+			moves: []string{"e8e7"},
+			want:  "8/4k3/8/8/8/8/8/1K2R2R w E - 1 2",
+		},
+		{
+			name:  "the king doesn't move",
+			fen:   "4k3/8/8/8/8/8/8/6KR w H - 0 1",
+			moves: []string{"g1h1"},
+			want:  "4k3/8/8/8/8/8/8/5RK1 b - - 1 1",
+		},
+		{
+			name:  "a rook taking a rook loses both rights",
+			fen:   "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			moves: []string{"h1h8"},
+			want:  "r3k2R/8/8/8/8/8/8/R3K3 b Qq - 0 1",
+		},
+		{
+			name:  "standard castling is unchanged",
+			fen:   "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			moves: []string{"e1g1"},
+			want:  "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+			b.Chess960 = b.Chess960 || c.chess960
+			b.Moves(c.moves...)
+			if got := b.FEN(); got != c.want {
+				t.Errorf("\nwant: %s\ngot:  %s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	cases := []struct {
+		name string
+		fen  string
+		want []string
+	}{
+		{name: "both sides", fen: "4k3/8/8/8/8/8/8/1R3KR1 w GB - 0 1", want: []string{"f1b1", "f1g1"}},
+		{name: "king passes an attacked square", fen: "3rk3/8/8/8/8/8/8/1R3KR1 w GB - 0 1", want: []string{"f1g1"}},
+		{name: "a piece in the way", fen: "4k3/8/8/8/8/8/8/1RN2KR1 w GB - 0 1", want: []string{"f1g1"}},
+		{name: "rook to the king's other side", fen: "4k3/8/8/8/8/8/8/5RK1 w F - 0 1", want: []string{"g1f1"}},
+		{name: "rook shields the king", fen: "4k3/8/8/8/8/8/8/5KRq w G - 0 1", want: nil},
+		{name: "king stays put", fen: "4k3/8/8/8/8/8/8/6KR w H - 0 1", want: []string{"g1h1"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+			if !b.Chess960 {
+				t.Fatal("Shredder-FEN isn't Chess960")
+			}
+			var got []string
+			for _, move := range b.LegalMoves() {
+				from, to := uciToIndex(move[:2]), uciToIndex(move[2:4])
+				if b.isCastle960(from, to) {
+					got = append(got, move)
+				}
+			}
+			sort.Strings(got)
+			if strings.Join(got, " ") != strings.Join(c.want, " ") {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestChess960Option(t *testing.T) {
+	u, _ := newTestUCI(t)
+	u.SetOption("UCI_Chess960", "true")
+
+	u.SetPosition("fen", "bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN", "w", "KQkq", "-", "0", "1",
+		"moves", "g2g3", "g7g6", "f1g1")
+	if want := "bqnbrkrn/pppppp1p/6p1/8/8/6P1/PPPPPP1P/BQNBRRKN b kq - 1 2"; u.fen != want {
+		t.Errorf("\nwant: %s\ngot:  %s", want, u.fen)
+	}
+}
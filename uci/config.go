@@ -0,0 +1,457 @@
+package uci
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultStockfishPath is SF when neither the EnginePath option,
+	// $TROLLFISH_ENGINE nor the config file say where it is, looked for on
+	// $PATH.
+	defaultStockfishPath = "stockfish"
+
+	// defaultConfigFile is read from the working directory unless
+	// $TROLLFISH_CONFIG names another file.
+	defaultConfigFile = "trollfish.toml"
+
+	// defaultThreadsReserve is how many CPUs are left for the GUI and the
+	// OS when Threads is detected.
+	defaultThreadsReserve = 2
+)
+
+// Config is the config file, a small subset of TOML. Every key is optional:
+//
+//	engine = "/usr/local/bin/stockfish"
+//	engine_timeout = 10000   # ms for SF to answer uci and isready
+//	engine_retries = 2       # restarts when it doesn't, then the built-in engine
+//	threads = 28             # 0 or missing: the CPU count less threads_reserve
+//	threads_reserve = 2      # CPUs left for the GUI and the OS
+//	hash = 7168              # MB, at most HashPercent of available memory
+//	log_path = "trollfish.log"
+//	log_max_size = 100       # MB before the log is rotated, 0 never
+//	log_max_age = 30         # days before it's rotated, and rotated logs removed
+//	log_keep = 5             # rotated logs kept, 0 all of them
+//	troll_level = 5          # 0-10, scales the personality knobs
+//	blunder_report = 100     # cp a move of ours can give up to SF's best
+//	                         # before the post-game blunder report lists it
+//
+//	[multipv]                # lines SF reports for the selector to pick from
+//	default = 5
+//	agro = 2
+//	anti_draw = 3
+//	pounce = 1
+//
+//	[agro]
+//	eval = 800               # agro once we're this far ahead
+//	middlegame_move = 23     # from this move, agro while the eval is under
+//	middlegame_eval = 150    # middlegame_eval
+//	endgame_move = 35        # from this move, always agro
+//	selector_eval = 2000     # SF's best line at least this turns agro on
+//
+//	[movetime]               # [min, max] ms
+//	opening = [250, 750]     # the first moves
+//	default = [1000, 1500]
+//	middlegame = [2000, 3000]
+//	endgame = [1500, 2500]
+//	advantage = [3500, 4500] # a small edge and time to use it
+//
+//	[options]                # UCI options, as if the GUI had set them
+//	OpeningBook = "/books/trolls.json"
+//	PlayBad = true
+//	ImbalanceMoves = 4
+//
+// A missing file is the defaults. The file is read again on SIGHUP; the log_*
+// keys only take effect at startup.
+type Config struct {
+	Engine         string
+	EngineTimeout  int // ms
+	EngineRetries  int
+	Threads        int // 0 is detected from the CPU count
+	ThreadsReserve int
+	Hash           int // MB
+	LogPath        string
+	LogMaxSize     int // MB
+	LogMaxAge      int // days
+	LogKeep        int
+	TrollLevel     int
+	BlunderReport  int // cp
+
+	MultiPV  MultiPVConfig
+	Agro     AgroConfig
+	MoveTime MoveTimeConfig
+
+	// Options are UCI option values by name, applied over the defaults
+	// before the GUI's setoption.
+	Options map[string]string
+}
+
+// MultiPVConfig is the number of lines the troll policy asks SF for in each
+// mode.
+type MultiPVConfig struct {
+	Default, Agro, AntiDraw, Pounce int
+}
+
+// AgroConfig is when trollfish stops trolling and plays to win.
+type AgroConfig struct {
+	Eval           int
+	MiddlegameMove int
+	MiddlegameEval int
+	EndgameMove    int
+	SelectorEval   int
+}
+
+// MoveTimeConfig is how long the time manager thinks in each phase.
+type MoveTimeConfig struct {
+	Opening, Default, Middlegame, Endgame, Advantage MoveTimeRange
+}
+
+// MoveTimeRange is a move time in ms picked at random from [Min, Max).
+type MoveTimeRange struct {
+	Min, Max int
+}
+
+func (r MoveTimeRange) pick() int {
+	if r.Max <= r.Min {
+		return r.Min
+	}
+	return r.Min + rand.Intn(r.Max-r.Min)
+}
+
+func defaultConfig() Config {
+	return Config{
+		EngineTimeout:  defaultEngineTimeout,
+		EngineRetries:  defaultEngineRetries,
+		ThreadsReserve: defaultThreadsReserve,
+		Hash:           hashMemory,
+		LogPath:        defaultLogPath,
+		LogMaxSize:     defaultLogMaxSize,
+		LogMaxAge:      defaultLogMaxAge,
+		LogKeep:        defaultLogKeep,
+		TrollLevel:     defaultTrollLevel,
+		BlunderReport:  defaultBlunderReport,
+		MultiPV: MultiPVConfig{
+			Default:  defaultMultiPV,
+			Agro:     agroMultiPV,
+			AntiDraw: antiDrawMultiPV,
+			Pounce:   pounceMultiPV,
+		},
+		Agro: AgroConfig{
+			Eval:           800,
+			MiddlegameMove: 23,
+			MiddlegameEval: 150,
+			EndgameMove:    35,
+			SelectorEval:   2000,
+		},
+		MoveTime: MoveTimeConfig{
+			Opening:    MoveTimeRange{250, 750},
+			Default:    MoveTimeRange{1000, 1500},
+			Middlegame: MoveTimeRange{2000, 3000},
+			Endgame:    MoveTimeRange{1500, 2500},
+			Advantage:  MoveTimeRange{3500, 4500},
+		},
+	}
+}
+
+// configPath returns the config file to read.
+func configPath() string {
+	if path := os.Getenv("TROLLFISH_CONFIG"); path != "" {
+		return path
+	}
+	return defaultConfigFile
+}
+
+// LoadConfig reads the config file at path over the defaults.
+func LoadConfig(path string) (Config, error) {
+	fp, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return defaultConfig(), nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	defer fp.Close()
+
+	cfg, err := parseConfig(fp)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func parseConfig(r io.Reader) (Config, error) {
+	values, err := parseTOML(r)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg := defaultConfig()
+	fields := map[string]interface{}{
+		"engine":          &cfg.Engine,
+		"engine_timeout":  &cfg.EngineTimeout,
+		"engine_retries":  &cfg.EngineRetries,
+		"threads":         &cfg.Threads,
+		"threads_reserve": &cfg.ThreadsReserve,
+		"hash":            &cfg.Hash,
+		"log_path":        &cfg.LogPath,
+		"log_max_size":    &cfg.LogMaxSize,
+		"log_max_age":     &cfg.LogMaxAge,
+		"log_keep":        &cfg.LogKeep,
+		"troll_level":     &cfg.TrollLevel,
+		"blunder_report":  &cfg.BlunderReport,
+
+		"multipv.default":   &cfg.MultiPV.Default,
+		"multipv.agro":      &cfg.MultiPV.Agro,
+		"multipv.anti_draw": &cfg.MultiPV.AntiDraw,
+		"multipv.pounce":    &cfg.MultiPV.Pounce,
+
+		"agro.eval":            &cfg.Agro.Eval,
+		"agro.middlegame_move": &cfg.Agro.MiddlegameMove,
+		"agro.middlegame_eval": &cfg.Agro.MiddlegameEval,
+		"agro.endgame_move":    &cfg.Agro.EndgameMove,
+		"agro.selector_eval":   &cfg.Agro.SelectorEval,
+
+		"movetime.opening":    &cfg.MoveTime.Opening,
+		"movetime.default":    &cfg.MoveTime.Default,
+		"movetime.middlegame": &cfg.MoveTime.Middlegame,
+		"movetime.endgame":    &cfg.MoveTime.Endgame,
+		"movetime.advantage":  &cfg.MoveTime.Advantage,
+	}
+
+	for key, v := range values {
+		var err error
+		if name := strings.TrimPrefix(key, "options."); name != key {
+			if cfg.Options == nil {
+				cfg.Options = make(map[string]string)
+			}
+			cfg.Options[name], err = v.option()
+			if err != nil {
+				return Config{}, fmt.Errorf("line %d: %s: %w", v.line, key, err)
+			}
+			continue
+		}
+
+		switch field := fields[key].(type) {
+		case *string:
+			*field, err = v.string()
+		case *int:
+			*field, err = v.int()
+		case *MoveTimeRange:
+			*field, err = v.moveTimeRange()
+		default:
+			err = errors.New("unknown key")
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("line %d: %s: %w", v.line, key, err)
+		}
+	}
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c Config) validate() error {
+	positive := map[string]int{
+		"hash":              c.Hash,
+		"engine_timeout":    c.EngineTimeout,
+		"multipv.default":   c.MultiPV.Default,
+		"multipv.agro":      c.MultiPV.Agro,
+		"multipv.anti_draw": c.MultiPV.AntiDraw,
+		"multipv.pounce":    c.MultiPV.Pounce,
+	}
+	for key, n := range positive {
+		if n < 1 {
+			return fmt.Errorf("%s: %d is less than 1", key, n)
+		}
+	}
+	nonNegative := map[string]int{
+		"threads":         c.Threads,
+		"engine_retries":  c.EngineRetries,
+		"threads_reserve": c.ThreadsReserve,
+		"log_max_size":    c.LogMaxSize,
+		"log_max_age":     c.LogMaxAge,
+		"log_keep":        c.LogKeep,
+		"blunder_report":  c.BlunderReport,
+	}
+	for key, n := range nonNegative {
+		if n < 0 {
+			return fmt.Errorf("%s: %d is less than 0", key, n)
+		}
+	}
+	if c.TrollLevel < 0 || c.TrollLevel > maxTrollLevel {
+		return fmt.Errorf("troll_level: %d isn't between 0 and %d", c.TrollLevel, maxTrollLevel)
+	}
+	if c.LogPath == "" {
+		return errors.New("log_path: empty")
+	}
+	return nil
+}
+
+// threads is SF's Threads: the configured count, or if there's none every
+// CPU but the reserve.
+func (c Config) threads() int {
+	if c.Threads > 0 {
+		return c.Threads
+	}
+	return autoThreads(runtime.NumCPU(), c.ThreadsReserve)
+}
+
+// autoThreads is the thread count for cpus CPUs with reserve kept back, at
+// least one.
+func autoThreads(cpus, reserve int) int {
+	return max(cpus-reserve, 1)
+}
+
+// enginePath returns SF's path from the environment, then the config file,
+// then the built-in default.
+func (c Config) enginePath() string {
+	if path := os.Getenv("TROLLFISH_ENGINE"); path != "" {
+		return path
+	}
+	if c.Engine != "" {
+		return c.Engine
+	}
+	return defaultStockfishPath
+}
+
+// defaultEnginePath is SF's path for the command-line tools, which don't
+// have options. A config file that can't be read is reported by the check
+// command; here it's ignored.
+func defaultEnginePath() string {
+	cfg, _ := LoadConfig(configPath())
+	return cfg.enginePath()
+}
+
+// tomlValue is a value from the config file, kept as written until the key
+// says what type it should be.
+type tomlValue struct {
+	raw  string
+	line int
+}
+
+func (v tomlValue) string() (string, error) {
+	if len(v.raw) < 2 || v.raw[0] != '"' || v.raw[len(v.raw)-1] != '"' {
+		return "", fmt.Errorf("'%s' isn't a string", v.raw)
+	}
+	return strconv.Unquote(v.raw)
+}
+
+func (v tomlValue) int() (int, error) {
+	n, err := strconv.Atoi(strings.ReplaceAll(v.raw, "_", ""))
+	if err != nil {
+		return 0, fmt.Errorf("'%s' isn't an integer", v.raw)
+	}
+	return n, nil
+}
+
+// option reads a UCI option value: a string, a number or a boolean.
+func (v tomlValue) option() (string, error) {
+	if strings.HasPrefix(v.raw, `"`) {
+		return v.string()
+	}
+	if v.raw == "true" || v.raw == "false" {
+		return v.raw, nil
+	}
+	if _, err := v.int(); err != nil {
+		return "", fmt.Errorf("'%s' isn't a string, number or boolean", v.raw)
+	}
+	return strings.ReplaceAll(v.raw, "_", ""), nil
+}
+
+// moveTimeRange reads a two-element array of ms, "[250, 750]".
+func (v tomlValue) moveTimeRange() (MoveTimeRange, error) {
+	bad := fmt.Errorf("'%s' isn't a [min, max] range", v.raw)
+	if !strings.HasPrefix(v.raw, "[") || !strings.HasSuffix(v.raw, "]") {
+		return MoveTimeRange{}, bad
+	}
+	parts := strings.Split(v.raw[1:len(v.raw)-1], ",")
+	if len(parts) != 2 {
+		return MoveTimeRange{}, bad
+	}
+	var r MoveTimeRange
+	for i, part := range parts {
+		n, err := tomlValue{raw: strings.TrimSpace(part)}.int()
+		if err != nil || n < 0 {
+			return MoveTimeRange{}, bad
+		}
+		if i == 0 {
+			r.Min = n
+		} else {
+			r.Max = n
+		}
+	}
+	if r.Max < r.Min {
+		return MoveTimeRange{}, fmt.Errorf("min %d is more than max %d", r.Min, r.Max)
+	}
+	return r, nil
+}
+
+// parseTOML reads "key = value" lines into a map. Keys in a [table] are
+// prefixed with its name and a dot.
+func parseTOML(r io.Reader) (map[string]tomlValue, error) {
+	values := make(map[string]tomlValue)
+
+	var table string
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: bad table '%s'", lineNumber, line)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if table != "" {
+			key = table + "." + key
+		}
+		if _, dup := values[key]; dup {
+			return nil, fmt.Errorf("line %d: %s is set twice", lineNumber, key)
+		}
+		values[key] = tomlValue{raw: value, line: lineNumber}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// stripTOMLComment removes a '#' comment that isn't inside a string.
+func stripTOMLComment(line string) string {
+	var quoted bool
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case '#':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
@@ -0,0 +1,220 @@
+package uci
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	const file = `
+# where SF lives
+engine = "/opt/sf/stockfish # not a comment"  # a comment
+threads = 8
+threads_reserve = 1
+hash = 2_048
+log_keep = 10
+blunder_report = 150
+
+[multipv]
+default = 7
+
+[agro]
+eval = 600
+
+[movetime]
+opening = [100, 200]
+
+[options]
+OpeningBook = "/books/trolls.json"
+PlayBad = true
+ImbalanceMoves = 4
+`
+	cfg, err := parseConfig(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := defaultConfig()
+	want.Engine = "/opt/sf/stockfish # not a comment"
+	want.Threads, want.ThreadsReserve, want.Hash = 8, 1, 2048
+	want.LogKeep = 10
+	want.BlunderReport = 150
+	want.MultiPV.Default = 7
+	want.Agro.Eval = 600
+	want.MoveTime.Opening = MoveTimeRange{100, 200}
+	want.Options = map[string]string{
+		"OpeningBook":    "/books/trolls.json",
+		"PlayBad":        "true",
+		"ImbalanceMoves": "4",
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("want: %+v\ngot:  %+v", want, cfg)
+	}
+
+	bad := map[string]string{
+		`engine = /opt/sf`:                     "isn't a string",
+		`engine "/opt/sf"`:                     "expected key = value",
+		"engine = \"a\"\nengine = \"b\"":       "set twice",
+		`thread = 4`:                           "unknown key",
+		`threads = "4"`:                        "isn't an integer",
+		`threads = -1`:                         "less than 0",
+		`hash = 0`:                             "less than 1",
+		`troll_level = 11`:                     "between 0 and 10",
+		"[movetime]\nopening = [200]":          "isn't a [min, max] range",
+		"[movetime]\nopening = [200, 100]":     "more than max",
+		`log_path = ""`:                        "empty",
+		`log_max_size = -1`:                    "less than 0",
+		`blunder_report = -1`:                  "less than 0",
+		"[options]\nPlayBad = yes":             "isn't a string, number or boolean",
+		"[engine\nengine = \"a\"":              "bad table",
+		"[paths]\nengine = \"/opt/sf\"":        "unknown key",
+		"engine = \"a\"\n\nfoo = \"b\" # line": "line 3",
+	}
+	for file, want := range bad {
+		_, err := parseConfig(strings.NewReader(file))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: want error containing %q got %v", file, want, err)
+		}
+	}
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "trollfish.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, defaultConfig()) {
+		t.Errorf("want the defaults, got %+v", cfg)
+	}
+}
+
+func TestEnginePath(t *testing.T) {
+	t.Setenv("TROLLFISH_ENGINE", "")
+	t.Setenv("TROLLFISH_CONFIG", filepath.Join(t.TempDir(), "missing.toml"))
+
+	if got := (Config{}).enginePath(); got != defaultStockfishPath {
+		t.Errorf("default: want: %s got: %s", defaultStockfishPath, got)
+	}
+	if got := (Config{Engine: "/cfg/sf"}).enginePath(); got != "/cfg/sf" {
+		t.Errorf("config: want: /cfg/sf got: %s", got)
+	}
+
+	t.Setenv("TROLLFISH_ENGINE", "/env/sf")
+	if got := (Config{Engine: "/cfg/sf"}).enginePath(); got != "/env/sf" {
+		t.Errorf("env: want: /env/sf got: %s", got)
+	}
+
+	u, _ := newTestUCI(t)
+	if got := u.stockfishPath(); got != "/env/sf" {
+		t.Errorf("no option: want: /env/sf got: %s", got)
+	}
+	u.SetOption("EnginePath", "/option/sf")
+	if got := u.stockfishPath(); got != "/option/sf" {
+		t.Errorf("option: want: /option/sf got: %s", got)
+	}
+}
+
+func TestMoveTimeRange(t *testing.T) {
+	r := MoveTimeRange{250, 750}
+	for i := 0; i < 100; i++ {
+		if n := r.pick(); n < r.Min || n >= r.Max {
+			t.Fatalf("%d isn't in [%d, %d)", n, r.Min, r.Max)
+		}
+	}
+	if n := (MoveTimeRange{500, 500}).pick(); n != 500 {
+		t.Errorf("empty range: want: 500 got: %d", n)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trollfish.toml")
+	t.Setenv("TROLLFISH_CONFIG", path)
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("[options]\nImbalanceMoves = 4\n")
+	u, _ := newTestUCI(t)
+	if got := u.options.Int("ImbalanceMoves"); got != 4 {
+		t.Errorf("New: ImbalanceMoves: want: 4 got: %d", got)
+	}
+
+	u.gameMoveCount, u.fen = 12, "8/8/8/8/8/8/8/K6k w - - 0 40"
+	write("[agro]\neval = 600\n[options]\nImbalanceMoves = 6\n")
+	if err := u.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if u.config.Agro.Eval != 600 || u.options.Int("ImbalanceMoves") != 6 {
+		t.Errorf("reload: agro eval %d ImbalanceMoves %d", u.config.Agro.Eval, u.options.Int("ImbalanceMoves"))
+	}
+	if u.gameMoveCount != 12 || u.fen != "8/8/8/8/8/8/8/K6k w - - 0 40" {
+		t.Error("reload dropped the game")
+	}
+
+	// a bad file keeps the old config
+	for _, bad := range []string{"[agro]\neval = high\n", "[options]\nImbalanceMoves = 1000\n", "[options]\nNoSuchOption = 1\n"} {
+		write(bad)
+		if err := u.ReloadConfig(); err == nil {
+			t.Errorf("%q: want an error", bad)
+		}
+		if u.config.Agro.Eval != 600 || u.options.Int("ImbalanceMoves") != 6 {
+			t.Errorf("%q: config changed", bad)
+		}
+	}
+}
+
+func TestReloadConfigTrollLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trollfish.toml")
+	t.Setenv("TROLLFISH_CONFIG", path)
+	t.Setenv("TROLLFISH_STATE", filepath.Join(t.TempDir(), "options.json"))
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("troll_level = 3\n")
+	u, _ := newTestUCI(t)
+	if o, _ := u.options.Lookup("Troll Level"); o.Default != "3" || u.options.Int("Troll Level") != 3 {
+		t.Errorf("New: Troll Level default %s value %d, want the file's 3", o.Default, u.options.Int("Troll Level"))
+	}
+
+	// the file's level is reported by the option until the GUI sets one
+	write("troll_level = 7\n")
+	if err := u.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if u.config.TrollLevel != 7 || u.options.Int("Troll Level") != 7 {
+		t.Errorf("reload: troll level %d option %d, want 7", u.config.TrollLevel, u.options.Int("Troll Level"))
+	}
+
+	u.parseLine("setoption name Troll Level value 9")
+	write("troll_level = 2\n")
+	if err := u.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if u.config.TrollLevel != 9 || u.options.Int("Troll Level") != 9 {
+		t.Errorf("reload after setoption: troll level %d option %d, want the GUI's 9", u.config.TrollLevel, u.options.Int("Troll Level"))
+	}
+}
+
+func TestConfigThreads(t *testing.T) {
+	if got := (Config{Threads: 6, ThreadsReserve: 2}).threads(); got != 6 {
+		t.Errorf("configured: want 6 got %d", got)
+	}
+	cases := []struct{ cpus, reserve, want int }{
+		{cpus: 16, reserve: 2, want: 14},
+		{cpus: 16, reserve: 0, want: 16},
+		{cpus: 2, reserve: 2, want: 1},
+		{cpus: 1, reserve: 4, want: 1},
+	}
+	for _, c := range cases {
+		if got := autoThreads(c.cpus, c.reserve); got != c.want {
+			t.Errorf("%d cpus reserve %d: want %d got %d", c.cpus, c.reserve, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package uci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// transcriptLines is how much of the log a crash report includes.
+const transcriptLines = 200
+
+// transcript keeps the last transcriptLines log lines in memory.
+type transcript struct {
+	mtx   sync.Mutex
+	lines []string
+	next  int
+}
+
+func (t *transcript) add(line string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if len(t.lines) < transcriptLines {
+		t.lines = append(t.lines, line)
+		return
+	}
+	t.lines[t.next] = line
+	t.next = (t.next + 1) % transcriptLines
+}
+
+// tail returns the lines oldest first.
+func (t *transcript) tail() []string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	lines := make([]string, 0, len(t.lines))
+	lines = append(lines, t.lines[t.next:]...)
+	return append(lines, t.lines[:t.next]...)
+}
+
+// recoverCrash writes a crash report for a panic in one of our goroutines,
+// stops SF so it isn't orphaned, and re-panics.
+func (u *UCI) recoverCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	reason := fmt.Sprintf("panic: %v", r)
+	u.writeCrashReport(reason, debug.Stack())
+	u.telemetry.sendNow("error", errorTelemetry{Reason: reason, FEN: u.fen})
+	if sf := u.engine(); sf != nil {
+		sf.Quit()
+	}
+	u.syncLog()
+	panic(r)
+}
+
+// watchEngine writes a crash report if SF exits without being asked to,
+// answers the search it was running with the built-in engine and starts SF
+// again for the rest of the game. A "go infinite" or "go ponder" is answered
+// on stop or ponderhit, same as without an engine.
+func (u *UCI) watchEngine(sf EngineBackend) {
+	select {
+	case <-sf.Exited():
+		if err := sf.Err(); err != nil {
+			reason := fmt.Sprintf("engine: %v", err)
+			u.logInfo("ERR: " + reason)
+			u.writeCrashReport(reason, nil)
+
+			u.moveListMtx.Lock()
+			fen := u.fen
+			searching := u.searchDone != nil && u.bookStop == nil
+			held := searching && u.untilStop
+			if held {
+				u.analysisHeld = true
+			}
+			u.moveListMtx.Unlock()
+			u.telemetry.send("error", errorTelemetry{Reason: reason, FEN: fen})
+			if searching && !held {
+				u.playFallbackMove(reason)
+			}
+			u.restartEngine(sf)
+		}
+	case <-u.ctx.Done():
+	}
+}
+
+// writeCrashReport writes trollfish-crash-<time>.txt next to the log and
+// returns its path.
+func (u *UCI) writeCrashReport(reason string, stack []byte) string {
+	name := fmt.Sprintf("trollfish-crash-%s.txt", time.Now().Format("20060102-150405"))
+	path := filepath.Join(u.crashDir, name)
+
+	if err := os.WriteFile(path, []byte(u.crashReport(reason, stack)), 0644); err != nil {
+		u.logInfo(fmt.Sprintf("ERR: write crash report: %v", err))
+		return ""
+	}
+
+	u.logInfo(fmt.Sprintf("crash report written to %s", path))
+	return path
+}
+
+// crashReport has everything needed to reproduce a crash: the position, the
+// selector's state, the option values and the tail of the transcript.
+func (u *UCI) crashReport(reason string, stack []byte) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%s crash report\n", u.name))
+	sb.WriteString(fmt.Sprintf("time: %s\n", time.Now().Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("reason: %s\n", reason))
+
+	// a panic may have happened with the lock held, SF exiting can't have
+	if stack == nil {
+		u.moveListMtx.Lock()
+		defer u.moveListMtx.Unlock()
+	} else if u.moveListMtx.TryLock() {
+		defer u.moveListMtx.Unlock()
+	} else {
+		sb.WriteString("state: moveListMtx held, values may be inconsistent\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("\nfen: %s\n", u.fen))
+	sb.WriteString("\nselector:\n")
+	sb.WriteString(fmt.Sprintf("  move: %d\n", u.gameMoveCount))
+	sb.WriteString(fmt.Sprintf("  active_color: %s\n", u.gameActiveColor))
+	sb.WriteString(fmt.Sprintf("  eval: %d\n", u.gameScore.CP))
+	sb.WriteString(fmt.Sprintf("  mate_in: %d\n", u.gameScore.Mate))
+	sb.WriteString(fmt.Sprintf("  agro: %v\n", u.gameAgro))
+	sb.WriteString(fmt.Sprintf("  multipv: %d\n", u.gameMultiPV))
+	sb.WriteString(fmt.Sprintf("  play_bad: %v\n", u.playBad))
+	sb.WriteString(fmt.Sprintf("  start_agro: %v\n", u.startAgro))
+	sb.WriteString(fmt.Sprintf("  go_mate: %d\n", u.goMate))
+	for _, m := range u.moveList {
+		sb.WriteString(fmt.Sprintf("  line: %s\n", m))
+	}
+
+	if u.options != nil {
+		sb.WriteString("\noptions:\n")
+		for _, o := range u.options.All() {
+			sb.WriteString(fmt.Sprintf("  %s: %s\n", o.Name, u.options.String(o.Name)))
+		}
+	}
+
+	if len(stack) > 0 {
+		sb.WriteString(fmt.Sprintf("\nstack:\n%s", stack))
+	}
+
+	sb.WriteString(fmt.Sprintf("\ntranscript (last %d lines):\n", transcriptLines))
+	for _, line := range u.transcript.tail() {
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,68 @@
+package uci
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTranscriptTail(t *testing.T) {
+	// arrange
+	var tr transcript
+
+	// act
+	for i := 0; i < transcriptLines+5; i++ {
+		tr.add(fmt.Sprintf("line %d", i))
+	}
+	tail := tr.tail()
+
+	// assert
+	if len(tail) != transcriptLines {
+		t.Fatalf("len, want: %d got: %d", transcriptLines, len(tail))
+	}
+	if tail[0] != "line 5" {
+		t.Errorf("first, want: 'line 5' got: '%s'", tail[0])
+	}
+	if want := fmt.Sprintf("line %d", transcriptLines+4); tail[len(tail)-1] != want {
+		t.Errorf("last, want: '%s' got: '%s'", want, tail[len(tail)-1])
+	}
+}
+
+func TestWriteCrashReport(t *testing.T) {
+	// arrange
+	u, err := New("trollfish", "test", Option{Name: "PlayBad", Type: OptionTypeString, Default: "false"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.crashDir = t.TempDir()
+	u.log = nopWriteCloser{}
+	u.fen = "8/8/8/4k3/8/8/8/4K2Q w - - 0 1"
+	u.gameAgro = true
+	u.logInfo("-> go wtime 1000 btime 1000")
+
+	// act
+	path := u.writeCrashReport("engine: signal: segmentation fault", nil)
+
+	// assert
+	if path == "" {
+		t.Fatal("no report written")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(b)
+
+	for _, want := range []string{
+		"reason: engine: signal: segmentation fault",
+		"fen: 8/8/8/4k3/8/8/8/4K2Q w - - 0 1",
+		"agro: true",
+		"PlayBad: false",
+		"-> go wtime 1000 btime 1000",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing '%s':\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package uci
+
+import (
+	"strings"
+	"sync"
+)
+
+// currLine produces "info currline" output for UCI_ShowCurrLine. Engines that
+// report currline have it forwarded as is; for the ones that don't (SF dropped
+// it) the line being searched is taken from the main PV as it changes.
+type currLine struct {
+	mtx        sync.Mutex
+	enabled    bool
+	fromEngine bool
+	last       string
+}
+
+func (c *currLine) setEnabled(enabled bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.enabled = enabled
+	c.last = ""
+}
+
+// engineLine returns the engine's own currline info line to forward, or "" if
+// it shouldn't be.
+func (c *currLine) engineLine(line string) string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.fromEngine = true
+	if !c.enabled {
+		return ""
+	}
+	return strings.TrimSpace(line)
+}
+
+// pvLine returns the currline info line for a PV update, or "" if there's
+// nothing new to send.
+func (c *currLine) pvLine(m Info) string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !c.enabled || c.fromEngine || m.MultiPV > 1 || m.PV == "" || m.PV == c.last {
+		return ""
+	}
+	c.last = m.PV
+	return "info currline 1 " + m.PV
+}
@@ -0,0 +1,30 @@
+package uci
+
+import "testing"
+
+func TestCurrLine(t *testing.T) {
+	var c currLine
+
+	if got := c.pvLine(Info{MultiPV: 1, PV: "e2e4 e7e5"}); got != "" {
+		t.Errorf("disabled: got '%s'", got)
+	}
+
+	c.setEnabled(true)
+	if got, want := c.pvLine(Info{MultiPV: 1, PV: "e2e4 e7e5"}), "info currline 1 e2e4 e7e5"; got != want {
+		t.Errorf("want '%s' got '%s'", want, got)
+	}
+	if got := c.pvLine(Info{MultiPV: 1, PV: "e2e4 e7e5"}); got != "" {
+		t.Errorf("repeated PV: got '%s'", got)
+	}
+	if got := c.pvLine(Info{MultiPV: 2, PV: "d2d4"}); got != "" {
+		t.Errorf("multipv 2: got '%s'", got)
+	}
+
+	// once the engine reports its own, only those are forwarded
+	if got, want := c.engineLine("info currline 1 d2d4 d7d5 "), "info currline 1 d2d4 d7d5"; got != want {
+		t.Errorf("want '%s' got '%s'", want, got)
+	}
+	if got := c.pvLine(Info{MultiPV: 1, PV: "c2c4"}); got != "" {
+		t.Errorf("engine currline: got '%s'", got)
+	}
+}
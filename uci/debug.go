@@ -0,0 +1,41 @@
+package uci
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// setDebug handles "debug on" and "debug off". While it's on, SF's output is
+// forwarded to the GUI as info strings and every log sink gets the protocol
+// traffic, whatever its level.
+func (u *UCI) setDebug(on bool) {
+	var v int64
+	if on {
+		v = 1
+	}
+	atomic.StoreInt64(&u.debug, v)
+
+	if l, ok := u.logSinks(); ok {
+		l.setVerbose(on)
+	}
+
+	if on {
+		u.logInfo("debug: on")
+	} else {
+		u.logInfo("debug: off")
+	}
+}
+
+// debugEngineLine forwards a line from SF to the GUI when debug is on. SF's
+// own info strings go as they are, anything else is wrapped in one.
+func (u *UCI) debugEngineLine(line string) {
+	if atomic.LoadInt64(&u.debug) == 0 {
+		return
+	}
+
+	if strings.HasPrefix(line, "info string ") {
+		u.WriteLine(line)
+		return
+	}
+	u.WriteLine("info string SF: " + line)
+}
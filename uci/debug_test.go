@@ -0,0 +1,64 @@
+package uci
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebug(t *testing.T) {
+	u, out := newTestUCI(t)
+
+	logPath := filepath.Join(t.TempDir(), "errors.log")
+	l, err := openLogSinks([]logSinkConfig{{kind: "file", target: logPath, level: LogError}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.log = l
+
+	eng := newFakeBackend()
+	u.sf = eng
+	loopDone := make(chan struct{})
+	go func() {
+		u.stockFishReadLoop(eng)
+		close(loopDone)
+	}()
+
+	eng.output <- "info string quiet"
+	u.parseLine("debug on")
+	u.parseLine("isready")
+	eng.output <- "info string NNUE evaluation using nn-1111.nnue"
+	eng.output <- "Unknown command: 'foo'."
+	eng.output <- "readyok" // once it's read, the lines before it are handled
+	u.parseLine("debug off")
+	u.parseLine("stop")
+	eng.output <- "info string quiet again"
+	eng.Quit()
+	<-loopDone
+	_ = l.Close()
+
+	got := out.String()
+	for _, want := range []string{
+		"info string NNUE evaluation using nn-1111.nnue\n",
+		"info string SF: Unknown command: 'foo'.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "quiet") {
+		t.Errorf("forwarded with debug off:\n%s", got)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "-> isready") {
+		t.Errorf("debug: traffic not logged:\n%s", b)
+	}
+	if strings.Contains(string(b), "-> stop") {
+		t.Errorf("debug off: traffic still logged:\n%s", b)
+	}
+}
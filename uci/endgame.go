@@ -0,0 +1,401 @@
+package uci
+
+import (
+	"fmt"
+	"sync"
+	"unicode"
+)
+
+// Exact play for KQK, KRK and KPK.
+//
+// The tables are built by retrograde-style iteration over every placement of
+// the white king, black king and white piece: the black king is mated in n
+// plies when every move leads to a position where white mates in n-1, and
+// white mates in n plies when some move leads to a black-to-move position
+// lost in n-1. KPK promotions are resolved through the KQK and KRK tables,
+// so those are built first. Positions with the piece on the black side are
+// mirrored before probing.
+
+const (
+	egMateScore = 10_000
+
+	egUnknown int8 = -1
+	egIllegal int8 = -2
+
+	egTableSize = 2 * 64 * 64 * 64
+)
+
+var (
+	egTablesOnce sync.Once
+	egTablesDone = make(chan struct{})
+	egTables     map[rune][]int8
+
+	egKingMoves [64][]int
+)
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		for _, o := range kingOffsets {
+			r, c := sq/8+o[0], sq%8+o[1]
+			if onBoard(r, c) {
+				egKingMoves[sq] = append(egKingMoves[sq], r*8+c)
+			}
+		}
+	}
+}
+
+// buildEndgameTables builds the tables once; it's safe to call from multiple goroutines.
+func buildEndgameTables() {
+	egTablesOnce.Do(func() {
+		tables := make(map[rune][]int8)
+		tables['q'] = buildEndgameTable('q', nil)
+		tables['r'] = buildEndgameTable('r', nil)
+		tables['p'] = buildEndgameTable('p', tables)
+		egTables = tables
+		close(egTablesDone)
+	})
+}
+
+func endgameTablesReady() bool {
+	select {
+	case <-egTablesDone:
+		return true
+	default:
+		return false
+	}
+}
+
+func egIndex(blackToMove bool, wk, bk, x int) int {
+	var stm int
+	if blackToMove {
+		stm = 1
+	}
+	return ((stm*64+wk)*64+bk)*64 + x
+}
+
+func kingsTouch(a, b int) bool {
+	dr, dc := a/8-b/8, a%8-b%8
+	return dr >= -1 && dr <= 1 && dc >= -1 && dc <= 1
+}
+
+// egAttacks reports whether the white piece on x attacks sq. blocker is the
+// only square (the white king) that can interrupt a slider.
+func egAttacks(piece rune, x, sq, blocker int) bool {
+	if x == sq {
+		return false
+	}
+
+	xr, xc := x/8, x%8
+	sr, sc := sq/8, sq%8
+
+	if piece == 'p' {
+		return sr == xr-1 && (sc == xc-1 || sc == xc+1)
+	}
+
+	dr, dc := sign(sr-xr), sign(sc-xc)
+	diagonal := sr-xr == sc-xc || sr-xr == xc-sc
+	straight := sr == xr || sc == xc
+	if !(straight || (piece == 'q' && diagonal)) {
+		return false
+	}
+
+	for r, c := xr+dr, xc+dc; r != sr || c != sc; r, c = r+dr, c+dc {
+		if r*8+c == blocker {
+			return false
+		}
+	}
+	return true
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	if n > 0 {
+		return 1
+	}
+	return 0
+}
+
+func egLegal(piece rune, blackToMove bool, wk, bk, x int) bool {
+	if wk == bk || wk == x || bk == x || kingsTouch(wk, bk) {
+		return false
+	}
+	if piece == 'p' && (x/8 == 0 || x/8 == 7) {
+		return false
+	}
+	// the side not to move can't be in check
+	return blackToMove || !egAttacks(piece, x, bk, wk)
+}
+
+// egBlackMoves returns the white-to-move positions reachable by the black king.
+// escape is true if black can capture the white piece.
+func egBlackMoves(piece rune, wk, bk, x int) (next []int, escape bool) {
+	for _, to := range egKingMoves[bk] {
+		if kingsTouch(to, wk) {
+			continue
+		}
+		if to == x {
+			escape = true
+			continue
+		}
+		if egAttacks(piece, x, to, wk) {
+			continue
+		}
+		next = append(next, egIndex(false, wk, to, x))
+	}
+	return next, escape
+}
+
+func buildEndgameTable(piece rune, promotions map[rune][]int8) []int8 {
+	dtm := make([]int8, egTableSize)
+
+	var white, black []int
+	for i := range dtm {
+		blackToMove := i >= egTableSize/2
+		wk, bk, x := (i>>12)&63, (i>>6)&63, i&63
+		if !egLegal(piece, blackToMove, wk, bk, x) {
+			dtm[i] = egIllegal
+			continue
+		}
+		dtm[i] = egUnknown
+		if blackToMove {
+			black = append(black, i)
+		} else {
+			white = append(white, i)
+		}
+	}
+
+	// checkmates
+	for _, i := range black {
+		wk, bk, x := (i>>12)&63, (i>>6)&63, i&63
+		next, escape := egBlackMoves(piece, wk, bk, x)
+		if len(next) == 0 && !escape && egAttacks(piece, x, bk, wk) {
+			dtm[i] = 0
+		}
+	}
+
+	// promotions can land on any distance, so keep going until we're past
+	// the longest one and two plies in a row have added nothing
+	var maxPromotion int8
+	for _, t := range promotions {
+		for _, v := range t {
+			if v > maxPromotion {
+				maxPromotion = v
+			}
+		}
+	}
+
+	lastChange := 0
+	for n := 1; n-lastChange <= 2 || n <= int(maxPromotion)+2; n++ {
+		want := int8(n - 1)
+
+		if n%2 == 1 {
+			remaining := white[:0]
+			for _, i := range white {
+				if dtm[i] != egUnknown {
+					continue
+				}
+				if egWhiteWins(piece, dtm, promotions, i, want) {
+					dtm[i] = int8(n)
+					lastChange = n
+					continue
+				}
+				remaining = append(remaining, i)
+			}
+			white = remaining
+			continue
+		}
+
+		remaining := black[:0]
+		for _, i := range black {
+			if dtm[i] != egUnknown {
+				continue
+			}
+			wk, bk, x := (i>>12)&63, (i>>6)&63, i&63
+			next, escape := egBlackMoves(piece, wk, bk, x)
+			lost := !escape && len(next) > 0
+			for _, j := range next {
+				if dtm[j] < 0 || dtm[j] > want {
+					lost = false
+					break
+				}
+			}
+			if lost {
+				dtm[i] = int8(n)
+				lastChange = n
+				continue
+			}
+			remaining = append(remaining, i)
+		}
+		black = remaining
+	}
+
+	return dtm
+}
+
+// egWhiteWins reports whether white has a move from position i to a
+// black-to-move position lost in exactly want plies.
+func egWhiteWins(piece rune, dtm []int8, promotions map[rune][]int8, i int, want int8) bool {
+	wk, bk, x := (i>>12)&63, (i>>6)&63, i&63
+
+	for _, to := range egKingMoves[wk] {
+		if to == x || kingsTouch(to, bk) {
+			continue
+		}
+		if dtm[egIndex(true, to, bk, x)] == want {
+			return true
+		}
+	}
+
+	if piece == 'p' {
+		to := x - 8
+		if to == wk || to == bk {
+			return false
+		}
+		if to/8 == 0 {
+			for _, promote := range "qr" {
+				if promotions[promote][egIndex(true, wk, bk, to)] == want {
+					return true
+				}
+			}
+			return false
+		}
+		if dtm[egIndex(true, wk, bk, to)] == want {
+			return true
+		}
+		if x/8 == 6 && x-16 != wk && x-16 != bk && dtm[egIndex(true, wk, bk, x-16)] == want {
+			return true
+		}
+		return false
+	}
+
+	xr, xc := x/8, x%8
+	dirs := rookDirs
+	if piece == 'q' {
+		dirs = append(append([][2]int{}, rookDirs...), bishopDirs...)
+	}
+	for _, d := range dirs {
+		for r, c := xr+d[0], xc+d[1]; onBoard(r, c); r, c = r+d[0], c+d[1] {
+			to := r*8 + c
+			if to == wk || to == bk {
+				break
+			}
+			if dtm[egIndex(true, wk, bk, to)] == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// probeEndgame scores a position covered by the built-in tables from the side
+// to move's point of view: egMateScore-plies when mating, -(egMateScore-plies)
+// when getting mated and 0 for a draw. ok is false when the material isn't
+// covered or the tables aren't built yet.
+func probeEndgame(b Board) (score int, ok bool) {
+	if !endgameTablesReady() {
+		return 0, false
+	}
+
+	wk, bk, x := -1, -1, -1
+	var piece rune
+	for sq, c := range b.Pos {
+		switch c {
+		case ' ':
+		case 'K':
+			wk = sq
+		case 'k':
+			bk = sq
+		default:
+			if x != -1 {
+				return 0, false
+			}
+			x, piece = sq, c
+		}
+	}
+
+	if wk == -1 || bk == -1 {
+		return 0, false
+	}
+
+	// bare kings and a lone minor piece can't mate
+	switch unicode.ToLower(piece) {
+	case 0, 'n', 'b':
+		return 0, true
+	}
+
+	blackToMove := b.ActiveColor == "b"
+	if isBlackPiece(piece) {
+		mirror := func(sq int) int { return (7-sq/8)*8 + sq%8 }
+		wk, bk, x = mirror(bk), mirror(wk), mirror(x)
+		blackToMove = !blackToMove
+	}
+
+	v := egTables[unicode.ToLower(piece)][egIndex(blackToMove, wk, bk, x)]
+	switch {
+	case v < 0:
+		return 0, true
+	case !blackToMove:
+		return egMateScore - int(v), true
+	default:
+		return -(egMateScore - int(v)), true
+	}
+}
+
+// EndgameMove returns the best move and its score in the current position when
+// it's covered by the built-in endgame tables.
+func EndgameMove(b Board) (string, int, bool) {
+	if _, ok := probeEndgame(b); !ok {
+		return "", 0, false
+	}
+
+	white := b.ActiveColor == "w"
+
+	var (
+		bestMove     string
+		bestScore    int
+		bestMaterial int
+	)
+	for _, move := range b.LegalMoves() {
+		child := b.Clone()
+		child.Moves(move)
+
+		childScore, ok := probeEndgame(child)
+		if !ok {
+			continue
+		}
+
+		score := -childScore
+		if childScore < 0 {
+			score--
+		} else if childScore > 0 {
+			score++
+		}
+
+		// prefer keeping our piece when the result is the same
+		var material int
+		for _, c := range child.Pos {
+			if c != ' ' && unicode.ToLower(c) != 'k' && isWhitePiece(c) == white {
+				material++
+			}
+		}
+
+		if bestMove == "" || score > bestScore || (score == bestScore && material > bestMaterial) {
+			bestMove, bestScore, bestMaterial = move, score, material
+		}
+	}
+
+	return bestMove, bestScore, bestMove != ""
+}
+
+// egScoreString formats a table score as a UCI score.
+func egScoreString(score int) string {
+	switch {
+	case score > 0:
+		return fmt.Sprintf("mate %d", (egMateScore-score+1)/2)
+	case score < 0:
+		return fmt.Sprintf("mate -%d", (egMateScore+score)/2)
+	}
+	return "cp 0"
+}
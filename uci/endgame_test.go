@@ -0,0 +1,70 @@
+package uci
+
+import (
+	"testing"
+)
+
+func TestProbeEndgame(t *testing.T) {
+	buildEndgameTables()
+
+	// arrange
+	cases := []struct {
+		name string
+		fen  string
+		want string
+	}{
+		{name: "KQK mate in 1", fen: "k7/8/1K6/8/8/8/7Q/8 w - - 0 1", want: "mate 1"},
+		{name: "KQK stalemate", fen: "k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", want: "cp 0"},
+		{name: "KRK mate in 1", fen: "k7/8/1K6/8/8/8/8/7R w - - 0 1", want: "mate 1"},
+		{name: "KRK hanging rook", fen: "8/8/8/8/8/8/1k6/1R5K b - - 0 1", want: "cp 0"},
+		{name: "KPK key squares", fen: "4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", want: "mate 11"},
+		{name: "KPK stalemate", fen: "4k3/4P3/4K3/8/8/8/8/8 b - - 0 1", want: "cp 0"},
+		{name: "KPK black pawn", fen: "8/8/8/8/4p3/4k3/8/4K3 w - - 0 1", want: "mate -12"},
+		{name: "KPK rook pawn", fen: "k7/8/8/8/8/8/P7/K7 w - - 0 1", want: "cp 0"},
+		{name: "KBK", fen: "4k3/8/8/8/8/8/8/4KB2 w - - 0 1", want: "cp 0"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			score, ok := probeEndgame(FENtoBoard(c.fen))
+
+			// assert
+			if !ok {
+				t.Fatal("position not covered")
+			}
+			if got := egScoreString(score); c.want != got {
+				t.Errorf("want: '%s' got: '%s'", c.want, got)
+			}
+		})
+	}
+}
+
+func TestEndgameMove(t *testing.T) {
+	buildEndgameTables()
+
+	// arrange
+	cases := []struct {
+		name string
+		fen  string
+		want string
+	}{
+		{name: "KQK mate in 1", fen: "k7/8/1K6/8/8/8/7Q/8 w - - 0 1", want: "h2h8"},
+		{name: "KPK underpromotion avoids stalemate", fen: "8/5P1k/5K2/8/8/8/8/8 w - - 0 1", want: "f7f8r"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			got, _, ok := EndgameMove(FENtoBoard(c.fen))
+
+			// assert
+			if !ok {
+				t.Fatal("position not covered")
+			}
+			if c.want != got {
+				t.Errorf("want: '%s' got: '%s'", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package uci
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"trollfish/stockfish"
+)
+
+// EngineBackend is the UCI engine trollfish plays through: SF by default, or
+// any other engine that speaks UCI wrapped to fit.
+type EngineBackend interface {
+	// Write sends a command to the engine.
+	Write(s string)
+	// Output is the engine's stdout, a line at a time. It's closed when the
+	// engine exits.
+	Output() <-chan string
+	// Quit asks the engine to exit and waits for it. It's safe to call more
+	// than once.
+	Quit()
+	// Running reports whether the engine is up.
+	Running() bool
+	// Exited is closed once the engine has exited.
+	Exited() <-chan struct{}
+	// Err returns why the engine exited when it wasn't asked to.
+	Err() error
+}
+
+// EngineStarter starts the engine at path. logInfo is trollfish's log.
+type EngineStarter func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error)
+
+// EngineNotFoundError is the error an EngineStarter returns when there's no
+// engine binary at the path.
+type EngineNotFoundError = stockfish.NotFoundError
+
+// StartStockfish is the default EngineStarter.
+func StartStockfish(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+	sf, err := stockfish.Start(ctx, path, logInfo)
+	if err != nil {
+		return nil, err
+	}
+	return stockfishBackend{sf}, nil
+}
+
+// stockfishBackend adapts *stockfish.StockFish, whose Output is a field.
+type stockfishBackend struct {
+	*stockfish.StockFish
+}
+
+func (b stockfishBackend) Output() <-chan string {
+	return b.StockFish.Output
+}
+
+// noEngine is the backend before an engine is started, or when none could
+// be; the built-in engine plays. Writes are dropped.
+type noEngine struct{}
+
+var noEngineExited = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+func (noEngine) Write(string)            {}
+func (noEngine) Output() <-chan string   { return nil }
+func (noEngine) Quit()                   {}
+func (noEngine) Running() bool           { return false }
+func (noEngine) Exited() <-chan struct{} { return noEngineExited }
+func (noEngine) Err() error              { return nil }
+
+// engineTypes are the EngineType option's built-in backends.
+var engineTypes = map[string]EngineStarter{
+	"stockfish": StartStockfish,
+	"lc0":       StartLc0,
+}
+
+// SetEngineStarter replaces how engines are started, to wrap an engine other
+// than SF or lc0. It must be called before Start, and overrides EngineType.
+func (u *UCI) SetEngineStarter(start EngineStarter) {
+	u.startBackend = start
+}
+
+// engineStarter returns the EngineStarter: the one set by SetEngineStarter,
+// then the EngineType option's. Variants are played by Fairy-Stockfish, which
+// is started as SF whatever EngineType says.
+func (u *UCI) engineStarter() EngineStarter {
+	if u.startBackend != nil {
+		return u.startBackend
+	}
+
+	u.moveListMtx.Lock()
+	chess := u.variant.isChess()
+	u.moveListMtx.Unlock()
+	if !chess {
+		return StartStockfish
+	}
+	if start, ok := engineTypes[u.options.Combo("EngineType")]; ok {
+		return start
+	}
+	return StartStockfish
+}
+
+// setEngineType handles EngineType, restarting the engine as the new type
+// unless a variant engine is playing.
+func (u *UCI) setEngineType(string) {
+	u.moveListMtx.Lock()
+	chess := u.variant.isChess()
+	u.moveListMtx.Unlock()
+
+	if atomic.LoadInt64(&u.started) == 0 || !chess {
+		return
+	}
+	if err := u.switchEngine(u.stockfishPath()); err != nil {
+		u.WriteLine(fmt.Sprintf("info string ERR: %v", err))
+	}
+}
+
+// engine returns the running engine. The goroutines watching it swap it out
+// when it crashes or stops answering, so it's always read through here.
+func (u *UCI) engine() EngineBackend {
+	u.sfMtx.RLock()
+	defer u.sfMtx.RUnlock()
+	return u.sf
+}
+
+// runningEnginePath returns the path the running engine was started from.
+func (u *UCI) runningEnginePath() string {
+	u.sfMtx.RLock()
+	defer u.sfMtx.RUnlock()
+	return u.enginePath
+}
+
+// setEngine makes sf, started from path, the running engine and returns the
+// one it replaces.
+func (u *UCI) setEngine(sf EngineBackend, path string) EngineBackend {
+	u.sfMtx.Lock()
+	defer u.sfMtx.Unlock()
+	old := u.sf
+	u.sf, u.enginePath = sf, path
+	return old
+}
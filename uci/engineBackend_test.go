@@ -0,0 +1,226 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBackend is an EngineBackend that records what it's sent.
+type fakeBackend struct {
+	mtx    sync.Mutex
+	lines  []string
+	output chan string
+	exited chan struct{}
+	quit   sync.Once
+	err    error
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{output: make(chan string), exited: make(chan struct{})}
+}
+
+func (e *fakeBackend) Write(s string) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.lines = append(e.lines, s)
+}
+
+func (e *fakeBackend) Output() <-chan string { return e.output }
+
+func (e *fakeBackend) Quit() {
+	e.quit.Do(func() {
+		close(e.output)
+		close(e.exited)
+	})
+}
+
+func (e *fakeBackend) Running() bool {
+	select {
+	case <-e.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func (e *fakeBackend) Exited() <-chan struct{} { return e.exited }
+func (e *fakeBackend) Err() error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return e.err
+}
+
+// crash exits the engine with err, as if it died.
+func (e *fakeBackend) crash(err error) {
+	e.mtx.Lock()
+	e.err = err
+	e.mtx.Unlock()
+	e.Quit()
+}
+
+func (e *fakeBackend) sent() []string {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return append([]string(nil), e.lines...)
+}
+
+func TestEngineStarter(t *testing.T) {
+	u, _ := newTestUCI(t)
+
+	if u.engine().Running() {
+		t.Error("running before Start")
+	}
+
+	var started []string
+	engines := map[string]*fakeBackend{"/engines/a": newFakeBackend(), "/engines/b": newFakeBackend()}
+	u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+		started = append(started, path)
+		if e, ok := engines[path]; ok {
+			return e, nil
+		}
+		return nil, errors.New("not found")
+	})
+
+	if err := u.switchEngine("/engines/a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.switchEngine("/engines/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.switchEngine("/engines/c"); err == nil {
+		t.Error("/engines/c: want an error")
+	}
+
+	if a := engines["/engines/a"]; a.Running() {
+		t.Error("a: still running after the switch")
+	}
+	b := engines["/engines/b"]
+	if u.engine() != b || !b.Running() {
+		t.Error("b: want the current engine")
+	}
+	if sent := b.sent(); len(sent) == 0 || sent[0] != "uci" {
+		t.Errorf("b: want uci first, got %q", sent)
+	}
+	if len(started) != 3 {
+		t.Errorf("want 3 starts, got %q", started)
+	}
+}
+
+func TestEngineRestart(t *testing.T) {
+	u, _ := newTestUCI(t)
+
+	started := make(chan *fakeBackend, maxEngineRestarts+2)
+	u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+		e := newFakeBackend()
+		started <- e
+		return e, nil
+	})
+	if err := u.switchEngine("/engines/sf"); err != nil {
+		t.Fatal(err)
+	}
+	sf := <-started
+
+	u.SetOption("Skill Level", "5")
+	u.SetOption("Clear Hash", "")
+	u.SetPosition("startpos", "moves", "e2e4")
+
+	next := func() *fakeBackend {
+		t.Helper()
+		select {
+		case e := <-started:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("engine not restarted")
+		}
+		return nil
+	}
+
+	sf.crash(errors.New("signal: segmentation fault"))
+	sf = next()
+
+	// the restart's commands are sent right after it starts
+	deadline := time.Now().Add(5 * time.Second)
+	want := []string{"uci", "setoption name Skill Level value 5", "position startpos moves e2e4"}
+	for {
+		sent := strings.Join(sf.sent(), "\n")
+		missing := false
+		for _, cmd := range want {
+			missing = missing || !strings.Contains(sent, cmd)
+		}
+		if !missing {
+			if strings.Contains(sent, "Clear Hash") {
+				t.Errorf("button replayed:\n%s", sent)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want %q in:\n%s", want, sent)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// a position that kills SF every time isn't retried forever
+	for i := 1; i < maxEngineRestarts; i++ {
+		sf.crash(errors.New("signal: segmentation fault"))
+		sf = next()
+	}
+	sf.crash(errors.New("signal: segmentation fault"))
+	select {
+	case <-started:
+		t.Errorf("restarted more than %d times", maxEngineRestarts)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	u.ResetGame()
+	u.moveListMtx.Lock()
+	restarts := u.engineRestarts
+	u.moveListMtx.Unlock()
+	if restarts != 0 {
+		t.Errorf("restarts after a new game want: 0 got: %d", restarts)
+	}
+}
+
+// TestEngineRestartInput restarts crashed engines while the GUI's commands
+// are being handled; run with -race.
+func TestEngineRestartInput(t *testing.T) {
+	u, _ := newTestUCI(t)
+
+	started := make(chan *fakeBackend, maxEngineRestarts+1)
+	u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+		e := newFakeBackend()
+		started <- e
+		return e, nil
+	})
+	if err := u.switchEngine("/engines/sf"); err != nil {
+		t.Fatal(err)
+	}
+	sf := <-started
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			u.parseLine("isready")
+			u.parseLine("position startpos moves e2e4 e7e5")
+			u.parseLine("setoption name UCI_Variant value chess")
+		}
+	}()
+
+	for i := 0; i < maxEngineRestarts; i++ {
+		sf.crash(errors.New("signal: segmentation fault"))
+		select {
+		case sf = <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("engine not restarted")
+		}
+	}
+	<-done
+
+	if u.engine() != sf {
+		t.Error("want the last engine started")
+	}
+}
@@ -0,0 +1,100 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// engineCaps is what an engine said about itself in its answer to uci: its
+// name and the options it has. Engines differ, an older SF has "Use NNUE", a
+// newer one doesn't, and not every build has UCI_ShowWDL, so options are only
+// sent to an engine that has them. Until the engine has answered all of them
+// are sent; one it doesn't know is ignored.
+type engineCaps struct {
+	name    string
+	options map[string]string // lower case name -> type
+	known   bool              // uciok was seen
+}
+
+// parse reads an "id name" or "option name" line.
+func (c *engineCaps) parse(line string) {
+	if name := strings.TrimPrefix(line, "id name "); name != line {
+		c.name = strings.TrimSpace(name)
+		return
+	}
+
+	rest := strings.TrimPrefix(line, "option name ")
+	if rest == line {
+		return
+	}
+	name, typ := rest, ""
+	if i := strings.Index(rest, " type "); i != -1 {
+		name = rest[:i]
+		if f := strings.Fields(rest[i+len(" type "):]); len(f) > 0 {
+			typ = f[0]
+		}
+	}
+	if c.options == nil {
+		c.options = make(map[string]string)
+	}
+	c.options[strings.ToLower(strings.TrimSpace(name))] = typ
+}
+
+// supports reports whether the engine has the option, or hasn't said yet.
+func (c engineCaps) supports(option string) bool {
+	if !c.known {
+		return true
+	}
+	_, ok := c.options[strings.ToLower(option)]
+	return ok
+}
+
+func (c engineCaps) String() string {
+	name := c.name
+	if name == "" {
+		name = "unnamed engine"
+	}
+	return fmt.Sprintf("%s, %d options", name, len(c.options))
+}
+
+// engineAnswered records the capabilities sf sent before its uciok, if it's
+// still the running engine. They're kept by path so a restarted engine, or
+// one switched back to, is set up right before it answers again.
+func (u *UCI) engineAnswered(sf EngineBackend, caps engineCaps) {
+	caps.known = true
+
+	u.moveListMtx.Lock()
+	current := u.engine() == sf
+	if current {
+		u.engineCaps = caps
+		if u.knownEngines == nil {
+			u.knownEngines = make(map[string]engineCaps)
+		}
+		u.knownEngines[u.runningEnginePath()] = caps
+	}
+	u.moveListMtx.Unlock()
+
+	if current {
+		u.logInfo(fmt.Sprintf("engine: %s", caps))
+	}
+}
+
+// engineSupports reports whether SF has the option, logging it if it doesn't.
+func (u *UCI) engineSupports(option string) bool {
+	u.moveListMtx.Lock()
+	caps := u.engineCaps
+	u.moveListMtx.Unlock()
+
+	if !caps.supports(option) {
+		u.logInfo(fmt.Sprintf("engine: %s has no option %s, not set", caps.name, option))
+		return false
+	}
+	return true
+}
+
+// setEngineOption sends "setoption" to SF if it has the option.
+func (u *UCI) setEngineOption(name, value string) {
+	if u.engineSupports(name) {
+		u.engine().Write(fmt.Sprintf("setoption name %s value %s", name, value))
+	}
+}
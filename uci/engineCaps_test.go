@@ -0,0 +1,83 @@
+package uci
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngineCaps(t *testing.T) {
+	u, _ := newTestUCI(t)
+
+	started := make(chan *fakeBackend, 2)
+	u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+		e := newFakeBackend()
+		started <- e
+		return e, nil
+	})
+	if err := u.switchEngine("/engines/sf"); err != nil {
+		t.Fatal(err)
+	}
+	sf := <-started
+
+	// an older SF: "Use NNUE", no UCI_ShowWDL
+	for _, line := range []string{
+		"id name Stockfish 14",
+		"id author the Stockfish developers",
+		"option name Threads type spin default 1 min 1 max 512",
+		"option name Hash type spin default 16 min 1 max 33554432",
+		"option name Move Overhead type spin default 10 min 0 max 5000",
+		"option name SyzygyPath type string default <empty>",
+		"option name Use NNUE type check default true",
+		"uciok",
+	} {
+		sf.output <- line
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(strings.Join(sf.sent(), "\n"), "setoption name Move Overhead") {
+		if time.Now().After(deadline) {
+			t.Fatalf("engine not set up after uciok:\n%s", strings.Join(sf.sent(), "\n"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	u.moveListMtx.Lock()
+	caps := u.engineCaps
+	u.moveListMtx.Unlock()
+	if caps.name != "Stockfish 14" || !caps.supports("use nnue") || caps.supports("UCI_ShowWDL") {
+		t.Errorf("caps: %+v", caps)
+	}
+
+	u.SetOption("UCI_ShowWDL", "true")
+	u.SetOption("SyzygyPath", "/tb")
+	u.SetOption("Use NNUE", "false")
+	u.SetOption("SyzygyProbeDepth", "4")
+
+	check := func(e *fakeBackend, want, notWant []string) {
+		t.Helper()
+		sent := strings.Join(e.sent(), "\n")
+		for _, cmd := range want {
+			if !strings.Contains(sent, cmd) {
+				t.Errorf("want %q in:\n%s", cmd, sent)
+			}
+		}
+		for _, cmd := range notWant {
+			if strings.Contains(sent, cmd) {
+				t.Errorf("%q sent:\n%s", cmd, sent)
+			}
+		}
+	}
+	// what the first engine got before it answered isn't gated
+	check(sf,
+		[]string{"setoption name SyzygyPath value /tb", "setoption name Use NNUE value false"},
+		[]string{"UCI_ShowWDL value true", "SyzygyProbeDepth"})
+
+	// a restart knows what the engine has before it answers
+	if err := u.switchEngine("/engines/sf"); err != nil {
+		t.Fatal(err)
+	}
+	check(<-started,
+		[]string{"uci", "setoption name SyzygyPath value /tb", "setoption name Use NNUE value false"},
+		[]string{"UCI_ShowWDL", "SyzygyProbeDepth", "Ponder"})
+}
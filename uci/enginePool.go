@@ -0,0 +1,196 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// enginePool runs searches on a set of engine processes in parallel, one
+// search per engine at a time. Searches past the pool's size queue for the
+// next free engine. An engine that died is replaced before it's handed out
+// again, with the options the pool was given.
+type enginePool struct {
+	ctx     context.Context
+	path    string
+	logInfo func(string)
+	size    int
+
+	idle chan *searcher // free engines
+
+	// optionsMtx is held while every engine is taken to set an option, so
+	// two of those can't each wait on engines the other holds
+	optionsMtx sync.Mutex
+	mtx        sync.Mutex
+	options    [][2]string // name, value; guarded by mtx
+	closed     bool        // guarded by mtx
+}
+
+// newEnginePool starts n engines at path. The engines quit when ctx is done
+// or the pool is closed.
+func newEnginePool(ctx context.Context, path string, n int, logInfo func(string)) (*enginePool, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("engine pool of %d engines", n)
+	}
+
+	p := &enginePool{ctx: ctx, path: path, logInfo: logInfo, size: n, idle: make(chan *searcher, n)}
+
+	// SF takes a moment to load its net, start them together
+	type started struct {
+		s   *searcher
+		err error
+	}
+	results := make(chan started, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			s, err := startSearcherPath(ctx, path, logInfo)
+			results <- started{s, err}
+		}()
+	}
+
+	var firstErr error
+	for i := 0; i < n; i++ {
+		r := <-results
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		p.idle <- r.s
+	}
+	if firstErr != nil {
+		close(p.idle)
+		for s := range p.idle {
+			s.quit()
+		}
+		return nil, firstErr
+	}
+	return p, nil
+}
+
+// acquire takes the next free engine, waiting for one if they're all busy.
+func (p *enginePool) acquire(ctx context.Context) (*searcher, error) {
+	p.mtx.Lock()
+	closed := p.closed
+	p.mtx.Unlock()
+	if closed {
+		return nil, errors.New("engine pool closed")
+	}
+
+	select {
+	case s := <-p.idle:
+		return s, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.ctx.Done():
+		return nil, p.ctx.Err()
+	}
+}
+
+// release gives s back to the pool, replacing it first if it died. A search
+// only fails when the engine's output ends, which can be before the process
+// is reaped, so failed says it's dead too.
+func (p *enginePool) release(s *searcher, failed bool) {
+	if failed || !s.sf.Running() {
+		s.quit()
+		s = p.replace(s)
+	}
+	p.idle <- s
+}
+
+// replace starts a new engine in place of dead. If it can't, dead is kept
+// and the next search on it fails, and tries again.
+func (p *enginePool) replace(dead *searcher) *searcher {
+	if p.ctx.Err() != nil {
+		return dead
+	}
+
+	s, err := startSearcherPath(p.ctx, p.path, p.logInfo)
+	if err != nil {
+		p.logInfo(fmt.Sprintf("ERR: engine pool: restart engine: %v", err))
+		return dead
+	}
+
+	p.mtx.Lock()
+	options := append([][2]string(nil), p.options...)
+	p.mtx.Unlock()
+	for _, o := range options {
+		s.setOption(o[0], o[1])
+	}
+	if err := s.ready(); err != nil {
+		p.logInfo(fmt.Sprintf("ERR: engine pool: restart engine: %v", err))
+	}
+	return s
+}
+
+// search runs "go" on fen on the next free engine, see searcher.stream.
+func (p *enginePool) search(ctx context.Context, fen string, goArgs []string, updates chan<- Info) (searchResult, error) {
+	s, err := p.acquire(ctx)
+	if err != nil {
+		return searchResult{}, err
+	}
+	r, err := s.stream(ctx, fen, goArgs, updates)
+	p.release(s, err != nil)
+	return r, err
+}
+
+// setOption sets an option on every engine, once each has finished its
+// search, and on the engines that replace them.
+func (p *enginePool) setOption(name, value string) error {
+	p.optionsMtx.Lock()
+	defer p.optionsMtx.Unlock()
+
+	engines := make([]*searcher, 0, p.size)
+	defer func() {
+		for _, s := range engines {
+			p.release(s, false)
+		}
+	}()
+	for len(engines) < p.size {
+		s, err := p.acquire(p.ctx)
+		if err != nil {
+			return err
+		}
+		engines = append(engines, s)
+	}
+
+	p.mtx.Lock()
+	set := false
+	for i, o := range p.options {
+		if strings.EqualFold(o[0], name) {
+			p.options[i][1], set = value, true
+		}
+	}
+	if !set {
+		p.options = append(p.options, [2]string{name, value})
+	}
+	p.mtx.Unlock()
+
+	var firstErr error
+	for _, s := range engines {
+		s.setOption(name, value)
+		if err := s.ready(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// close waits for the running searches and quits the engines. It's safe to
+// call more than once.
+func (p *enginePool) close() {
+	p.mtx.Lock()
+	if p.closed {
+		p.mtx.Unlock()
+		return
+	}
+	p.closed = true
+	p.mtx.Unlock()
+
+	for i := 0; i < p.size; i++ {
+		(<-p.idle).quit()
+	}
+}
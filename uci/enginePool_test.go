@@ -0,0 +1,89 @@
+package uci
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowEngine takes 300ms a search, and dies on a position of "crash".
+const slowEngine = `#!/bin/sh
+while read -r cmd rest; do
+	case "$cmd" in
+	uci) echo "id name slow"; echo "uciok" ;;
+	isready) echo "readyok" ;;
+	position) [ "$rest" = "fen crash" ] && exit 1 ;;
+	go)
+		sleep 0.3
+		echo "info depth 1 multipv 1 score cp 20 nodes 10 pv e2e4"
+		echo "bestmove e2e4"
+		;;
+	quit) exit 0 ;;
+	esac
+done
+`
+
+func TestEnginePool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "engine")
+	if err := os.WriteFile(path, []byte(slowEngine), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p, err := newEnginePool(ctx, path, 2, func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.close()
+
+	// 4 searches on 2 engines take 2 searches' time, not 4
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := p.search(ctx, startPosFEN, []string{"depth", "1"}, nil)
+			if err == nil && r.BestMove != "e2e4" {
+				t.Errorf("bestmove want: e2e4 got: %s", r.BestMove)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 1100*time.Millisecond {
+		t.Errorf("4 searches on 2 engines took %v", d)
+	}
+
+	if err := p.setOption("MultiPV", "3"); err != nil {
+		t.Fatal(err)
+	}
+
+	// an engine that dies is replaced for the next search
+	if _, err := p.search(ctx, "crash", []string{"depth", "1"}, nil); err == nil {
+		t.Error("crash: want an error")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.search(ctx, startPosFEN, []string{"depth", "1"}, nil); err != nil {
+			t.Fatalf("after the crash: %v", err)
+		}
+	}
+	p.mtx.Lock()
+	options := p.options
+	p.mtx.Unlock()
+	if len(options) != 1 || options[0] != [2]string{"MultiPV", "3"} {
+		t.Errorf("options: %q", options)
+	}
+}
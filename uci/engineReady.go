@@ -0,0 +1,96 @@
+package uci
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultEngineTimeout is how long SF gets to answer uci or isready, in
+	// ms. A big hash can take a few seconds to allocate.
+	defaultEngineTimeout = 10_000
+
+	// defaultEngineRetries is how many times SF is restarted when it doesn't
+	// answer before the built-in engine takes over.
+	defaultEngineRetries = 2
+)
+
+// askEngine sends cmd to SF and makes sure reply comes back, "uciok" for
+// "uci" or "readyok" for "isready". If SF doesn't answer in time it's
+// restarted and asked again; once the retries are used up the GUI is told and
+// the built-in engine answers instead, so the GUI isn't left waiting.
+func (u *UCI) askEngine(cmd, reply string) {
+	answered := u.expectReply(reply)
+	u.engine().Write(cmd)
+	go u.awaitReply(cmd, reply, answered)
+}
+
+// expectReply returns a channel closed when SF next sends reply.
+func (u *UCI) expectReply(reply string) <-chan struct{} {
+	u.moveListMtx.Lock()
+	defer u.moveListMtx.Unlock()
+
+	if u.engineReplies == nil {
+		u.engineReplies = make(map[string]chan struct{})
+	}
+	c, ok := u.engineReplies[reply]
+	if !ok {
+		c = make(chan struct{})
+		u.engineReplies[reply] = c
+	}
+	return c
+}
+
+// engineReplied is called by the read loop for each uciok and readyok.
+func (u *UCI) engineReplied(reply string) {
+	u.moveListMtx.Lock()
+	defer u.moveListMtx.Unlock()
+
+	if c, ok := u.engineReplies[reply]; ok {
+		close(c)
+		delete(u.engineReplies, reply)
+	}
+}
+
+func (u *UCI) awaitReply(cmd, reply string, answered <-chan struct{}) {
+	u.moveListMtx.Lock()
+	timeout := time.Duration(u.config.EngineTimeout) * time.Millisecond
+	retries := u.config.EngineRetries
+	u.moveListMtx.Unlock()
+
+	for try := 1; ; try++ {
+		select {
+		case <-answered:
+			return
+		case <-u.ctx.Done():
+			return
+		case <-time.After(timeout):
+		}
+
+		if try > retries {
+			break
+		}
+		u.logInfo(fmt.Sprintf("ERR: engine: no %s after %v, restarting (retry %d of %d)", reply, timeout, try, retries))
+		// a new engine is sent uci, its uciok goes to the GUI if the first
+		// never did
+		if err := u.switchEngine(u.runningEnginePath()); err != nil {
+			u.logInfo(fmt.Sprintf("ERR: restart engine: %v", err))
+			break
+		}
+		answered = u.expectReply(reply)
+		if cmd != "uci" {
+			u.engine().Write(cmd)
+		}
+	}
+
+	msg := fmt.Sprintf("engine '%s' didn't answer %s, using the built-in engine", u.runningEnginePath(), cmd)
+	u.logInfo("ERR: " + msg)
+	u.WriteLine("info string ERR: " + msg)
+
+	u.setEngine(noEngine{}, "").Quit()
+
+	if reply != "uciok" || atomic.CompareAndSwapInt64(&u.sfInitialized, 0, 1) {
+		u.WriteLine(reply)
+	}
+}
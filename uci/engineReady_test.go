@@ -0,0 +1,66 @@
+package uci
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEngineReadyTimeout(t *testing.T) {
+	cases := []struct {
+		name    string
+		answer  int // the engine that answers uci, 0 for none
+		wantErr bool
+	}{
+		{name: "answers after a restart", answer: 2},
+		{name: "never answers", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u, out := newTestUCI(t)
+			u.config.EngineTimeout, u.config.EngineRetries = 50, 1
+
+			var started int64
+			u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+				n := atomic.AddInt64(&started, 1)
+				e := newFakeBackend()
+				if int(n) == c.answer {
+					go func() { e.output <- "uciok" }()
+				}
+				return e, nil
+			})
+			if err := u.switchEngine("/engines/sf"); err != nil {
+				t.Fatal(err)
+			}
+
+			u.SetUCI()
+
+			deadline := time.Now().Add(5 * time.Second)
+			for !strings.Contains(out.String(), "uciok\n") {
+				if time.Now().After(deadline) {
+					t.Fatalf("no uciok:\n%s", out.String())
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			// anything late would show up by now
+			time.Sleep(100 * time.Millisecond)
+
+			got := out.String()
+			if n := strings.Count(got, "uciok\n"); n != 1 {
+				t.Errorf("want 1 uciok got %d:\n%s", n, got)
+			}
+			if hasErr := strings.Contains(got, "info string ERR: engine '/engines/sf' didn't answer uci"); hasErr != c.wantErr {
+				t.Errorf("error want: %v got:\n%s", c.wantErr, got)
+			}
+			if running := u.sf.Running(); running == c.wantErr {
+				t.Errorf("engine running: %v", running)
+			}
+			if n := atomic.LoadInt64(&started); n != 2 {
+				t.Errorf("want 2 engines started got %d", n)
+			}
+		})
+	}
+}
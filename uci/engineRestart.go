@@ -0,0 +1,75 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxEngineRestarts is how many times SF is restarted in a game before the
+// built-in engine is left to play the rest, so a position that crashes SF
+// every time doesn't restart it forever.
+const maxEngineRestarts = 3
+
+// engineOptions are the options the GUI set that trollfish passed on to SF as
+// they were, in the order they were first set, to send again to a new
+// engine. Buttons aren't kept; pressing one isn't a setting.
+type engineOptions struct {
+	names  []string
+	values map[string]engineOption // by lower case name
+}
+
+type engineOption struct {
+	name, value string
+}
+
+func (o *engineOptions) set(name, value string) {
+	key := strings.ToLower(name)
+	if o.values == nil {
+		o.values = make(map[string]engineOption)
+	}
+	if _, ok := o.values[key]; !ok {
+		o.names = append(o.names, key)
+	}
+	o.values[key] = engineOption{name: name, value: value}
+}
+
+func (o *engineOptions) all() []engineOption {
+	all := make([]engineOption, 0, len(o.names))
+	for _, key := range o.names {
+		all = append(all, o.values[key])
+	}
+	return all
+}
+
+// restartEngine starts the engine at the same path after dead exited on its
+// own, and sends it the options and position the GUI gave the old one.
+func (u *UCI) restartEngine(dead EngineBackend) {
+	if u.ctx.Err() != nil || u.engine() != dead {
+		return
+	}
+
+	u.moveListMtx.Lock()
+	restarts := u.engineRestarts
+	if restarts < maxEngineRestarts {
+		u.engineRestarts++
+	}
+	position, variant := u.position, u.variant
+	u.moveListMtx.Unlock()
+
+	if restarts >= maxEngineRestarts {
+		u.logInfo(fmt.Sprintf("ERR: engine: restarted %d times this game, the built-in engine plays", restarts))
+		return
+	}
+
+	if err := u.switchEngine(u.runningEnginePath()); err != nil {
+		u.logInfo(fmt.Sprintf("ERR: restart engine: %v", err))
+		return
+	}
+	if !variant.isChess() {
+		u.engine().Write(fmt.Sprintf("setoption name UCI_Variant value %s", variant))
+	}
+	if position != "" {
+		u.engine().Write(position)
+	}
+	u.logInfo(fmt.Sprintf("engine restarted (%d of %d this game)", restarts+1, maxEngineRestarts))
+}
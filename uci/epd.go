@@ -0,0 +1,83 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// epdRecord is one line of an EPD file: the four position fields and the
+// operations that follow them (bm, am, id, c0, ...).
+type epdRecord struct {
+	FEN string
+	Ops map[string]string
+}
+
+func parseEPD(line string) (epdRecord, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return epdRecord{}, fmt.Errorf("epd: expected at least 4 fields: '%s'", line)
+	}
+
+	rec := epdRecord{
+		FEN: strings.Join(fields[:4], " ") + " 0 1",
+		Ops: make(map[string]string),
+	}
+
+	rest := strings.Join(fields[4:], " ")
+	for _, op := range splitEPDOps(rest) {
+		op = strings.TrimSpace(op)
+		if op == "" {
+			continue
+		}
+		var opcode, operand string
+		if idx := strings.Index(op, " "); idx != -1 {
+			opcode, operand = op[:idx], strings.TrimSpace(op[idx+1:])
+		} else {
+			opcode = op
+		}
+		rec.Ops[opcode] = strings.Trim(operand, `"`)
+	}
+
+	// a few suites carry the move counters as operations
+	if hmvc, ok := rec.Ops["hmvc"]; ok {
+		rec.FEN = strings.Join(fields[:4], " ") + " " + hmvc + " 1"
+		if fmvn, ok := rec.Ops["fmvn"]; ok {
+			rec.FEN = strings.Join(fields[:4], " ") + " " + hmvc + " " + fmvn
+		}
+	}
+
+	return rec, nil
+}
+
+// splitEPDOps splits on semicolons outside of quoted strings.
+func splitEPDOps(s string) []string {
+	var ops []string
+	var quoted bool
+	start := 0
+	for i, c := range s {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				ops = append(ops, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(ops, s[start:])
+}
+
+// sanMoves converts a space separated list of SAN moves to UCI.
+func (r epdRecord) sanMoves(op string) ([]string, error) {
+	b := FENtoBoard(r.FEN)
+	var moves []string
+	for _, san := range strings.Fields(r.Ops[op]) {
+		move, err := b.ParseSAN(san)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, move)
+	}
+	return moves, nil
+}
@@ -0,0 +1,377 @@
+package uci
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// defaultEngineReleases is the GitHub API for SF's releases.
+const defaultEngineReleases = "https://api.github.com/repos/official-stockfish/Stockfish/releases"
+
+// engineBuilds are SF's release builds for each platform, fastest first. The
+// CPU features aren't checked up front: a build the CPU can't run dies on its
+// first instruction, and the next one is tried.
+var engineBuilds = map[string][]string{
+	"linux/amd64":   {"stockfish-ubuntu-x86-64-avx2", "stockfish-ubuntu-x86-64-sse41-popcnt", "stockfish-ubuntu-x86-64"},
+	"windows/amd64": {"stockfish-windows-x86-64-avx2", "stockfish-windows-x86-64-sse41-popcnt", "stockfish-windows-x86-64"},
+	"darwin/amd64":  {"stockfish-macos-x86-64-avx2", "stockfish-macos-x86-64-sse41-popcnt", "stockfish-macos-x86-64"},
+	"darwin/arm64":  {"stockfish-macos-m1-apple-silicon"},
+}
+
+// engineArchives are the archive formats SF's builds are released in.
+var engineArchives = []string{".tar", ".tar.gz", ".zip"}
+
+// FetchEngineOptions configures the fetch-engine command.
+type FetchEngineOptions struct {
+	// Version is the SF release, "17" or its tag "sf_17". Empty is the
+	// latest.
+	Version string
+
+	// Dir is where the engine is installed, in a directory per release. The
+	// default is $TROLLFISH_DATA, or trollfish in the user's data directory.
+	Dir string
+
+	// SHA256 is the build's checksum, for a release that doesn't publish
+	// one.
+	SHA256 string
+
+	// Releases is the releases API, for a mirror. The default is GitHub's.
+	Releases string
+}
+
+type engineRelease struct {
+	Tag    string        `json:"tag_name"`
+	Assets []engineAsset `json:"assets"`
+}
+
+type engineAsset struct {
+	Name   string `json:"name"`
+	URL    string `json:"browser_download_url"`
+	Digest string `json:"digest"` // "sha256:<hex>"
+}
+
+// FetchEngine downloads SF's build for this platform, verifies its checksum,
+// installs it and writes its path to the config file as the engine, writing
+// progress to w. It returns the engine's path.
+func FetchEngine(ctx context.Context, opts FetchEngineOptions, w io.Writer) (string, error) {
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	builds, ok := engineBuilds[platform]
+	if !ok {
+		return "", fmt.Errorf("no Stockfish build for %s, build it from source and set engine in %s", platform, configPath())
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		var err error
+		if dir, err = dataDir(); err != nil {
+			return "", err
+		}
+	}
+
+	release, err := fetchRelease(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, release.Tag)
+
+	var tried int
+	for _, build := range builds {
+		asset, ok := release.asset(build)
+		if !ok {
+			continue
+		}
+		tried++
+
+		enginePath, err := installEngine(ctx, asset, build, dir, opts.SHA256, w)
+		if err != nil {
+			return "", err
+		}
+
+		// a build for a newer CPU dies before it answers
+		r := checkEngine(ctx, "engine", enginePath)
+		if r.Err != nil {
+			_, _ = fmt.Fprintf(w, "%s doesn't run here: %v\n", build, r.Err)
+			_ = os.Remove(enginePath)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "installed %s\n", r.Detail)
+
+		config := configPath()
+		if err := writeConfigEngine(config, enginePath); err != nil {
+			return "", fmt.Errorf("write %s: %w", config, err)
+		}
+		_, _ = fmt.Fprintf(w, "set engine in %s\n", config)
+		if env := os.Getenv("TROLLFISH_ENGINE"); env != "" {
+			_, _ = fmt.Fprintf(w, "$TROLLFISH_ENGINE is set and overrides it: %s\n", env)
+		}
+		return enginePath, nil
+	}
+
+	if tried == 0 {
+		return "", fmt.Errorf("release %s has no build for %s", release.Tag, platform)
+	}
+	return "", fmt.Errorf("none of release %s's builds for %s run here", release.Tag, platform)
+}
+
+// asset returns the release's archive of build.
+func (r engineRelease) asset(build string) (engineAsset, bool) {
+	for _, a := range r.Assets {
+		for _, ext := range engineArchives {
+			if a.Name == build+ext {
+				return a, true
+			}
+		}
+	}
+	return engineAsset{}, false
+}
+
+func fetchRelease(ctx context.Context, opts FetchEngineOptions) (engineRelease, error) {
+	url := opts.Releases
+	if url == "" {
+		url = defaultEngineReleases
+	}
+	switch v := opts.Version; {
+	case v == "":
+		url += "/latest"
+	case strings.HasPrefix(v, "sf_"):
+		url += "/tags/" + v
+	default:
+		url += "/tags/sf_" + v
+	}
+
+	resp, err := httpGet(ctx, url, "application/vnd.github+json")
+	if err != nil {
+		return engineRelease{}, err
+	}
+	defer resp.Body.Close()
+
+	var release engineRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return engineRelease{}, fmt.Errorf("%s: %w", url, err)
+	}
+	if release.Tag == "" {
+		return engineRelease{}, fmt.Errorf("%s: no release", url)
+	}
+	return release, nil
+}
+
+func httpGet(ctx context.Context, url, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// installEngine downloads asset, checks it against the release's digest or
+// sha, and extracts build's binary into dir.
+func installEngine(ctx context.Context, asset engineAsset, build, dir, sha string, w io.Writer) (string, error) {
+	want := strings.TrimPrefix(asset.Digest, "sha256:")
+	if want == "" || want == asset.Digest {
+		want = sha
+	}
+	if want == "" {
+		return "", fmt.Errorf("release doesn't publish a checksum for %s, pass one to check it against", asset.Name)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	archive, err := os.CreateTemp(dir, asset.Name+".*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	_, _ = fmt.Fprintf(w, "downloading %s\n", asset.URL)
+	resp, err := httpGet(ctx, asset.URL, "")
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(archive, h), resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", asset.Name, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, want) {
+		return "", fmt.Errorf("%s: checksum %s, want %s", asset.Name, got, want)
+	}
+
+	binary := build
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	enginePath := filepath.Join(dir, binary)
+	if err := extractEngine(archive, size, asset.Name, binary, enginePath); err != nil {
+		return "", fmt.Errorf("%s: %w", asset.Name, err)
+	}
+	return enginePath, nil
+}
+
+// extractEngine writes the file named binary, in any directory of the
+// archive, to dst.
+func extractEngine(archive *os.File, size int64, name, binary, dst string) error {
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(name, ".zip") {
+		zr, err := zip.NewReader(archive, size)
+		if err != nil {
+			return err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() || path.Base(f.Name) != binary {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			return writeEngine(r, dst)
+		}
+		return fmt.Errorf("no %s in the archive", binary)
+	}
+
+	var r io.Reader = bufio.NewReader(archive)
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("no %s in the archive", binary)
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeReg && path.Base(hdr.Name) == binary {
+			return writeEngine(tr, dst)
+		}
+	}
+}
+
+// writeEngine writes an executable to dst, replacing what's there only once
+// it's all written.
+func writeEngine(r io.Reader, dst string) error {
+	fp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fp.Name())
+
+	if _, err := io.Copy(fp, r); err != nil {
+		_ = fp.Close()
+		return err
+	}
+	if err := fp.Chmod(0755); err != nil {
+		_ = fp.Close()
+		return err
+	}
+	if err := fp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(fp.Name(), dst)
+}
+
+// dataDir is where downloaded engines go: $TROLLFISH_DATA, or trollfish in
+// the user's data directory.
+func dataDir() (string, error) {
+	if dir := os.Getenv("TROLLFISH_DATA"); dir != "" {
+		return dir, nil
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return filepath.Join(dir, "trollfish"), nil
+		}
+	case "darwin":
+		dir, err := os.UserConfigDir() // ~/Library/Application Support
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "trollfish"), nil
+	default:
+		if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+			return filepath.Join(dir, "trollfish"), nil
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "trollfish"), nil
+}
+
+// writeConfigEngine sets the engine key of the config file at path, keeping
+// the rest of the file as it is. A missing file is created.
+func writeConfigEngine(path, engine string) error {
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	line := "engine = " + strconv.Quote(engine)
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(b) == 0 {
+		lines = nil
+	}
+
+	set := false
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(stripTOMLComment(l))
+		if strings.HasPrefix(trimmed, "[") {
+			// engine is a top level key
+			break
+		}
+		if key, _, ok := strings.Cut(trimmed, "="); ok && strings.TrimSpace(key) == "engine" {
+			lines[i] = line
+			set = true
+			break
+		}
+	}
+	if !set {
+		lines = append([]string{line}, lines...)
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
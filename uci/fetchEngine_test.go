@@ -0,0 +1,119 @@
+package uci
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeEngineScript answers uci like SF does.
+const fakeEngineScript = `#!/bin/sh
+while read -r line; do
+	case "$line" in
+	uci) echo "id name Stockfish 17"; echo "uciok" ;;
+	quit) exit 0 ;;
+	esac
+done
+`
+
+func TestFetchEngine(t *testing.T) {
+	builds, ok := engineBuilds[runtime.GOOS+"/"+runtime.GOARCH]
+	if !ok || runtime.GOOS == "windows" {
+		t.Skipf("no shell script engine for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	build := builds[len(builds)-1]
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for name, body := range map[string]string{
+		"stockfish/" + build:  fakeEngineScript,
+		"stockfish/README.md": "Stockfish",
+	} {
+		hdr := &tar.Header{Name: name, Mode: 0755, Size: int64(len(body)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = tw.Write([]byte(body))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(archive.Bytes())
+
+	var digest string
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("/releases/tags/sf_17", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(engineRelease{Tag: "sf_17", Assets: []engineAsset{
+			{Name: "stockfish-other.tar", URL: srv.URL + "/other.tar"},
+			{Name: build + ".tar", URL: srv.URL + "/" + build + ".tar", Digest: digest},
+		}})
+	})
+	mux.HandleFunc("/"+build+".tar", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive.Bytes())
+	})
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "trollfish.toml")
+	t.Setenv("TROLLFISH_CONFIG", config)
+	t.Setenv("TROLLFISH_ENGINE", "")
+	if err := os.WriteFile(config, []byte("# mine\nthreads = 4\n\n[options]\nPlayBad = true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := FetchEngineOptions{Version: "17", Dir: filepath.Join(dir, "engines"), Releases: srv.URL + "/releases"}
+
+	// a bad checksum installs nothing
+	digest = "sha256:" + strings.Repeat("0", 64)
+	if _, err := FetchEngine(context.Background(), opts, io.Discard); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("bad checksum: want a checksum error got %v", err)
+	}
+	if cfg, err := LoadConfig(config); err != nil || cfg.Engine != "" {
+		t.Fatalf("bad checksum: engine set to '%s' (%v)", cfg.Engine, err)
+	}
+
+	digest = "sha256:" + hex.EncodeToString(sum[:])
+	var out bytes.Buffer
+	path, err := FetchEngine(context.Background(), opts, &out)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	if want := filepath.Join(dir, "engines", "sf_17", build); path != want {
+		t.Errorf("path want: %s got: %s", want, path)
+	}
+	if !strings.Contains(out.String(), "installed Stockfish 17") {
+		t.Errorf("output:\n%s", out.String())
+	}
+
+	cfg, err := LoadConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Engine != path || cfg.Threads != 4 || cfg.Options["PlayBad"] != "true" {
+		t.Errorf("config: engine '%s' threads %d options %v", cfg.Engine, cfg.Threads, cfg.Options)
+	}
+
+	// fetching again replaces the engine key rather than adding another
+	if _, err := FetchEngine(context.Background(), opts, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "engine = "); n != 1 {
+		t.Errorf("want 1 engine key got %d:\n%s", n, b)
+	}
+}
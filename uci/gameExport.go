@@ -0,0 +1,234 @@
+package uci
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gameRecord is a finished game as exported to GameExportDir: every move
+// played, and for each of ours what SF would have played, both scored.
+type gameRecord struct {
+	Ended   time.Time    `json:"ended"`
+	Variant Variant      `json:"variant"`
+	Start   string       `json:"start"`
+	Moves   []string     `json:"moves"`
+	Result  string       `json:"result"`
+	Reason  string       `json:"reason,omitempty"`
+	Evals   []moveRecord `json:"evals"`
+}
+
+// moveRecord is a moveEval as exported. The evals are for the side that
+// moved.
+type moveRecord struct {
+	MoveNumber int    `json:"move_number"`
+	Color      string `json:"color"`
+	FEN        string `json:"fen"`
+	Played     string `json:"played"`
+	Eval       Score  `json:"eval"`
+	SFBest     string `json:"sf_best"`
+	SFEval     Score  `json:"sf_eval"`
+	MoveTime   int    `json:"movetime"`
+	Clock      int    `json:"clock,omitempty"`
+	OppClock   int    `json:"opp_clock,omitempty"`
+	Agro       bool   `json:"agro"`
+}
+
+func newGameRecord(h *History, variant Variant, result GameResult, evals []moveEval) gameRecord {
+	start := h.Start()
+	r := gameRecord{
+		Ended:   time.Now(),
+		Variant: variant,
+		Start:   start.FEN(),
+		Moves:   h.Moves(),
+		Result:  result.Result,
+		Reason:  result.Reason,
+		Evals:   make([]moveRecord, 0, len(evals)),
+	}
+	if r.Variant == "" {
+		r.Variant = VariantChess
+	}
+	if r.Result == "" {
+		// abandoned, or still going when the GUI moved on
+		r.Result = "*"
+	}
+	for _, m := range evals {
+		r.Evals = append(r.Evals, moveRecord{
+			MoveNumber: m.moveNumber,
+			Color:      m.color,
+			FEN:        m.fen,
+			Played:     m.playedMove,
+			Eval:       m.playedEval,
+			SFBest:     m.sfMove,
+			SFEval:     m.sfEval,
+			MoveTime:   m.moveTime,
+			Clock:      m.clock,
+			OppClock:   m.oppClock,
+			Agro:       m.agro,
+		})
+	}
+	return r
+}
+
+// exportGame writes the game that just ended to GameExportDir as
+// trollfish-game-<time>.json and .pgn, if the option's set and a move was
+// played.
+func (u *UCI) exportGame(evals []moveEval) {
+	dir := u.options.String("GameExportDir")
+
+	u.moveListMtx.Lock()
+	h := u.history
+	variant := u.variant
+	result := u.gameResult
+	u.moveListMtx.Unlock()
+
+	if dir == "" || h == nil || h.Len() == 0 {
+		return
+	}
+
+	r := newGameRecord(h, variant, result, evals)
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		u.logInfo(fmt.Sprintf("ERR: export game: %v", err))
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		u.logInfo(fmt.Sprintf("ERR: export game: %v", err))
+		return
+	}
+	base := filepath.Join(dir, fmt.Sprintf("trollfish-game-%s", r.Ended.Format("20060102-150405")))
+	files := []struct {
+		path string
+		b    []byte
+	}{
+		{base + ".json", append(b, '\n')},
+		{base + ".pgn", []byte(gamePGN(h, r, evals))},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f.path, f.b, 0644); err != nil {
+			u.logInfo(fmt.Sprintf("ERR: export game: %v", err))
+			return
+		}
+	}
+	u.logInfo(fmt.Sprintf("game exported to %s.json and .pgn", base))
+}
+
+// gamePGN is the game as PGN for any GUI to review. The moves trollfish has a
+// search for get the eval after them, from White's side, and the time left on
+// the mover's clock: ours as the line we played and our clock less the move
+// time, the opponent's as our next search's best line and their clock at its
+// "go".
+func gamePGN(h *History, r gameRecord, evals []moveEval) string {
+	start := h.Start()
+	startPly := gamePly(atoi(start.FullMove), start.ActiveColor)
+	moves := h.Moves()
+
+	comments := make([]string, len(moves))
+	var ours string
+	for _, m := range evals {
+		i := gamePly(m.moveNumber, m.color) - startPly
+		if i < 0 || i >= len(moves) || moves[i] != m.playedMove {
+			// not this game's move, a GUI can take moves back
+			continue
+		}
+		ours = m.color
+		comments[i] = pgnComment(m.playedEval.WhitePOV(m.color), m.clock, max(m.clock-m.moveTime, 0))
+		if i > 0 && comments[i-1] == "" {
+			comments[i-1] = pgnComment(m.sfEval.WhitePOV(m.color), m.oppClock, m.oppClock)
+		}
+	}
+
+	white, black := "?", "?"
+	switch ours {
+	case "w":
+		white = "trollfish"
+	case "b":
+		black = "trollfish"
+	}
+
+	var sb strings.Builder
+	tag := func(name, value string) {
+		sb.WriteString(fmt.Sprintf("[%s \"%s\"]\n", name, strings.ReplaceAll(value, `"`, `\"`)))
+	}
+	tag("Event", "trollfish game")
+	tag("Site", "?")
+	tag("Date", r.Ended.Format("2006.01.02"))
+	tag("Round", "-")
+	tag("White", white)
+	tag("Black", black)
+	tag("Result", r.Result)
+	if r.Variant != VariantChess {
+		tag("Variant", string(r.Variant))
+	}
+	if r.Start != r.Variant.startFEN() {
+		tag("SetUp", "1")
+		tag("FEN", r.Start)
+	}
+	if r.Reason != "" {
+		tag("Termination", r.Reason)
+	}
+	sb.WriteString("\n")
+
+	var tokens []string
+	b := start.Clone()
+	for i, move := range moves {
+		switch {
+		case b.ActiveColor == "w":
+			tokens = append(tokens, b.FullMove+".")
+		case i == 0 || comments[i-1] != "":
+			tokens = append(tokens, b.FullMove+"...")
+		}
+		tokens = append(tokens, pgnSAN(&b, move))
+		if comments[i] != "" {
+			tokens = append(tokens, comments[i])
+		}
+		b.Moves(move)
+	}
+	tokens = append(tokens, r.Result)
+
+	var line int
+	for _, t := range tokens {
+		if line > 0 && line+1+len(t) > pgnLineLength {
+			sb.WriteString("\n")
+			line = 0
+		}
+		if line > 0 {
+			sb.WriteString(" ")
+			line++
+		}
+		sb.WriteString(t)
+		line += len(t)
+	}
+	sb.WriteString("\n\n")
+	return sb.String()
+}
+
+// pgnLineLength is where the movetext wraps, PGN's export format's 80
+// columns.
+const pgnLineLength = 79
+
+// pgnComment is a move's comment with its eval and, if the GUI sent a clock,
+// the time left after it.
+func pgnComment(eval Score, clock, left int) string {
+	evalStr := fmt.Sprintf("%0.2f", float64(eval.CP)/100)
+	if eval.Mate != 0 {
+		evalStr = fmt.Sprintf("#%d", eval.Mate)
+	}
+	if clock <= 0 {
+		return fmt.Sprintf("{ [%%eval %s] }", evalStr)
+	}
+	s := left / 1000
+	return fmt.Sprintf("{ [%%eval %s] [%%clk %d:%02d:%02d] }", evalStr, s/3600, s/60%60, s%60)
+}
+
+// pgnSAN is a move in SAN, with crazyhouse drops written as their UCI move.
+func pgnSAN(b *Board, move string) string {
+	if len(move) > 1 && move[1] == '@' {
+		return strings.ToUpper(move[:1]) + move[1:]
+	}
+	return b.SAN(move)
+}
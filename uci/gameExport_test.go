@@ -0,0 +1,95 @@
+package uci
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportGame(t *testing.T) {
+	u, out := newTestUCI(t)
+
+	dir := filepath.Join(t.TempDir(), "games")
+	u.SetOption("GameExportDir", dir)
+	u.SetOption("OwnBook", "false")
+
+	eng := newScriptedEngine([]string{
+		"info depth 18 seldepth 24 multipv 1 score cp 42 nodes 900000 nps 900000 time 1000 pv e1g1 f8e7 f1e1",
+		"bestmove e1g1 ponder f8e7",
+	})
+	u.sf = eng
+	go u.stockFishReadLoop(eng)
+
+	u.ResetGame()
+	u.SetPosition("startpos", "moves", "e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6", "b5a4", "g8f6")
+	u.Go("wtime", "60000", "btime", "60000")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "bestmove ") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no bestmove:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	u.SetPosition("startpos", "moves", "e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6", "b5a4", "g8f6", "e1g1", "f8e7")
+	u.ResetGame()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "trollfish-game-*.json"))
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("want 1 exported game got %q (%v)", paths, err)
+	}
+	b, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r gameRecord
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.Fatalf("%v\n%s", err, b)
+	}
+
+	if r.Start != startPosFEN || len(r.Moves) != 10 || r.Result != "*" || r.Variant != VariantChess {
+		t.Errorf("game: %+v", r)
+	}
+	if len(r.Evals) != 1 {
+		t.Fatalf("want 1 eval got %d:\n%s", len(r.Evals), b)
+	}
+	m := r.Evals[0]
+	if m.MoveNumber != 5 || m.Color != "w" || m.Played != "e1g1" || m.SFBest != "e1g1" ||
+		m.Eval != (Score{CP: 42}) || m.SFEval != (Score{CP: 42}) || m.MoveTime < 0 ||
+		m.FEN != "r1bqkb1r/1ppp1ppp/p1n2n2/4p3/B3P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 2 5" {
+		t.Errorf("eval: %+v", m)
+	}
+	if !strings.Contains(string(b), `"sf_eval": {`+"\n"+`        "cp": 42`) {
+		t.Errorf("scores want {\"cp\": 42}:\n%s", b)
+	}
+
+	if m.Clock != 60000 || m.OppClock != 60000 {
+		t.Errorf("clocks want 60000 got %d %d", m.Clock, m.OppClock)
+	}
+
+	b, err = os.ReadFile(strings.TrimSuffix(paths[0], ".json") + ".pgn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgn := string(b)
+	// our clock is less the move time, the opponent's as of our "go"
+	want := regexp.MustCompile(`(?s)\[White "trollfish"\]\n\[Black "\?"\]\n\[Result "\*"\]\n\n` +
+		`1\. e4 e5 2\. Nf3 Nc6 3\. Bb5 a6 4\. Ba4 Nf6 \{ \[%eval 0\.42\] \[%clk 0:01:00\] \} 5\. O-O\n` +
+		`\{ \[%eval 0\.42\] \[%clk 0:0(1:00|0:59)\] \} 5\.\.\. Be7 \*\n`)
+	if !want.MatchString(pgn) {
+		t.Errorf("want %s got:\n%s", want, pgn)
+	}
+	if strings.Contains(pgn, "FEN") {
+		t.Errorf("the start position needs no FEN tag:\n%s", pgn)
+	}
+
+	// nothing played, nothing exported
+	u.ResetGame()
+	if paths, _ := filepath.Glob(filepath.Join(dir, "*")); len(paths) != 2 {
+		t.Errorf("want 2 files got %q", paths)
+	}
+}
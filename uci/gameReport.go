@@ -0,0 +1,228 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxCPLoss caps the loss of a single move so one missed mate doesn't
+// drown out the rest of the game. lichess clamps evals to +/-1000 the same way.
+const maxCPLoss = 1000
+
+type gamePhase int
+
+const (
+	phaseOpening gamePhase = iota
+	phaseMiddlegame
+	phaseEndgame
+
+	phaseCount
+)
+
+func (p gamePhase) String() string {
+	switch p {
+	case phaseOpening:
+		return "opening"
+	case phaseMiddlegame:
+		return "middlegame"
+	case phaseEndgame:
+		return "endgame"
+	}
+	return fmt.Sprintf("phase(%d)", int(p))
+}
+
+// boardPhase is a simplified version of lichess' Divider: endgame once there are
+// 6 or fewer majors and minors on the board, middlegame once a few pieces have
+// been traded or the opening has gone on long enough.
+func boardPhase(b Board) gamePhase {
+	var majorsAndMinors int
+	for _, c := range b.Pos {
+		switch unicode.ToLower(c) {
+		case 'n', 'b', 'r', 'q':
+			majorsAndMinors++
+		}
+	}
+
+	if majorsAndMinors <= 6 {
+		return phaseEndgame
+	}
+
+	if majorsAndMinors <= 10 || atoi(b.FullMove) > 12 {
+		return phaseMiddlegame
+	}
+
+	return phaseOpening
+}
+
+// moveEval is one of our moves with Stockfish's best line and the line we played,
+// both scored from the side to move's perspective.
+type moveEval struct {
+	moveNumber  int
+	color       string // the side that moved
+	fen         string // before the move
+	phase       gamePhase
+	sfMove      string
+	sfScore     int // clamped, for the ACPL
+	sfEval      Score
+	playedMove  string
+	playedScore int // clamped, for the ACPL
+	playedEval  Score
+	moveTime    int // ms from "go" to SF's bestmove
+	clock       int // ms left for the side that moved at "go", 0 without a clock
+	oppClock    int // and for the other side, after its last move
+	agro        bool
+}
+
+func newMoveEval(fen string, moveNumber int, sf, played Info) moveEval {
+	return moveEval{
+		moveNumber:  moveNumber,
+		fen:         fen,
+		phase:       boardPhase(FENtoBoard(fen)),
+		sfMove:      pvMove(sf.PV),
+		sfScore:     clampScore(sf),
+		sfEval:      sf.Eval(),
+		playedMove:  pvMove(played.PV),
+		playedScore: clampScore(played),
+		playedEval:  played.Eval(),
+	}
+}
+
+func (m moveEval) cpLoss() int {
+	loss := m.sfScore - m.playedScore
+	if loss < 0 {
+		return 0
+	}
+	return min(loss, maxCPLoss)
+}
+
+// clampScore converts mate scores to centipawns and clamps to +/-maxCPLoss.
+func clampScore(m Info) int {
+	if m.Mate > 0 {
+		return maxCPLoss
+	}
+	if m.Mate < 0 {
+		return -maxCPLoss
+	}
+	return max(-maxCPLoss, min(m.Score, maxCPLoss))
+}
+
+type acplStats struct {
+	loss  [phaseCount]int
+	moves [phaseCount]int
+}
+
+func (s *acplStats) add(m moveEval) {
+	s.loss[m.phase] += m.cpLoss()
+	s.moves[m.phase]++
+}
+
+func (s *acplStats) merge(o acplStats) {
+	for p := gamePhase(0); p < phaseCount; p++ {
+		s.loss[p] += o.loss[p]
+		s.moves[p] += o.moves[p]
+	}
+}
+
+func (s acplStats) totalMoves() int {
+	var n int
+	for _, v := range s.moves {
+		n += v
+	}
+	return n
+}
+
+func (s acplStats) acpl() float64 {
+	var loss int
+	for _, v := range s.loss {
+		loss += v
+	}
+	n := s.totalMoves()
+	if n == 0 {
+		return 0
+	}
+	return float64(loss) / float64(n)
+}
+
+func (s acplStats) phaseACPL(p gamePhase) float64 {
+	if s.moves[p] == 0 {
+		return 0
+	}
+	return float64(s.loss[p]) / float64(s.moves[p])
+}
+
+func (s acplStats) String() string {
+	str := fmt.Sprintf("acpl %0.1f moves %d", s.acpl(), s.totalMoves())
+	for p := gamePhase(0); p < phaseCount; p++ {
+		str += fmt.Sprintf(" %s %0.1f (%d)", p, s.phaseACPL(p), s.moves[p])
+	}
+	return str
+}
+
+// recordMove adds a move to the current game's history. Callers must hold moveListMtx.
+func (u *UCI) recordMove(m moveEval) {
+	if len(u.gameHistory) == 0 {
+		// GUIs set UCI_Opponent before the next game's ucinewgame, which
+		// ends this one
+		u.gameOpponent = u.opponent
+	}
+	u.gameHistory = append(u.gameHistory, m)
+}
+
+// reportGame emits the post-game report for the current game and folds it into
+// the session totals. The game history is cleared afterwards.
+func (u *UCI) reportGame() {
+	u.moveListMtx.Lock()
+	history := u.gameHistory
+	u.gameHistory = nil
+	u.moveListMtx.Unlock()
+
+	u.exportGame(history)
+	u.archiveGame(history)
+
+	u.moveListMtx.Lock()
+	u.gameOpponent = opponent{}
+	u.moveListMtx.Unlock()
+
+	if len(history) == 0 {
+		return
+	}
+
+	var game acplStats
+	for _, m := range history {
+		game.add(m)
+	}
+
+	u.session.games++
+	u.sessionACPL.merge(game)
+
+	u.moveListMtx.Lock()
+	latency := u.latency.lines()
+	agro := u.gameAgro
+	result := u.gameResult
+	u.moveListMtx.Unlock()
+
+	u.telemetry.send("game", newGameTelemetry(game, agro, result))
+
+	white, black := gameAccuracy(history)
+	if white.color == "b" {
+		white, black = black, white
+	}
+	accuracy := fmt.Sprintf("game accuracy: %s, %s", white, black)
+	u.logInfo(accuracy)
+
+	lines := []string{
+		fmt.Sprintf("info string game report: result %s %s", result, game),
+		"info string " + accuracy,
+	}
+	for _, line := range blunderReport(history, u.config.BlunderReport, u.config.blunderTolerance()) {
+		u.logInfo(line)
+		lines = append(lines, "info string "+line)
+	}
+	lines = append(lines, fmt.Sprintf("info string session report: games %d %s", u.session.games, u.sessionACPL))
+	u.WriteLines(append(lines, latency...)...)
+}
+
+func pvMove(pv string) string {
+	return strings.Split(pv, " ")[0]
+}
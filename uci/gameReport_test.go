@@ -0,0 +1,64 @@
+package uci
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBoardPhase(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen  string
+		want gamePhase
+	}{
+		{fen: startPosFEN, want: phaseOpening},
+		{fen: "r1bqkb1r/pp3ppp/2n1pn2/2pp4/3P4/2N1PN2/PPP1BPPP/R1BQK2R w KQkq - 2 6", want: phaseOpening},
+		{fen: "r1bqkb1r/pp3ppp/2n1pn2/2pp4/3P4/2N1PN2/PPP1BPPP/R1BQK2R w KQkq - 2 16", want: phaseMiddlegame},
+		{fen: "3q2k1/4npp1/4p2p/p7/4Q3/1PN3P1/P4P1P/6K1 w - - 0 30", want: phaseEndgame},
+		{fen: "8/8/3k1p2/5P2/3K4/8/8/8 b - - 39 135", want: phaseEndgame},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen, func(t *testing.T) {
+			// act
+			got := boardPhase(FENtoBoard(c.fen))
+
+			// assert
+			if c.want != got {
+				t.Errorf("want: %s got: %s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestACPLStats(t *testing.T) {
+	// arrange
+	moves := []moveEval{
+		{phase: phaseOpening, sfScore: 30, playedScore: 10},
+		{phase: phaseOpening, sfScore: 30, playedScore: 40},
+		{phase: phaseMiddlegame, sfScore: 50, playedScore: -50},
+		{phase: phaseEndgame, sfScore: maxCPLoss, playedScore: -maxCPLoss},
+	}
+
+	// act
+	var s acplStats
+	for _, m := range moves {
+		s.add(m)
+	}
+
+	// assert
+	checks := []struct {
+		name      string
+		want, got float64
+	}{
+		{"opening", 10, s.phaseACPL(phaseOpening)},
+		{"middlegame", 100, s.phaseACPL(phaseMiddlegame)},
+		{"endgame", maxCPLoss, s.phaseACPL(phaseEndgame)},
+		{"total", float64(20+100+maxCPLoss) / 4, s.acpl()},
+	}
+	for _, c := range checks {
+		if math.Abs(c.want-c.got) > 0.001 {
+			t.Errorf("%s, want: %0.2f got: %0.2f", c.name, c.want, c.got)
+		}
+	}
+}
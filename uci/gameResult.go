@@ -0,0 +1,140 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// GameResult is how a game ended. Result is "" while it's still going.
+type GameResult struct {
+	Result string // "1-0", "0-1" or "1/2-1/2"
+	Reason string
+}
+
+func (r GameResult) String() string {
+	if r.Result == "" {
+		return "*"
+	}
+	return fmt.Sprintf("%s (%s)", r.Result, r.Reason)
+}
+
+// Result returns how the game ended, if it has: checkmate, stalemate, the
+// fifty-move rule, threefold repetition, insufficient material or a variant's
+// own rule. Draws that need a claim in over-the-board chess are reported as
+// soon as they can be claimed.
+func (h *History) Result() GameResult {
+	b := h.Board()
+
+	if result := b.VariantResult(); result != "" {
+		return GameResult{Result: result, Reason: "variant win"}
+	}
+
+	// the move generator doesn't know every variant's legality (drops,
+	// explosions), so mates and dead positions are only judged in chess
+	if b.Variant.isChess() {
+		if len(b.LegalMoves()) == 0 {
+			if !b.InCheck() {
+				return GameResult{Result: "1/2-1/2", Reason: "stalemate"}
+			}
+			if b.ActiveColor == "w" {
+				return GameResult{Result: "0-1", Reason: "checkmate"}
+			}
+			return GameResult{Result: "1-0", Reason: "checkmate"}
+		}
+		if b.insufficientMaterial() {
+			return GameResult{Result: "1/2-1/2", Reason: "insufficient material"}
+		}
+	}
+
+	if h.Repetitions() >= 3 {
+		return GameResult{Result: "1/2-1/2", Reason: "threefold repetition"}
+	}
+	if atoi(b.HalfmoveClock) >= 100 {
+		return GameResult{Result: "1/2-1/2", Reason: "fifty-move rule"}
+	}
+
+	return GameResult{}
+}
+
+// repetitionKey identifies a position for repetition: placement, side to
+// move, castling rights and the en passant square.
+func repetitionKey(b *Board) string {
+	fields := strings.Fields(b.FEN())
+	if len(fields) < 4 {
+		return b.FEN()
+	}
+	return strings.Join(fields[:4], " ")
+}
+
+// insufficientMaterial is true when neither side can mate: bare kings, a
+// single minor piece, or only bishops all on the same color squares.
+func (b *Board) insufficientMaterial() bool {
+	var minors, knights int
+	bishopColors := make(map[int]bool)
+	for sq, c := range b.Pos {
+		switch unicode.ToLower(c) {
+		case 'p', 'r', 'q':
+			return false
+		case 'n':
+			minors++
+			knights++
+		case 'b':
+			minors++
+			bishopColors[(sq/8+sq%8)%2] = true
+		}
+	}
+
+	if minors <= 1 {
+		return true
+	}
+	return knights == 0 && len(bishopColors) == 1
+}
+
+// terminalScore returns the UCI score of the current position if the side to
+// move has no legal moves: "mate 0" when it's mated, "cp 0" when it's
+// stalemated. ok is false when there are moves, or when the move generator
+// doesn't know the variant's rules.
+func (u *UCI) terminalScore() (score string, ok bool) {
+	u.moveListMtx.Lock()
+	h := u.history
+	u.moveListMtx.Unlock()
+
+	if h == nil {
+		// no position yet
+		return "", false
+	}
+	b := h.Board()
+	if !b.Variant.isChess() || len(b.LegalMoves()) > 0 {
+		return "", false
+	}
+	if b.InCheck() {
+		return "mate 0", true
+	}
+	return "cp 0", true
+}
+
+// checkGameOver records the result once the current position ends the game,
+// and tells the GUI.
+func (u *UCI) checkGameOver() {
+	u.moveListMtx.Lock()
+	h := u.history
+	u.moveListMtx.Unlock()
+
+	result := h.Result()
+	if result.Result == "" {
+		return
+	}
+
+	u.moveListMtx.Lock()
+	if u.gameResult.Result != "" {
+		u.moveListMtx.Unlock()
+		return
+	}
+	u.gameResult = result
+	u.session.addResult(result.Result)
+	u.moveListMtx.Unlock()
+
+	u.WriteLine(fmt.Sprintf("info string game over: %s", result))
+	u.clearSavedGame()
+}
@@ -0,0 +1,39 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectResult(t *testing.T) {
+	cases := []struct {
+		name    string
+		variant Variant
+		fen     string
+		moves   string
+		want    GameResult
+	}{
+		{name: "in progress", fen: startPosFEN, moves: "e2e4 e7e5", want: GameResult{}},
+		{name: "fool's mate", fen: startPosFEN, moves: "f2f3 e7e5 g2g4 d8h4", want: GameResult{Result: "0-1", Reason: "checkmate"}},
+		{name: "stalemate", fen: "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", want: GameResult{Result: "1/2-1/2", Reason: "stalemate"}},
+		{name: "queen left", fen: "7k/8/8/8/8/8/8/q5K1 w - - 0 1", moves: "g1h2 a1b2 h2h3 b2c3", want: GameResult{}},
+		{name: "king takes the last piece", fen: "7k/8/8/8/8/8/6q1/6K1 w - - 0 1", moves: "g1g2", want: GameResult{Result: "1/2-1/2", Reason: "insufficient material"}},
+		{name: "same color bishops", fen: "7k/8/8/8/3b4/8/8/2B3K1 w - - 0 1", want: GameResult{Result: "1/2-1/2", Reason: "insufficient material"}},
+		{name: "opposite color bishops", fen: "7k/8/8/8/4b3/8/8/2B3K1 w - - 0 1", want: GameResult{}},
+		{name: "threefold", fen: startPosFEN, moves: "g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8", want: GameResult{Result: "1/2-1/2", Reason: "threefold repetition"}},
+		{name: "fifty moves", fen: "7k/8/8/8/8/8/R7/6K1 w - - 99 80", moves: "a2a3", want: GameResult{Result: "1/2-1/2", Reason: "fifty-move rule"}},
+		{name: "variant win", variant: VariantKingOfTheHill, fen: "7k/8/8/8/8/4K3/8/8 w - - 0 1", moves: "e3e4", want: GameResult{Result: "1-0", Reason: "variant win"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+			b.Variant = c.variant
+
+			h, _ := (*History)(nil).sync(b, strings.Fields(c.moves))
+			if got := h.Result(); got != c.want {
+				t.Errorf("want %v got %v", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package uci
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	gameStateFile = "trollfish-game.json"
+
+	// gameStateMaxAge is how old a saved game can be and still be resumed;
+	// anything older was abandoned, not interrupted.
+	gameStateMaxAge = time.Hour
+)
+
+// savedGame is the state of the game in progress, written after every
+// position and move so a restarted process can pick the game back up.
+type savedGame struct {
+	Saved       time.Time `json:"saved"`
+	Variant     Variant   `json:"variant"`
+	Start       string    `json:"start"`
+	Moves       []string  `json:"moves"`
+	ActiveColor string    `json:"active_color"`
+	OurStart    int       `json:"our_start_time"`
+	OppStart    int       `json:"opp_start_time"`
+	MultiPV     int       `json:"multipv"`
+	MateIn      int       `json:"mate_in"`
+	Eval        int       `json:"eval"`
+	Agro        bool      `json:"agro"`
+}
+
+func (u *UCI) gameStatePath() string {
+	return filepath.Join(u.crashDir, gameStateFile)
+}
+
+// saveGame writes the game in progress. It's written to a temp file and
+// renamed so a crash mid-write can't leave a torn file behind.
+func (u *UCI) saveGame() {
+	u.moveListMtx.Lock()
+	if u.history == nil || u.gameResult.Result != "" {
+		u.moveListMtx.Unlock()
+		return
+	}
+	start := u.history.Start()
+	g := savedGame{
+		Saved:       time.Now(),
+		Variant:     u.variant,
+		Start:       start.FEN(),
+		Moves:       u.history.Moves(),
+		ActiveColor: u.gameActiveColor,
+		OurStart:    u.gameClock.ourStart,
+		OppStart:    u.gameClock.oppStart,
+		MultiPV:     u.gameMultiPV,
+		MateIn:      u.gameScore.Mate,
+		Eval:        u.gameScore.CP,
+		Agro:        u.gameAgro,
+	}
+	u.moveListMtx.Unlock()
+
+	b, err := json.Marshal(g)
+	if err != nil {
+		u.logInfo(fmt.Sprintf("ERR: save game: %v", err))
+		return
+	}
+
+	path := u.gameStatePath()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		u.logInfo(fmt.Sprintf("ERR: save game: %v", err))
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		u.logInfo(fmt.Sprintf("ERR: save game: %v", err))
+	}
+}
+
+// clearSavedGame removes the saved game once it's over or a new one starts.
+func (u *UCI) clearSavedGame() {
+	if err := os.Remove(u.gameStatePath()); err != nil && !os.IsNotExist(err) {
+		u.logInfo(fmt.Sprintf("ERR: remove saved game: %v", err))
+	}
+}
+
+func loadSavedGame(path string, now time.Time) (savedGame, bool, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return savedGame{}, false, nil
+	}
+	if err != nil {
+		return savedGame{}, false, err
+	}
+
+	var g savedGame
+	if err := json.Unmarshal(b, &g); err != nil {
+		return savedGame{}, false, err
+	}
+	if now.Sub(g.Saved) > gameStateMaxAge {
+		return savedGame{}, false, nil
+	}
+	return g, true, nil
+}
+
+// resumeGame restores a game interrupted by a restart and sends SF its
+// position, so the GUI's next "position" continues the game as if nothing
+// happened. The variant itself comes back when the GUI sets UCI_Variant again.
+func (u *UCI) resumeGame() {
+	g, ok, err := loadSavedGame(u.gameStatePath(), time.Now())
+	if err != nil {
+		u.logInfo(fmt.Sprintf("ERR: load saved game: %v", err))
+		return
+	}
+	if !ok {
+		u.clearSavedGame()
+		return
+	}
+
+	start := FENtoBoard(g.Start)
+	start.Variant = g.Variant
+	h, _ := (*History)(nil).sync(start, g.Moves)
+	b := h.Board()
+
+	u.moveListMtx.Lock()
+	u.history = h
+	u.fen = b.FEN()
+	u.gameMoveCount = atoi(b.FullMove)
+	u.gameActiveColor = g.ActiveColor
+	u.gameClock = gameClock{ourStart: g.OurStart, oppStart: g.OppStart}
+	u.gameScore = Score{CP: g.Eval, Mate: g.MateIn}
+	u.gameAgro = g.Agro
+	u.applyMultiPV()
+	u.moveListMtx.Unlock()
+
+	position := "position fen " + g.Start
+	if len(g.Moves) > 0 {
+		position += " moves " + strings.Join(g.Moves, " ")
+	}
+	u.engine().Write(position)
+
+	u.logInfo(fmt.Sprintf("resumed game saved %s: %d moves, fen '%s'", g.Saved.Format(time.RFC3339), len(g.Moves), u.fen))
+}
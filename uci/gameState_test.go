@@ -0,0 +1,57 @@
+package uci
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveAndResumeGame(t *testing.T) {
+	dir := t.TempDir()
+
+	u := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}, out: io.Discard, crashDir: dir, variant: VariantChess}
+	u.setHistory(FENtoBoard(startPosFEN), []string{"e2e4", "e7e5"})
+	u.gameClock = gameClock{ourStart: 60_000, oppStart: 180_000}
+	u.gameMultiPV = agroMultiPV
+	u.gameScore = Score{CP: 120}
+	u.gameAgro = true
+	u.saveGame()
+
+	// a new process
+	r := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}, out: io.Discard, crashDir: dir}
+	r.resumeGame()
+
+	if r.fen != u.fen {
+		t.Errorf("fen\nwant: %s\ngot:  %s", u.fen, r.fen)
+	}
+	if got, want := r.history.Moves(), []string{"e2e4", "e7e5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("moves: want %v got %v", want, got)
+	}
+	if r.gameClock != u.gameClock || r.gameMultiPV != agroMultiPV || r.gameScore != u.gameScore || !r.gameAgro {
+		t.Errorf("policy state not restored: clock %v multipv %d eval %v agro %v", r.gameClock, r.gameMultiPV, r.gameScore, r.gameAgro)
+	}
+
+	// the game continues from the GUI's next position without starting over
+	r.setHistory(FENtoBoard(startPosFEN), []string{"e2e4", "e7e5", "g1f3"})
+	if r.history.Len() != 3 {
+		t.Errorf("want 3 moves got %d", r.history.Len())
+	}
+
+	// game over clears it
+	r.setHistory(FENtoBoard(startPosFEN), []string{"f2f3", "e7e5", "g2g4", "d8h4"})
+	if _, ok, err := loadSavedGame(r.gameStatePath(), time.Now()); ok || err != nil {
+		t.Errorf("saved game after mate: ok %v err %v", ok, err)
+	}
+}
+
+func TestLoadSavedGameTooOld(t *testing.T) {
+	dir := t.TempDir()
+
+	u := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}, out: io.Discard, crashDir: dir}
+	u.setHistory(FENtoBoard(startPosFEN), []string{"e2e4"})
+
+	if _, ok, _ := loadSavedGame(u.gameStatePath(), time.Now().Add(2*gameStateMaxAge)); ok {
+		t.Error("an abandoned game shouldn't be resumed")
+	}
+}
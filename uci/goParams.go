@@ -0,0 +1,183 @@
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GoParams are the arguments of a UCI "go" command. Times are in milliseconds.
+type GoParams struct {
+	SearchMoves []string
+	Ponder      bool
+	WTime       int
+	BTime       int
+	WInc        int
+	BInc        int
+	MovesToGo   int
+	Depth       int
+	Nodes       int
+	Mate        int
+	MoveTime    int
+	Infinite    bool
+
+	// sent has a bit per goNumbers key the GUI sent, so one sent as 0 is kept
+	sent uint16
+}
+
+// goNumbers are the go parameters that take a number, in String's order.
+var goNumbers = []string{"wtime", "btime", "winc", "binc", "movestogo", "depth", "nodes", "mate", "movetime"}
+
+// ParseGoParams parses the tokens following "go". Unknown tokens and missing
+// or invalid values are errors.
+func ParseGoParams(v []string) (GoParams, error) {
+	var p GoParams
+
+	for i := 0; i < len(v); i++ {
+		key := v[i]
+
+		switch key {
+		case "ponder":
+			p.Ponder = true
+			continue
+		case "infinite":
+			p.Infinite = true
+			continue
+		case "searchmoves":
+			for i+1 < len(v) && !isGoKeyword(v[i+1]) {
+				i++
+				p.SearchMoves = append(p.SearchMoves, v[i])
+			}
+			if len(p.SearchMoves) == 0 {
+				return p, fmt.Errorf("go: searchmoves without moves")
+			}
+			continue
+		}
+
+		if !isGoKeyword(key) {
+			return p, fmt.Errorf("go: unknown parameter '%s'", key)
+		}
+
+		if i+1 >= len(v) {
+			return p, fmt.Errorf("go: %s without a value", key)
+		}
+		i++
+		n, err := strconv.Atoi(v[i])
+		if err != nil {
+			return p, fmt.Errorf("go: %s value '%s' is not a number", key, v[i])
+		}
+
+		for bit, name := range goNumbers {
+			if name == key {
+				p.sent |= 1 << bit
+			}
+		}
+		switch key {
+		case "wtime":
+			p.WTime = n
+		case "btime":
+			p.BTime = n
+		case "winc":
+			p.WInc = n
+		case "binc":
+			p.BInc = n
+		case "movestogo":
+			p.MovesToGo = n
+		case "depth":
+			p.Depth = n
+		case "nodes":
+			p.Nodes = n
+		case "mate":
+			p.Mate = n
+		case "movetime":
+			p.MoveTime = n
+		}
+	}
+
+	return p, nil
+}
+
+func isGoKeyword(s string) bool {
+	switch s {
+	case "searchmoves", "ponder", "wtime", "btime", "winc", "binc", "movestogo",
+		"depth", "nodes", "mate", "movetime", "infinite":
+		return true
+	}
+	return false
+}
+
+// HasClock reports whether the GUI sent either side's remaining time.
+func (p GoParams) HasClock() bool {
+	return p.WTime > 0 || p.BTime > 0
+}
+
+// Limited reports whether the GUI constrained the search itself, with a
+// depth, node count, mate or fixed move time.
+func (p GoParams) Limited() bool {
+	return p.Depth > 0 || p.Nodes > 0 || p.Mate > 0 || p.MoveTime > 0
+}
+
+// isAnalysis reports whether the search is for an analysis GUI rather than a
+// game: infinite, or limited by depth or nodes with no clock to play on.
+func (p GoParams) isAnalysis() bool {
+	return p.Infinite || (!p.HasClock() && (p.Depth > 0 || p.Nodes > 0))
+}
+
+// allows reports whether move is one the GUI let us play: any move when it
+// didn't send searchmoves.
+func (p GoParams) allows(move string) bool {
+	return len(p.SearchMoves) == 0 || hasToken(p.SearchMoves, move)
+}
+
+// restrictToSearchMoves drops the lines whose first move isn't in
+// searchMoves. With no searchmoves, or nothing left, moveList is returned as
+// is.
+func restrictToSearchMoves(moveList []Info, searchMoves []string) []Info {
+	if len(searchMoves) == 0 {
+		return moveList
+	}
+
+	var kept []Info
+	for _, info := range moveList {
+		if hasToken(searchMoves, pvMove(info.PV)) {
+			kept = append(kept, info)
+		}
+	}
+	if len(kept) == 0 {
+		return moveList
+	}
+	return kept
+}
+
+// Clock returns our and the opponent's time and increment, where color is
+// the side we're playing ("w" or "b").
+func (p GoParams) Clock(color string) (ourTime, ourInc, oppTime, oppInc int) {
+	if color == "b" {
+		return p.BTime, p.BInc, p.WTime, p.WInc
+	}
+	return p.WTime, p.WInc, p.BTime, p.BInc
+}
+
+// String formats the parameters as they'd follow "go", searchmoves last. A
+// number is left out if it's 0, unless the GUI sent it.
+func (p GoParams) String() string {
+	var parts []string
+	numbers := []int{p.WTime, p.BTime, p.WInc, p.BInc, p.MovesToGo, p.Depth, p.Nodes, p.Mate, p.MoveTime}
+	if p.Ponder {
+		parts = append(parts, "ponder")
+	}
+	for bit, n := range numbers {
+		if n != 0 || p.sent&(1<<bit) != 0 {
+			parts = append(parts, goNumbers[bit], strconv.Itoa(n))
+		}
+	}
+	if p.Infinite {
+		parts = append(parts, "infinite")
+	}
+	if len(p.SearchMoves) > 0 {
+		parts = append(parts, "searchmoves")
+		parts = append(parts, p.SearchMoves...)
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,190 @@
+package uci
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGoParams(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   []string
+		want    GoParams
+		wantErr bool
+	}{
+		{
+			name:  "clock",
+			input: []string{"wtime", "60000", "btime", "55000", "winc", "1000", "binc", "2000"},
+			want:  GoParams{WTime: 60000, BTime: 55000, WInc: 1000, BInc: 2000},
+		},
+		{
+			name:  "btime first with movestogo",
+			input: []string{"btime", "300", "wtime", "400", "movestogo", "10"},
+			want:  GoParams{WTime: 400, BTime: 300, MovesToGo: 10},
+		},
+		{
+			name:  "ponder after clock",
+			input: []string{"wtime", "1000", "btime", "2000", "ponder"},
+			want:  GoParams{WTime: 1000, BTime: 2000, Ponder: true},
+		},
+		{
+			name:  "searchmoves then limit",
+			input: []string{"searchmoves", "e2e4", "d2d4", "depth", "12"},
+			want:  GoParams{SearchMoves: []string{"e2e4", "d2d4"}, Depth: 12},
+		},
+		{
+			name:  "limits",
+			input: []string{"nodes", "1000000", "mate", "3", "movetime", "500", "infinite"},
+			want:  GoParams{Nodes: 1000000, Mate: 3, MoveTime: 500, Infinite: true},
+		},
+		{
+			name:  "empty",
+			input: nil,
+			want:  GoParams{},
+		},
+		{name: "missing value", input: []string{"wtime"}, wantErr: true},
+		{name: "invalid value", input: []string{"depth", "x"}, wantErr: true},
+		{name: "unknown", input: []string{"fast"}, wantErr: true},
+		{name: "empty searchmoves", input: []string{"searchmoves", "infinite"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			got, err := ParseGoParams(c.input)
+
+			// assert
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("want: error got: %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// which numbers were sent is String's, see TestGoParamsStringZero
+			got.sent = 0
+			if !reflect.DeepEqual(c.want, got) {
+				t.Errorf("\nwant: %+v\ngot:  %+v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestGoParamsClock(t *testing.T) {
+	p := GoParams{WTime: 1, WInc: 2, BTime: 3, BInc: 4}
+
+	if ourTime, ourInc, oppTime, oppInc := p.Clock("w"); ourTime != 1 || ourInc != 2 || oppTime != 3 || oppInc != 4 {
+		t.Errorf("w: got %d %d %d %d", ourTime, ourInc, oppTime, oppInc)
+	}
+	if ourTime, ourInc, oppTime, oppInc := p.Clock("b"); ourTime != 3 || ourInc != 4 || oppTime != 1 || oppInc != 2 {
+		t.Errorf("b: got %d %d %d %d", ourTime, ourInc, oppTime, oppInc)
+	}
+}
+
+func TestGoParamsString(t *testing.T) {
+	input := []string{"ponder", "wtime", "100", "btime", "200", "movestogo", "5", "searchmoves", "e2e4", "g1f3"}
+
+	p, err := ParseGoParams(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "ponder wtime 100 btime 200 movestogo 5 searchmoves e2e4 g1f3"
+	if got := p.String(); got != want {
+		t.Errorf("\nwant: %s\ngot:  %s", want, got)
+	}
+}
+
+func TestGoParamsStringZero(t *testing.T) {
+	cases := map[string]string{
+		"wtime 0 btime 1000":       "wtime 0 btime 1000",
+		"wtime 0 btime 0 winc 0":   "wtime 0 btime 0 winc 0",
+		"depth 0 infinite":         "depth 0 infinite",
+		"":                         "",
+		"searchmoves e2e4 nodes 5": "nodes 5 searchmoves e2e4",
+	}
+	for input, want := range cases {
+		p, err := ParseGoParams(strings.Fields(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.String(); got != want {
+			t.Errorf("'%s', want: '%s' got: '%s'", input, want, got)
+		}
+	}
+
+	// a 0 that wasn't sent stays out
+	if got := (GoParams{MoveTime: 500}).String(); got != "movetime 500" {
+		t.Errorf("want: 'movetime 500' got: '%s'", got)
+	}
+}
+
+func TestGoParamsLimits(t *testing.T) {
+	cases := []struct {
+		input    string
+		limited  bool
+		analysis bool
+	}{
+		{input: "wtime 60000 btime 60000", limited: false, analysis: false},
+		{input: "infinite", limited: false, analysis: true},
+		{input: "depth 20", limited: true, analysis: true},
+		{input: "nodes 100000", limited: true, analysis: true},
+		{input: "movetime 1000", limited: true, analysis: false},
+		{input: "mate 3", limited: true, analysis: false},
+		{input: "wtime 60000 btime 60000 depth 8", limited: true, analysis: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			p, err := ParseGoParams(strings.Fields(c.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Limited(); got != c.limited {
+				t.Errorf("limited want: %v got: %v", c.limited, got)
+			}
+			if got := p.isAnalysis(); got != c.analysis {
+				t.Errorf("analysis want: %v got: %v", c.analysis, got)
+			}
+		})
+	}
+}
+
+func TestRestrictToSearchMoves(t *testing.T) {
+	moveList := []Info{
+		{MultiPV: 1, Score: 50, PV: "e2e4 e7e5"},
+		{MultiPV: 2, Score: 40, PV: "d2d4 d7d5"},
+		{MultiPV: 3, Score: 20, PV: "g1f3 g8f6"},
+	}
+
+	cases := []struct {
+		name        string
+		searchMoves []string
+		want        []string
+		allowsNf3   bool
+	}{
+		{name: "no searchmoves", want: []string{"e2e4 e7e5", "d2d4 d7d5", "g1f3 g8f6"}, allowsNf3: true},
+		{name: "restricted", searchMoves: []string{"g1f3", "d2d4"}, want: []string{"d2d4 d7d5", "g1f3 g8f6"}, allowsNf3: true},
+		{name: "nothing left", searchMoves: []string{"b1c3"}, want: []string{"e2e4 e7e5", "d2d4 d7d5", "g1f3 g8f6"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			for _, info := range restrictToSearchMoves(moveList, c.searchMoves) {
+				got = append(got, info.PV)
+			}
+			if !reflect.DeepEqual(c.want, got) {
+				t.Errorf("\nwant: %q\ngot:  %q", c.want, got)
+			}
+
+			p := GoParams{SearchMoves: c.searchMoves}
+			if got := p.allows("g1f3"); got != c.allowsNf3 {
+				t.Errorf("allows g1f3 want: %v got: %v", c.allowsNf3, got)
+			}
+		})
+	}
+}
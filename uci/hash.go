@@ -0,0 +1,80 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultHashPercent is how much of the available memory SF's hash may
+	// take. The configured Hash is sized for the big box and would swap a
+	// small one.
+	defaultHashPercent = 50
+
+	// minHash is the smallest hash SF gets however little memory is free.
+	minHash = 16 // MB
+)
+
+// availableMemory returns the memory available for new allocations in MB, or
+// false where /proc/meminfo can't be read.
+func availableMemory() (int, bool) {
+	fp, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	defer fp.Close()
+
+	return parseMemAvailable(fp)
+}
+
+// parseMemAvailable reads MemAvailable, in kB, from /proc/meminfo and returns
+// it in MB.
+func parseMemAvailable(r io.Reader) (int, bool) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kb, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, false
+		}
+		return kb / 1024, true
+	}
+	return 0, false
+}
+
+// capHash caps hash at percent of the available memory, but not below minHash.
+func capHash(hash, availableMB, percent int) int {
+	return max(min(hash, availableMB*percent/100), min(hash, minHash))
+}
+
+// engineHash is SF's Hash: the config's, capped at HashPercent of the memory
+// that's available. Callers must hold moveListMtx.
+func (u *UCI) engineHash() int {
+	hash := u.config.Hash
+	available, ok := availableMemory()
+	if !ok {
+		return hash
+	}
+
+	capped := capHash(hash, available, u.options.Int("HashPercent"))
+	if capped != hash {
+		u.logWarn(fmt.Sprintf("hash: %d MB capped to %d MB, %d MB available", hash, capped, available))
+	}
+	return capped
+}
+
+// setHashPercent handles HashPercent, resizing SF's hash.
+func (u *UCI) setHashPercent(string) {
+	u.moveListMtx.Lock()
+	hash := u.engineHash()
+	u.moveListMtx.Unlock()
+
+	u.engine().Write(fmt.Sprintf("setoption name Hash value %d", hash))
+}
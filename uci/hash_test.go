@@ -0,0 +1,41 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMemAvailable(t *testing.T) {
+	const meminfo = `MemTotal:       16318412 kB
+MemFree:          512000 kB
+MemAvailable:    8388608 kB
+Buffers:          123456 kB
+`
+	if got, ok := parseMemAvailable(strings.NewReader(meminfo)); !ok || got != 8192 {
+		t.Errorf("want: 8192 true got: %d %v", got, ok)
+	}
+	if _, ok := parseMemAvailable(strings.NewReader("MemTotal: 16318412 kB\n")); ok {
+		t.Error("no MemAvailable: want false")
+	}
+}
+
+func TestCapHash(t *testing.T) {
+	cases := []struct {
+		name                     string
+		hash, available, percent int
+		want                     int
+	}{
+		{name: "plenty of memory", hash: 7168, available: 64_000, percent: 50, want: 7168},
+		{name: "small box", hash: 7168, available: 4096, percent: 50, want: 2048},
+		{name: "almost nothing free", hash: 7168, available: 20, percent: 50, want: minHash},
+		{name: "small configured hash", hash: 8, available: 10, percent: 50, want: 8},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := capHash(c.hash, c.available, c.percent); got != c.want {
+				t.Errorf("want: %d got: %d", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package uci
+
+// History is a game's moves from its starting position along with the
+// position after each one, so moves can be taken back and earlier positions
+// looked up without replaying the game.
+type History struct {
+	start  Board
+	moves  []string
+	boards []Board // boards[i] is the position after moves[i]
+}
+
+// NewHistory starts a history at the given position.
+func NewHistory(start Board) *History {
+	return &History{start: start.Clone()}
+}
+
+// Push plays a move.
+func (h *History) Push(move string) {
+	b := h.Board()
+	b.Moves(move)
+	h.moves = append(h.moves, move)
+	h.boards = append(h.boards, b)
+}
+
+// Undo takes back the last move and returns it. It returns false if there are
+// no moves to take back.
+func (h *History) Undo() (string, bool) {
+	if len(h.moves) == 0 {
+		return "", false
+	}
+	n := len(h.moves) - 1
+	move := h.moves[n]
+	h.moves = h.moves[:n]
+	h.boards = h.boards[:n]
+	return move, true
+}
+
+// Board returns a copy of the current position.
+func (h *History) Board() Board {
+	if len(h.boards) == 0 {
+		return h.start.Clone()
+	}
+	return h.boards[len(h.boards)-1].Clone()
+}
+
+// Start returns a copy of the starting position.
+func (h *History) Start() Board {
+	return h.start.Clone()
+}
+
+// Moves returns the moves played, in UCI notation.
+func (h *History) Moves() []string {
+	return append([]string(nil), h.moves...)
+}
+
+// Len is the number of moves played.
+func (h *History) Len() int {
+	return len(h.moves)
+}
+
+// Repetitions returns how many times the current position has occurred,
+// counting this one.
+func (h *History) Repetitions() int {
+	current := h.Board()
+	return h.count(repetitionKey(&current))
+}
+
+// count returns how many times a position, by its repetitionKey, has occurred.
+func (h *History) count(key string) int {
+	n := 0
+	if repetitionKey(&h.start) == key {
+		n++
+	}
+	for i := range h.boards {
+		if repetitionKey(&h.boards[i]) == key {
+			n++
+		}
+	}
+	return n
+}
+
+// sync brings the history in line with a position sent as start plus moves,
+// taking back moves the GUI no longer has and playing the new ones. A
+// different starting position starts a new history. It returns the history
+// to use and how many moves were taken back.
+func (h *History) sync(start Board, moves []string) (*History, int) {
+	if h == nil || h.start.FEN() != start.FEN() {
+		h = NewHistory(start)
+	}
+
+	common := 0
+	for common < len(h.moves) && common < len(moves) && h.moves[common] == moves[common] {
+		common++
+	}
+
+	var undone int
+	for h.Len() > common {
+		h.Undo()
+		undone++
+	}
+	for _, move := range moves[common:] {
+		h.Push(move)
+	}
+
+	return h, undone
+}
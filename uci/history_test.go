@@ -0,0 +1,74 @@
+package uci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func historyFEN(h *History) string {
+	b := h.Board()
+	return b.FEN()
+}
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(FENtoBoard(startPosFEN))
+
+	for _, move := range []string{"e2e4", "e7e5", "g1f3"} {
+		h.Push(move)
+	}
+	if got, want := historyFEN(h), "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"; got != want {
+		t.Errorf("\nwant: %s\ngot:  %s", want, got)
+	}
+
+	move, ok := h.Undo()
+	if move != "g1f3" || !ok {
+		t.Errorf("undo: want g1f3, true got %s, %v", move, ok)
+	}
+	if got, want := historyFEN(h), "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"; got != want {
+		t.Errorf("\nwant: %s\ngot:  %s", want, got)
+	}
+
+	h.Undo()
+	h.Undo()
+	if _, ok := h.Undo(); ok {
+		t.Error("undo past the start")
+	}
+	if got := historyFEN(h); got != startPosFEN {
+		t.Errorf("\nwant: %s\ngot:  %s", startPosFEN, got)
+	}
+}
+
+func TestHistorySync(t *testing.T) {
+	start := FENtoBoard(startPosFEN)
+
+	h, undone := (*History)(nil).sync(start, []string{"e2e4", "e7e5", "g1f3"})
+	if undone != 0 || h.Len() != 3 {
+		t.Fatalf("new: undone %d len %d", undone, h.Len())
+	}
+
+	// takeback of the last two moves and a different one played
+	h2, undone := h.sync(start, []string{"e2e4", "c7c5"})
+	if h2 != h || undone != 2 {
+		t.Errorf("takeback: same %v undone %d", h2 == h, undone)
+	}
+	if got, want := h.Moves(), []string{"e2e4", "c7c5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+
+	// a new game
+	h3, _ := h.sync(FENtoBoard("7k/8/8/8/8/8/8/K7 w - - 0 1"), nil)
+	if h3 == h || h3.Len() != 0 {
+		t.Error("different start should be a new history")
+	}
+}
+
+func TestHistoryRepetitions(t *testing.T) {
+	h, _ := (*History)(nil).sync(FENtoBoard(startPosFEN), []string{"g1f3", "g8f6", "f3g1", "f6g8"})
+	if got := h.Repetitions(); got != 2 {
+		t.Errorf("want 2 got %d", got)
+	}
+	h.Undo()
+	if got := h.Repetitions(); got != 1 {
+		t.Errorf("after undo: want 1 got %d", got)
+	}
+}
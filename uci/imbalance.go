@@ -0,0 +1,97 @@
+package uci
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// defaultImbalanceMoves is how many moves we play at full strength after
+// the material goes unbalanced.
+const defaultImbalanceMoves = 6
+
+type material struct {
+	queens, rooks, minors, pawns int
+}
+
+func (b *Board) material() (white, black material) {
+	for _, c := range b.Pos {
+		m := &black
+		if isWhitePiece(c) {
+			m = &white
+		}
+		switch unicode.ToLower(c) {
+		case 'q':
+			m.queens++
+		case 'r':
+			m.rooks++
+		case 'n', 'b':
+			m.minors++
+		case 'p':
+			m.pawns++
+		}
+	}
+	return white, black
+}
+
+// materialImbalance names the imbalance on the board, or returns "" if there
+// isn't one. A side simply being a piece up isn't an imbalance; the side
+// short of the heavier piece has to have lighter ones for it: a queen for
+// pieces, the exchange (rook for a minor) or a piece for pawns.
+func (b *Board) materialImbalance() string {
+	w, bl := b.material()
+
+	// compensated is true when the side with fewer of one kind of piece has
+	// at least by more of another
+	compensated := func(short, long func(material) int, by int) bool {
+		if short(w) < short(bl) {
+			return long(w)-long(bl) >= by
+		}
+		if short(bl) < short(w) {
+			return long(bl)-long(w) >= by
+		}
+		return false
+	}
+
+	queens := func(m material) int { return m.queens }
+	rooks := func(m material) int { return m.rooks }
+	minors := func(m material) int { return m.minors }
+	pieces := func(m material) int { return m.rooks + m.minors }
+	pawns := func(m material) int { return m.pawns }
+
+	switch {
+	case compensated(queens, pieces, 2):
+		return "queen vs pieces"
+	case compensated(rooks, minors, 1):
+		return "exchange"
+	case compensated(minors, pawns, 2):
+		return "piece vs pawns"
+	}
+	return ""
+}
+
+// checkImbalance switches the selector to full strength for imbalanceMoves
+// moves once the material goes unbalanced. Staying near 0.00 makes no sense
+// when equality depends on who gets their pieces working first. Callers must
+// hold moveListMtx.
+func (u *UCI) checkImbalance() {
+	if u.history == nil {
+		return
+	}
+
+	b := u.history.Board()
+	if !b.Variant.isChess() {
+		return
+	}
+
+	kind := b.materialImbalance()
+	if kind == u.imbalance {
+		return
+	}
+	u.imbalance = kind
+	if kind == "" {
+		return
+	}
+
+	u.logInfo(fmt.Sprintf("imbalance: %s, full strength for %d moves", kind, u.imbalanceMoves))
+	u.imbalanceLeft = u.imbalanceMoves
+}
@@ -0,0 +1,47 @@
+package uci
+
+import "testing"
+
+func TestMaterialImbalance(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want string
+	}{
+		{startPosFEN, ""},
+		// white is just a knight up
+		{"rnbqkb1r/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ""},
+		{"rnb1kbnr/pppppppp/8/8/8/8/PPPPPPPP/1NBQKBN1 w - - 0 1", "queen vs pieces"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/1NBQKBNR w Kkq - 0 1", ""},
+		{"rnbqkbn1/pppppppp/8/8/8/8/PPPPPPPP/1NBQKBNR w Kq - 0 1", ""},
+		{"rnbqkb1r/pppppppp/8/8/8/8/PPPPPPPP/1NBQKBNR w Kkq - 0 1", "exchange"},
+		{"rnbqkb1r/pppppppp/8/8/8/8/PPPPPPP1/RNBQKBNR w KQkq - 0 1", ""},
+		{"rnbqkb1r/pppp4/8/8/8/8/PPPPPPPP/RNBQKB1R w KQkq - 0 1", ""},
+		{"rnbqkb1r/pppppppp/8/8/8/8/PPPP4/RNBQKBNR w KQkq - 0 1", "piece vs pawns"},
+	}
+
+	for _, tt := range tests {
+		b := FENtoBoard(tt.fen)
+		if got := b.materialImbalance(); got != tt.want {
+			t.Errorf("%s: want: %q got: %q", tt.fen, tt.want, got)
+		}
+	}
+}
+
+func TestSelectMoveImbalance(t *testing.T) {
+	u := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}, imbalanceMoves: 2}
+	u.history = NewHistory(FENtoBoard("rnbqkb1r/pppppppp/8/8/8/8/PPPPPPPP/1NBQKBNR w Kkq - 0 1"))
+
+	moveList := []Info{
+		{MultiPV: 1, Score: 150, PV: "e2e4"},
+		{MultiPV: 2, Score: 10, PV: "a2a3"},
+	}
+
+	// full strength for two moves, then back to trolling while the same
+	// imbalance stays on the board
+	for i, want := range []string{"e2e4", "e2e4", "a2a3"} {
+		got := u.selectMove(moveList, moveList[0])
+		if pvMove(got.PV) != want {
+			t.Errorf("move %d: want: %s got: %s", i, want, got.PV)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package uci
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// jsonInfo is an Info line in the shape emitted by the JSONInfo option, for
+// bridges and overlays that don't want to parse UCI.
+type jsonInfo struct {
+	Depth    int      `json:"depth"`
+	SelDepth int      `json:"seldepth"`
+	MultiPV  int      `json:"multipv"`
+	CP       *int     `json:"cp,omitempty"`
+	Mate     *int     `json:"mate,omitempty"`
+	WDL      []int    `json:"wdl,omitempty"`
+	Nodes    int64    `json:"nodes"`
+	NPS      int64    `json:"nps"`
+	HashFull int      `json:"hashfull"`
+	TBHits   int      `json:"tbhits"`
+	Time     int      `json:"time"`
+	PV       []string `json:"pv"`
+
+	CurrLine   []string `json:"currline,omitempty"`
+	Refutation []string `json:"refutation,omitempty"`
+}
+
+// JSON returns the line as a single-line JSON object. Exactly one of cp and
+// mate is set; wdl, currline and refutation are only there if the engine sent
+// them.
+func (m Info) JSON() string {
+	v := jsonInfo{
+		Depth:    m.Depth,
+		SelDepth: m.SelDepth,
+		MultiPV:  m.MultiPV,
+		Nodes:    m.Nodes,
+		NPS:      m.NPS,
+		HashFull: m.HashFull,
+		TBHits:   m.TBHits,
+		Time:     m.Time,
+		PV:       strings.Fields(m.PV),
+
+		CurrLine:   strings.Fields(m.CurrLine),
+		Refutation: strings.Fields(m.Refutation),
+	}
+	if m.Mate != 0 {
+		v.Mate = &m.Mate
+	} else {
+		v.CP = &m.Score
+	}
+	if m.WDL.valid() {
+		v.WDL = []int{m.WDL.Win, m.WDL.Draw, m.WDL.Loss}
+	}
+	if v.PV == nil {
+		v.PV = []string{}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
@@ -0,0 +1,42 @@
+package uci
+
+import (
+	"testing"
+)
+
+func TestInfoJSON(t *testing.T) {
+	cases := []struct {
+		name string
+		info Info
+		want string
+	}{
+		{
+			name: "cp",
+			info: Info{Depth: 20, SelDepth: 28, MultiPV: 1, Score: -35, Nodes: 1000, NPS: 500, Time: 2, PV: "e7e5 g1f3"},
+			want: `{"depth":20,"seldepth":28,"multipv":1,"cp":-35,"nodes":1000,"nps":500,"hashfull":0,"tbhits":0,"time":2,"pv":["e7e5","g1f3"]}`,
+		},
+		{
+			name: "mate",
+			info: Info{Depth: 5, MultiPV: 2, Mate: 3, PV: "d1h5"},
+			want: `{"depth":5,"seldepth":0,"multipv":2,"mate":3,"nodes":0,"nps":0,"hashfull":0,"tbhits":0,"time":0,"pv":["d1h5"]}`,
+		},
+		{
+			name: "wdl",
+			info: Info{Depth: 8, MultiPV: 1, Score: 21, WDL: WDL{214, 634, 152}, PV: "e2e4"},
+			want: `{"depth":8,"seldepth":0,"multipv":1,"cp":21,"wdl":[214,634,152],"nodes":0,"nps":0,"hashfull":0,"tbhits":0,"time":0,"pv":["e2e4"]}`,
+		},
+		{
+			name: "refutation",
+			info: Info{Depth: 12, Refutation: "d1h5 g7g6 h5f3", CurrLine: "e2e4 e7e5", CurrLineCPU: 2},
+			want: `{"depth":12,"seldepth":0,"multipv":0,"cp":0,"nodes":0,"nps":0,"hashfull":0,"tbhits":0,"time":0,"pv":[],"currline":["e2e4","e7e5"],"refutation":["d1h5","g7g6","h5f3"]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.info.JSON(); got != c.want {
+				t.Errorf("\nwant: %s\ngot:  %s", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package uci
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// logContext is the game state stamped on each structured log line. It's
+// kept by the log sinks, not read from the UCI, since lines are logged with
+// moveListMtx held.
+type logContext struct {
+	fen      string
+	eval     *int
+	moveTime int
+	agro     bool
+}
+
+// logRecord is a line of the "json" log sink, for post-processing games
+// without scraping the text log. Traffic has a direction and a command;
+// anything else is a message.
+type logRecord struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Direction string `json:"direction,omitempty"`
+	Command   string `json:"command,omitempty"`
+	Message   string `json:"message,omitempty"`
+	FEN       string `json:"fen,omitempty"`
+	Eval      *int   `json:"eval,omitempty"`
+	MoveTime  int    `json:"movetime,omitempty"`
+	Agro      bool   `json:"agro"`
+}
+
+// logDirections are the text log's traffic prefixes. The SF ones come first,
+// "SF: -> " isn't a message that starts with "-> ".
+var logDirections = []struct {
+	prefix    string
+	direction string
+}{
+	{"SF: -> ", "to_engine"},
+	{"SF: <- ", "from_engine"},
+	{"-> ", "from_gui"},
+	{"<- ", "to_gui"},
+}
+
+func newLogRecord(level LogLevel, msg string, c logContext) logRecord {
+	r := logRecord{
+		Time:     time.Now().UTC().Format(time.RFC3339Nano),
+		Level:    level.String(),
+		Message:  msg,
+		FEN:      c.fen,
+		Eval:     c.eval,
+		MoveTime: c.moveTime,
+		Agro:     c.agro,
+	}
+	for _, d := range logDirections {
+		if cmd := strings.TrimPrefix(msg, d.prefix); cmd != msg {
+			r.Direction, r.Command, r.Message = d.direction, cmd, ""
+			break
+		}
+	}
+	return r
+}
+
+// jsonWriter writes a JSON object per line, with l's context. It's called
+// with l.mtx held.
+func jsonWriter(w io.Writer, l *logSinks) func(LogLevel, string) error {
+	return func(level LogLevel, line string) error {
+		b, err := json.Marshal(newLogRecord(level, stripTimestamp(line), l.context))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	}
+}
+
+// setContext updates the game state stamped on structured log lines.
+func (l *logSinks) setContext(fn func(c *logContext)) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	fn(&l.context)
+}
+
+// setLogContext updates the structured log's game state, if the log has one,
+// from the next line logged on. fn runs on the log's goroutine, so it mustn't
+// read the UCI.
+func (u *UCI) setLogContext(fn func(c *logContext)) {
+	l, ok := u.logSinks()
+	if !ok {
+		return
+	}
+	if a, ok := u.log.(*asyncLog); ok {
+		a.do(func() { l.setContext(fn) })
+		return
+	}
+	l.setContext(fn)
+}
@@ -0,0 +1,149 @@
+package uci
+
+import (
+	"fmt"
+	"unicode"
+)
+
+const (
+	// kingAttackThreshold is the kingDanger at which an attack on the
+	// opponent's king is concrete enough to stop trolling.
+	kingAttackThreshold = 8
+
+	// kingAttackMinScore keeps speculative attacks in positions SF doesn't
+	// like for us from turning agro on.
+	kingAttackMinScore = 100
+
+	kingAttackerWeight = 2
+)
+
+// kingZone returns the king's square and the squares around it.
+func kingZone(sq int) []int {
+	zone := []int{sq}
+	row, col := sq/8, sq%8
+	for _, o := range kingOffsets {
+		if r, c := row+o[0], col+o[1]; onBoard(r, c) {
+			zone = append(zone, r*8+c)
+		}
+	}
+	return zone
+}
+
+// pieceAttacks reports whether the knight, bishop, rook or queen on from
+// attacks sq.
+func (b *Board) pieceAttacks(from, sq int) bool {
+	dr, dc := sq/8-from/8, sq%8-from%8
+
+	var dirs [][2]int
+	switch unicode.ToLower(b.Pos[from]) {
+	case 'n':
+		for _, o := range knightOffsets {
+			if o[0] == dr && o[1] == dc {
+				return true
+			}
+		}
+		return false
+	case 'b':
+		dirs = bishopDirs
+	case 'r':
+		dirs = rookDirs
+	case 'q':
+		dirs = append(append(dirs, bishopDirs...), rookDirs...)
+	default:
+		return false
+	}
+
+	for _, d := range dirs {
+		for r, c := from/8+d[0], from%8+d[1]; onBoard(r, c); r, c = r+d[0], c+d[1] {
+			if r*8+c == sq {
+				return true
+			}
+			if b.Pos[r*8+c] != ' ' {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// kingDanger scores how exposed a king is: kingAttackerWeight for each enemy
+// knight, bishop, rook or queen hitting the squares around it, plus 1 for
+// each file on or next to the king without its own pawns and 2 more if the
+// file has no pawns at all. An attack without the queen scores 0.
+func (b *Board) kingDanger(white bool) int {
+	king := b.kingSquare(white)
+	if king == -1 {
+		return 0
+	}
+	zone := kingZone(king)
+
+	enemy := isBlackPiece
+	if !white {
+		enemy = isWhitePiece
+	}
+
+	var attackers int
+	var queen bool
+	for from, p := range b.Pos {
+		if !enemy(p) {
+			continue
+		}
+		for _, sq := range zone {
+			if b.pieceAttacks(from, sq) {
+				attackers++
+				if unicode.ToLower(p) == 'q' {
+					queen = true
+				}
+				break
+			}
+		}
+	}
+	if !queen {
+		return 0
+	}
+
+	danger := kingAttackerWeight * attackers
+	for col := king%8 - 1; col <= king%8+1; col++ {
+		if col < 0 || col > 7 {
+			continue
+		}
+		var own, other bool
+		for row := 0; row < 8; row++ {
+			switch p := b.Pos[row*8+col]; {
+			case p == 'P' && white, p == 'p' && !white:
+				own = true
+			case p == 'P', p == 'p':
+				other = true
+			}
+		}
+		if !own {
+			danger++
+			if !other {
+				danger += 2
+			}
+		}
+	}
+	return danger
+}
+
+// kingAttack reports whether we have a concrete attack on the opponent's
+// king, which turns agro on before the eval gets to 2000. Callers must hold
+// moveListMtx.
+func (u *UCI) kingAttack(engineMove Info) bool {
+	if u.history == nil || engineMove.Mate != 0 || engineMove.Score < kingAttackMinScore {
+		return false
+	}
+
+	b := u.history.Board()
+	if !b.Variant.isChess() {
+		return false
+	}
+
+	danger := b.kingDanger(b.ActiveColor != "w")
+	if danger < kingAttackThreshold {
+		return false
+	}
+
+	u.logInfo(fmt.Sprintf("king_attack: danger %d score %d", danger, engineMove.Score))
+	return true
+}
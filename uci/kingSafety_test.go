@@ -0,0 +1,54 @@
+package uci
+
+import "testing"
+
+func TestKingDanger(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		white bool
+		want  int
+	}{
+		{
+			name: "start position",
+			fen:  startPosFEN,
+			want: 0,
+		},
+		{
+			// queen, rook and bishop on the king, g and h files open
+			name: "attack",
+			fen:  "r4rk1/pp3p2/2p5/8/3B4/8/PP3PQR/4R1K1 w - - 0 1",
+			want: 3*kingAttackerWeight + 3 + 3 + 0,
+		},
+		{
+			name: "no queen",
+			fen:  "r4rk1/pp3p2/2p5/8/3B4/8/PP3P1R/4R1K1 w - - 0 1",
+			want: 0,
+		},
+		{
+			name:  "white king is fine",
+			fen:   "r4rk1/pp3p2/2p5/8/3B4/8/PP3PQR/4R1K1 w - - 0 1",
+			white: true,
+			want:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := FENtoBoard(tt.fen)
+			if got := b.kingDanger(tt.white); got != tt.want {
+				t.Errorf("want: %d got: %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestKingAttackAgro(t *testing.T) {
+	u := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}}
+	u.history = NewHistory(FENtoBoard("r4rk1/pp3p2/2p5/8/3B4/8/PP3PQR/4R1K1 w - - 0 1"))
+
+	u.selectMove(nil, Info{Score: 300, PV: "h2h8"})
+	if !u.gameAgro {
+		t.Error("want agro")
+	}
+}
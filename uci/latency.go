@@ -0,0 +1,130 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram. Anything
+// slower lands in the overflow bucket.
+var latencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+type latencyHistogram struct {
+	counts []int // len(latencyBuckets)+1, the last is overflow
+	n      int
+	sum    time.Duration
+	max    time.Duration
+}
+
+func (h *latencyHistogram) add(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]int, len(latencyBuckets)+1)
+	}
+
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+
+	h.n++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+func (h latencyHistogram) avg() time.Duration {
+	if h.n == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.n)
+}
+
+// String prints the non-empty buckets, e.g. "avg 12ms max 40ms <=10ms:3 <=25ms:5 <=50ms:1".
+func (h latencyHistogram) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("avg %dms max %dms", h.avg().Milliseconds(), h.max.Milliseconds()))
+	for i, count := range h.counts {
+		if count == 0 {
+			continue
+		}
+		if i < len(latencyBuckets) {
+			sb.WriteString(fmt.Sprintf(" <=%dms:%d", latencyBuckets[i].Milliseconds(), count))
+		} else {
+			sb.WriteString(fmt.Sprintf(" >%dms:%d", latencyBuckets[len(latencyBuckets)-1].Milliseconds(), count))
+		}
+	}
+	return sb.String()
+}
+
+// moveLatency splits the time from receiving "go" to sending "bestmove" into
+// the time SF spent searching and everything else (book lookups, endgame
+// probes, move selection, IO).
+type moveLatency struct {
+	total    latencyHistogram
+	engine   latencyHistogram
+	overhead latencyHistogram
+}
+
+// goTiming tracks the search in flight. A zero start means the search isn't
+// timed (infinite and ponder searches wait on the GUI, not on us).
+type goTiming struct {
+	start       time.Time
+	engineStart time.Time
+	engineDone  time.Time
+}
+
+func (l *moveLatency) add(t goTiming, done time.Time) {
+	if t.start.IsZero() {
+		return
+	}
+
+	total := done.Sub(t.start)
+	var engine time.Duration
+	if !t.engineStart.IsZero() && !t.engineDone.IsZero() {
+		engine = t.engineDone.Sub(t.engineStart)
+	}
+
+	l.total.add(total)
+	l.engine.add(engine)
+	l.overhead.add(total - engine)
+}
+
+func (l moveLatency) lines() []string {
+	if l.total.n == 0 {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("info string session latency: moves %d total %s", l.total.n, l.total),
+		fmt.Sprintf("info string session latency: engine %s", l.engine),
+		fmt.Sprintf("info string session latency: overhead %s", l.overhead),
+	}
+}
+
+// startEngineTiming notes when SF's part of a timed search started. Callers
+// must hold moveListMtx.
+func (u *UCI) startEngineTiming() {
+	if !u.goTiming.start.IsZero() {
+		u.goTiming.engineStart = time.Now()
+	}
+}
+
+// recordLatency closes out the timed search after bestmove has been sent.
+// Callers must hold moveListMtx.
+func (u *UCI) recordLatency() {
+	u.latency.add(u.goTiming, time.Now())
+	u.goTiming = goTiming{}
+}
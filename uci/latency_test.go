@@ -0,0 +1,44 @@
+package uci
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMoveLatency(t *testing.T) {
+	// arrange
+	var l moveLatency
+	start := time.Now()
+
+	searched := goTiming{
+		start:       start,
+		engineStart: start.Add(2 * time.Millisecond),
+		engineDone:  start.Add(1002 * time.Millisecond),
+	}
+	book := goTiming{start: start}
+
+	// act
+	l.add(searched, start.Add(1005*time.Millisecond))
+	l.add(book, start.Add(3*time.Millisecond))
+	l.add(goTiming{}, start.Add(time.Hour)) // untimed
+
+	// assert
+	if l.total.n != 2 {
+		t.Fatalf("moves, want: 2 got: %d", l.total.n)
+	}
+
+	wantTotal := "avg 504ms max 1005ms <=5ms:1 <=2500ms:1"
+	if got := l.total.String(); got != wantTotal {
+		t.Errorf("total\nwant: %s\ngot:  %s", wantTotal, got)
+	}
+
+	wantEngine := "avg 500ms max 1000ms <=5ms:1 <=1000ms:1"
+	if got := l.engine.String(); got != wantEngine {
+		t.Errorf("engine\nwant: %s\ngot:  %s", wantEngine, got)
+	}
+
+	wantOverhead := "avg 4ms max 5ms <=5ms:2"
+	if got := l.overhead.String(); got != wantOverhead {
+		t.Errorf("overhead\nwant: %s\ngot:  %s", wantOverhead, got)
+	}
+}
@@ -0,0 +1,48 @@
+package uci
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// lc0 speaks UCI but names a few options differently and has no hash table;
+// lc0Options translates SF's names. An empty name drops the option.
+// UCI_ShowWDL stays on whatever the GUI wants, the selector uses it.
+var lc0Options = map[string]string{
+	"Hash":          "",
+	"Move Overhead": "MoveOverheadMs",
+	"UCI_ShowWDL":   "",
+}
+
+// StartLc0 is the EngineStarter for Leela Chess Zero. lc0 is asked for its
+// win/draw/loss estimates, which the selector prefers to centipawns.
+func StartLc0(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+	sf, err := StartStockfish(ctx, path, logInfo)
+	if err != nil {
+		return nil, err
+	}
+	return lc0Backend{sf}, nil
+}
+
+type lc0Backend struct {
+	EngineBackend
+}
+
+func (b lc0Backend) Write(s string) {
+	if rest := strings.TrimPrefix(s, "setoption name "); rest != s {
+		name, value, _ := strings.Cut(rest, " value ")
+		if lc0Name, ok := lc0Options[name]; ok {
+			if lc0Name == "" {
+				return
+			}
+			s = fmt.Sprintf("setoption name %s value %s", lc0Name, value)
+		}
+	}
+
+	b.EngineBackend.Write(s)
+
+	if s == "uci" {
+		b.EngineBackend.Write("setoption name UCI_ShowWDL value true")
+	}
+}
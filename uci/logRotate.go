@@ -0,0 +1,159 @@
+package uci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLogMaxSize = 100 // MB
+	defaultLogMaxAge  = 30  // days
+	defaultLogKeep    = 5
+
+	// rotatedLogLayout is the suffix of a rotated log, the time it was
+	// rotated: trollfish.log.2026-10-17T10-00-00.
+	rotatedLogLayout = "2006-01-02T15-04-05"
+)
+
+// logRotation is when a file log sink is rotated and how many of the old ones
+// are kept. Zero values turn that limit off.
+type logRotation struct {
+	maxSize int64         // bytes
+	maxAge  time.Duration // since this session opened the file; older rotated logs are removed
+	keep    int
+}
+
+// logRotation is the config file's rotation policy for file log sinks.
+func (c Config) logRotation() logRotation {
+	return logRotation{
+		maxSize: int64(c.LogMaxSize) << 20,
+		maxAge:  time.Duration(c.LogMaxAge) * 24 * time.Hour,
+		keep:    c.LogKeep,
+	}
+}
+
+// rotatingFile is a log file that's renamed aside once it's too big or too
+// old, with a fresh one started in its place.
+type rotatingFile struct {
+	path   string
+	policy logRotation
+	now    func() time.Time
+
+	fp      *os.File
+	size    int64
+	started time.Time
+}
+
+func openRotatingFile(path string, policy logRotation) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, policy: policy, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	// a log that outgrew the limit last session is rotated before it's
+	// added to
+	if f.size > 0 && f.due() {
+		if err := f.rotate(); err != nil {
+			_ = f.fp.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	fp, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := fp.Stat()
+	if err != nil {
+		_ = fp.Close()
+		return err
+	}
+	f.fp, f.size, f.started = fp, fi.Size(), f.now()
+	return nil
+}
+
+// due reports whether the file has passed its size or age limit.
+func (f *rotatingFile) due() bool {
+	if f.policy.maxSize > 0 && f.size >= f.policy.maxSize {
+		return true
+	}
+	return f.policy.maxAge > 0 && f.now().Sub(f.started) >= f.policy.maxAge
+}
+
+// Write appends p, rotating first if the file is due. A failed rotation
+// keeps writing to the file it has.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if f.size > 0 && f.due() {
+		_ = f.rotate()
+	}
+	n, err := f.fp.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate renames the file aside, starts a new one and removes the rotated
+// logs past the retention limits.
+func (f *rotatingFile) rotate() error {
+	rotated := f.path + "." + f.now().Format(rotatedLogLayout)
+	if err := os.Rename(f.path, rotated); err != nil {
+		return err
+	}
+	old := f.fp
+	if err := f.open(); err != nil {
+		f.fp = old
+		return err
+	}
+	_ = old.Close()
+
+	return f.prune()
+}
+
+// prune removes rotated logs beyond the newest keep, and those older than
+// maxAge.
+func (f *rotatingFile) prune() error {
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return err
+	}
+
+	type rotatedLog struct {
+		path string
+		at   time.Time
+	}
+	var logs []rotatedLog
+	for _, m := range matches {
+		at, err := time.ParseInLocation(rotatedLogLayout, strings.TrimPrefix(m, f.path+"."), time.Local)
+		if err != nil {
+			continue // not one of ours
+		}
+		logs = append(logs, rotatedLog{path: m, at: at})
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].at.After(logs[j].at) })
+
+	var firstErr error
+	for i, l := range logs {
+		tooMany := f.policy.keep > 0 && i >= f.policy.keep
+		tooOld := f.policy.maxAge > 0 && f.now().Sub(l.at) > f.policy.maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(l.path); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("remove rotated log: %w", err)
+		}
+	}
+	return firstErr
+}
+
+func (f *rotatingFile) Sync() error {
+	return f.fp.Sync()
+}
+
+func (f *rotatingFile) Close() error {
+	return f.fp.Close()
+}
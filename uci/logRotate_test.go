@@ -0,0 +1,71 @@
+package uci
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "trollfish.log")
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+
+	// rotated logs from earlier sessions, one past the age limit
+	for _, at := range []time.Time{now.Add(-time.Hour), now.Add(-2 * time.Hour), now.Add(-40 * 24 * time.Hour)} {
+		if err := os.WriteFile(path+"."+at.Format(rotatedLogLayout), []byte("old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// not a rotated log
+	if err := os.WriteFile(path+".bak", []byte("keep\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := openRotatingFile(path, logRotation{maxSize: 10, maxAge: 30 * 24 * time.Hour, keep: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.now = func() time.Time { return now }
+
+	for _, line := range []string{"0123456789\n", "next\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "next\n" {
+		t.Errorf("log want: %q got: %q", "next\n", b)
+	}
+
+	matches, _ := filepath.Glob(path + ".*")
+	sort.Strings(matches)
+	want := []string{
+		path + "." + now.Add(-time.Hour).Format(rotatedLogLayout),
+		path + "." + now.Format(rotatedLogLayout),
+		path + ".bak",
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("want: %q\ngot:  %q", want, matches)
+	}
+	for i := range want {
+		if matches[i] != want[i] {
+			t.Errorf("want: %q\ngot:  %q", want, matches)
+			break
+		}
+	}
+
+	// the age limit rotates too
+	f.policy.maxSize = 0
+	f.now = func() time.Time { return now.Add(31 * 24 * time.Hour) }
+	if !f.due() {
+		t.Error("31 days old: want due")
+	}
+}
@@ -0,0 +1,289 @@
+package uci
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// defaultLogPath is the log file when neither the LogSinks option nor the
+// config file say otherwise.
+const defaultLogPath = "trollfish.log"
+
+// defaultLogSpec is the log configuration when none is given: everything to
+// path.
+func defaultLogSpec(path string) string {
+	return "file=" + path + ":debug"
+}
+
+// LogLevel filters what a log sink receives.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota // protocol traffic in both directions
+	LogInfo                  // decisions, reports, lifecycle
+	LogWarn                  // something's off but play goes on
+	LogError                 // errors
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+func parseLogLevel(s string) (LogLevel, error) {
+	for l := LogDebug; l <= LogError; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level '%s'", s)
+}
+
+// logLineLevel classifies a log line by the conventions the rest of the code
+// already writes them in: "ERR"/"SF ERR" for errors, "->"/"<-" for traffic.
+// SF's stderr is a warning, it's where a bad net or a crash shows up. It's the
+// level of lines logged without one, by logInfo and by SF.
+func logLineLevel(line string) LogLevel {
+	switch {
+	case strings.HasPrefix(line, "ERR"), strings.HasPrefix(line, "SF ERR"):
+		return LogError
+	case strings.HasPrefix(line, "SF STDERR"), strings.Contains(line, "WARNING"):
+		return LogWarn
+	case strings.HasPrefix(line, "-> "), strings.HasPrefix(line, "<- "), strings.HasPrefix(line, "SF: ->"), strings.HasPrefix(line, "SF: <-"):
+		return LogDebug
+	}
+	return LogInfo
+}
+
+// logSinkConfig is one sink of a log configuration: "file=<path>",
+// "json=<path>" (JSON lines), "syslog" (which is journald on systemd hosts) or
+// "stderr", with an optional ":<level>".
+type logSinkConfig struct {
+	kind     string
+	target   string
+	level    LogLevel
+	rotation logRotation // file and json sinks
+}
+
+// parseLogSinks parses a comma separated log configuration, for example
+// "file=trollfish.log:debug,syslog:error".
+func parseLogSinks(spec string) ([]logSinkConfig, error) {
+	var configs []logSinkConfig
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		c := logSinkConfig{level: LogInfo}
+		if idx := strings.LastIndex(item, ":"); idx != -1 {
+			level, err := parseLogLevel(item[idx+1:])
+			if err != nil {
+				return nil, err
+			}
+			c.level = level
+			item = item[:idx]
+		}
+		c.kind, c.target, _ = strings.Cut(item, "=")
+
+		switch c.kind {
+		case "file", "json":
+			if c.target == "" {
+				return nil, fmt.Errorf("%[1]s log sink needs a path: %[1]s=<path>", c.kind)
+			}
+		case "syslog", "stderr":
+		default:
+			return nil, fmt.Errorf("unknown log sink '%s'", c.kind)
+		}
+
+		configs = append(configs, c)
+	}
+	if len(configs) == 0 {
+		return nil, errors.New("no log sinks")
+	}
+	return configs, nil
+}
+
+type logSink struct {
+	level LogLevel
+	write func(level LogLevel, line string) error
+	close func() error
+}
+
+// logSinks writes each line to every sink whose level lets it through. It's
+// the UCI's log writer.
+type logSinks struct {
+	mtx      sync.Mutex
+	sinks    []logSink
+	files    []*rotatingFile
+	verbose  bool     // every line to every sink, for "debug on"
+	level    LogLevel // every sink's level while override is set, by the LogLevel option
+	override bool
+	context  logContext // for the json sinks
+}
+
+// openLogSinks opens the configured sinks. A stderr sink isn't allowed in UCI
+// mode, where stderr is redirected to a file for crashes.
+func openLogSinks(configs []logSinkConfig, stdio bool) (*logSinks, error) {
+	var l logSinks
+	for _, c := range configs {
+		switch c.kind {
+		case "file", "json":
+			fp, err := openRotatingFile(c.target, c.rotation)
+			if err != nil {
+				_ = l.Close()
+				return nil, fmt.Errorf("open log: %w", err)
+			}
+			write := lineWriter(fp)
+			if c.kind == "json" {
+				write = jsonWriter(fp, &l)
+			}
+			l.files = append(l.files, fp)
+			l.sinks = append(l.sinks, logSink{level: c.level, write: write, close: fp.Close})
+		case "syslog":
+			write, closeFn, err := openSyslog()
+			if err != nil {
+				_ = l.Close()
+				return nil, fmt.Errorf("open syslog: %w", err)
+			}
+			l.sinks = append(l.sinks, logSink{level: c.level, write: write, close: closeFn})
+		case "stderr":
+			if stdio {
+				_ = l.Close()
+				return nil, errors.New("the stderr log sink is only available outside UCI mode")
+			}
+			l.sinks = append(l.sinks, logSink{level: c.level, write: lineWriter(os.Stderr)})
+		}
+	}
+	return &l, nil
+}
+
+func lineWriter(w io.Writer) func(LogLevel, string) error {
+	return func(_ LogLevel, line string) error {
+		_, err := io.WriteString(w, line+"\n")
+		return err
+	}
+}
+
+// stripTimestamp returns a log line's message without the ts() prefix.
+func stripTimestamp(line string) string {
+	if strings.HasPrefix(line, "[") {
+		if idx := strings.Index(line, "] "); idx != -1 {
+			return line[idx+2:]
+		}
+	}
+	return line
+}
+
+// Write logs one line, without its trailing newline, to the sinks that
+// accept its level.
+func (l *logSinks) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+
+	l.write(logLineLevel(stripTimestamp(line)), line)
+	return len(p), nil
+}
+
+func (l *logSinks) write(level LogLevel, line string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	for _, s := range l.sinks {
+		floor := s.level
+		if l.override {
+			floor = l.level
+		}
+		if level >= floor || l.verbose {
+			_ = s.write(level, line)
+		}
+	}
+}
+
+// setVerbose lets every line through to every sink, whatever its level.
+func (l *logSinks) setVerbose(on bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.verbose = on
+}
+
+// setLevel sets every sink's level, overriding the configured ones. ok false
+// goes back to them.
+func (l *logSinks) setLevel(level LogLevel, ok bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.level, l.override = level, ok
+}
+
+// Sync flushes the file sinks to disk.
+func (l *logSinks) Sync() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	var firstErr error
+	for _, fp := range l.files {
+		if err := fp.Sync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (l *logSinks) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	var firstErr error
+	for _, s := range l.sinks {
+		if s.close == nil {
+			continue
+		}
+		if err := s.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	l.sinks = nil
+	return firstErr
+}
+
+// nopWriteCloser is the log until Start opens the sinks; options set from
+// the config file log as they're applied.
+type nopWriteCloser struct{}
+
+func (nopWriteCloser) Write(p []byte) (int, error) { return len(p), nil }
+func (nopWriteCloser) Close() error                { return nil }
+
+// setLogLevel handles the LogLevel option: every log sink at one level, or
+// "default" for the levels they were configured with.
+func (u *UCI) setLogLevel(value string) {
+	l, ok := u.logSinks()
+	if !ok {
+		return
+	}
+	level, err := parseLogLevel(value)
+	l.setLevel(level, err == nil)
+}
+
+// SetLogSinks sets where the log goes, replacing the default of everything
+// to trollfish.log. It must be called before Start or Serve.
+func (u *UCI) SetLogSinks(spec string) error {
+	if _, err := parseLogSinks(spec); err != nil {
+		return err
+	}
+	u.logSpec = spec
+	return nil
+}
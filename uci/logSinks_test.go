@@ -0,0 +1,258 @@
+package uci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseLogSinks(t *testing.T) {
+	configs, err := parseLogSinks("file=trollfish.log:debug, syslog:error,stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []logSinkConfig{
+		{kind: "file", target: "trollfish.log", level: LogDebug},
+		{kind: "syslog", level: LogError},
+		{kind: "stderr", level: LogInfo},
+	}
+	if len(configs) != len(want) {
+		t.Fatalf("want %v got %v", want, configs)
+	}
+	for i := range want {
+		if configs[i] != want[i] {
+			t.Errorf("%d: want %v got %v", i, want[i], configs[i])
+		}
+	}
+
+	for _, bad := range []string{"", "file", "json", "file=x.log:loud", "kafka"} {
+		if _, err := parseLogSinks(bad); err == nil {
+			t.Errorf("'%s': expected an error", bad)
+		}
+	}
+}
+
+func TestLogSinksLevels(t *testing.T) {
+	dir := t.TempDir()
+	all, warns, errs := filepath.Join(dir, "all.log"), filepath.Join(dir, "warnings.log"), filepath.Join(dir, "errors.log")
+
+	l, err := openLogSinks([]logSinkConfig{
+		{kind: "file", target: all, level: LogDebug},
+		{kind: "file", target: warns, level: LogWarn},
+		{kind: "file", target: errs, level: LogError},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []string{
+		"[2026-10-17 10:00:00] -> go wtime 1000 btime 1000",
+		"[2026-10-17 10:00:00] book_move: e2e4 delay: 0ms",
+		"[2026-10-17 10:00:00] SF STDERR [pid 42]: terminate called after throwing an instance of 'std::bad_alloc'",
+		"[2026-10-17 10:00:00] ERR: engine: signal: killed",
+	}
+	for _, line := range lines {
+		_, _ = l.Write([]byte(line + "\n"))
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(path string) string {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if got, want := read(all), strings.Join(lines, "\n")+"\n"; got != want {
+		t.Errorf("all\nwant: %q\ngot:  %q", want, got)
+	}
+	if got, want := read(warns), strings.Join(lines[2:], "\n")+"\n"; got != want {
+		t.Errorf("warnings\nwant: %q\ngot:  %q", want, got)
+	}
+	if got, want := read(errs), lines[3]+"\n"; got != want {
+		t.Errorf("errors\nwant: %q\ngot:  %q", want, got)
+	}
+
+	if _, err := openLogSinks([]logSinkConfig{{kind: "stderr"}}, true); err == nil {
+		t.Error("stderr sink in UCI mode: expected an error")
+	}
+}
+
+type recordLogger struct {
+	mtx    sync.Mutex
+	lines  []string
+	closed bool
+}
+
+func (l *recordLogger) Log(level LogLevel, msg string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.lines = append(l.lines, level.String()+" "+msg)
+}
+
+func (l *recordLogger) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.closed = true
+	return nil
+}
+
+func (l *recordLogger) has(line string) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for _, s := range l.lines {
+		if s == line {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetLogger(t *testing.T) {
+	u, _ := newTestUCI(t)
+	u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+		return nil, errors.New("no engine")
+	})
+	in, w := io.Pipe()
+	u.SetIO(in, io.Discard)
+
+	logger := &recordLogger{}
+	u.SetLogger(logger)
+
+	if _, _, err := u.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.WriteString(w, "isready\nquit\n")
+	<-u.ctx.Done()
+
+	for _, want := range []string{"debug -> isready", "info engine stopped"} {
+		if !logger.has(want) {
+			t.Errorf("want '%s' in %q", want, logger.lines)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(".", defaultLogPath)); err == nil {
+		t.Errorf("%s written with a Logger set", defaultLogPath)
+	}
+}
+
+func TestFileLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "engine.log")
+	logger, err := NewFileLogger(path, LogInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Log(LogDebug, "-> isready")
+	logger.Log(LogInfo, "book_move: e2e4")
+	if err := logger.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); strings.Contains(got, "isready") || !strings.HasSuffix(got, "] book_move: e2e4\n") {
+		t.Errorf("log:\n%s", got)
+	}
+}
+
+func TestJSONLogSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trollfish.jsonl")
+	l, err := openLogSinks([]logSinkConfig{{kind: "json", target: path, level: LogDebug}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.setContext(func(c *logContext) {
+		eval := -35
+		c.fen, c.eval, c.moveTime, c.agro = startPosFEN, &eval, 1500, true
+	})
+	for _, line := range []string{
+		"[2026-10-17 10:00:00] -> go wtime 1000 btime 1000",
+		"[2026-10-17 10:00:00] SF: -> go movetime 1500",
+		"[2026-10-17 10:00:00] book_move: e2e4 delay: 0ms",
+	} {
+		_, _ = l.Write([]byte(line + "\n"))
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	want := []logRecord{
+		{Level: "debug", Direction: "from_gui", Command: "go wtime 1000 btime 1000"},
+		{Level: "debug", Direction: "to_engine", Command: "go movetime 1500"},
+		{Level: "info", Message: "book_move: e2e4 delay: 0ms"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("want %d lines got:\n%s", len(want), b)
+	}
+	for i, line := range lines {
+		var got logRecord
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if got.Level != want[i].Level || got.Direction != want[i].Direction || got.Command != want[i].Command || got.Message != want[i].Message {
+			t.Errorf("%d: want %+v got %+v", i, want[i], got)
+		}
+		if got.FEN != startPosFEN || got.Eval == nil || *got.Eval != -35 || got.MoveTime != 1500 || !got.Agro || got.Time == "" {
+			t.Errorf("%d: context: %s", i, line)
+		}
+	}
+}
+
+func TestLogLevelOption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trollfish.log")
+	l, err := openLogSinks([]logSinkConfig{{kind: "file", target: path, level: LogInfo}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := newTestUCI(t)
+	u.log = l
+
+	u.logDebug("SF: <- info depth 1")
+	u.logInfo("book_move: e2e4 delay: 0ms")
+	u.SetOption("LogLevel", "warn")
+	u.logInfo("takeback: 1 moves")
+	u.logWarn("hash: 7168 MB capped to 2048 MB, 4096 MB available")
+	u.SetOption("LogLevel", "debug")
+	u.logDebug("SF: <- info depth 2")
+	u.SetOption("LogLevel", "default")
+	u.logDebug("SF: <- info depth 3")
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		got = append(got, stripTimestamp(line))
+	}
+	want := []string{
+		"book_move: e2e4 delay: 0ms",
+		"hash: 7168 MB capped to 2048 MB, 4096 MB available",
+		"SF: <- info depth 2",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("\nwant: %q\ngot:  %q", want, got)
+	}
+}
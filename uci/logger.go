@@ -0,0 +1,83 @@
+package uci
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Logger receives trollfish's log, to route it somewhere other than the log
+// sinks. msg has no timestamp or trailing newline; level is what a log sink
+// would file it under. Log is called from the log's own goroutine, one line
+// at a time. If the Logger is also an io.Closer it's closed when the engine
+// quits.
+type Logger interface {
+	Log(level LogLevel, msg string)
+}
+
+// NewFileLogger returns the default Logger: lines at level and above,
+// timestamped, appended to the file at path.
+func NewFileLogger(path string, level LogLevel) (Logger, error) {
+	return openLogSinks([]logSinkConfig{{kind: "file", target: path, level: level}}, false)
+}
+
+// Log writes msg to the sinks that accept level.
+func (l *logSinks) Log(level LogLevel, msg string) {
+	l.write(level, fmt.Sprintf("%s %s", ts(), msg))
+}
+
+// SetLogger sends the log to logger instead of the log sinks. It must be
+// called before Start or Serve, and overrides SetLogSinks.
+func (u *UCI) SetLogger(logger Logger) {
+	u.logger = logger
+}
+
+// openLog returns the log writer: the Logger set by SetLogger, otherwise the
+// configured log sinks.
+func (u *UCI) openLog(stdio bool) (io.WriteCloser, error) {
+	switch l := u.logger.(type) {
+	case nil:
+	case *logSinks:
+		return l, nil
+	default:
+		return loggerWriter{l}, nil
+	}
+
+	u.moveListMtx.Lock()
+	spec, rotation := u.logSpec, u.config.logRotation()
+	if spec == "" {
+		spec = defaultLogSpec(u.config.LogPath)
+	}
+	u.moveListMtx.Unlock()
+
+	configs, err := parseLogSinks(spec)
+	if err != nil {
+		return nil, err
+	}
+	for i := range configs {
+		configs[i].rotation = rotation
+	}
+	return openLogSinks(configs, stdio)
+}
+
+// loggerWriter adapts a Logger to the log writer, one line per Write.
+type loggerWriter struct {
+	Logger
+}
+
+func (w loggerWriter) Write(p []byte) (int, error) {
+	msg := stripTimestamp(strings.TrimRight(string(p), "\n"))
+	w.Log(logLineLevel(msg), msg)
+	return len(p), nil
+}
+
+func (w loggerWriter) write(level LogLevel, line string) {
+	w.Log(level, stripTimestamp(line))
+}
+
+func (w loggerWriter) Close() error {
+	if c, ok := w.Logger.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
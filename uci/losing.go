@@ -0,0 +1,112 @@
+package uci
+
+import "fmt"
+
+const (
+	defaultLosingThinkEval  = -60
+	defaultLosingThinkTime  = 3500 // ms
+	defaultLosingThinkClock = 50   // % of the opponent's clock
+	defaultSwindleEval      = -300
+	defaultResignEval       = 0 // never
+
+	// swindleTolerance is how much eval we'll give up for a line that sets
+	// the opponent problems.
+	swindleTolerance = 50
+
+	// resignMoves is how many moves in a row the eval has to be past the
+	// resign point.
+	resignMoves = 3
+)
+
+// losingPolicy is what we do when SF says we're losing, from thinking longer
+// to playing for tricks to giving up. Each step is set by a UCI option.
+type losingPolicy struct {
+	// thinkEval is the eval below which we take thinkTime over a move, as
+	// long as we have at least thinkClock percent of the opponent's clock.
+	thinkEval  int
+	thinkTime  int
+	thinkClock int
+
+	// swindleEval is the eval at or below which we stop trolling and look
+	// for tricks.
+	swindleEval int
+
+	// resignEval is the eval at or below which we resign; 0 never does.
+	resignEval int
+}
+
+var defaultLosingPolicy = losingPolicy{
+	thinkEval:   defaultLosingThinkEval,
+	thinkTime:   defaultLosingThinkTime,
+	thinkClock:  defaultLosingThinkClock,
+	swindleEval: defaultSwindleEval,
+	resignEval:  defaultResignEval,
+}
+
+// moveTime returns how long to think when losing, or false if we're not
+// losing or can't afford to stop and think.
+func (p losingPolicy) moveTime(eval Score, ourTime, oppClock int) (int, bool) {
+	if eval.cp() >= p.thinkEval || ourTime*100 <= oppClock*p.thinkClock {
+		return 0, false
+	}
+	return p.thinkTime, true
+}
+
+func (p losingPolicy) swindling(engineMove Info) bool {
+	return engineMove.Eval().cp() <= p.swindleEval
+}
+
+func (p losingPolicy) resigning(engineMove Info) bool {
+	if p.resignEval == 0 {
+		return false
+	}
+	return engineMove.Eval().cp() <= p.resignEval
+}
+
+// setLosingPolicy returns an option handler that sets one of the losing
+// policy's thresholds.
+func (u *UCI) setLosingPolicy(set func(p *losingPolicy, n int)) func(string) {
+	return func(value string) {
+		u.moveListMtx.Lock()
+		set(&u.losing, atoi(value))
+		u.moveListMtx.Unlock()
+	}
+}
+
+// swindleMove picks, among the lines within swindleTolerance of SF's, the one
+// most likely to trip the opponent up: checks first, then captures. Callers
+// must hold moveListMtx.
+func (u *UCI) swindleMove(moveList []Info, engineMove Info) Info {
+	if u.history == nil {
+		return engineMove
+	}
+
+	b := u.history.Board()
+
+	best := engineMove
+	bestPriority := scramblePriority(b, pvMove(engineMove.PV), "")
+	for _, line := range moveList {
+		if line.Mate != engineMove.Mate || engineMove.Score-line.Score > swindleTolerance {
+			continue
+		}
+		if priority := scramblePriority(b, pvMove(line.PV), ""); priority > bestPriority {
+			best, bestPriority = line, priority
+		}
+	}
+	return best
+}
+
+// checkResign tells the GUI we resign once the eval has been past the resign
+// point for resignMoves moves in a row. Callers must hold moveListMtx.
+func (u *UCI) checkResign(engineMove Info) {
+	if !u.losing.resigning(engineMove) {
+		u.resignCount = 0
+		return
+	}
+
+	u.resignCount++
+	if u.resignCount == resignMoves {
+		u.logInfo(fmt.Sprintf("resign: eval %d mate %d", engineMove.Score, engineMove.Mate))
+		u.WriteLine("info string resign")
+	}
+}
@@ -0,0 +1,69 @@
+package uci
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLosingPolicyMoveTime(t *testing.T) {
+	p := defaultLosingPolicy
+
+	tests := []struct {
+		name              string
+		eval              Score
+		ourTime, oppClock int
+		want              int
+		wantOK            bool
+	}{
+		{"equal", Score{}, 60_000, 60_000, 0, false},
+		{"losing", Score{CP: -100}, 60_000, 60_000, defaultLosingThinkTime, true},
+		{"at the threshold", Score{CP: defaultLosingThinkEval}, 60_000, 60_000, 0, false},
+		{"behind on the clock", Score{CP: -100}, 29_000, 60_000, 0, false},
+		{"half the opponent's clock", Score{CP: -100}, 31_000, 60_000, defaultLosingThinkTime, true},
+		{"getting mated", Score{Mate: -4}, 60_000, 60_000, defaultLosingThinkTime, true},
+		{"mating", Score{Mate: 4}, 60_000, 60_000, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := p.moveTime(tt.eval, tt.ourTime, tt.oppClock)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: want: %d %v got: %d %v", tt.name, tt.want, tt.wantOK, got, ok)
+		}
+	}
+}
+
+func TestLosingPolicyOptions(t *testing.T) {
+	u, _ := newTestUCI(t)
+
+	u.SetOption("LosingThinkEval", "-200")
+	u.SetOption("SwindleEval", "-500")
+	u.SetOption("ResignEval", "-900")
+
+	want := defaultLosingPolicy
+	want.thinkEval, want.swindleEval, want.resignEval = -200, -500, -900
+	if u.losing != want {
+		t.Errorf("\nwant: %+v\ngot:  %+v", want, u.losing)
+	}
+}
+
+func TestSwindleAndResign(t *testing.T) {
+	var out bytes.Buffer
+	u := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}, out: &out, losing: defaultLosingPolicy, playBad: true}
+	u.losing.resignEval = -900
+	u.history = NewHistory(FENtoBoard("4k3/8/8/8/8/2q5/8/4K2R w - - 0 1"))
+
+	moveList := []Info{
+		{MultiPV: 1, Score: -950, PV: "e1f2"},
+		{MultiPV: 2, Score: -980, PV: "h1h8"},
+		{MultiPV: 3, Score: -1200, PV: "h1h2"},
+	}
+
+	for i := 0; i < resignMoves; i++ {
+		got := u.selectMove(moveList, moveList[0])
+		if pvMove(got.PV) != "h1h8" {
+			t.Errorf("move %d: want: h1h8 got: %s", i, got.PV)
+		}
+	}
+	if got := out.String(); got != "info string resign\n" {
+		t.Errorf("want: resign got: %q", got)
+	}
+}
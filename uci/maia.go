@@ -0,0 +1,183 @@
+package uci
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// defaultMaiaWeight is the centipawns a move is worth to the selector if
+	// Maia is sure a human would play it.
+	defaultMaiaWeight = 100
+
+	// maiaNodes is how deep Maia looks. Its policy is the human model; a
+	// search would make it play like an engine again.
+	maiaNodes = 1
+)
+
+// maiaPolicyRe matches the prior in lc0's VerboseMoveStats, for example
+// "info string g1f3  (159 ) N: 0 (+ 0) (P: 31.42%) ...".
+var maiaPolicyRe = regexp.MustCompile(`\(P:\s*([0-9.]+)%\)`)
+
+// maia is a second engine, lc0 running Maia weights, asked for the moves a
+// human at its rating would play. SF still decides what's playable; among the
+// playable lines the selector prefers the ones Maia thinks a human would find.
+type maia struct {
+	mtx sync.Mutex // one query at a time
+	s   *searcher
+
+	policyMtx sync.Mutex
+	fen       string
+	policy    map[string]float64 // move -> probability for fen
+}
+
+// startMaia starts lc0 at path with the Maia weights file.
+func (u *UCI) startMaia(path, weights string) (*maia, error) {
+	eng, err := StartLc0(u.ctx, path, u.logInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	s := searcher{sf: eng, logInfo: u.logInfo}
+	eng.Write("uci")
+	if _, err := s.waitFor("uciok"); err != nil {
+		eng.Quit()
+		return nil, err
+	}
+	if weights != "" {
+		s.setOption("WeightsFile", weights)
+	}
+	s.setOption("VerboseMoveStats", "true")
+	s.setOption("Threads", 1)
+	if err := s.ready(); err != nil {
+		eng.Quit()
+		return nil, err
+	}
+
+	return &maia{s: &s}, nil
+}
+
+// ask starts a policy query for fen in the background. It's answered long
+// before SF's search is, since Maia doesn't search.
+func (m *maia) ask(fen string) {
+	if m == nil {
+		return
+	}
+
+	m.policyMtx.Lock()
+	m.fen, m.policy = fen, nil
+	m.policyMtx.Unlock()
+
+	go func() {
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+
+		m.s.sf.Write("position fen " + fen)
+		m.s.sf.Write(fmt.Sprintf("go nodes %d", maiaNodes))
+		lines, err := m.s.waitFor("bestmove")
+		if err != nil {
+			m.s.logInfo(fmt.Sprintf("ERR: maia: %v", err))
+			return
+		}
+
+		policy := parseMaiaPolicy(lines)
+		m.policyMtx.Lock()
+		if m.fen == fen {
+			m.policy = policy
+		}
+		m.policyMtx.Unlock()
+	}()
+}
+
+// moveProbabilities returns Maia's policy for fen, or nil if it hasn't
+// answered for that position.
+func (m *maia) moveProbabilities(fen string) map[string]float64 {
+	if m == nil {
+		return nil
+	}
+
+	m.policyMtx.Lock()
+	defer m.policyMtx.Unlock()
+	if m.fen != fen {
+		return nil
+	}
+	return m.policy
+}
+
+func (m *maia) quit() {
+	if m != nil {
+		m.s.sf.Quit()
+	}
+}
+
+// parseMaiaPolicy reads the move priors from lc0's VerboseMoveStats lines.
+func parseMaiaPolicy(lines []string) map[string]float64 {
+	policy := make(map[string]float64)
+	for _, line := range lines {
+		rest := strings.TrimPrefix(line, "info string ")
+		if rest == line {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 || !isUCIMove(fields[0]) {
+			continue
+		}
+		m := maiaPolicyRe.FindStringSubmatch(rest)
+		if m == nil {
+			continue
+		}
+		p, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			continue
+		}
+		policy[fields[0]] = p / 100
+	}
+	return policy
+}
+
+// isUCIMove reports whether s looks like a move in long algebraic notation.
+func isUCIMove(s string) bool {
+	if len(s) != 4 && len(s) != 5 {
+		return false
+	}
+	return s[0] >= 'a' && s[0] <= 'h' && s[1] >= '1' && s[1] <= '8' &&
+		s[2] >= 'a' && s[2] <= 'h' && s[3] >= '1' && s[3] <= '8'
+}
+
+// humanBonus is how much more the selector likes a line because Maia thinks
+// a human would play its first move, in the units of equalityDist.
+func humanBonus(policy map[string]float64, weight int, move Info) int {
+	if policy == nil {
+		return 0
+	}
+	return int(policy[pvMove(move.PV)] * float64(weight))
+}
+
+// setMaia handles MaiaPath and MaiaWeights, (re)starting Maia. An empty path
+// turns it off.
+func (u *UCI) setMaia(string) {
+	if atomic.LoadInt64(&u.started) == 0 {
+		return
+	}
+
+	var m *maia
+	if path := u.options.String("MaiaPath"); path != "" {
+		var err error
+		m, err = u.startMaia(path, u.options.String("MaiaWeights"))
+		if err != nil {
+			u.WriteLine(fmt.Sprintf("info string ERR: maia: %v", err))
+			return
+		}
+		u.logInfo(fmt.Sprintf("maia: started %s", path))
+	}
+
+	u.moveListMtx.Lock()
+	old := u.maia
+	u.maia = m
+	u.moveListMtx.Unlock()
+	old.quit()
+}
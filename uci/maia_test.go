@@ -0,0 +1,51 @@
+package uci
+
+import (
+	"testing"
+)
+
+func TestParseMaiaPolicy(t *testing.T) {
+	lines := []string{
+		"info string e2e4  (322 ) N:       0 (+ 0) (P: 41.20%) (WL:  -.-----) (D: -.---) (M:  -.-) (Q: -0.01) (V:  -.----)",
+		"info string d2d4  (293 ) N:       0 (+ 0) (P:  30.5%) (WL:  -.-----) (D: -.---) (M:  -.-) (Q: -0.01) (V:  -.----)",
+		"info string e7e8q (1791) N:       0 (+ 0) (P: 0.02%) (Q: -0.01)",
+		"info string node  (  20) N:       1 (+ 0) (P: 100.00%) (WL:  0.01)",
+		"info depth 1 seldepth 1 time 20 nodes 1 score cp 3 pv e2e4",
+	}
+	got := parseMaiaPolicy(lines)
+	want := map[string]float64{"e2e4": 0.412, "d2d4": 0.305, "e7e8q": 0.0002}
+	if len(got) != len(want) {
+		t.Fatalf("want: %v got: %v", want, got)
+	}
+	for move, p := range want {
+		if d := got[move] - p; d > 1e-9 || d < -1e-9 {
+			t.Errorf("%s: want: %v got: %v", move, p, got[move])
+		}
+	}
+}
+
+func TestSelectMoveMaia(t *testing.T) {
+	moveList := []Info{
+		{MultiPV: 1, Score: 20, PV: "g1f3"},
+		{MultiPV: 2, Score: 10, PV: "b1a3"},
+	}
+
+	u, _ := newTestUCI(t)
+	u.fen = startPosFEN
+
+	// without Maia the most equal line is the engine-flavored one
+	if got := u.selectMove(moveList, moveList[0]); got.PV != "b1a3" {
+		t.Errorf("no maia: want: b1a3 got: %s", got.PV)
+	}
+
+	u.maia = &maia{fen: startPosFEN, policy: map[string]float64{"g1f3": 0.3, "b1a3": 0.001}}
+	if got := u.selectMove(moveList, moveList[0]); got.PV != "g1f3" {
+		t.Errorf("maia: want: g1f3 got: %s", got.PV)
+	}
+
+	// a policy for another position is ignored
+	u.fen = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	if got := u.selectMove(moveList, moveList[0]); got.PV != "b1a3" {
+		t.Errorf("stale policy: want: b1a3 got: %s", got.PV)
+	}
+}
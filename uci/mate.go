@@ -0,0 +1,164 @@
+package uci
+
+import (
+	"fmt"
+	"sort"
+)
+
+// defaultMateNodes bounds how much work a mate proof may do. Proofs beyond a
+// handful of moves blow up quickly with this move generator; they report
+// "not confirmed" instead of stalling the GUI.
+const defaultMateNodes = 200_000
+
+// mateProver proves that the side to move can force mate within n moves.
+type mateProver struct {
+	nodes    int
+	maxNodes int
+	aborted  bool
+}
+
+// proveMate returns the first move of a forced mate in at most n moves.
+// hint is tried first at the root (usually SF's PV). ok is false when no mate
+// was found or the node budget ran out; check p.aborted to tell them apart.
+func (p *mateProver) proveMate(b Board, n int, hint string) (move string, ok bool) {
+	moves := orderMateMoves(b, b.LegalMoves(), hint)
+	for _, m := range moves {
+		child := b.Clone()
+		child.Moves(m)
+		if p.defenderLoses(child, n-1) {
+			return m, true
+		}
+		if p.aborted {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// attackerWins reports whether the side to move mates within n moves.
+func (p *mateProver) attackerWins(b Board, n int) bool {
+	if n <= 0 || p.aborted {
+		return false
+	}
+
+	for _, m := range orderMateMoves(b, b.LegalMoves(), "") {
+		child := b.Clone()
+		child.Moves(m)
+		if p.defenderLoses(child, n-1) {
+			return true
+		}
+		if p.aborted {
+			return false
+		}
+	}
+	return false
+}
+
+// defenderLoses reports whether every reply of the side to move can be met
+// with a mate in at most n moves. The side to move is mated when it has no
+// moves and is in check.
+func (p *mateProver) defenderLoses(b Board, n int) bool {
+	p.nodes++
+	if p.maxNodes > 0 && p.nodes > p.maxNodes {
+		p.aborted = true
+		return false
+	}
+
+	inCheck := b.InCheck()
+
+	// the last attacking move has to mate, so quiet moves can't be the answer
+	if n == 0 {
+		return inCheck && len(b.LegalMoves()) == 0
+	}
+
+	moves := b.LegalMoves()
+	if len(moves) == 0 {
+		return inCheck
+	}
+
+	for _, m := range moves {
+		child := b.Clone()
+		child.Moves(m)
+		if !p.attackerWins(child, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// orderMateMoves puts the hint first, then checks, then captures.
+func orderMateMoves(b Board, moves []string, hint string) []string {
+	rank := make(map[string]int, len(moves))
+	for _, m := range moves {
+		switch {
+		case m == hint:
+			rank[m] = 0
+		case givesCheck(b, m):
+			rank[m] = 1
+		case b.Pos[uciToIndex(m[2:4])] != ' ':
+			rank[m] = 2
+		default:
+			rank[m] = 3
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return rank[moves[i]] < rank[moves[j]]
+	})
+	return moves
+}
+
+func givesCheck(b Board, move string) bool {
+	child := b.Clone()
+	child.Moves(move)
+	return child.InCheck()
+}
+
+// verifyMate answers "go mate n" from SF's result, but only claims the mate
+// once the board confirms a forced mate of that length.
+func (u *UCI) verifyMate(n int, sfMove string, best Info) {
+	u.moveListMtx.Lock()
+	fen, chess960 := u.fen, u.chess960
+	u.moveListMtx.Unlock()
+
+	if fen == "" {
+		u.WriteLine("bestmove " + sfMove)
+		return
+	}
+
+	maxMoves := n
+	if best.Mate > 0 && best.Mate < n {
+		maxMoves = best.Mate
+	}
+
+	b := FENtoBoard(fen)
+	b.Chess960 = b.Chess960 || chess960
+	p := mateProver{maxNodes: defaultMateNodes}
+
+	for moves := 1; moves <= maxMoves; moves++ {
+		move, ok := p.proveMate(b, moves, sfMove)
+		if p.aborted {
+			break
+		}
+		if !ok {
+			continue
+		}
+
+		pv := move
+		if move == sfMove && best.PV != "" {
+			pv = best.PV
+		}
+
+		u.logInfo(fmt.Sprintf("mate_search: mate %d confirmed move: %s nodes: %d", moves, move, p.nodes))
+		u.WriteLine(fmt.Sprintf("info depth %d score mate %d nodes %d pv %s", moves*2-1, moves, p.nodes, pv))
+		u.WriteLine("bestmove " + move)
+		return
+	}
+
+	if p.aborted {
+		u.WriteLine(fmt.Sprintf("info string mate %d not confirmed, search limit reached after %d nodes", n, p.nodes))
+	} else {
+		u.WriteLine(fmt.Sprintf("info string no forced mate in %d", n))
+	}
+	u.logInfo(fmt.Sprintf("mate_search: mate %d not confirmed sf_move: %s sf_mate: %d nodes: %d aborted: %v", n, sfMove, best.Mate, p.nodes, p.aborted))
+	u.WriteLine("bestmove " + sfMove)
+}
@@ -0,0 +1,82 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProveMate(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name   string
+		fen    string
+		n      int
+		want   string
+		wantOK bool
+	}{
+		{name: "scholar's mate", fen: "r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4", n: 1, want: "h5f7", wantOK: true},
+		{name: "KRK mate in 2", fen: "k7/8/2K5/8/8/8/8/7R w - - 0 1", n: 2, wantOK: true}, // Kb6 and Kc7 both mate
+		{name: "KRK no mate in 1", fen: "k7/8/2K5/8/8/8/8/7R w - - 0 1", n: 1, wantOK: false},
+		{name: "start position", fen: startPosFEN, n: 1, wantOK: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			p := mateProver{maxNodes: defaultMateNodes}
+			got, ok := p.proveMate(FENtoBoard(c.fen), c.n, "")
+
+			// assert
+			if p.aborted {
+				t.Fatalf("node limit reached after %d nodes", p.nodes)
+			}
+			if c.wantOK != ok {
+				t.Fatalf("ok, want: %v got: %v (%s)", c.wantOK, ok, got)
+			}
+			if c.want != "" && c.want != got {
+				t.Errorf("want: '%s' got: '%s'", c.want, got)
+			}
+		})
+	}
+}
+
+func TestGoMateUnverified(t *testing.T) {
+	u, out := newTestUCI(t)
+
+	// SF claims a mate the start position doesn't have
+	search := []string{
+		"info depth 1 seldepth 1 multipv 1 score mate 1 nodes 20 nps 20000 time 1 pv e2e4",
+		"info depth 2 seldepth 2 multipv 1 score mate 1 nodes 400 nps 20000 time 20 pv e2e4 e7e5",
+		"bestmove e2e4 ponder e7e5",
+	}
+	eng := newScriptedEngine(search, search)
+	u.setEngine(eng, "")
+	go u.stockFishReadLoop(eng)
+
+	goMate := func(want string) {
+		t.Helper()
+		start := len(out.String())
+		u.SetPosition("startpos")
+		u.Go("mate", "1")
+
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String()[start:], "bestmove ") {
+			if time.Now().After(deadline) {
+				t.Fatalf("no bestmove:\n%s", out.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if s := out.String()[start:]; strings.Contains(s, "score mate") || !strings.Contains(s, want+"\nbestmove e2e4\n") {
+			t.Errorf("want: %s got:\n%s", want, s)
+		}
+	}
+
+	goMate("info string no forced mate in 1")
+
+	// a variant's mate can't be proven, it's answered without the selector
+	u.moveListMtx.Lock()
+	u.variant = VariantAtomic
+	u.moveListMtx.Unlock()
+	goMate("info string mate 1 not verified in atomic")
+}
@@ -0,0 +1,172 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// miniEngineDepth is how deep the built-in engine searches, in plies.
+	miniEngineDepth = 3
+
+	miniMateScore      = 100_000
+	miniMobilityWeight = 5 // cp per move
+
+	// unresponsiveGrace is how long past its movetime SF has to answer before
+	// the built-in engine plays instead.
+	unresponsiveGrace = 5 * time.Second
+)
+
+var miniPieceValues = map[rune]int{'p': 100, 'n': 300, 'b': 320, 'r': 500, 'q': 900}
+
+// miniSearch is the built-in fallback engine: material and mobility with a
+// small alpha-beta search over the move generator. It's only there so a legal,
+// sane move can always be played; it returns "" if there's no legal move.
+func miniSearch(b Board, depth int) (string, int) {
+	moves := orderMiniMoves(&b, b.LegalMoves())
+
+	bestMove, alpha := "", -miniMateScore-1
+	for _, move := range moves {
+		child := b.Clone()
+		child.Moves(move)
+
+		score := -miniNegamax(child, depth-1, -miniMateScore-1, -alpha, 1)
+		if score > alpha {
+			bestMove, alpha = move, score
+		}
+	}
+
+	if bestMove == "" {
+		return "", 0
+	}
+	return bestMove, alpha
+}
+
+func miniNegamax(b Board, depth, alpha, beta, ply int) int {
+	moves := b.LegalMoves()
+	if len(moves) == 0 {
+		if b.InCheck() {
+			return -miniMateScore + ply
+		}
+		return 0
+	}
+
+	if depth <= 0 {
+		return miniEval(&b, len(moves))
+	}
+
+	for _, move := range orderMiniMoves(&b, moves) {
+		child := b.Clone()
+		child.Moves(move)
+
+		score := -miniNegamax(child, depth-1, -beta, -alpha, ply+1)
+		if score >= beta {
+			return beta
+		}
+		alpha = max(alpha, score)
+	}
+	return alpha
+}
+
+// miniEval scores the position for the side to move, which has legalMoves
+// moves.
+func miniEval(b *Board, legalMoves int) int {
+	white := b.ActiveColor == "w"
+
+	var score int
+	for _, c := range b.Pos {
+		v := miniPieceValues[unicode.ToLower(c)]
+		if isWhitePiece(c) == white {
+			score += v
+		} else {
+			score -= v
+		}
+	}
+
+	opp := b.Clone()
+	if white {
+		opp.ActiveColor = "b"
+	} else {
+		opp.ActiveColor = "w"
+	}
+	opp.EnPassantSquare = "-"
+	mobility := legalMoves - len(opp.pseudoLegalMoves())
+
+	return score + mobility*miniMobilityWeight
+}
+
+// orderMiniMoves puts captures first so alpha-beta cuts sooner.
+func orderMiniMoves(b *Board, moves []string) []string {
+	ordered := make([]string, 0, len(moves))
+	var quiet []string
+	for _, move := range moves {
+		if b.isCapture(move) || len(move) > 4 {
+			ordered = append(ordered, move)
+		} else {
+			quiet = append(quiet, move)
+		}
+	}
+	return append(ordered, quiet...)
+}
+
+// playFallbackMove answers the search in progress with the built-in engine,
+// for when SF isn't there or isn't answering.
+func (u *UCI) playFallbackMove(reason string) {
+	u.moveListMtx.Lock()
+	fen := u.fen
+	if fen == "" {
+		fen = u.variant.startFEN()
+	}
+	b := FENtoBoard(fen)
+	b.Variant = u.variant
+	u.moveList = nil
+	u.moveListPrinted = false
+	u.moveListNodes = 0
+	u.moveListMtx.Unlock()
+
+	move, score := miniSearch(b, miniEngineDepth)
+
+	u.logInfo(fmt.Sprintf("fallback_move: %s score: %d reason: %s", move, score, reason))
+
+	if move == "" {
+		u.WriteLine("bestmove (none)")
+	} else {
+		u.WriteLine(fmt.Sprintf("info depth %d score cp %d pv %s", miniEngineDepth, score, move))
+		u.WriteLine("bestmove " + move)
+	}
+	u.finishSearch()
+}
+
+// watchSearch plays the fallback move if SF hasn't answered a movetime search
+// well after the time's up. SF's bestmove, if it ever comes, is dropped.
+func (u *UCI) watchSearch(done chan struct{}, args string) {
+	p, err := ParseGoParams(strings.Fields(args))
+	if err != nil || p.MoveTime == 0 {
+		return
+	}
+
+	t := time.NewTimer(time.Duration(p.MoveTime)*time.Millisecond + unresponsiveGrace)
+	defer t.Stop()
+
+	select {
+	case <-done:
+		return
+	case <-u.ctx.Done():
+		return
+	case <-t.C:
+	}
+
+	u.moveListMtx.Lock()
+	if u.searchDone != done {
+		u.moveListMtx.Unlock()
+		return
+	}
+	u.staleBestMoves++
+	u.moveListMtx.Unlock()
+
+	u.logInfo("ERR: engine unresponsive")
+	u.engine().Write("stop")
+	u.playFallbackMove("engine unresponsive")
+}
@@ -0,0 +1,32 @@
+package uci
+
+import "testing"
+
+func TestMiniSearch(t *testing.T) {
+	cases := []struct {
+		name string
+		fen  string
+		want string
+	}{
+		{name: "mate in one", fen: "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", want: "a1a8"},
+		{name: "free queen", fen: "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1", want: "d1d5"},
+		{name: "no legal moves", fen: "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", want: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			move, _ := miniSearch(FENtoBoard(c.fen), miniEngineDepth)
+			if move != c.want {
+				t.Errorf("want '%s' got '%s'", c.want, move)
+			}
+		})
+	}
+}
+
+func TestMiniSearchStartPosition(t *testing.T) {
+	b := FENtoBoard(startPosFEN)
+	move, _ := miniSearch(b, miniEngineDepth)
+	if !b.IsLegal(move) {
+		t.Errorf("'%s' isn't legal", move)
+	}
+}
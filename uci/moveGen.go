@@ -0,0 +1,370 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Board squares are indexed a8=0 .. h1=63; row 0 is the 8th rank.
+
+var (
+	knightOffsets = [][2]int{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1}}
+	kingOffsets   = [][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+	bishopDirs    = [][2]int{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
+	rookDirs      = [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+)
+
+func isWhitePiece(c rune) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func isBlackPiece(c rune) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+var squareNames [64]string
+
+func init() {
+	for idx := range squareNames {
+		squareNames[idx] = fmt.Sprintf("%c%c", 'a'+idx%8, '8'-idx/8)
+	}
+}
+
+func indexToUCI(idx int) string {
+	return squareNames[idx]
+}
+
+func onBoard(row, col int) bool {
+	return row >= 0 && row < 8 && col >= 0 && col < 8
+}
+
+// Clone returns a deep copy of the board.
+func (b Board) Clone() Board {
+	pos := make([]rune, len(b.Pos))
+	copy(pos, b.Pos)
+	b.Pos = pos
+	return b
+}
+
+func (b *Board) kingSquare(white bool) int {
+	king := 'k'
+	if white {
+		king = 'K'
+	}
+	for i, c := range b.Pos {
+		if c == king {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsAttacked reports whether sq is attacked by the given side.
+func (b *Board) IsAttacked(sq int, byWhite bool) bool {
+	row, col := sq/8, sq%8
+
+	own := func(c rune) bool {
+		if byWhite {
+			return isWhitePiece(c)
+		}
+		return isBlackPiece(c)
+	}
+	is := func(c rune, piece rune) bool {
+		return own(c) && unicode.ToLower(c) == piece
+	}
+
+	// pawns attack "backwards" from the target's point of view
+	pawnRow := row + 1
+	if !byWhite {
+		pawnRow = row - 1
+	}
+	for _, dc := range []int{-1, 1} {
+		if onBoard(pawnRow, col+dc) && is(b.Pos[pawnRow*8+col+dc], 'p') {
+			return true
+		}
+	}
+
+	for _, o := range knightOffsets {
+		r, c := row+o[0], col+o[1]
+		if onBoard(r, c) && is(b.Pos[r*8+c], 'n') {
+			return true
+		}
+	}
+
+	for _, o := range kingOffsets {
+		r, c := row+o[0], col+o[1]
+		if onBoard(r, c) && is(b.Pos[r*8+c], 'k') {
+			return true
+		}
+	}
+
+	slide := func(dirs [][2]int, pieces string) bool {
+		for _, d := range dirs {
+			for r, c := row+d[0], col+d[1]; onBoard(r, c); r, c = r+d[0], c+d[1] {
+				p := b.Pos[r*8+c]
+				if p == ' ' {
+					continue
+				}
+				if own(p) && (unicode.ToLower(p) == rune(pieces[0]) || unicode.ToLower(p) == rune(pieces[1])) {
+					return true
+				}
+				break
+			}
+		}
+		return false
+	}
+
+	return slide(bishopDirs, "bq") || slide(rookDirs, "rq")
+}
+
+// InCheck reports whether the side to move is in check.
+func (b *Board) InCheck() bool {
+	white := b.ActiveColor == "w"
+	sq := b.kingSquare(white)
+	if sq == -1 {
+		return false
+	}
+	return b.IsAttacked(sq, !white)
+}
+
+// LegalMoves returns the legal moves for the side to move in UCI notation.
+func (b *Board) LegalMoves() []string {
+	if b.Variant.isAntichess() {
+		return b.antichessMoves()
+	}
+
+	white := b.ActiveColor == "w"
+
+	moves := b.pseudoLegalMoves()
+	if b.Variant == VariantCrazyhouse {
+		moves = append(moves, b.dropMoves()...)
+	}
+
+	var legal []string
+	for _, move := range moves {
+		if b.Variant == VariantAtomic {
+			if b.atomicLegal(move) {
+				legal = append(legal, move)
+			}
+			continue
+		}
+
+		pos := b.Clone()
+		pos.movePieces(move)
+		king := pos.kingSquare(white)
+		if king != -1 && pos.IsAttacked(king, !white) {
+			continue
+		}
+		legal = append(legal, move)
+	}
+	return legal
+}
+
+// IsLegal reports whether the UCI move is legal in the current position.
+func (b *Board) IsLegal(move string) bool {
+	for _, m := range b.LegalMoves() {
+		if m == move {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Board) pseudoLegalMoves() []string {
+	white := b.ActiveColor == "w"
+
+	own := isBlackPiece
+	enemy := isWhitePiece
+	if white {
+		own, enemy = isWhitePiece, isBlackPiece
+	}
+
+	var moves []string
+	add := func(from, to int) {
+		moves = append(moves, indexToUCI(from)+indexToUCI(to))
+	}
+
+	for sq, p := range b.Pos {
+		if !own(p) {
+			continue
+		}
+
+		row, col := sq/8, sq%8
+
+		switch unicode.ToLower(p) {
+		case 'p':
+			dir, startRow, promoteRow := 1, 1, 7
+			if white {
+				dir, startRow, promoteRow = -1, 6, 0
+			}
+
+			addPawn := func(to int) {
+				if to/8 != promoteRow {
+					add(sq, to)
+					return
+				}
+				promotions := "qrbn"
+				if b.Variant.isAntichess() {
+					promotions = "qrbnk"
+				}
+				for _, promote := range promotions {
+					moves = append(moves, fmt.Sprintf("%s%s%c", indexToUCI(sq), indexToUCI(to), promote))
+				}
+			}
+
+			r := row + dir
+			if !onBoard(r, col) {
+				break
+			}
+			if b.Pos[r*8+col] == ' ' {
+				addPawn(r*8 + col)
+				// horde pawns can double step from the first rank too
+				double := row == startRow || (white && b.Variant == VariantHorde && row == 7)
+				if double && b.Pos[(r+dir)*8+col] == ' ' {
+					add(sq, (r+dir)*8+col)
+				}
+			}
+			for _, dc := range []int{-1, 1} {
+				if !onBoard(r, col+dc) {
+					continue
+				}
+				to := r*8 + col + dc
+				if enemy(b.Pos[to]) || indexToUCI(to) == b.EnPassantSquare {
+					addPawn(to)
+				}
+			}
+		case 'n', 'k':
+			offsets := knightOffsets
+			if unicode.ToLower(p) == 'k' {
+				offsets = kingOffsets
+			}
+			for _, o := range offsets {
+				r, c := row+o[0], col+o[1]
+				if onBoard(r, c) && !own(b.Pos[r*8+c]) {
+					add(sq, r*8+c)
+				}
+			}
+		case 'b', 'r', 'q':
+			var dirs [][2]int
+			switch unicode.ToLower(p) {
+			case 'b':
+				dirs = bishopDirs
+			case 'r':
+				dirs = rookDirs
+			default:
+				dirs = append(append(dirs, bishopDirs...), rookDirs...)
+			}
+			for _, d := range dirs {
+				for r, c := row+d[0], col+d[1]; onBoard(r, c); r, c = r+d[0], c+d[1] {
+					to := r*8 + c
+					if own(b.Pos[to]) {
+						break
+					}
+					add(sq, to)
+					if b.Pos[to] != ' ' {
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return append(moves, b.castlingMoves()...)
+}
+
+func (b *Board) castlingMoves() []string {
+	if b.Chess960 {
+		return b.castlingMoves960()
+	}
+
+	white := b.ActiveColor == "w"
+
+	type castle struct {
+		right   rune
+		king    int
+		rook    int
+		empty   []int
+		safe    []int
+		uciMove string
+	}
+
+	castles := []castle{
+		{right: 'k', king: 4, rook: 7, empty: []int{5, 6}, safe: []int{4, 5, 6}, uciMove: "e8g8"},
+		{right: 'q', king: 4, rook: 0, empty: []int{1, 2, 3}, safe: []int{4, 3, 2}, uciMove: "e8c8"},
+	}
+	kingPiece, rookPiece := 'k', 'r'
+	if white {
+		castles = []castle{
+			{right: 'K', king: 60, rook: 63, empty: []int{61, 62}, safe: []int{60, 61, 62}, uciMove: "e1g1"},
+			{right: 'Q', king: 60, rook: 56, empty: []int{57, 58, 59}, safe: []int{60, 59, 58}, uciMove: "e1c1"},
+		}
+		kingPiece, rookPiece = 'K', 'R'
+	}
+
+	var moves []string
+castleLoop:
+	for _, c := range castles {
+		if !strings.ContainsRune(b.Castling, c.right) || b.Pos[c.king] != kingPiece || b.Pos[c.rook] != rookPiece {
+			continue
+		}
+		for _, sq := range c.empty {
+			if b.Pos[sq] != ' ' {
+				continue castleLoop
+			}
+		}
+		for _, sq := range c.safe {
+			// the king isn't royal when losing is the goal
+			if !b.Variant.isAntichess() && b.IsAttacked(sq, !white) {
+				continue castleLoop
+			}
+		}
+		moves = append(moves, c.uciMove)
+	}
+	return moves
+}
+
+// movePieces updates only the piece placement for a move; it's cheaper than
+// Moves and is enough to test a move for legality.
+func (b *Board) movePieces(move string) {
+	if move[1] == '@' {
+		piece := unicode.ToLower(rune(move[0]))
+		if b.ActiveColor == "w" {
+			piece = unicode.ToUpper(piece)
+		}
+		b.Pos[uciToIndex(move[2:4])] = piece
+		return
+	}
+
+	from, to := uciToIndex(move[:2]), uciToIndex(move[2:4])
+	piece := b.Pos[from]
+
+	if b.Chess960 && b.isCastle960(from, to) {
+		b.castle960(from, to)
+		return
+	}
+
+	if unicode.ToLower(piece) == 'p' && move[2:4] == b.EnPassantSquare && b.Pos[to] == ' ' {
+		// captured pawn sits beside the moving pawn
+		b.Pos[from/8*8+to%8] = ' '
+	}
+
+	b.Pos[to] = piece
+	b.Pos[from] = ' '
+
+	if len(move) > 4 {
+		promote := rune(move[4])
+		if isWhitePiece(piece) {
+			promote = unicode.ToUpper(promote)
+		}
+		b.Pos[to] = promote
+	}
+
+	if unicode.ToLower(piece) == 'k' && (to-from == 2 || from-to == 2) {
+		if to > from {
+			b.Pos[to-1], b.Pos[to+1] = b.Pos[to+1], ' '
+		} else {
+			b.Pos[to+1], b.Pos[to-2] = b.Pos[to-2], ' '
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package uci
+
+import (
+	"testing"
+)
+
+func perft(b Board, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+
+	moves := b.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+
+	var n int
+	for _, move := range moves {
+		child := b.Clone()
+		child.Moves(move)
+		n += perft(child, depth-1)
+	}
+	return n
+}
+
+func TestPerft(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen   string
+		depth int
+		want  int
+	}{
+		{fen: startPosFEN, depth: 3, want: 8902},
+		{fen: "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", depth: 3, want: 97862},
+		{fen: "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", depth: 4, want: 43238},
+		{fen: "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", depth: 3, want: 9467},
+		{fen: "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", depth: 3, want: 62379},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen, func(t *testing.T) {
+			// act
+			got := perft(FENtoBoard(c.fen), c.depth)
+
+			// assert
+			if c.want != got {
+				t.Errorf("want: %d got: %d", c.want, got)
+			}
+		})
+	}
+}
+
+func TestInCheck(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen  string
+		want bool
+	}{
+		{fen: startPosFEN, want: false},
+		{fen: "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", want: true},
+		{fen: "r1b1kbnr/pppp1ppp/2n5/4P3/1q6/5N2/PPPBPPPP/RN1QKB1R b KQkq - 6 5", want: false},
+		{fen: "r1b1kbnr/pppp1ppp/2n5/4P3/1q6/5N2/PPP1PPPP/RNBQKB1R w KQkq - 5 5", want: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen, func(t *testing.T) {
+			// act
+			b := FENtoBoard(c.fen)
+			got := b.InCheck()
+
+			// assert
+			if c.want != got {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package uci
+
+// movesToGoReserve is how many moves' worth of time is kept back at a time
+// control, so a slow GUI or a long think on the last move doesn't flag.
+const movesToGoReserve = 1
+
+// movesToGoBudget is the most time to spend on a move when the GUI sent
+// movestogo: an even share of our clock over the moves left to the time
+// control, plus most of the increment. The humanized move times assume
+// sudden death and would otherwise spend the clock before the control.
+func movesToGoBudget(ourTime, ourInc, movesToGo int) int {
+	return ourTime/(movesToGo+movesToGoReserve) + ourInc*3/4
+}
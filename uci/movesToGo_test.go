@@ -0,0 +1,20 @@
+package uci
+
+import "testing"
+
+func TestMovesToGoBudget(t *testing.T) {
+	cases := []struct {
+		ourTime, ourInc, movesToGo int
+		want                       int
+	}{
+		{ourTime: 600_000, movesToGo: 39, want: 15_000},
+		{ourTime: 600_000, ourInc: 2000, movesToGo: 39, want: 16_500},
+		{ourTime: 10_000, movesToGo: 1, want: 5000},
+	}
+
+	for _, c := range cases {
+		if got := movesToGoBudget(c.ourTime, c.ourInc, c.movesToGo); got != c.want {
+			t.Errorf("%d+%d movestogo %d: want %d got %d", c.ourTime, c.ourInc, c.movesToGo, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package uci
+
+import (
+	"fmt"
+	"sort"
+)
+
+// multiPVLines returns the lines to forward to the GUI for one batch of SF
+// output: a single line per multipv index, the deepest one, in multipv order.
+// SF reports the lines it hasn't searched yet at the current depth from the
+// previous iteration, so depth-1 is still current; anything older is stale and
+// dropped.
+func multiPVLines(moveList []Info) []Info {
+	best := make(map[int]Info, len(moveList))
+	maxDepth := 0
+	for _, move := range moveList {
+		maxDepth = max(maxDepth, move.Depth)
+
+		prev, ok := best[move.MultiPV]
+		if !ok || move.Depth > prev.Depth || (move.Depth == prev.Depth && move.Nodes > prev.Nodes) {
+			best[move.MultiPV] = move
+		}
+	}
+
+	lines := make([]Info, 0, len(best))
+	for _, move := range best {
+		if move.Depth < maxDepth-1 {
+			continue
+		}
+		lines = append(lines, move)
+	}
+
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].MultiPV < lines[j].MultiPV
+	})
+
+	return lines
+}
+
+// policyMultiPV is the MultiPV the troll policy needs: enough lines to pick a
+// playable inferior move from, just the top two once it's playing to win (a
+// few more if a draw is in sight), or only the best line while pouncing on a
+// blunder. An analysis GUI gets the MultiPV it asked for. Callers must hold
+// moveListMtx.
+func (u *UCI) policyMultiPV() int {
+	if u.analysis {
+		return max(u.analysisMultiPV, 1)
+	}
+	if u.pounce.active() {
+		return u.config.MultiPV.Pounce
+	}
+	if u.gameAgro {
+		if u.gameScore.cp() >= antiDrawMinScore && drawInSight(u.history) {
+			return u.config.MultiPV.AntiDraw
+		}
+		return u.config.MultiPV.Agro
+	}
+	return u.config.MultiPV.Default
+}
+
+// applyMultiPV sends SF the policy's MultiPV if it's not what SF already has.
+// SF ignores option changes during a search, so it's left for the next one
+// if a search is running. Callers must hold moveListMtx.
+func (u *UCI) applyMultiPV() {
+	u.gameMultiPV = u.policyMultiPV()
+	if u.searchDone != nil || u.gameMultiPV == u.sentMultiPV {
+		return
+	}
+
+	u.engine().Write(fmt.Sprintf("setoption name MultiPV value %d", u.gameMultiPV))
+	u.sentMultiPV = u.gameMultiPV
+}
+
+// setMultiPV handles MultiPV, the number of lines an analysis GUI wants. Games
+// are left to the troll policy.
+func (u *UCI) setMultiPV(value string) {
+	u.moveListMtx.Lock()
+	defer u.moveListMtx.Unlock()
+
+	u.analysisMultiPV = atoi(value)
+	u.applyMultiPV()
+}
+
+// setMultiPVConfig returns an option handler that sets one of the troll
+// policy's MultiPV values, overriding the config file until it's reloaded.
+func (u *UCI) setMultiPVConfig(set func(c *MultiPVConfig, n int)) func(string) {
+	return func(value string) {
+		u.moveListMtx.Lock()
+		defer u.moveListMtx.Unlock()
+
+		set(&u.config.MultiPV, atoi(value))
+		u.applyMultiPV()
+	}
+}
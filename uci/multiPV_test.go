@@ -0,0 +1,82 @@
+package uci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMultiPVLines(t *testing.T) {
+	moveList := []Info{
+		{Depth: 20, MultiPV: 2, Nodes: 1000, PV: "d2d4"},
+		{Depth: 20, MultiPV: 1, Nodes: 1000, PV: "e2e4"},
+		{Depth: 19, MultiPV: 3, Nodes: 1000, PV: "g1f3"},
+		{Depth: 19, MultiPV: 1, Nodes: 900, PV: "c2c4"},  // older line for multipv 1
+		{Depth: 17, MultiPV: 4, Nodes: 1000, PV: "b2b3"}, // stale
+	}
+
+	got := multiPVLines(moveList)
+	want := []Info{
+		{Depth: 20, MultiPV: 1, Nodes: 1000, PV: "e2e4"},
+		{Depth: 20, MultiPV: 2, Nodes: 1000, PV: "d2d4"},
+		{Depth: 19, MultiPV: 3, Nodes: 1000, PV: "g1f3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nwant: %v\ngot:  %v", want, got)
+	}
+
+	if got := multiPVLines(nil); len(got) != 0 {
+		t.Errorf("empty: got %v", got)
+	}
+}
+
+func TestApplyMultiPV(t *testing.T) {
+	u := &UCI{sf: noEngine{}, config: defaultConfig()}
+
+	u.applyMultiPV()
+	if u.gameMultiPV != defaultMultiPV || u.sentMultiPV != defaultMultiPV {
+		t.Fatalf("new game: game %d sent %d", u.gameMultiPV, u.sentMultiPV)
+	}
+
+	// agro during a search waits for the search to finish
+	u.searchDone = make(chan struct{})
+	u.setAgro()
+	u.applyMultiPV()
+	if u.gameMultiPV != agroMultiPV || u.sentMultiPV != defaultMultiPV {
+		t.Fatalf("searching: game %d sent %d", u.gameMultiPV, u.sentMultiPV)
+	}
+
+	u.searchDone = nil
+	u.applyMultiPV()
+	if u.sentMultiPV != agroMultiPV {
+		t.Fatalf("after search: sent %d", u.sentMultiPV)
+	}
+}
+
+func TestMultiPVOptions(t *testing.T) {
+	u, _ := newTestUCI(t)
+	eng := newFakeBackend()
+	u.sf = eng
+
+	u.SetOption("MultiPVDefault", "7")
+	u.SetOption("MultiPV", "4") // only for analysis, SF stays at 7
+	u.SetPosition("startpos", "moves", "e2e4")
+	u.Go("infinite")
+
+	want := []string{
+		"setoption name MultiPV value 7",
+		"position startpos moves e2e4",
+		"setoption name MultiPV value 4",
+		"go infinite",
+	}
+	if got := eng.sent(); !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %q\ngot:  %q", want, got)
+	}
+
+	u.moveListMtx.Lock()
+	u.analysis = false
+	got := u.policyMultiPV()
+	u.moveListMtx.Unlock()
+	if got != 7 {
+		t.Errorf("game multipv want: 7 got: %d", got)
+	}
+}
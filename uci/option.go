@@ -1,5 +1,10 @@
 package uci
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Option struct {
 	Name string
 	Type OptionType
@@ -8,6 +13,11 @@ type Option struct {
 	Min     int
 	Max     int
 	Options []string
+
+	// OnChange, if set, is called with the normalized value each time the
+	// option is set, before trollfish's own handler if the name is one of
+	// its builtin options. Buttons are called with "".
+	OnChange func(value string)
 }
 
 func (o Option) DefaultValue() string {
@@ -16,3 +26,19 @@ func (o Option) DefaultValue() string {
 	}
 	return o.Default
 }
+
+func (o Option) validate() error {
+	if o.Name == "" {
+		return errors.New("option name is empty")
+	}
+	if o.Type < OptionTypeCheck || o.Type > OptionTypeString {
+		return fmt.Errorf("option '%s': unknown type %d", o.Name, o.Type)
+	}
+	if o.Type == OptionTypeSpin && o.Min > o.Max {
+		return fmt.Errorf("option '%s': min %d > max %d", o.Name, o.Min, o.Max)
+	}
+	if o.Type == OptionTypeCombo && len(o.Options) == 0 {
+		return fmt.Errorf("option '%s': combo has no values", o.Name)
+	}
+	return nil
+}
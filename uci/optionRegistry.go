@@ -0,0 +1,222 @@
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Options is the registry of UCI options. Values are validated against the
+// option's type on Set, and subscribers are notified after a value is set,
+// so the engine, book and personality code read typed values instead of
+// parsing setoption strings themselves.
+type Options struct {
+	mtx   sync.RWMutex
+	byKey map[string]*optionValue // lower case name -> option
+	order []*optionValue
+}
+
+type optionValue struct {
+	Option
+	value string
+	subs  []func(value string)
+}
+
+func newOptions(options ...Option) (*Options, error) {
+	o := Options{byKey: make(map[string]*optionValue)}
+	for _, opt := range options {
+		if err := o.Register(opt); err != nil {
+			return nil, err
+		}
+	}
+	return &o, nil
+}
+
+// Register adds an option with its default value. Registering a name twice is
+// an error.
+func (o *Options) Register(opt Option) error {
+	if err := opt.validate(); err != nil {
+		return err
+	}
+
+	key := strings.ToLower(opt.Name)
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	if _, ok := o.byKey[key]; ok {
+		return fmt.Errorf("option '%s' already registered", opt.Name)
+	}
+
+	v := &optionValue{Option: opt, value: opt.Default}
+	if opt.OnChange != nil {
+		v.subs = append(v.subs, opt.OnChange)
+	}
+	o.byKey[key] = v
+	o.order = append(o.order, v)
+	return nil
+}
+
+// Set validates and stores a value, then notifies the option's subscribers.
+// Subscribers are notified even if the value didn't change, since options
+// forwarded to SF may not match SF's own value (Threads is set on uciok).
+func (o *Options) Set(name, value string) error {
+	o.mtx.Lock()
+	v, ok := o.byKey[strings.ToLower(name)]
+	if !ok {
+		o.mtx.Unlock()
+		return fmt.Errorf("option '%s' not found", name)
+	}
+
+	value, err := v.parse(value)
+	if err != nil {
+		o.mtx.Unlock()
+		return err
+	}
+
+	v.value = value
+	subs := append([]func(string){}, v.subs...)
+	o.mtx.Unlock()
+
+	for _, fn := range subs {
+		fn(value)
+	}
+	return nil
+}
+
+// has reports whether the named option is registered.
+func (o *Options) has(name string) bool {
+	o.mtx.RLock()
+	defer o.mtx.RUnlock()
+
+	_, ok := o.byKey[strings.ToLower(name)]
+	return ok
+}
+
+// check validates a value for the named option without setting it.
+func (o *Options) check(name, value string) error {
+	o.mtx.RLock()
+	defer o.mtx.RUnlock()
+
+	v, ok := o.byKey[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("option '%s' not found", name)
+	}
+	_, err := v.parse(value)
+	return err
+}
+
+// OnChange subscribes fn to the named option being set.
+func (o *Options) OnChange(name string, fn func(value string)) error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	v, ok := o.byKey[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("option '%s' not found", name)
+	}
+	v.subs = append(v.subs, fn)
+	return nil
+}
+
+// OnPress subscribes fn to the named button being pressed.
+func (o *Options) OnPress(name string, fn func()) error {
+	v, ok := o.Lookup(name)
+	if !ok {
+		return fmt.Errorf("option '%s' not found", name)
+	}
+	if v.Type != OptionTypeButton {
+		return fmt.Errorf("option '%s' is not a button", name)
+	}
+	return o.OnChange(name, func(string) { fn() })
+}
+
+// Lookup returns the named option's definition.
+func (o *Options) Lookup(name string) (Option, bool) {
+	o.mtx.RLock()
+	defer o.mtx.RUnlock()
+
+	v, ok := o.byKey[strings.ToLower(name)]
+	if !ok {
+		return Option{}, false
+	}
+	return v.Option, true
+}
+
+// All returns the option definitions in registration order.
+func (o *Options) All() []Option {
+	o.mtx.RLock()
+	defer o.mtx.RUnlock()
+
+	opts := make([]Option, 0, len(o.order))
+	for _, v := range o.order {
+		opts = append(opts, v.Option)
+	}
+	return opts
+}
+
+// String returns the current value of a string option, or any option's raw value.
+func (o *Options) String(name string) string {
+	o.mtx.RLock()
+	defer o.mtx.RUnlock()
+
+	v, ok := o.byKey[strings.ToLower(name)]
+	if !ok {
+		return ""
+	}
+	return v.value
+}
+
+// Bool returns the current value of a check option.
+func (o *Options) Bool(name string) bool {
+	return o.String(name) == "true"
+}
+
+// Int returns the current value of a spin option.
+func (o *Options) Int(name string) int {
+	return atoi(o.String(name))
+}
+
+// Combo returns the current value of a combo option.
+func (o *Options) Combo(name string) string {
+	return o.String(name)
+}
+
+// parse validates value against the option's type and returns it normalized.
+func (v *optionValue) parse(value string) (string, error) {
+	switch v.Type {
+	case OptionTypeCheck:
+		switch strings.ToLower(value) {
+		case "true":
+			return "true", nil
+		case "false":
+			return "false", nil
+		}
+		return "", fmt.Errorf("option '%s': value '%s' is not true or false", v.Name, value)
+	case OptionTypeSpin:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("option '%s': value '%s' is not a number", v.Name, value)
+		}
+		if n < v.Min || n > v.Max {
+			return "", fmt.Errorf("option '%s': value %d out of range [%d, %d]", v.Name, n, v.Min, v.Max)
+		}
+		return strconv.Itoa(n), nil
+	case OptionTypeCombo:
+		for _, s := range v.Options {
+			if strings.EqualFold(s, value) {
+				return s, nil
+			}
+		}
+		return "", fmt.Errorf("option '%s': value '%s' is not one of %s", v.Name, value, strings.Join(v.Options, ", "))
+	case OptionTypeButton:
+		return "", nil
+	case OptionTypeString:
+		if value == "<empty>" {
+			return "", nil
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("option '%s': unknown type %d", v.Name, v.Type)
+}
@@ -0,0 +1,74 @@
+package uci
+
+import (
+	"testing"
+)
+
+func TestOptionsSet(t *testing.T) {
+	// arrange
+	opts, err := newOptions(
+		Option{Name: "Threads", Type: OptionTypeSpin, Default: "1", Min: 1, Max: 8},
+		Option{Name: "OwnBook", Type: OptionTypeCheck, Default: "true"},
+		Option{Name: "Style", Type: OptionTypeCombo, Default: "Troll", Options: []string{"Troll", "Solid"}},
+		Option{Name: "SyzygyPath", Type: OptionTypeString, Default: ""},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var notified []string
+	if err := opts.OnChange("threads", func(value string) { notified = append(notified, value) }); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "Threads", value: "4"},
+		{name: "Threads", value: "9", wantErr: true},
+		{name: "Threads", value: "x", wantErr: true},
+		{name: "OwnBook", value: "FALSE"},
+		{name: "OwnBook", value: "no", wantErr: true},
+		{name: "style", value: "solid"},
+		{name: "Style", value: "Wild", wantErr: true},
+		{name: "SyzygyPath", value: "<empty>"},
+		{name: "Hash", value: "16", wantErr: true},
+	}
+
+	// act
+	for _, c := range cases {
+		err := opts.Set(c.name, c.value)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s=%s want error: %v got: %v", c.name, c.value, c.wantErr, err)
+		}
+	}
+
+	// assert
+	if got := opts.Int("Threads"); got != 4 {
+		t.Errorf("Threads want: 4 got: %d", got)
+	}
+	if got := opts.Bool("OwnBook"); got {
+		t.Errorf("OwnBook want: false got: %v", got)
+	}
+	if got := opts.Combo("Style"); got != "Solid" {
+		t.Errorf("Style want: Solid got: %s", got)
+	}
+	if got := opts.String("SyzygyPath"); got != "" {
+		t.Errorf("SyzygyPath want: '' got: '%s'", got)
+	}
+	if len(notified) != 1 || notified[0] != "4" {
+		t.Errorf("notifications want: [4] got: %v", notified)
+	}
+}
+
+func TestOptionsRegisterDuplicate(t *testing.T) {
+	_, err := newOptions(
+		Option{Name: "Threads", Type: OptionTypeSpin, Default: "1", Min: 1, Max: 8},
+		Option{Name: "threads", Type: OptionTypeSpin, Default: "1", Min: 1, Max: 8},
+	)
+	if err == nil {
+		t.Fatal("want: error got: nil")
+	}
+}
@@ -0,0 +1,143 @@
+package uci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultOptionStateFile is where the GUI's option values are kept between
+// sessions, in the working directory unless $TROLLFISH_STATE names another
+// file.
+const defaultOptionStateFile = "trollfish-options.json"
+
+// restoreFirst are the engine paths, restored before the options that start
+// or talk to an engine.
+var restoreFirst = map[string]bool{"EnginePath": true, "EngineType": true, "VariantEngine": true}
+
+// keptOption reports whether an option is kept for the next session. The
+// UCI_ options belong to the game the GUI sets them for (opponent, variant,
+// chess960), it sends them again for the next one.
+func keptOption(o Option) bool {
+	return o.Type != OptionTypeButton && !strings.HasPrefix(o.Name, "UCI_")
+}
+
+func optionStatePath() string {
+	if path := os.Getenv("TROLLFISH_STATE"); path != "" {
+		return path
+	}
+	return defaultOptionStateFile
+}
+
+// loadOptionState reads the saved option values. A missing file is none.
+func loadOptionState(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// saveOptionState writes the option values, replacing the file in one step
+// so a crash can't leave half of it.
+func saveOptionState(path string, values map[string]string) error {
+	b, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// restoreOptions sets the option values saved by the last session, over the
+// config file's, engine paths first. Values that no longer fit their option
+// are dropped, and so are options that aren't kept any more.
+func (u *UCI) restoreOptions() error {
+	values, err := loadOptionState(optionStatePath())
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if restoreFirst[names[i]] != restoreFirst[names[j]] {
+			return restoreFirst[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		if o, ok := u.options.Lookup(name); !ok || !keptOption(o) {
+			continue
+		}
+		if err := u.options.check(name, values[name]); err != nil {
+			continue
+		}
+		u.setUserOption(name, values[name])
+	}
+	return nil
+}
+
+// setUserOption sets an option for the GUI and remembers it for the next
+// session. Buttons, the game's UCI_ options and SF's own options aren't kept.
+func (u *UCI) setUserOption(name, value string) {
+	u.SetOption(name, value)
+
+	o, ok := u.options.Lookup(name)
+	if !ok || !keptOption(o) {
+		return
+	}
+
+	u.moveListMtx.Lock()
+	defer u.moveListMtx.Unlock()
+	if u.userOptions == nil {
+		u.userOptions = make(map[string]string)
+	}
+	u.userOptions[o.Name] = u.options.String(o.Name)
+}
+
+// saveOptions keeps the GUI's option values for the next session.
+func (u *UCI) saveOptions() {
+	u.moveListMtx.Lock()
+	values := make(map[string]string, len(u.userOptions))
+	for name, value := range u.userOptions {
+		values[name] = value
+	}
+	u.moveListMtx.Unlock()
+
+	if len(values) == 0 {
+		return
+	}
+	if err := saveOptionState(optionStatePath(), values); err != nil {
+		u.logInfo(fmt.Sprintf("ERR: save options: %v", err))
+	}
+}
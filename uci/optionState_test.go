@@ -0,0 +1,72 @@
+package uci
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOptionState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.json")
+	t.Setenv("TROLLFISH_STATE", path)
+
+	u, _ := newTestUCI(t)
+
+	u.parseLine("setoption name Troll Level value 8")
+	u.parseLine("setoption name PolyglotBook value /books/my book.bin")
+	u.parseLine("setoption name Threads value 4")
+	u.parseLine("setoption name ReloadConfig")
+	u.parseLine("setoption name Skill Level value 10")
+	u.parseLine("setoption name UCI_Opponent value GM 2700 human Magnus")
+	u.parseLine("setoption name UCI_Chess960 value true")
+	u.Quit()
+
+	values, err := loadOptionState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"Troll Level": "8", "PolyglotBook": "/books/my book.bin", "Threads": "4"}
+	if !reflect.DeepEqual(want, values) {
+		t.Fatalf("\nwant: %v\ngot:  %v", want, values)
+	}
+
+	// a value that no longer fits is dropped, and so is the last game's
+	// variant, which would be set before its engine's path is known
+	values["Troll Level"] = "11"
+	values["UCI_Variant"] = "atomic"
+	values["UCI_Opponent"] = "GM 2700 human Magnus"
+	values["VariantEngine"] = "/engines/fairy"
+	if err := saveOptionState(path, values); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err = New("test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.options.String("PolyglotBook"); got != "/books/my book.bin" {
+		t.Errorf("PolyglotBook want: '/books/my book.bin' got: '%s'", got)
+	}
+	if got := u.engineThreads(); got != 4 {
+		t.Errorf("threads want: 4 got: %d", got)
+	}
+	if got := u.config.TrollLevel; got != defaultTrollLevel {
+		t.Errorf("troll level want: %d got: %d", defaultTrollLevel, got)
+	}
+	if got := u.options.String("VariantEngine"); got != "/engines/fairy" {
+		t.Errorf("VariantEngine want: '/engines/fairy' got: '%s'", got)
+	}
+	for _, name := range []string{"UCI_Variant", "UCI_Opponent"} {
+		if o, _ := u.options.Lookup(name); u.options.String(name) != o.Default {
+			t.Errorf("%s restored: '%s'", name, u.options.String(name))
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New("test", "test"); err == nil {
+		t.Error("corrupt state file: want an error")
+	}
+}
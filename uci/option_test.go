@@ -0,0 +1,156 @@
+package uci
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewValidatesOptions(t *testing.T) {
+	cases := []struct {
+		name    string
+		option  Option
+		wantErr bool
+	}{
+		{name: "spin", option: Option{Name: "Threads", Type: OptionTypeSpin, Default: "1", Min: 1, Max: 8}},
+		{name: "string", option: Option{Name: "SyzygyPath", Type: OptionTypeString}},
+		{name: "no name", option: Option{Type: OptionTypeString}, wantErr: true},
+		{name: "no type", option: Option{Name: "PlayBad"}, wantErr: true},
+		{name: "min > max", option: Option{Name: "Threads", Type: OptionTypeSpin, Min: 8, Max: 1}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			u, err := New("trollfish", "test", c.option)
+
+			// assert
+			if c.wantErr {
+				if err == nil {
+					t.Fatal("want: error got: nil")
+				}
+				return
+			}
+			if err != nil || u == nil {
+				t.Fatalf("want: no error got: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseSetOption(t *testing.T) {
+	cases := []struct {
+		line      string
+		wantName  string
+		wantValue string
+		wantOK    bool
+	}{
+		{line: "setoption name Threads value 4", wantName: "Threads", wantValue: "4", wantOK: true},
+		{line: "setoption name Move Overhead value 300", wantName: "Move Overhead", wantValue: "300", wantOK: true},
+		{line: "setoption name SyzygyPath value /tb/3-4-5 men", wantName: "SyzygyPath", wantValue: "/tb/3-4-5 men", wantOK: true},
+		{line: "setoption name Clear Hash", wantName: "Clear Hash", wantOK: true},
+		{line: "setoption name UCI_Variant value", wantName: "UCI_Variant", wantOK: true},
+		{line: "setoption name value 4"},
+		{line: "setoption Threads 4"},
+		{line: "setoption"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.line, func(t *testing.T) {
+			name, value, ok := parseSetOption(strings.Fields(c.line)[1:])
+			if name != c.wantName || value != c.wantValue || ok != c.wantOK {
+				t.Errorf("want: '%s' '%s' %v got: '%s' '%s' %v", c.wantName, c.wantValue, c.wantOK, name, value, ok)
+			}
+		})
+	}
+}
+
+func TestSetOptionForwardsUnknown(t *testing.T) {
+	u, _ := newTestUCI(t)
+	eng := newFakeBackend()
+	u.sf = eng
+
+	u.parseLine("setoption name Skill Level value 10")
+	u.parseLine("setoption name Clear Hash")
+	u.parseLine("setoption name PolyglotBook value /books/my book.bin")
+
+	want := []string{"setoption name Skill Level value 10", "setoption name Clear Hash"}
+	if got := eng.sent(); !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %q\ngot:  %q", want, got)
+	}
+	if got := u.options.String("PolyglotBook"); got != "/books/my book.bin" {
+		t.Errorf("PolyglotBook want: '/books/my book.bin' got: '%s'", got)
+	}
+}
+
+func TestSetUCIOptionTypes(t *testing.T) {
+	u, err := New("test", "test",
+		Option{Name: "Ponder", Type: OptionTypeCheck, Default: "false"},
+		Option{Name: "Style", Type: OptionTypeCombo, Default: "Normal", Options: []string{"Solid", "Normal", "Risky"}},
+		Option{Name: "Clear Log", Type: OptionTypeButton},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	u.log, u.out = nopWriteCloser{}, &out
+
+	u.SetUCI()
+
+	for _, want := range []string{
+		"option name Ponder type check default false\n",
+		"option name Style type combo default Normal var Solid var Normal var Risky\n",
+		"option name Clear Log type button\n",
+		"option name ReloadConfig type button\n",
+		"uciok\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in:\n%s", want, out.String())
+		}
+	}
+
+	var pressed int
+	if err := u.options.OnPress("Clear Log", func() { pressed++ }); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.options.OnPress("Ponder", func() {}); err == nil {
+		t.Error("OnPress on a check: want an error")
+	}
+
+	u.parseLine("setoption name Clear Log")
+	if pressed != 1 {
+		t.Errorf("pressed want: 1 got: %d", pressed)
+	}
+}
+
+func TestOptionOnChange(t *testing.T) {
+	var styles, threads []string
+	u, err := New("test", "test",
+		Option{Name: "Style", Type: OptionTypeCombo, Default: "Normal", Options: []string{"Solid", "Normal", "Risky"},
+			OnChange: func(value string) { styles = append(styles, value) }},
+		Option{Name: "Threads", Type: OptionTypeSpin, Default: "1", Min: 1, Max: 8,
+			OnChange: func(value string) { threads = append(threads, value) }},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.log, u.out = nopWriteCloser{}, io.Discard
+
+	u.parseLine("setoption name Style value risky")
+	u.parseLine("setoption name Style value Reckless")
+	u.parseLine("setoption name Threads value 4")
+
+	if want := []string{"Risky"}; !reflect.DeepEqual(want, styles) {
+		t.Errorf("Style want: %q got: %q", want, styles)
+	}
+	if want := []string{"4"}; !reflect.DeepEqual(want, threads) {
+		t.Errorf("Threads want: %q got: %q", want, threads)
+	}
+	u.moveListMtx.Lock()
+	defer u.moveListMtx.Unlock()
+	if u.threads != 4 {
+		t.Errorf("builtin Threads handler want: 4 got: %d", u.threads)
+	}
+}
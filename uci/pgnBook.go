@@ -0,0 +1,254 @@
+package uci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// PGNBookOptions controls which moves of a PGN go into the book.
+type PGNBookOptions struct {
+	// Color is "white" or "black" to keep only that side's moves, for a
+	// repertoire; empty keeps both.
+	Color string
+
+	// Plies leaves out moves past this many plies from the start of the
+	// game; 0 keeps them all.
+	Plies int
+}
+
+// pgnBook collects replies by position, so lines that transpose into each
+// other share their replies.
+type pgnBook struct {
+	keys  []string // in the order first seen
+	lines map[string]*pgnBookLine
+}
+
+type pgnBookLine struct {
+	comment string // the moves that first reached the position
+	moves   []string
+	weights map[string]int
+}
+
+// pgnBookKey is the FEN prefix a position is filed under: placement, side to
+// move and castling rights. The en passant square is left off, so the same
+// position reached by different move orders is one line.
+func pgnBookKey(b *Board) string {
+	return strings.Join(strings.Fields(b.FEN())[:3], " ")
+}
+
+func (pb *pgnBook) add(b *Board, move string, path []string) {
+	key := pgnBookKey(b)
+	l, ok := pb.lines[key]
+	if !ok {
+		l = &pgnBookLine{comment: strings.Join(path, " "), weights: make(map[string]int)}
+		pb.lines[key] = l
+		pb.keys = append(pb.keys, key)
+	}
+	if l.weights[move] == 0 {
+		l.moves = append(l.moves, move)
+	}
+	l.weights[move]++
+}
+
+// file returns the book in the OpeningBook option's JSON form, each reply
+// weighted by how often it was played.
+func (pb *pgnBook) file() bookFile {
+	var f bookFile
+	for _, key := range pb.keys {
+		l := pb.lines[key]
+		line := bookFileLine{Comment: l.comment, FEN: key}
+		for _, move := range l.moves {
+			weight := l.weights[move]
+			line.Replies = append(line.Replies, bookFileReply{Move: move, Weight: &weight})
+		}
+		f.Lines = append(f.Lines, line)
+	}
+	return f
+}
+
+// RunPGNBook compiles the games in the PGN files, variations included, into
+// an opening book and writes it to w as JSON for the OpeningBook option.
+func RunPGNBook(paths []string, opts PGNBookOptions, w io.Writer) error {
+	switch opts.Color {
+	case "", "white", "black":
+	default:
+		return fmt.Errorf("color '%s' isn't white or black", opts.Color)
+	}
+
+	pb := pgnBook{lines: make(map[string]*pgnBookLine)}
+	for _, path := range paths {
+		fp, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		err = importPGN(fp, opts, &pb)
+		fp.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if len(pb.keys) == 0 {
+		return errors.New("no moves to put in the book")
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(pb.file())
+}
+
+// pgnFrame is the state of one line of play. A variation starts a new frame
+// from the position before the move it replaces.
+type pgnFrame struct {
+	board, prev    Board
+	path, prevPath []string
+	ply, prevPly   int
+	hasPrev        bool
+}
+
+// importPGN reads every game in r into pb.
+func importPGN(r io.Reader, opts PGNBookOptions, pb *pgnBook) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	text := string(data)
+
+	var (
+		game     = 1
+		startFEN = startPosFEN
+		inMoves  bool
+		cur      pgnFrame
+		stack    []pgnFrame
+	)
+
+	newGame := func() {
+		cur = pgnFrame{board: FENtoBoard(startFEN)}
+		stack = stack[:0]
+	}
+	endGame := func() {
+		if inMoves {
+			game++
+		}
+		inMoves = false
+		startFEN = startPosFEN
+	}
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("game %d: %s", game, fmt.Sprintf(format, args...))
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '%' && (i == 0 || text[i-1] == '\n'):
+			i = skipPast(text, i, '\n')
+		case c == ';':
+			i = skipPast(text, i, '\n')
+		case c == '{':
+			i = skipPast(text, i, '}')
+		case c == '[':
+			end := skipPast(text, i, ']')
+			if inMoves {
+				endGame()
+			}
+			tag, value := parsePGNTag(text[i:end])
+			if tag == "FEN" {
+				startFEN = value
+			}
+			i = end
+		case c == '(':
+			if !inMoves || !cur.hasPrev {
+				return fail("variation before any move")
+			}
+			stack = append(stack, cur)
+			cur = pgnFrame{board: cur.prev.Clone(), path: cur.prevPath, ply: cur.prevPly}
+			i++
+		case c == ')':
+			if len(stack) == 0 {
+				return fail("unmatched ')'")
+			}
+			cur = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			i++
+		case c == '$':
+			i = skipToken(text, i+1)
+		default:
+			end := skipToken(text, i)
+			token := text[i:end]
+			i = end
+
+			switch token {
+			case "1-0", "0-1", "1/2-1/2", "*":
+				if len(stack) != 0 {
+					return fail("unclosed variation")
+				}
+				endGame()
+				continue
+			}
+
+			// move numbers: "12." "12..." or run into the move as "12.e4"
+			token = strings.TrimLeft(token, "0123456789")
+			token = strings.TrimLeft(token, ".")
+			if token == "" {
+				continue
+			}
+
+			if !inMoves {
+				inMoves = true
+				newGame()
+			}
+
+			move, err := cur.board.ParseSAN(token)
+			if err != nil {
+				return fail("%v", err)
+			}
+
+			white := cur.board.ActiveColor == "w"
+			keep := opts.Color == "" || (opts.Color == "white") == white
+			if keep && (opts.Plies == 0 || cur.ply < opts.Plies) {
+				pb.add(&cur.board, move, cur.path)
+			}
+
+			cur.prev, cur.prevPath, cur.prevPly, cur.hasPrev = cur.board.Clone(), cur.path, cur.ply, true
+			cur.path = append(append([]string(nil), cur.path...), cur.board.SAN(move))
+			cur.board.Moves(move)
+			cur.ply++
+		}
+	}
+
+	if len(stack) != 0 {
+		return fail("unclosed variation")
+	}
+	return nil
+}
+
+// skipPast returns the index after the first end at or after i, or the end
+// of s.
+func skipPast(s string, i int, end byte) int {
+	if n := strings.IndexByte(s[i:], end); n != -1 {
+		return i + n + 1
+	}
+	return len(s)
+}
+
+// skipToken returns the index of the end of the token starting at i.
+func skipToken(s string, i int) int {
+	for i < len(s) && !unicode.IsSpace(rune(s[i])) && !strings.ContainsRune("(){};[$", rune(s[i])) {
+		i++
+	}
+	return i
+}
+
+// parsePGNTag splits `[Event "Casual"]` into its name and value.
+func parsePGNTag(s string) (string, string) {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	name, value, _ := strings.Cut(strings.TrimSpace(s), " ")
+	return name, strings.Trim(strings.TrimSpace(value), `"`)
+}
@@ -0,0 +1,135 @@
+package uci
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPGN = `[Event "Prep"]
+[White "Me"]
+
+1. e4 e5 {the main line} 2. Nf3 (2. Bc4 Nf6 $1 (2... Bc5) 3. d3) 2... Nc6 3. Bb5 1-0
+
+[Event "Transposition"]
+
+1. Nf3 Nc6 2. e4 e5 3. Bb5 a6 *
+
+% escaped line 1. d4
+[Event "Set up"]
+[SetUp "1"]
+[FEN "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"]
+
+2... d6 ; a comment
+3.d4 1/2-1/2
+`
+
+func TestImportPGN(t *testing.T) {
+	pb := pgnBook{lines: make(map[string]*pgnBookLine)}
+	if err := importPGN(strings.NewReader(testPGN), PGNBookOptions{}, &pb); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(pb.file()); err != nil {
+		t.Fatal(err)
+	}
+	book, err := parseOpeningBook(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		moves string
+		want  []string
+	}{
+		{"", []string{"e2e4", "g1f3"}},
+		{"e2e4", []string{"e7e5"}},
+		{"e2e4 e7e5", []string{"g1f3", "f1c4"}},
+		{"e2e4 e7e5 f1c4", []string{"g8f6", "f8c5"}},
+		{"e2e4 e7e5 f1c4 g8f6", []string{"d2d3"}},
+		// both games reach it, one from the set-up position
+		{"e2e4 e7e5 g1f3", []string{"b8c6", "d7d6"}},
+		{"e2e4 e7e5 g1f3 b8c6", []string{"f1b5"}},
+		{"e2e4 e7e5 g1f3 b8c6 f1b5", []string{"a7a6"}},
+		{"e2e4 e7e5 g1f3 d7d6", []string{"d2d4"}},
+		{"d2d4", nil},
+	}
+	for _, tt := range tests {
+		b := FENtoBoard(startPosFEN)
+		b.Moves(strings.Fields(tt.moves)...)
+		fen := b.FEN()
+
+		var got []string
+		for _, l := range book.lines {
+			if fenHasPrefix(fen, l.prefix) {
+				for _, r := range l.replies {
+					got = append(got, r.move)
+				}
+				break
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: want: %v got: %v", tt.moves, tt.want, got)
+		}
+	}
+
+	// the transposition counts Nc6 twice
+	key := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq"
+	if got := pb.lines[key].weights["f1b5"]; got != 2 {
+		t.Errorf("Bb5 weight: want: 2 got: %d", got)
+	}
+}
+
+func TestImportPGNOptions(t *testing.T) {
+	pb := pgnBook{lines: make(map[string]*pgnBookLine)}
+	opts := PGNBookOptions{Color: "black", Plies: 2}
+	if err := importPGN(strings.NewReader(testPGN), opts, &pb); err != nil {
+		t.Fatal(err)
+	}
+
+	var moves []string
+	for _, key := range pb.keys {
+		moves = append(moves, pb.lines[key].moves...)
+	}
+	// the set-up game's first move is its ply 0
+	if got := strings.Join(moves, " "); got != "e7e5 b8c6 d7d6" {
+		t.Errorf("want: %q got: %q", "e7e5 b8c6 d7d6", got)
+	}
+}
+
+func TestImportPGNErrors(t *testing.T) {
+	for _, pgn := range []string{
+		"1. e4 e5 2. Ke3 *",
+		"1. e4 (1. d4 *",
+		"1. e4 ) *",
+		"( 1. e4 ) *",
+	} {
+		pb := pgnBook{lines: make(map[string]*pgnBookLine)}
+		if err := importPGN(strings.NewReader(pgn), PGNBookOptions{}, &pb); err == nil {
+			t.Errorf("%s: want an error", pgn)
+		}
+	}
+}
+
+func TestRunPGNBook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prep.pgn")
+	if err := os.WriteFile(path, []byte(testPGN), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := RunPGNBook([]string{path}, PGNBookOptions{Color: "white"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseOpeningBook(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunPGNBook([]string{path}, PGNBookOptions{Color: "red"}, &buf); err == nil {
+		t.Error("want an error for color red")
+	}
+}
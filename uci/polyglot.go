@@ -0,0 +1,135 @@
+package uci
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// polyglotEntrySize is the size of a Polyglot book entry: key, move, weight
+// and learn, big-endian.
+const polyglotEntrySize = 16
+
+// polyglotBook is a Polyglot .bin book, entries sorted by key as the format
+// requires.
+type polyglotBook struct {
+	entries []polyglotEntry
+}
+
+type polyglotEntry struct {
+	key    uint64
+	move   uint16
+	weight uint16
+}
+
+func loadPolyglotBook(filename string) (*polyglotBook, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return parsePolyglotBook(fp)
+}
+
+func parsePolyglotBook(r io.Reader) (*polyglotBook, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%polyglotEntrySize != 0 {
+		return nil, fmt.Errorf("size %d isn't a multiple of %d", len(data), polyglotEntrySize)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("no entries")
+	}
+
+	book := polyglotBook{entries: make([]polyglotEntry, 0, len(data)/polyglotEntrySize)}
+	for i := 0; i < len(data); i += polyglotEntrySize {
+		e := polyglotEntry{
+			key:    binary.BigEndian.Uint64(data[i:]),
+			move:   binary.BigEndian.Uint16(data[i+8:]),
+			weight: binary.BigEndian.Uint16(data[i+10:]),
+		}
+		if n := len(book.entries); n != 0 && e.key < book.entries[n-1].key {
+			return nil, fmt.Errorf("entry %d: not sorted by key", n+1)
+		}
+		book.entries = append(book.entries, e)
+	}
+	return &book, nil
+}
+
+// polyglotMove converts a Polyglot move to UCI. Castling is stored as the king
+// taking its own rook, so it's turned back into the king's two-square move.
+func polyglotMove(b *Board, move uint16) string {
+	square := func(file, rank uint16) string {
+		return fmt.Sprintf("%c%c", 'a'+rune(file), '1'+rune(rank))
+	}
+
+	from := square(move>>6&7, move>>9&7)
+	to := square(move&7, move>>3&7)
+
+	switch piece := b.Pos[uciToIndex(from)]; {
+	case piece == 'K' && from == "e1" && to == "h1":
+		to = "g1"
+	case piece == 'K' && from == "e1" && to == "a1":
+		to = "c1"
+	case piece == 'k' && from == "e8" && to == "h8":
+		to = "g8"
+	case piece == 'k' && from == "e8" && to == "a8":
+		to = "c8"
+	}
+
+	uci := from + to
+	if promo := move >> 12 & 7; promo != 0 && promo <= 4 {
+		uci += string(" nbrq"[promo])
+	}
+	return uci
+}
+
+// Move picks a reply for the position by the book's weights, or returns "" if
+// the position isn't in the book. Moves that aren't legal here, from a key
+// collision or a broken book, are skipped.
+func (book *polyglotBook) Move(b Board) string {
+	if book == nil {
+		return ""
+	}
+
+	key := b.PolyglotKey()
+	i := sort.Search(len(book.entries), func(i int) bool {
+		return book.entries[i].key >= key
+	})
+
+	var replies []repertoireReply
+	for ; i < len(book.entries) && book.entries[i].key == key; i++ {
+		e := book.entries[i]
+		move := polyglotMove(&b, e.move)
+		if !b.IsLegal(move) {
+			continue
+		}
+		replies = append(replies, repertoireReply{move: move, weight: int(e.weight)})
+	}
+	return pickReply(replies)
+}
+
+// setPolyglotBook handles the PolyglotBook option; an empty path turns the
+// book off.
+func (u *UCI) setPolyglotBook(filename string) {
+	var book *polyglotBook
+	if filename != "" {
+		var err error
+		book, err = loadPolyglotBook(filename)
+		if err != nil {
+			u.WriteLine(fmt.Sprintf("info string ERR: polyglot book '%s': %v", filename, err))
+			return
+		}
+		u.logInfo(fmt.Sprintf("polyglot book: %s, %d entries", filename, len(book.entries)))
+	}
+
+	u.moveListMtx.Lock()
+	u.polyglot = book
+	u.moveListMtx.Unlock()
+}
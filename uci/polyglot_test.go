@@ -0,0 +1,76 @@
+package uci
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"testing"
+)
+
+func polyglotBytes(entries []polyglotEntry) []byte {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		_ = binary.Write(&buf, binary.BigEndian, e.key)
+		_ = binary.Write(&buf, binary.BigEndian, e.move)
+		_ = binary.Write(&buf, binary.BigEndian, e.weight)
+		_ = binary.Write(&buf, binary.BigEndian, uint32(0))
+	}
+	return buf.Bytes()
+}
+
+func polyglotEncode(move string) uint16 {
+	from, to := uciToIndex(move[:2]), uciToIndex(move[2:4])
+	sq := func(i int) uint16 { return uint16((7-i/8)*8 + i%8) }
+	return sq(from)<<6 | sq(to)
+}
+
+func TestPolyglotBook(t *testing.T) {
+	start := FENtoBoard(startPosFEN)
+	castle := FENtoBoard("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	blackCastle := FENtoBoard("r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1")
+	promo := FENtoBoard("8/P6k/8/8/8/8/8/K7 w - - 0 1")
+
+	entries := []polyglotEntry{
+		{key: start.PolyglotKey(), move: polyglotEncode("e2e4"), weight: 1},
+		{key: start.PolyglotKey(), move: polyglotEncode("d2d4"), weight: 0},
+		{key: start.PolyglotKey(), move: polyglotEncode("e2e5"), weight: 100}, // illegal
+		{key: castle.PolyglotKey(), move: polyglotEncode("e1h1"), weight: 1},
+		{key: blackCastle.PolyglotKey(), move: polyglotEncode("e8a8"), weight: 1},
+		{key: promo.PolyglotKey(), move: polyglotEncode("a7a8") | 4<<12, weight: 1},
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	book, err := parsePolyglotBook(bytes.NewReader(polyglotBytes(entries)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		b    Board
+		want string
+	}{
+		{start, "e2e4"},
+		{castle, "e1g1"},
+		{blackCastle, "e8c8"},
+		{promo, "a7a8q"},
+		{FENtoBoard("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"), ""},
+	}
+	for _, tt := range tests {
+		if got := book.Move(tt.b); got != tt.want {
+			t.Errorf("%s: want: %q got: %q", tt.b.FEN(), tt.want, got)
+		}
+	}
+}
+
+func TestParsePolyglotBookErrors(t *testing.T) {
+	unsorted := polyglotBytes([]polyglotEntry{{key: 2}, {key: 1}})
+	for name, data := range map[string][]byte{
+		"empty":    nil,
+		"short":    make([]byte, polyglotEntrySize+1),
+		"unsorted": unsorted,
+	} {
+		if _, err := parsePolyglotBook(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package uci
+
+import "fmt"
+
+const (
+	// pounceSwing is how much an opponent's move has to improve our eval,
+	// in centipawns, to count as a blunder.
+	pounceSwing = 200
+
+	// pounceMoves is how many of our moves pounce mode lasts, counting the
+	// one that punishes the blunder.
+	pounceMoves = 3
+
+	// pounceMultiPV has SF spend the whole search on its best line.
+	pounceMultiPV = 1
+
+	// pounceTimeFactor is how much longer than usual we think while pouncing.
+	pounceTimeFactor = 2
+)
+
+// pounce tracks our eval across the opponent's moves. When one of them hands
+// us a big swing the troll policy stands down for a few moves and SF plays
+// the punishing line at full strength; the moment to stop trolling is exactly
+// when the opponent blunders.
+type pounce struct {
+	expected  int  // clamped score of the line we last played
+	hasEval   bool // whether expected is from an SF search this game
+	movesLeft int
+}
+
+// observe compares SF's eval after the opponent's move with what we expected
+// after ours and starts pouncing on a big enough swing. It returns the swing
+// and whether it started pouncing.
+func (p *pounce) observe(engineMove Info) (int, bool) {
+	if !p.hasEval {
+		return 0, false
+	}
+
+	swing := clampScore(engineMove) - p.expected
+	if swing < pounceSwing {
+		return swing, false
+	}
+
+	p.movesLeft = pounceMoves
+	return swing, true
+}
+
+// played records the line we played, counting down the pounce.
+func (p *pounce) played(move Info) {
+	p.expected = clampScore(move)
+	p.hasEval = true
+	if p.movesLeft > 0 {
+		p.movesLeft--
+	}
+}
+
+func (p pounce) active() bool {
+	return p.movesLeft > 0
+}
+
+// checkPounce looks for an opponent blunder before the policy picks a move
+// and tells the GUI when it finds one. Callers must hold moveListMtx.
+func (u *UCI) checkPounce(engineMove Info) {
+	swing, ok := u.pounce.observe(engineMove)
+	if !ok {
+		return
+	}
+
+	u.session.pounces++
+	u.logInfo(fmt.Sprintf("pounce: swing %d eval %d mate %d", swing, engineMove.Score, engineMove.Mate))
+	u.WriteLine(fmt.Sprintf("info string pounce: opponent blundered, eval swung %+d cp, punishing with %s", swing, pvMove(engineMove.PV)))
+}
@@ -0,0 +1,61 @@
+package uci
+
+import (
+	"io"
+	"testing"
+)
+
+func TestPounce(t *testing.T) {
+	var p pounce
+
+	// nothing to compare against until we've played an SF move
+	if _, ok := p.observe(Info{Score: 500}); ok {
+		t.Fatal("pounced without an eval")
+	}
+
+	p.played(Info{Score: 30})
+	if swing, ok := p.observe(Info{Score: 120}); ok || swing != 90 {
+		t.Fatalf("small swing: swing %d pounced %v", swing, ok)
+	}
+
+	p.played(Info{Score: 20})
+	if swing, ok := p.observe(Info{Score: 350}); !ok || swing != 330 {
+		t.Fatalf("blunder: swing %d pounced %v", swing, ok)
+	}
+
+	for i := 0; i < pounceMoves; i++ {
+		if !p.active() {
+			t.Fatalf("move %d: not pouncing", i)
+		}
+		p.played(Info{Score: 350})
+	}
+	if p.active() {
+		t.Fatal("still pouncing")
+	}
+
+	// a mate out of nowhere is a blunder too
+	if _, ok := p.observe(Info{Mate: 4}); !ok {
+		t.Fatal("missed mate")
+	}
+}
+
+func TestSelectMovePounce(t *testing.T) {
+	u := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}, out: io.Discard, playBad: true}
+	u.pounce.played(Info{Score: 10})
+
+	moveList := []Info{
+		{MultiPV: 1, Score: 400, PV: "d1h5"},
+		{MultiPV: 2, Score: 20, PV: "g1f3"},
+		{MultiPV: 3, Score: -40, PV: "b1c3"},
+	}
+	got := u.selectMove(moveList, moveList[0])
+	if got.PV != "d1h5" {
+		t.Errorf("want: d1h5 got: %s", got.PV)
+	}
+	if u.session.pounces != 1 {
+		t.Errorf("pounces want: 1 got: %d", u.session.pounces)
+	}
+	if u.policyMultiPV() != pounceMultiPV {
+		t.Errorf("multipv want: %d got: %d", pounceMultiPV, u.policyMultiPV())
+	}
+}
@@ -0,0 +1,261 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PuzzleOptions configures a puzzle or STS run.
+type PuzzleOptions struct {
+	// Humanized scores the troll move selector instead of SF's best move,
+	// to measure how much tactics the personality gives up.
+	Humanized bool
+
+	// Nodes limits each search; MoveTime (ms) is used when Nodes is zero.
+	Nodes    int
+	MoveTime int
+}
+
+func (o PuzzleOptions) goArgs() []string {
+	if o.Nodes > 0 {
+		return []string{"nodes", fmt.Sprintf("%d", o.Nodes)}
+	}
+	moveTime := o.MoveTime
+	if moveTime <= 0 {
+		moveTime = 1000
+	}
+	return []string{"movetime", fmt.Sprintf("%d", moveTime)}
+}
+
+// puzzle is a position with either a forced line (lichess) or a set of best
+// and avoid moves (EPD).
+type puzzle struct {
+	id    string
+	fen   string
+	line  []string // lichess: opponent's move first, then alternating solution moves
+	best  []string
+	avoid []string
+}
+
+// PuzzleScore is the result of a puzzle run.
+type PuzzleScore struct {
+	Solved int
+	Total  int
+}
+
+func (s PuzzleScore) String() string {
+	var pct float64
+	if s.Total > 0 {
+		pct = float64(s.Solved) / float64(s.Total) * 100
+	}
+	return fmt.Sprintf("solved %d/%d (%0.1f%%)", s.Solved, s.Total, pct)
+}
+
+// RunPuzzles solves every puzzle in an EPD (bm/am) or lichess puzzle CSV file,
+// writing a line per puzzle and the final score to w.
+func RunPuzzles(ctx context.Context, path string, opts PuzzleOptions, w io.Writer) (PuzzleScore, error) {
+	puzzles, err := loadPuzzles(path)
+	if err != nil {
+		return PuzzleScore{}, err
+	}
+
+	s, err := startSearcher(ctx, func(string) {})
+	if err != nil {
+		return PuzzleScore{}, err
+	}
+	defer s.quit()
+
+	var sel *UCI
+	if opts.Humanized {
+		if sel, err = newSelector(); err != nil {
+			return PuzzleScore{}, err
+		}
+		s.setOption("MultiPV", defaultMultiPV)
+		buildEndgameTables()
+	}
+
+	var score PuzzleScore
+	for _, p := range puzzles {
+		played, ok, err := solvePuzzle(s, sel, p, opts)
+		if err != nil {
+			return score, fmt.Errorf("puzzle %s: %w", p.id, err)
+		}
+
+		score.Total++
+		result := "FAIL"
+		if ok {
+			score.Solved++
+			result = "ok"
+		}
+
+		expected := strings.Join(p.best, ",")
+		if len(p.line) > 0 {
+			expected = strings.Join(p.line[1:], " ")
+		} else if len(p.avoid) > 0 {
+			expected = strings.TrimSpace(expected + " avoid " + strings.Join(p.avoid, ","))
+		}
+		_, _ = fmt.Fprintf(w, "%s %s played %s expected %s\n", p.id, result, strings.Join(played, " "), expected)
+	}
+
+	_, _ = fmt.Fprintln(w, score)
+	return score, nil
+}
+
+func solvePuzzle(s *searcher, sel *UCI, p puzzle, opts PuzzleOptions) (played []string, ok bool, err error) {
+	b := FENtoBoard(p.fen)
+
+	if len(p.line) == 0 {
+		move, err := chooseMove(s, sel, b, opts)
+		if err != nil {
+			return nil, false, err
+		}
+		ok = (len(p.best) == 0 || hasToken(p.best, move)) && !hasToken(p.avoid, move)
+		return []string{move}, ok, nil
+	}
+
+	b.Moves(p.line[0])
+	for i := 1; i < len(p.line); i += 2 {
+		move, err := chooseMove(s, sel, b, opts)
+		if err != nil {
+			return played, false, err
+		}
+		played = append(played, move)
+
+		if move != p.line[i] {
+			// lichess accepts any mate on the last move
+			child := b.Clone()
+			child.Moves(move)
+			mate := child.InCheck() && len(child.LegalMoves()) == 0
+			return played, mate && i == len(p.line)-1, nil
+		}
+
+		b.Moves(p.line[i:min(i+2, len(p.line))]...)
+	}
+
+	return played, true, nil
+}
+
+// chooseMove runs the same pipeline as a game: endgame tables, then SF, then
+// (when humanized) the troll selector on sel.
+func chooseMove(s *searcher, sel *UCI, b Board, opts PuzzleOptions) (string, error) {
+	if opts.Humanized {
+		if move, _, ok := EndgameMove(b); ok {
+			return move, nil
+		}
+	}
+
+	res, err := s.search(b.FEN(), opts.goArgs()...)
+	if err != nil {
+		return "", err
+	}
+
+	if !opts.Humanized || len(res.Lines) == 0 {
+		return res.BestMove, nil
+	}
+
+	return sel.selectFor(b, res.Lines, res.Best()), nil
+}
+
+// newSelector is the UCI the humanized tools choose moves with: the config
+// file and options a game would have, without a GUI or an engine.
+func newSelector() (*UCI, error) {
+	u, err := New("trollfish", "tools")
+	if err != nil {
+		return nil, err
+	}
+	u.out = io.Discard
+	return u, nil
+}
+
+// selectFor runs the troll selector on SF's lines for b as if a game had just
+// reached it, so one position's choice doesn't carry over to the next.
+func (u *UCI) selectFor(b Board, lines []Info, engineMove Info) string {
+	u.moveListMtx.Lock()
+	defer u.moveListMtx.Unlock()
+
+	u.fen, u.gameActiveColor, u.gameMoveCount = b.FEN(), b.ActiveColor, atoi(b.FullMove)
+	u.gameScore = engineMove.Eval()
+	u.gameAgro = u.startAgro
+	u.pounce, u.imbalanceLeft = pounce{}, 0
+	return pvMove(u.selectMove(lines, engineMove).PV)
+}
+
+func loadPuzzles(path string) ([]puzzle, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readLichessPuzzles(fp)
+	}
+	return readEPDPuzzles(fp)
+}
+
+// readLichessPuzzles reads the lichess puzzle database format:
+// PuzzleId,FEN,Moves,Rating,...
+func readLichessPuzzles(r io.Reader) ([]puzzle, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	var puzzles []puzzle
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) < 3 || rec[0] == "PuzzleId" {
+			continue
+		}
+		moves := strings.Fields(rec[2])
+		if len(moves) < 2 {
+			return nil, fmt.Errorf("puzzle %s: expected at least 2 moves", rec[0])
+		}
+		puzzles = append(puzzles, puzzle{id: rec[0], fen: rec[1], line: moves})
+	}
+	return puzzles, nil
+}
+
+func readEPDPuzzles(r io.Reader) ([]puzzle, error) {
+	var puzzles []puzzle
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rec, err := parseEPD(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		p := puzzle{id: rec.Ops["id"], fen: rec.FEN}
+		if p.id == "" {
+			p.id = fmt.Sprintf("#%d", n)
+		}
+		if p.best, err = rec.sanMoves("bm"); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if p.avoid, err = rec.sanMoves("am"); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if len(p.best) == 0 && len(p.avoid) == 0 {
+			return nil, fmt.Errorf("line %d: no bm or am operation", n)
+		}
+		puzzles = append(puzzles, p)
+	}
+
+	return puzzles, sc.Err()
+}
@@ -0,0 +1,44 @@
+package uci
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestChooseMoveHumanized(t *testing.T) {
+	t.Setenv("TROLLFISH_STATE", filepath.Join(t.TempDir(), "state.json"))
+
+	// SF's best wins a pawn and more, the second line keeps the game level
+	search := []string{
+		"info depth 20 seldepth 28 multipv 1 score cp 180 nodes 1000000 nps 1000000 time 1000 pv e2e4 e7e5",
+		"info depth 20 seldepth 28 multipv 2 score cp 20 nodes 1000000 nps 1000000 time 1000 pv g1f3 d7d5",
+		"bestmove e2e4 ponder e7e5",
+	}
+	b := FENtoBoard(startPosFEN)
+
+	choose := func(opts PuzzleOptions) string {
+		t.Helper()
+		eng := newScriptedEngine(search)
+		defer eng.Quit()
+
+		var sel *UCI
+		if opts.Humanized {
+			var err error
+			if sel, err = newSelector(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		move, err := chooseMove(&searcher{sf: eng, logInfo: func(string) {}}, sel, b, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return move
+	}
+
+	if got := choose(PuzzleOptions{Nodes: 1000}); got != "e2e4" {
+		t.Errorf("raw, want: e2e4 got: %s", got)
+	}
+	if got := choose(PuzzleOptions{Nodes: 1000, Humanized: true}); got != "g1f3" {
+		t.Errorf("humanized, want: g1f3 got: %s", got)
+	}
+}
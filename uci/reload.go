@@ -0,0 +1,87 @@
+package uci
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+// checkConfigOptions validates the config file's UCI options so a bad one
+// doesn't leave the file half applied. Buttons are actions, not settings.
+func (u *UCI) checkConfigOptions(cfg Config) error {
+	for name, value := range cfg.Options {
+		if o, ok := u.options.Lookup(name); ok && o.Type == OptionTypeButton {
+			return fmt.Errorf("%s: option '%s' is a button", configPath(), name)
+		}
+		if err := u.options.check(name, value); err != nil {
+			return fmt.Errorf("%s: %w", configPath(), err)
+		}
+	}
+	return nil
+}
+
+// applyConfigOptions sets the config file's UCI options, which must have been
+// checked.
+func (u *UCI) applyConfigOptions(cfg Config) {
+	names := make([]string, 0, len(cfg.Options))
+	for name := range cfg.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		u.SetOption(name, cfg.Options[name])
+	}
+}
+
+// ReloadConfig reads the config file again and applies it without restarting
+// SF. The game in progress carries on; the new thresholds and move times take
+// effect from the next search. If the file can't be read the old config is
+// kept.
+func (u *UCI) ReloadConfig() error {
+	cfg, err := LoadConfig(configPath())
+	if err != nil {
+		return err
+	}
+	if err := u.checkConfigOptions(cfg); err != nil {
+		return err
+	}
+
+	u.moveListMtx.Lock()
+	// a Troll Level the GUI set outlasts the file's troll_level
+	_, guiTrollLevel := u.userOptions["Troll Level"]
+	if guiTrollLevel {
+		cfg.TrollLevel = u.config.TrollLevel
+	}
+	oldThreads, oldHash := u.engineThreads(), u.engineHash()
+	u.config = cfg
+	threads, hash := u.engineThreads(), u.engineHash()
+	u.moveListMtx.Unlock()
+
+	if !guiTrollLevel {
+		_ = u.options.Set("Troll Level", strconv.Itoa(cfg.TrollLevel))
+	}
+	u.applyConfigOptions(cfg)
+
+	if atomic.LoadInt64(&u.started) == 0 {
+		return nil
+	}
+	if threads != oldThreads || hash != oldHash {
+		u.engine().Write(fmt.Sprintf("setoption name Threads value %d", threads))
+		u.engine().Write(fmt.Sprintf("setoption name Hash value %d", hash))
+	}
+	// a new engine path switches engines, as the EnginePath option does
+	u.setEnginePath("")
+
+	u.logInfo(fmt.Sprintf("config: reloaded %s", configPath()))
+	return nil
+}
+
+// pressReloadConfig handles the ReloadConfig button, the GUI's way to do what
+// SIGHUP does.
+func (u *UCI) pressReloadConfig() {
+	if err := u.ReloadConfig(); err != nil {
+		u.WriteLine(fmt.Sprintf("info string ERR: config: %v", err))
+	}
+}
@@ -0,0 +1,78 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// repertoires are the books for each color set by the WhiteRepertoire and
+// BlackRepertoire options, for example the Smith-Morra as white and the
+// Englund as black. An option can list several books separated by ';', and
+// each new game moves on to the next one for that color.
+type repertoires struct {
+	books [2][]*openingBook // white, black
+	next  [2]int
+	game  [2]*openingBook // the books for the current game
+}
+
+func colorIndex(color string) int {
+	if color == "b" {
+		return 1
+	}
+	return 0
+}
+
+// rotate picks the books for a new game.
+func (r *repertoires) rotate() {
+	for c, books := range r.books {
+		if len(books) == 0 {
+			r.game[c] = nil
+			continue
+		}
+		r.game[c] = books[r.next[c]%len(books)]
+		r.next[c] = (r.next[c] + 1) % len(books)
+	}
+}
+
+// book returns the current game's book for color, or nil if there isn't one.
+func (r *repertoires) book(color string) *openingBook {
+	return r.game[colorIndex(color)]
+}
+
+// set replaces color's books. The first one is used until the next game,
+// and for it too.
+func (r *repertoires) set(color string, books []*openingBook) {
+	c := colorIndex(color)
+	r.books[c] = books
+	r.next[c] = 0
+	r.game[c] = nil
+	if len(books) != 0 {
+		r.game[c] = books[0]
+	}
+}
+
+// setRepertoire returns the handler for the WhiteRepertoire or
+// BlackRepertoire option. If any of the books can't be loaded the old ones
+// are kept.
+func (u *UCI) setRepertoire(color string) func(string) {
+	return func(value string) {
+		var books []*openingBook
+		for _, filename := range strings.Split(value, ";") {
+			filename = strings.TrimSpace(filename)
+			if filename == "" {
+				continue
+			}
+			book, err := loadOpeningBook(filename)
+			if err != nil {
+				u.WriteLine(fmt.Sprintf("info string ERR: repertoire '%s': %v", filename, err))
+				return
+			}
+			books = append(books, book)
+		}
+		u.logInfo(fmt.Sprintf("repertoire: %s: %d books", color, len(books)))
+
+		u.moveListMtx.Lock()
+		u.repertoires.set(color, books)
+		u.moveListMtx.Unlock()
+	}
+}
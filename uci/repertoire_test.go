@@ -0,0 +1,59 @@
+package uci
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepertoires(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	italian := write("italian.json", `{"lines": [{"moves": "e2e4 e7e5", "replies": [{"move": "f1c4"}]}]}`)
+	scotch := write("scotch.json", `{"lines": [{"moves": "e2e4 e7e5", "replies": [{"move": "d2d4"}]}]}`)
+	french := write("french.json", `{"lines": [{"moves": "e2e4", "replies": [{"move": "e7e6"}]}]}`)
+
+	u, _ := newTestUCI(t)
+	u.SetOption("WhiteRepertoire", italian+";"+scotch)
+	u.SetOption("BlackRepertoire", french)
+
+	afterE4 := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	afterE5 := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"
+
+	move := func(fen, color string) string {
+		u.fen, u.gameActiveColor = fen, color
+		return u.CasualBookMove()
+	}
+
+	// ucinewgame rotates; the first game after setting the option still
+	// gets the first book
+	for game, want := range []string{"f1c4", "f1c4", "d2d4", "f1c4"} {
+		if game != 0 {
+			u.repertoires.rotate()
+		}
+		if got := move(afterE5, "w"); got != want {
+			t.Errorf("game %d: want: %s got: %s", game+1, want, got)
+		}
+		if got := move(afterE4, "b"); got != "e7e6" {
+			t.Errorf("game %d: black: want: e7e6 got: %s", game+1, got)
+		}
+	}
+
+	// a bad file keeps the old books
+	u.SetOption("BlackRepertoire", filepath.Join(dir, "missing.json"))
+	if got := move(afterE4, "b"); got != "e7e6" {
+		t.Errorf("after a bad file: want: e7e6 got: %s", got)
+	}
+
+	u.SetOption("BlackRepertoire", "")
+	if u.repertoires.book("b") != nil {
+		t.Error("cleared: still have a black repertoire")
+	}
+}
@@ -0,0 +1,278 @@
+package uci
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replayMoveTimeout is how long a replayed move can take. SF's lines come
+// straight from the log, so only the selector's own work is waited on.
+const replayMoveTimeout = 10 * time.Second
+
+// replayIgnored are the GUI commands a replay doesn't send: quitting would
+// save the options over the real ones, the engine options would start other
+// engines, and the telemetry and book delays aren't part of the choice.
+var replayIgnored = []string{
+	"quit",
+	"setoption name EnginePath ",
+	"setoption name EngineType ",
+	"setoption name VariantEngine ",
+	"setoption name TelemetryURL ",
+	"setoption name BookDelayMin ",
+	"setoption name BookDelayMax ",
+}
+
+// ReplayOptions configures RunReplay.
+type ReplayOptions struct {
+	// Session is which session of the log to replay, counting from 1. 0 is
+	// the last one.
+	Session int
+
+	// Verbose reports every move, not just the ones that differ.
+	Verbose bool
+}
+
+// ReplayResult is the outcome of a replay.
+type ReplayResult struct {
+	Moves    int
+	Diverged int
+}
+
+// replayStep is a command from the GUI and the moves trollfish answered it
+// with, before the GUI's next command.
+type replayStep struct {
+	line  int // in the log
+	cmd   string
+	moves []string
+}
+
+// replaySession is one session of a log: what the GUI sent, SF's searches
+// and the seed the session's choices were made with.
+type replaySession struct {
+	seed     int64
+	hasSeed  bool
+	steps    []replayStep
+	searches [][]string
+}
+
+// parseReplayLog reads the sessions in a text log written at debug level.
+// A search is SF's lines up to its bestmove. An older log doesn't have SF's
+// lines, only the ones the GUI got: the last depth of each search and SF's
+// move as "sfbm", which is what the selector chose from.
+func parseReplayLog(r io.Reader) ([]replaySession, error) {
+	var sessions []replaySession
+	var s *replaySession
+
+	var engineLines, guiLines []string
+	var searching, searched bool // a "go" from the GUI, answered by a search
+	endSearch := func(lines []string) {
+		s.searches = append(s.searches, lines)
+		engineLines, guiLines, searched = nil, nil, true
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		msg := stripTimestamp(strings.TrimSpace(scanner.Text()))
+
+		if strings.HasPrefix(msg, "=====") {
+			sessions = append(sessions, replaySession{})
+			s = &sessions[len(sessions)-1]
+			engineLines, guiLines, searching = nil, nil, false
+			continue
+		}
+		if s == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(msg, "seed: "):
+			seed, err := strconv.ParseInt(strings.TrimPrefix(msg, "seed: "), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			s.seed, s.hasSeed = seed, true
+		case strings.HasPrefix(msg, "-> "):
+			cmd := strings.TrimPrefix(msg, "-> ")
+			s.steps = append(s.steps, replayStep{line: n, cmd: cmd})
+			if strings.HasPrefix(cmd, "go") {
+				searching, searched, guiLines = true, false, nil
+			}
+		case strings.HasPrefix(msg, "SF: <- info"), strings.HasPrefix(msg, "SF: <- bestmove"):
+			line := strings.TrimPrefix(msg, "SF: <- ")
+			engineLines = append(engineLines, line)
+			if strings.HasPrefix(line, "bestmove") {
+				endSearch(engineLines)
+			}
+		case strings.HasPrefix(msg, "<- "):
+			line := strings.TrimPrefix(msg, "<- ")
+			fallback := searching && !searched
+			switch {
+			case fallback && strings.HasPrefix(line, "info ") && strings.Contains(line, " pv "):
+				guiLines = append(guiLines, line)
+			case fallback && strings.HasPrefix(line, "sfbm "):
+				endSearch(append(guiLines, "bestmove "+strings.TrimPrefix(line, "sfbm ")))
+			case strings.HasPrefix(line, "bestmove "):
+				if fallback {
+					// an analysis search, passed on as SF sent it
+					endSearch(append(guiLines, line))
+				}
+				searching = false
+				if len(s.steps) > 0 {
+					step := &s.steps[len(s.steps)-1]
+					step.moves = append(step.moves, strings.Fields(line)[1])
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RunReplay replays a session of a trollfish log: the GUI's commands are sent
+// again, SF answers with its logged lines and the choices are made with the
+// logged seed. Every move that comes out different from the log is written to
+// w. The config file and saved options are today's, not the session's.
+func RunReplay(ctx context.Context, path string, opts ReplayOptions, w io.Writer) (ReplayResult, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return ReplayResult{}, err
+	}
+	sessions, err := parseReplayLog(fp)
+	_ = fp.Close()
+	if err != nil {
+		return ReplayResult{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	n := opts.Session
+	if n == 0 {
+		n = len(sessions)
+	}
+	if n < 1 || n > len(sessions) {
+		return ReplayResult{}, fmt.Errorf("%s: no session %d, the log has %d", path, n, len(sessions))
+	}
+	s := sessions[n-1]
+
+	if !s.hasSeed {
+		_, _ = fmt.Fprintln(w, "the log has no seed, book moves and move times may differ")
+	}
+	r, err := replay(ctx, s, opts.Verbose, w)
+	if err != nil {
+		return r, err
+	}
+
+	_, _ = fmt.Fprintf(w, "session %d: %d moves, %d diverged\n", n, r.Moves, r.Diverged)
+	if r.Diverged > 0 {
+		return r, fmt.Errorf("%d of %d moves diverged", r.Diverged, r.Moves)
+	}
+	return r, nil
+}
+
+func replay(ctx context.Context, s replaySession, verbose bool, w io.Writer) (ReplayResult, error) {
+	u, err := New("trollfish", "replay")
+	if err != nil {
+		return ReplayResult{}, err
+	}
+	crashDir, err := os.MkdirTemp("", "trollfish-replay")
+	if err != nil {
+		return ReplayResult{}, err
+	}
+	defer os.RemoveAll(crashDir)
+	u.crashDir = crashDir
+
+	moves := make(chan string, 64)
+	u.setOutput(&replayOutput{moves: moves})
+	u.ctx, u.cancel = context.WithCancel(ctx)
+	defer u.cancel()
+
+	eng := newScriptedEngine(s.searches...)
+	defer eng.Quit()
+	u.setEngine(eng, "replay")
+	u.SetEngineStarter(func(context.Context, string, func(string)) (EngineBackend, error) {
+		return nil, errors.New("a replay doesn't start engines")
+	})
+	go u.stockFishReadLoop(eng)
+
+	_ = u.options.Set("BookDelayMin", "0")
+	_ = u.options.Set("BookDelayMax", "0")
+	if s.hasSeed {
+		rand.Seed(s.seed)
+	}
+
+	var r ReplayResult
+	for _, step := range s.steps {
+		if replaySkips(step.cmd) {
+			continue
+		}
+		u.parseLine(step.cmd)
+
+		for _, logged := range step.moves {
+			var replayed string
+			select {
+			case replayed = <-moves:
+			case <-time.After(replayMoveTimeout):
+				return r, fmt.Errorf("line %d: no move replaying '%s'", step.line, step.cmd)
+			case <-ctx.Done():
+				return r, ctx.Err()
+			}
+
+			r.Moves++
+			where := fmt.Sprintf("line %d, move %d %s", step.line, u.gameMoveCount, u.gameActiveColor)
+			switch {
+			case replayed != logged:
+				r.Diverged++
+				_, _ = fmt.Fprintf(w, "%s: logged %s, replayed %s\n", where, logged, replayed)
+			case verbose:
+				_, _ = fmt.Fprintf(w, "%s: %s\n", where, logged)
+			}
+		}
+	}
+	return r, nil
+}
+
+func replaySkips(cmd string) bool {
+	for _, prefix := range replayIgnored {
+		if cmd == strings.TrimSpace(prefix) || strings.HasPrefix(cmd, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// replayOutput is the replay's GUI: it takes the moves trollfish plays.
+type replayOutput struct {
+	mtx   sync.Mutex
+	buf   bytes.Buffer
+	moves chan<- string
+}
+
+func (o *replayOutput) Write(p []byte) (int, error) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	o.buf.Write(p)
+	for {
+		line, err := o.buf.ReadString('\n')
+		if err != nil {
+			// not a whole line yet
+			o.buf.WriteString(line)
+			break
+		}
+		if move := strings.TrimPrefix(strings.TrimSpace(line), "bestmove "); move != strings.TrimSpace(line) {
+			o.moves <- strings.Fields(move)[0]
+		}
+	}
+	return len(p), nil
+}
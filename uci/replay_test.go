@@ -0,0 +1,53 @@
+package uci
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	logPath := runSession(t, filepath.Join("testdata", "sessions", "game.txt"))
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(b)
+	for _, want := range []string{"seed: ", "SF: <- bestmove e1g1"} {
+		if !strings.Contains(log, want) {
+			t.Fatalf("want '%s' in the log:\n%s", want, log)
+		}
+	}
+
+	var out strings.Builder
+	r, err := RunReplay(context.Background(), logPath, ReplayOptions{Verbose: true}, &out)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	if r.Moves != 1 || r.Diverged != 0 {
+		t.Errorf("want 1 move 0 diverged got %+v\n%s", r, out.String())
+	}
+
+	// a move the selector didn't choose is reported
+	bestmove := regexp.MustCompile(`(?m)<- bestmove \S+`)
+	changed := filepath.Join(t.TempDir(), "changed.log")
+	if err := os.WriteFile(changed, []byte(bestmove.ReplaceAllString(log, "<- bestmove a2a3")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	r, err = RunReplay(context.Background(), changed, ReplayOptions{}, &out)
+	if err == nil || r.Diverged != 1 {
+		t.Fatalf("want 1 diverged got %+v (%v)\n%s", r, err, out.String())
+	}
+	if !strings.Contains(out.String(), "logged a2a3, replayed ") {
+		t.Errorf("output:\n%s", out.String())
+	}
+
+	if _, err := RunReplay(context.Background(), logPath, ReplayOptions{Session: 2}, &out); err == nil {
+		t.Error("session 2 of 1: want an error")
+	}
+}
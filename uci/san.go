@@ -0,0 +1,102 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SAN converts a legal UCI move to Standard Algebraic Notation.
+func (b *Board) SAN(move string) string {
+	from, to := uciToIndex(move[:2]), uciToIndex(move[2:4])
+	piece := unicode.ToUpper(b.Pos[from])
+
+	var san strings.Builder
+
+	switch {
+	case piece == 'K' && to-from == 2:
+		san.WriteString("O-O")
+	case piece == 'K' && from-to == 2:
+		san.WriteString("O-O-O")
+	case piece == 'P':
+		capture := from%8 != to%8
+		if capture {
+			san.WriteByte(move[0])
+			san.WriteByte('x')
+		}
+		san.WriteString(move[2:4])
+		if len(move) > 4 {
+			san.WriteByte('=')
+			san.WriteRune(unicode.ToUpper(rune(move[4])))
+		}
+	default:
+		san.WriteRune(piece)
+
+		// disambiguate between identical pieces that can reach the same square
+		var sameFile, sameRank, ambiguous bool
+		for _, other := range b.LegalMoves() {
+			otherFrom := uciToIndex(other[:2])
+			if other[2:4] != move[2:4] || otherFrom == from || b.Pos[otherFrom] != b.Pos[from] {
+				continue
+			}
+			ambiguous = true
+			if otherFrom%8 == from%8 {
+				sameFile = true
+			}
+			if otherFrom/8 == from/8 {
+				sameRank = true
+			}
+		}
+		if ambiguous {
+			switch {
+			case !sameFile:
+				san.WriteByte(move[0])
+			case !sameRank:
+				san.WriteByte(move[1])
+			default:
+				san.WriteString(move[:2])
+			}
+		}
+
+		if b.Pos[to] != ' ' {
+			san.WriteByte('x')
+		}
+		san.WriteString(move[2:4])
+	}
+
+	child := b.Clone()
+	child.Moves(move)
+	if child.InCheck() {
+		if len(child.LegalMoves()) == 0 {
+			san.WriteByte('#')
+		} else {
+			san.WriteByte('+')
+		}
+	}
+
+	return san.String()
+}
+
+// ParseSAN finds the legal move matching a SAN string. Check, capture and
+// annotation marks are optional, and 0-0 castling is accepted.
+func (b *Board) ParseSAN(san string) (string, error) {
+	want := normalizeSAN(san)
+	for _, move := range b.LegalMoves() {
+		if normalizeSAN(b.SAN(move)) == want {
+			return move, nil
+		}
+	}
+	return "", fmt.Errorf("'%s' is not a legal move in '%s'", san, b.FEN())
+}
+
+func normalizeSAN(san string) string {
+	san = strings.TrimSuffix(san, "e.p.")
+	san = strings.ReplaceAll(san, "0", "O")
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '+', '#', '!', '?', 'x', '=':
+			return -1
+		}
+		return r
+	}, san)
+}
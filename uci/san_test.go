@@ -0,0 +1,45 @@
+package uci
+
+import (
+	"testing"
+)
+
+func TestSAN(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen  string
+		move string
+		want string
+	}{
+		{fen: startPosFEN, move: "g1f3", want: "Nf3"},
+		{fen: startPosFEN, move: "e2e4", want: "e4"},
+		{fen: "r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4", move: "h5f7", want: "Qxf7#"},
+		{fen: "r1bqkb1r/pp3ppp/2n1pn2/2pp4/3P4/2N1PN2/PPP1BPPP/R1BQK2R w KQkq - 2 6", move: "e1g1", want: "O-O"},
+		{fen: "r1bqkb1r/p4p1p/1p2pn2/2pp2pP/1n1P4/2N1PN2/PPP1BPP1/R1BQ1RK1 w kq g6 0 9", move: "h5g6", want: "hxg6"},
+		{fen: "r1bqkb1r/p4p1p/1p2pnP1/2pp3p/1n1P4/2N1PN2/PPP1BP2/R1BQ1RK1 w kq - 0 10", move: "g6g7", want: "g7"},
+		{fen: "r1bqkb1r/p4pP1/1p2p3/2pp3p/1n1Pn3/2N1PN2/PPP1BP2/R1BQ1RK1 w kq - 1 11", move: "g7h8q", want: "gxh8=Q"},
+		{fen: "4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", move: "a1d1", want: "Rad1"},
+		{fen: "4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", move: "f1d1", want: "Rfd1"},
+		{fen: "4k3/8/8/8/R7/8/8/R3K3 w - - 0 1", move: "a4a2", want: "R4a2"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			// act
+			b := FENtoBoard(c.fen)
+			got := b.SAN(c.move)
+			parsed, err := b.ParseSAN(c.want)
+
+			// assert
+			if c.want != got {
+				t.Errorf("SAN, want: '%s' got: '%s'", c.want, got)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.move != parsed {
+				t.Errorf("ParseSAN, want: '%s' got: '%s'", c.move, parsed)
+			}
+		})
+	}
+}
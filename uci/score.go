@@ -0,0 +1,86 @@
+package uci
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// scoreMateCP is what a mate is worth in centipawns, less the moves to it, so
+// mates compare beyond any eval and a nearer one beyond a further one.
+const scoreMateCP = 100_000
+
+// Score is an eval from one side's point of view: centipawns, or a forced
+// mate in Mate moves when Mate isn't 0, negative when it's that side getting
+// mated. Engines score for the side to move and GUIs show White's, so moving
+// between the two is always a WhitePOV call rather than a sign flip in place.
+type Score struct {
+	CP   int
+	Mate int
+}
+
+// Eval returns the line's score, for the side to move.
+func (m Info) Eval() Score {
+	return Score{CP: m.Score, Mate: m.Mate}
+}
+
+// Negate returns the score from the other side's point of view.
+func (s Score) Negate() Score {
+	return Score{CP: -s.CP, Mate: -s.Mate}
+}
+
+// WhitePOV converts a score for the side to move, color "w" or "b", to
+// White's point of view.
+func (s Score) WhitePOV(color string) Score {
+	if color == "b" {
+		return s.Negate()
+	}
+	return s
+}
+
+// cp is the score in centipawns, with mates past every eval; see scoreMateCP.
+func (s Score) cp() int {
+	switch {
+	case s.Mate > 0:
+		return scoreMateCP - s.Mate
+	case s.Mate < 0:
+		return -scoreMateCP - s.Mate
+	}
+	return s.CP
+}
+
+// String formats the score the way GUIs show one: pawns to two decimals, or
+// "M" and the moves to mate.
+func (s Score) String() string {
+	if s.Mate != 0 {
+		return fmt.Sprintf("M%d", s.Mate)
+	}
+	return fmt.Sprintf("%0.2f", float64(s.CP)/100)
+}
+
+// scoreJSON is a Score as JSON: exactly one of cp and mate, as in jsonInfo.
+type scoreJSON struct {
+	CP   *int `json:"cp,omitempty"`
+	Mate *int `json:"mate,omitempty"`
+}
+
+func (s Score) MarshalJSON() ([]byte, error) {
+	if s.Mate != 0 {
+		return json.Marshal(scoreJSON{Mate: &s.Mate})
+	}
+	return json.Marshal(scoreJSON{CP: &s.CP})
+}
+
+func (s *Score) UnmarshalJSON(b []byte) error {
+	var v scoreJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*s = Score{}
+	if v.CP != nil {
+		s.CP = *v.CP
+	}
+	if v.Mate != nil {
+		s.Mate = *v.Mate
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package uci
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	cases := []struct {
+		s     Score
+		color string
+		want  string // White's
+	}{
+		{Score{CP: 42}, "w", "0.42"},
+		{Score{CP: 42}, "b", "-0.42"},
+		{Score{CP: -130}, "b", "1.30"},
+		{Score{}, "b", "0.00"},
+		{Score{Mate: 3}, "w", "M3"},
+		{Score{Mate: 3}, "b", "M-3"},
+		{Score{Mate: -2}, "b", "M2"},
+	}
+	for _, c := range cases {
+		if got := c.s.WhitePOV(c.color).String(); got != c.want {
+			t.Errorf("%+v %s to move: want: %s got: %s", c.s, c.color, c.want, got)
+		}
+	}
+
+	// mates compare past any eval, the nearer the further
+	order := []Score{{Mate: -1}, {Mate: -5}, {CP: -5000}, {CP: 0}, {CP: 5000}, {Mate: 5}, {Mate: 1}}
+	for i := 1; i < len(order); i++ {
+		if order[i-1].cp() >= order[i].cp() {
+			t.Errorf("want %+v (%d) below %+v (%d)", order[i-1], order[i-1].cp(), order[i], order[i].cp())
+		}
+	}
+}
+
+func TestScoreJSON(t *testing.T) {
+	for s, want := range map[Score]string{
+		{CP: 42}:   `{"cp":42}`,
+		{}:         `{"cp":0}`,
+		{Mate: -3}: `{"mate":-3}`,
+	} {
+		b, err := json.Marshal(s)
+		if err != nil || string(b) != want {
+			t.Errorf("%+v: want: %s got: %s (%v)", s, want, b, err)
+		}
+		var got Score
+		if err := json.Unmarshal(b, &got); err != nil || got != s {
+			t.Errorf("%s: want: %+v got: %+v (%v)", b, s, got, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package uci
+
+const (
+	// scrambleTime is the clock both sides have to be under, in ms, for a
+	// mutual time scramble at the default Troll Level. One side low on time is handled by the regular
+	// time manager.
+	scrambleTime = 10_000
+
+	// scrambleTolerance is how much eval we'll give up for a move that's
+	// quick to find and hard to go wrong with.
+	scrambleTolerance = 150
+
+	scrambleMoveTime      = 100 // ms
+	scrambleRecaptureTime = 20  // ms
+)
+
+// isScramble is true when both clocks are under limit ms. Past that point
+// the game is decided by who flags first, so the troll policy gives way to
+// moving fast and keeping things simple.
+func isScramble(p GoParams, color string, limit int) bool {
+	if !p.HasClock() {
+		return false
+	}
+	ourTime, _, oppTime, _ := p.Clock(color)
+	return ourTime < limit && oppTime > 0 && oppTime < limit
+}
+
+// lastCapture returns the square the opponent's last move captured on.
+func lastCapture(h *History) (string, bool) {
+	if h == nil || h.Len() == 0 {
+		return "", false
+	}
+
+	before := h.start
+	if n := len(h.boards); n >= 2 {
+		before = h.boards[n-2]
+	}
+	if !before.Variant.isChess() {
+		return "", false
+	}
+
+	move := h.moves[len(h.moves)-1]
+	if !before.isCapture(move) {
+		return "", false
+	}
+	return move[2:4], true
+}
+
+// scramblePriority ranks how simple a move is to play in a scramble:
+// recaptures first, then checks, then other captures.
+func scramblePriority(b Board, move, recapture string) int {
+	if !b.Variant.isChess() || len(move) < 4 {
+		return 0
+	}
+
+	if recapture != "" && move[2:4] == recapture {
+		return 3
+	}
+	capture := b.isCapture(move)
+	after := b.Clone()
+	after.Moves(move)
+	if after.InCheck() {
+		return 2
+	}
+	if capture {
+		return 1
+	}
+	return 0
+}
+
+// scrambleMove picks, among the lines within scrambleTolerance of SF's, the
+// simplest one to play. Mating lines aren't second-guessed. Callers must
+// hold moveListMtx.
+func (u *UCI) scrambleMove(moveList []Info, engineMove Info) Info {
+	if u.history == nil || engineMove.Mate != 0 {
+		return engineMove
+	}
+
+	b := u.history.Board()
+	recapture, _ := lastCapture(u.history)
+
+	best := engineMove
+	bestPriority := scramblePriority(b, pvMove(engineMove.PV), recapture)
+	for _, line := range moveList {
+		if line.Mate != 0 || engineMove.Score-line.Score > scrambleTolerance {
+			continue
+		}
+		if priority := scramblePriority(b, pvMove(line.PV), recapture); priority > bestPriority {
+			best, bestPriority = line, priority
+		}
+	}
+	return best
+}
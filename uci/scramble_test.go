@@ -0,0 +1,85 @@
+package uci
+
+import (
+	"io"
+	"testing"
+)
+
+func TestIsScramble(t *testing.T) {
+	tests := []struct {
+		p    GoParams
+		want bool
+	}{
+		{GoParams{WTime: 8000, BTime: 6000}, true},
+		{GoParams{WTime: 8000, BTime: 60000}, false},
+		{GoParams{WTime: 60000, BTime: 6000}, false},
+		{GoParams{WTime: 8000}, false},
+		{GoParams{MoveTime: 100}, false},
+	}
+	for _, tt := range tests {
+		if got := isScramble(tt.p, "w", scrambleTime); got != tt.want {
+			t.Errorf("%v: want: %v got: %v", tt.p, tt.want, got)
+		}
+	}
+}
+
+func TestScrambleMove(t *testing.T) {
+	start := FENtoBoard("4k3/8/8/3r4/8/2N5/3P4/4K2R b - - 0 1")
+	moves := []string{"d5d2"} // black just took our pawn on d2
+
+	u := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}, out: io.Discard, scramble: true, playBad: true}
+	u.history, _ = (*History)(nil).sync(start, moves)
+
+	tests := []struct {
+		name     string
+		moveList []Info
+		want     string
+	}{
+		{
+			name: "recapture",
+			moveList: []Info{
+				{MultiPV: 1, Score: 100, PV: "h1h8"},
+				{MultiPV: 2, Score: 0, PV: "e1d2"},
+				{MultiPV: 3, Score: -50, PV: "c3b1"},
+			},
+			want: "e1d2",
+		},
+		{
+			name: "check",
+			moveList: []Info{
+				{MultiPV: 1, Score: 100, PV: "c3b1"},
+				{MultiPV: 2, Score: 0, PV: "h1h8"},
+			},
+			want: "h1h8",
+		},
+		{
+			name: "too much to give up",
+			moveList: []Info{
+				{MultiPV: 1, Score: 100, PV: "c3b1"},
+				{MultiPV: 2, Score: -100, PV: "h1h8"},
+			},
+			want: "c3b1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := u.selectMove(tt.moveList, tt.moveList[0])
+			if pvMove(got.PV) != tt.want {
+				t.Errorf("want: %s got: %s", tt.want, got.PV)
+			}
+		})
+	}
+}
+
+func TestLastCapture(t *testing.T) {
+	h, _ := (*History)(nil).sync(FENtoBoard(startPosFEN), []string{"e2e4", "d7d5", "e4d5"})
+	if sq, ok := lastCapture(h); !ok || sq != "d5" {
+		t.Errorf("want: d5 true got: %s %v", sq, ok)
+	}
+
+	h.Undo()
+	if _, ok := lastCapture(h); ok {
+		t.Error("d7d5 isn't a capture")
+	}
+}
@@ -0,0 +1,93 @@
+package uci
+
+import (
+	"strings"
+	"sync"
+)
+
+// scriptedEngine is an EngineBackend that answers like SF from a script, to
+// play through the info parser, the move selector and the time manager
+// without an engine binary: in tests, and to replay a log. uci and isready
+// get their replies, and each go gets the next search's lines, its infos and
+// bestmove. The replies are sent in order from a goroutine of its own, so
+// they can be written while the UCI holds its locks.
+type scriptedEngine struct {
+	mtx      sync.Mutex
+	lines    []string
+	searches [][]string
+
+	replies chan []string
+	output  chan string
+	exited  chan struct{}
+	quit    sync.Once
+}
+
+func newScriptedEngine(searches ...[]string) *scriptedEngine {
+	e := &scriptedEngine{
+		searches: searches,
+		replies:  make(chan []string, 64),
+		output:   make(chan string),
+		exited:   make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *scriptedEngine) run() {
+	defer close(e.output)
+
+	for {
+		select {
+		case lines := <-e.replies:
+			for _, line := range lines {
+				select {
+				case e.output <- line:
+				case <-e.exited:
+					return
+				}
+			}
+		case <-e.exited:
+			return
+		}
+	}
+}
+
+func (e *scriptedEngine) Write(s string) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if !e.Running() {
+		return
+	}
+	e.lines = append(e.lines, s)
+
+	switch cmd := strings.Fields(s + " ")[0]; cmd {
+	case "uci":
+		e.replies <- []string{"id name scripted", "uciok"}
+	case "isready":
+		e.replies <- []string{"readyok"}
+	case "go":
+		if len(e.searches) == 0 {
+			return
+		}
+		e.replies <- e.searches[0]
+		e.searches = e.searches[1:]
+	}
+}
+
+func (e *scriptedEngine) Output() <-chan string   { return e.output }
+func (e *scriptedEngine) Exited() <-chan struct{} { return e.exited }
+func (e *scriptedEngine) Err() error              { return nil }
+
+func (e *scriptedEngine) Quit() {
+	e.quit.Do(func() { close(e.exited) })
+}
+
+func (e *scriptedEngine) Running() bool {
+	select {
+	case <-e.exited:
+		return false
+	default:
+		return true
+	}
+}
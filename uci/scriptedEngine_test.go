@@ -0,0 +1,87 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// sent returns the commands the engine was sent that start with prefix.
+func (e *scriptedEngine) sent(prefix string) []string {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	var lines []string
+	for _, line := range e.lines {
+		if strings.HasPrefix(line, prefix) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestScriptedSearch(t *testing.T) {
+	// the Ruy Lopez, white to move; none of the lines is a blunder for the
+	// selector to avoid
+	search := []string{
+		"info depth 18 seldepth 24 multipv 1 score cp 42 nodes 900000 nps 900000 time 1000 pv e1g1 f8e7 f1e1",
+		"info depth 18 seldepth 22 multipv 2 score cp 35 nodes 900000 nps 900000 time 1000 pv d2d3 b7b5 a4b3",
+		"info depth 18 seldepth 21 multipv 3 score cp 30 nodes 900000 nps 900000 time 1000 pv b1c3 b7b5 a4b3",
+		"bestmove e1g1 ponder f8e7",
+	}
+	candidates := map[string]bool{"e1g1": true, "d2d3": true, "b1c3": true}
+
+	u, out := newTestUCI(t)
+
+	eng := newScriptedEngine(search)
+	u.sf = eng
+	go u.stockFishReadLoop(eng)
+
+	u.SetPosition("startpos", "moves", "e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6", "b5a4", "g8f6")
+	u.Go("wtime", "60000", "btime", "60000")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "bestmove ") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no bestmove:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := out.String()
+
+	// the time manager kept to a 20th of the clock
+	gos := eng.sent("go ")
+	if len(gos) != 1 {
+		t.Fatalf("want 1 go got %q", gos)
+	}
+	p, err := ParseGoParams(strings.Fields(strings.TrimPrefix(gos[0], "go ")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MoveTime < 5 || p.MoveTime > 60_000/20 || p.HasClock() {
+		t.Errorf("time manager: %s", gos[0])
+	}
+
+	// the parsed lines were passed on, and the move picked from them
+	for _, pv := range []string{"pv e1g1 f8e7 f1e1", "pv d2d3 b7b5 a4b3", "pv b1c3 b7b5 a4b3"} {
+		if !strings.Contains(got, pv) {
+			t.Errorf("want %q in:\n%s", pv, got)
+		}
+	}
+	var move string
+	for _, line := range strings.Split(got, "\n") {
+		if m := strings.TrimPrefix(line, "bestmove "); m != line {
+			move = strings.Fields(m)[0]
+		}
+	}
+	if !candidates[move] {
+		t.Errorf("bestmove %s isn't one of SF's lines:\n%s", move, got)
+	}
+	if !strings.Contains(got, "sfbm e1g1") {
+		t.Errorf("want SF's move reported:\n%s", got)
+	}
+
+	if n := len(eng.sent("position ")); n != 1 {
+		t.Errorf("want 1 position got %d", n)
+	}
+}
@@ -0,0 +1,158 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// searcher runs synchronous searches on its own engine instance. It's used by
+// the command-line tools, which don't have a GUI on the other end.
+type searcher struct {
+	sf      EngineBackend
+	logInfo func(string)
+}
+
+type searchResult struct {
+	BestMove string
+	Lines    []Info // last line reported for each multipv, in multipv order
+}
+
+// Best returns SF's main line, or just the best move if no PV was reported.
+func (r searchResult) Best() Info {
+	if len(r.Lines) > 0 {
+		return r.Lines[0]
+	}
+	return Info{PV: r.BestMove}
+}
+
+func startSearcher(ctx context.Context, logInfo func(string)) (*searcher, error) {
+	return startSearcherPath(ctx, defaultEnginePath(), logInfo)
+}
+
+func startSearcherPath(ctx context.Context, path string, logInfo func(string)) (*searcher, error) {
+	sf, err := StartStockfish(ctx, path, logInfo)
+	if err != nil {
+		return nil, err
+	}
+	return startSearcherBackend(sf, logInfo)
+}
+
+// startSearcherBackend starts a searcher on an engine that's already running.
+func startSearcherBackend(sf EngineBackend, logInfo func(string)) (*searcher, error) {
+	s := searcher{sf: sf, logInfo: logInfo}
+
+	sf.Write("uci")
+	if _, err := s.waitFor("uciok"); err != nil {
+		sf.Quit()
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (s *searcher) setOption(name string, value interface{}) {
+	s.sf.Write(fmt.Sprintf("setoption name %s value %v", name, value))
+}
+
+// ready blocks until the engine has processed everything sent so far.
+func (s *searcher) ready() error {
+	s.sf.Write("isready")
+	_, err := s.waitFor("readyok")
+	return err
+}
+
+// waitFor reads engine output until a line starting with cmd, returning the
+// lines read before it.
+func (s *searcher) waitFor(cmd string) ([]string, error) {
+	var lines []string
+	for {
+		select {
+		case line, ok := <-s.sf.Output():
+			if !ok {
+				return nil, fmt.Errorf("engine exited waiting for '%s'", cmd)
+			}
+			line = strings.TrimSpace(line)
+			if line == cmd || strings.HasPrefix(line, cmd+" ") {
+				return append(lines, line), nil
+			}
+			lines = append(lines, line)
+		case <-s.sf.Exited():
+			return nil, fmt.Errorf("engine exited waiting for '%s'", cmd)
+		}
+	}
+}
+
+// search runs "go" with the given arguments on fen and waits for bestmove.
+func (s *searcher) search(fen string, goArgs ...string) (searchResult, error) {
+	return s.stream(context.Background(), fen, goArgs, nil)
+}
+
+// stream runs "go" on fen like search, sending each PV line to updates as SF
+// reports it. Cancelling ctx stops the search, which still returns SF's
+// answer.
+func (s *searcher) stream(ctx context.Context, fen string, goArgs []string, updates chan<- Info) (searchResult, error) {
+	s.sf.Write("position fen " + fen)
+	s.sf.Write("go " + strings.Join(goArgs, " "))
+
+	byMultiPV := make(map[int]Info)
+	var result searchResult
+
+	stop := ctx.Done()
+	for {
+		var line string
+		select {
+		case <-stop:
+			s.sf.Write("stop")
+			stop = nil
+			continue
+		case l, ok := <-s.sf.Output():
+			if !ok {
+				return searchResult{}, errors.New("engine exited waiting for 'bestmove'")
+			}
+			line = strings.TrimSpace(l)
+		case <-s.sf.Exited():
+			return searchResult{}, errors.New("engine exited waiting for 'bestmove'")
+		}
+
+		parts := strings.Split(line, " ")
+		switch parts[0] {
+		case "info":
+			if len(parts) < 2 || parts[1] == "string" {
+				continue
+			}
+			info, err := parseInfo(parts, s.logInfo)
+			if err != nil {
+				s.logInfo(fmt.Sprintf("ERR: %v: %s", err, line))
+				continue
+			}
+			if info.PV == "" {
+				continue
+			}
+			byMultiPV[info.MultiPV] = info
+			if updates != nil {
+				select {
+				case updates <- info:
+				case <-ctx.Done():
+				}
+			}
+		case "bestmove":
+			if len(parts) > 1 {
+				result.BestMove = parts[1]
+			}
+			for _, info := range byMultiPV {
+				result.Lines = append(result.Lines, info)
+			}
+			sort.Slice(result.Lines, func(i, j int) bool {
+				return result.Lines[i].MultiPV < result.Lines[j].MultiPV
+			})
+			return result, nil
+		}
+	}
+}
+
+func (s *searcher) quit() {
+	s.sf.Quit()
+}
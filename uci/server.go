@@ -0,0 +1,97 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+)
+
+// Serve runs the engine as a long-lived server, accepting UCI sessions on ln
+// one at a time. SF stays up between sessions, so clients can attach and
+// detach without paying for engine startup. A client's "quit" ends its
+// session, not the server. Serve returns when ctx is done or Quit is called.
+func (u *UCI) Serve(ctx context.Context, ln net.Listener) error {
+	if !atomic.CompareAndSwapInt64(&u.started, 0, 1) {
+		return errors.New("engine already started")
+	}
+
+	u.setOutput(io.Discard)
+	if err := u.startEngine(ctx, false); err != nil {
+		atomic.StoreInt64(&u.started, 0)
+		return err
+	}
+
+	go func() {
+		<-u.ctx.Done()
+		_ = ln.Close()
+	}()
+
+	// get SF initialized before the first client shows up
+	u.engine().Write("uci")
+	u.engine().Write("isready")
+
+	u.logInfo(fmt.Sprintf("listening on %s", ln.Addr()))
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if u.ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		u.serveSession(conn)
+	}
+}
+
+func (u *UCI) serveSession(conn net.Conn) {
+	defer u.recoverCrash()
+	defer conn.Close()
+
+	u.logInfo(fmt.Sprintf("session started: %s", conn.RemoteAddr()))
+
+	// a client that doesn't send ucinewgame still starts a new game
+	u.ResetGame()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-u.ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	u.setOutput(conn)
+
+	r := bufio.NewScanner(conn)
+	for r.Scan() {
+		line := r.Text()
+		if strings.TrimSpace(line) == "quit" {
+			u.logDebug("-> quit")
+			break
+		}
+		u.parseLine(line)
+	}
+
+	// the next client shouldn't inherit a search or a game report
+	u.stopSearch()
+	u.reportGame()
+
+	u.setOutput(io.Discard)
+
+	u.logInfo("session ended")
+}
+
+func (u *UCI) setOutput(w io.Writer) {
+	u.mtxStdout.Lock()
+	defer u.mtxStdout.Unlock()
+	u.out = w
+}
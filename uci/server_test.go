@@ -0,0 +1,85 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServeNewSession(t *testing.T) {
+	t.Setenv("TROLLFISH_STATE", filepath.Join(t.TempDir(), "state.json"))
+
+	u, _ := newTestUCI(t)
+	dir := t.TempDir()
+	u.crashDir = dir
+	if err := u.SetLogSinks("file=" + filepath.Join(dir, "trollfish.log")); err != nil {
+		t.Fatal(err)
+	}
+	u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+		return newScriptedEngine(), nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- u.Serve(ctx, ln) }()
+
+	session := func(lines ...string) {
+		t.Helper()
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		for _, line := range append(lines, "isready") {
+			_, _ = fmt.Fprintln(conn, line)
+		}
+		r := bufio.NewScanner(conn)
+		for r.Scan() && r.Text() != "readyok" {
+		}
+		if err := r.Err(); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fmt.Fprintln(conn, "quit")
+	}
+
+	session("setoption name StartAgro value false", "position startpos moves e2e4 e7e5")
+	u.moveListMtx.Lock()
+	u.gameAgro = true
+	u.gameScore = Score{CP: 300}
+	u.moveListMtx.Unlock()
+
+	// the next client gets a new game, whatever the last one left behind
+	session()
+	u.moveListMtx.Lock()
+	if u.history != nil || u.gameAgro || u.gameScore != (Score{}) {
+		t.Errorf("want a new game got history: %v agro: %v score: %+v", u.history != nil, u.gameAgro, u.gameScore)
+	}
+	u.moveListMtx.Unlock()
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartAgroOption(t *testing.T) {
+	u, _ := newTestUCI(t)
+
+	for _, value := range []string{"true", "false"} {
+		u.SetOption("StartAgro", value)
+		if got := fmt.Sprint(u.gameAgro); got != value {
+			t.Errorf("StartAgro %s, want agro: %s got: %s", value, value, got)
+		}
+	}
+}
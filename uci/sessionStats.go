@@ -0,0 +1,66 @@
+package uci
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sessionStats are the counters behind the session summary printed on quit.
+type sessionStats struct {
+	games           int
+	results         map[string]int // "1-0", "0-1", "1/2-1/2"
+	moves           int
+	bookMoves       int
+	agroActivations int
+	pounces         int
+	engineRestarts  int
+}
+
+func (s *sessionStats) addResult(result string) {
+	if s.results == nil {
+		s.results = make(map[string]int)
+	}
+	s.results[result]++
+}
+
+func (s sessionStats) bookHitRate() float64 {
+	if s.moves == 0 {
+		return 0
+	}
+	return float64(s.bookMoves) / float64(s.moves) * 100
+}
+
+func (s sessionStats) resultsString() string {
+	if len(s.results) == 0 {
+		return "none"
+	}
+
+	results := make([]string, 0, len(s.results))
+	for result, n := range s.results {
+		results = append(results, fmt.Sprintf("%s:%d", result, n))
+	}
+	sort.Strings(results)
+	return strings.Join(results, ",")
+}
+
+// setAgro turns on agro for the rest of the game. Callers must hold moveListMtx.
+func (u *UCI) setAgro() {
+	if !u.gameAgro {
+		u.session.agroActivations++
+	}
+	u.gameAgro = true
+}
+
+// sessionSummary emits the session's counters. Called on quit.
+func (u *UCI) sessionSummary() {
+	u.moveListMtx.Lock()
+	s := u.session
+	avgMoveTime := u.latency.total.avg()
+	u.moveListMtx.Unlock()
+
+	u.WriteLine(fmt.Sprintf("info string session summary: games %d results %s moves %d avg_move_time %dms agro_activations %d pounces %d book_moves %d (%0.1f%%) engine_restarts %d",
+		s.games, s.resultsString(), s.moves, avgMoveTime.Milliseconds(),
+		s.agroActivations, s.pounces, s.bookMoves, s.bookHitRate(), s.engineRestarts,
+	))
+}
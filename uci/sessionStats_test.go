@@ -0,0 +1,42 @@
+package uci
+
+import (
+	"testing"
+)
+
+func TestSessionStats(t *testing.T) {
+	// arrange
+	s := sessionStats{moves: 40, bookMoves: 10}
+
+	// act
+	s.addResult("1-0")
+	s.addResult("1/2-1/2")
+	s.addResult("1-0")
+
+	// assert
+	if got := s.bookHitRate(); got != 25 {
+		t.Errorf("book hit rate, want: 25 got: %0.1f", got)
+	}
+
+	want := "1-0:2,1/2-1/2:1"
+	if got := s.resultsString(); got != want {
+		t.Errorf("results, want: %s got: %s", want, got)
+	}
+
+	if got := (sessionStats{}).resultsString(); got != "none" {
+		t.Errorf("no results, want: none got: %s", got)
+	}
+}
+
+func TestSetAgroCountsActivations(t *testing.T) {
+	var u UCI
+
+	u.setAgro()
+	u.setAgro()
+	u.gameAgro = false
+	u.setAgro()
+
+	if u.session.agroActivations != 2 {
+		t.Errorf("want: 2 got: %d", u.session.agroActivations)
+	}
+}
@@ -0,0 +1,214 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A session is a script of a UCI session from the GUI's side, in
+// testdata/sessions, played through the whole UCI loop against a
+// scriptedEngine. Each line is one of:
+//
+//	> uci                  sent to trollfish
+//	< uciok                a line trollfish has to write, exactly
+//	<~ ^bestmove (a|b)     a line trollfish has to write, matching the regexp
+//	sf> info depth 1 ...   the engine's next search; bestmove ends it
+//	# a comment
+//
+// Expected lines have to come in order, but other lines can come between
+// them: the GUI gets info strings and lines that depend on the clock.
+type session struct {
+	steps    []sessionStep
+	searches [][]string
+}
+
+type sessionStep struct {
+	line   int
+	send   string
+	expect string
+	re     *regexp.Regexp
+}
+
+func (s sessionStep) matches(line string) bool {
+	if s.re != nil {
+		return s.re.MatchString(line)
+	}
+	return line == s.expect
+}
+
+func (s sessionStep) String() string {
+	if s.re != nil {
+		return fmt.Sprintf("line %d: a line matching '%s'", s.line, s.re)
+	}
+	return fmt.Sprintf("line %d: '%s'", s.line, s.expect)
+}
+
+func parseSession(r io.Reader) (session, error) {
+	var tr session
+	var search []string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		prefix, rest, ok := strings.Cut(line, " ")
+		if !ok {
+			return session{}, fmt.Errorf("line %d: expected '<prefix> <line>'", n)
+		}
+		step := sessionStep{line: n}
+		switch prefix {
+		case ">":
+			step.send = rest
+		case "<":
+			step.expect = rest
+		case "<~":
+			re, err := regexp.Compile(rest)
+			if err != nil {
+				return session{}, fmt.Errorf("line %d: %w", n, err)
+			}
+			step.re = re
+		case "sf>":
+			search = append(search, rest)
+			if strings.HasPrefix(rest, "bestmove") {
+				tr.searches = append(tr.searches, search)
+				search = nil
+			}
+			continue
+		default:
+			return session{}, fmt.Errorf("line %d: unknown prefix '%s'", n, prefix)
+		}
+		tr.steps = append(tr.steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return session{}, err
+	}
+	if len(search) > 0 {
+		return session{}, fmt.Errorf("engine search without a bestmove: %q", search)
+	}
+	return tr, nil
+}
+
+// runSession plays the session at path and fails t on the first line
+// that doesn't come. It returns once trollfish has quit, so the options are
+// saved to the test's state file and the session's debug log is complete.
+func runSession(t *testing.T, path string) string {
+	fp, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := parseSession(fp)
+	_ = fp.Close()
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+
+	dir := t.TempDir()
+	t.Setenv("TROLLFISH_STATE", filepath.Join(dir, "trollfish-options.json"))
+	t.Setenv("TROLLFISH_CONFIG", filepath.Join(dir, "trollfish.toml"))
+
+	u, _ := newTestUCI(t)
+	u.crashDir = dir
+	logPath := filepath.Join(dir, "trollfish.log")
+	if err := u.SetLogSinks("file=" + logPath + ":debug"); err != nil {
+		t.Fatal(err)
+	}
+	eng := newScriptedEngine(tr.searches...)
+	u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+		return eng, nil
+	})
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	u.SetIO(inR, outW)
+	defer inW.Close()
+	defer outR.Close()
+
+	ctx, cancel, err := u.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lines := make(chan string, 512)
+	go func() {
+		defer close(lines)
+		r := bufio.NewScanner(outR)
+		for r.Scan() {
+			lines <- r.Text()
+		}
+	}()
+
+	var got []string
+	var sent string
+	for _, step := range tr.steps {
+		if step.send != "" {
+			sent = step.send
+			if _, err := io.WriteString(inW, step.send+"\n"); err != nil {
+				t.Fatalf("line %d: send '%s': %v", step.line, step.send, err)
+			}
+			continue
+		}
+
+		timeout := time.After(5 * time.Second)
+	wait:
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("output ended waiting for %s\ngot:\n%s", step, strings.Join(got, "\n"))
+				}
+				got = append(got, line)
+				if step.matches(line) {
+					break wait
+				}
+			case <-timeout:
+				t.Fatalf("timed out waiting for %s\ngot:\n%s", step, strings.Join(got, "\n"))
+			}
+		}
+	}
+
+	if sent != "quit" {
+		if _, err := io.WriteString(inW, "quit\n"); err != nil {
+			t.Fatalf("send 'quit': %v", err)
+		}
+	}
+	go func() {
+		// the output has to be read for quit's reports to be written
+		for range lines {
+		}
+	}()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for quit")
+	}
+	return logPath
+}
+
+func TestSessions(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "sessions", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no sessions")
+	}
+
+	for _, path := range paths {
+		path := path
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".txt"), func(t *testing.T) {
+			runSession(t, path)
+		})
+	}
+}
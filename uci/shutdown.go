@@ -0,0 +1,91 @@
+package uci
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// stopTimeout is how long SF has to answer "stop" with a bestmove.
+const stopTimeout = 2 * time.Second
+
+// finishSearch is called once bestmove has been sent to the GUI, ending the
+// search started by Go.
+func (u *UCI) finishSearch() {
+	u.moveListMtx.Lock()
+	defer u.moveListMtx.Unlock()
+
+	u.recordLatency()
+	u.session.moves++
+
+	if u.searchDone != nil {
+		close(u.searchDone)
+		u.searchDone = nil
+	}
+}
+
+// Shutdown is the graceful shutdown sequence for signals: stop the search in
+// progress and emit the bestmove we owe the GUI, then quit the engine and
+// flush the log.
+func (u *UCI) Shutdown() {
+	if atomic.LoadInt64(&u.started) == 0 {
+		return
+	}
+
+	u.logInfo("shutting down")
+
+	u.stopSearch()
+	u.Quit()
+}
+
+// stopSearch stops the search in progress, if any, and waits for its bestmove
+// to be sent.
+func (u *UCI) stopSearch() {
+	u.stopBookDelay()
+
+	u.moveListMtx.Lock()
+	done := u.searchDone
+	u.moveListMtx.Unlock()
+
+	if done == nil {
+		return
+	}
+
+	u.engine().Write("stop")
+	u.awaitStop(done, stopTimeout)
+}
+
+// awaitStop waits for the stopped search to send its bestmove. GUIs forfeit
+// an engine that goes quiet after stop, so if SF hasn't answered by timeout
+// the best line so far is played and SF's bestmove, if it ever comes, is
+// dropped.
+func (u *UCI) awaitStop(done chan struct{}, timeout time.Duration) {
+	select {
+	case <-done:
+		return
+	case <-time.After(timeout):
+	}
+
+	u.moveListMtx.Lock()
+	if u.searchDone != done {
+		u.moveListMtx.Unlock()
+		return
+	}
+	u.staleBestMoves++
+	var best Info
+	if moveList := restrictToSearchMoves(u.moveList, u.searchMoves); len(moveList) > 0 {
+		best = moveList[0]
+	}
+	u.analysis = false
+	u.moveList = nil
+	u.moveListPrinted = false
+	u.moveListNodes = 0
+	u.moveListMtx.Unlock()
+
+	u.logInfo("ERR: no bestmove after stop")
+	if best.PV == "" {
+		u.playFallbackMove("no bestmove after stop")
+		return
+	}
+	u.WriteLine("bestmove " + pvMove(best.PV))
+	u.finishSearch()
+}
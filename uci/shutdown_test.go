@@ -0,0 +1,53 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAwaitStop(t *testing.T) {
+	u, out := newTestUCI(t)
+
+	eng := newFakeBackend()
+	u.sf = eng
+	loopDone := make(chan struct{})
+	go func() {
+		u.stockFishReadLoop(eng)
+		close(loopDone)
+	}()
+
+	u.SetPosition("fen", "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4")
+	u.Go("wtime", "60000", "btime", "60000")
+	eng.output <- "info depth 10 seldepth 12 multipv 1 score cp 30 nodes 5000 nps 1000 time 5 pv d2d3 f8c5"
+
+	u.moveListMtx.Lock()
+	done := u.searchDone
+	u.moveListMtx.Unlock()
+
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		u.moveListMtx.Lock()
+		n := len(u.moveList)
+		u.moveListMtx.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("info line not read")
+		}
+	}
+
+	// SF never answers the stop
+	u.awaitStop(done, 10*time.Millisecond)
+	if !strings.Contains(out.String(), "bestmove d2d3\n") {
+		t.Fatalf("want bestmove d2d3 in:\n%s", out.String())
+	}
+
+	// its late bestmove is dropped
+	eng.output <- "bestmove d2d3 ponder f8c5"
+	eng.Quit()
+	<-loopDone
+	if n := strings.Count(out.String(), "bestmove "); n != 1 {
+		t.Errorf("want 1 bestmove got %d:\n%s", n, out.String())
+	}
+}
@@ -0,0 +1,16 @@
+package uci
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// redirectStderr points fd 2 at f, so runtime panics and fatal errors land in
+// it. Dup3 rather than Dup2, which linux/arm64 doesn't have.
+func redirectStderr(f *os.File) error {
+	if err := syscall.Dup3(int(f.Fd()), int(os.Stderr.Fd()), 0); err != nil {
+		return fmt.Errorf("redirect stderr to file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package uci
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// redirectStderr points fd 2 at f, so runtime panics and fatal errors land in
+// it. Plan 9's dup takes the fd to replace.
+func redirectStderr(f *os.File) error {
+	if _, err := syscall.Dup(int(f.Fd()), int(os.Stderr.Fd())); err != nil {
+		return fmt.Errorf("redirect stderr to file: %w", err)
+	}
+	return nil
+}
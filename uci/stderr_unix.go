@@ -0,0 +1,18 @@
+//go:build !linux && !windows && !plan9
+
+package uci
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// redirectStderr points fd 2 at f, so runtime panics and fatal errors land in
+// it.
+func redirectStderr(f *os.File) error {
+	if err := syscall.Dup2(int(f.Fd()), int(os.Stderr.Fd())); err != nil {
+		return fmt.Errorf("redirect stderr to file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package uci
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// setStdHandle isn't in syscall.
+var setStdHandle = syscall.NewLazyDLL("kernel32.dll").NewProc("SetStdHandle")
+
+// redirectStderr makes f the process's standard error handle, which the
+// runtime writes panics and fatal errors to. The handle is duplicated since
+// the caller closes f.
+func redirectStderr(f *os.File) error {
+	p, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return fmt.Errorf("redirect stderr to file: %w", err)
+	}
+	var h syscall.Handle
+	if err := syscall.DuplicateHandle(p, syscall.Handle(f.Fd()), p, &h, 0, true, syscall.DUPLICATE_SAME_ACCESS); err != nil {
+		return fmt.Errorf("redirect stderr to file: %w", err)
+	}
+	std := int32(syscall.STD_ERROR_HANDLE) // negative, sign extended
+	if ok, _, err := setStdHandle.Call(uintptr(std), uintptr(h)); ok == 0 {
+		_ = syscall.CloseHandle(h)
+		return fmt.Errorf("redirect stderr to file: %w", err)
+	}
+	os.Stderr = os.NewFile(uintptr(h), f.Name())
+	return nil
+}
@@ -0,0 +1,157 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// stsMaxPoints is what the best move in a Strategic Test Suite position is worth.
+const stsMaxPoints = 10
+
+type stsPosition struct {
+	id     string
+	suite  string
+	fen    string
+	points map[string]int // UCI move -> points
+}
+
+// STSScore is the result of a Strategic Test Suite run.
+type STSScore struct {
+	Points int
+	Max    int
+	Suites map[string]STSScore
+}
+
+func (s STSScore) String() string {
+	var pct float64
+	if s.Max > 0 {
+		pct = float64(s.Points) / float64(s.Max) * 100
+	}
+	return fmt.Sprintf("%d/%d (%0.1f%%)", s.Points, s.Max, pct)
+}
+
+// RunSTS scores every position in a Strategic Test Suite EPD file. STS scores
+// are only comparable at a fixed node count, so opts.Nodes should be set.
+func RunSTS(ctx context.Context, path string, opts PuzzleOptions, w io.Writer) (STSScore, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return STSScore{}, err
+	}
+	positions, err := readSTS(fp)
+	_ = fp.Close()
+	if err != nil {
+		return STSScore{}, err
+	}
+
+	s, err := startSearcher(ctx, func(string) {})
+	if err != nil {
+		return STSScore{}, err
+	}
+	defer s.quit()
+
+	var sel *UCI
+	if opts.Humanized {
+		if sel, err = newSelector(); err != nil {
+			return STSScore{}, err
+		}
+		s.setOption("MultiPV", defaultMultiPV)
+		buildEndgameTables()
+	}
+
+	score := STSScore{Suites: make(map[string]STSScore)}
+	for _, p := range positions {
+		move, err := chooseMove(s, sel, FENtoBoard(p.fen), opts)
+		if err != nil {
+			return score, fmt.Errorf("%s: %w", p.id, err)
+		}
+
+		points := p.points[move]
+		score.Points += points
+		score.Max += stsMaxPoints
+
+		suite := score.Suites[p.suite]
+		suite.Points += points
+		suite.Max += stsMaxPoints
+		score.Suites[p.suite] = suite
+
+		_, _ = fmt.Fprintf(w, "%s played %s points %d\n", p.id, move, points)
+	}
+
+	suites := make([]string, 0, len(score.Suites))
+	for name := range score.Suites {
+		suites = append(suites, name)
+	}
+	sort.Strings(suites)
+
+	for _, name := range suites {
+		_, _ = fmt.Fprintf(w, "%s: %s\n", name, score.Suites[name])
+	}
+	_, _ = fmt.Fprintf(w, "STS score: %s\n", score)
+
+	return score, nil
+}
+
+// readSTS reads STS positions. Points come from c8/c9 (points and UCI moves)
+// when present, otherwise from c0 ("f5=10, Be5+=2, ...").
+func readSTS(r io.Reader) ([]stsPosition, error) {
+	var positions []stsPosition
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rec, err := parseEPD(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		p := stsPosition{
+			id:     rec.Ops["id"],
+			fen:    rec.FEN,
+			points: make(map[string]int),
+		}
+		if p.id == "" {
+			p.id = fmt.Sprintf("#%d", n)
+		}
+		p.suite = p.id
+		if idx := strings.LastIndex(p.id, "."); idx != -1 {
+			p.suite = p.id[:idx]
+		}
+
+		if moves, points := strings.Fields(rec.Ops["c9"]), strings.Fields(rec.Ops["c8"]); len(moves) > 0 && len(moves) == len(points) {
+			for i, move := range moves {
+				p.points[move] = atoi(points[i])
+			}
+		} else {
+			b := FENtoBoard(rec.FEN)
+			for _, item := range strings.Split(rec.Ops["c0"], ",") {
+				item = strings.TrimSpace(item)
+				idx := strings.LastIndex(item, "=") // promotions have an '=' too
+				if idx == -1 {
+					continue
+				}
+				move, err := b.ParseSAN(item[:idx])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", n, err)
+				}
+				p.points[move] = atoi(item[idx+1:])
+			}
+		}
+
+		if len(p.points) == 0 {
+			return nil, fmt.Errorf("line %d: no c0 or c8/c9 points", n)
+		}
+
+		positions = append(positions, p)
+	}
+
+	return positions, sc.Err()
+}
@@ -0,0 +1,39 @@
+package uci
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadSTS(t *testing.T) {
+	// arrange
+	epd := `1kr5/3n4/q3p2p/p2n2p1/PppB1P2/5BP1/1P2Q2P/3R2K1 w - - bm f5; id "STS(v1.0) Undermine.001"; c0 "f5=10, Be5+=2, Bf2=3, Bg4=2"; c8 "10 2 3 2"; c9 "f4f5 d4e5 f3f2 f3g4";
+1q2rb1k/prp3pp/1pn1p3/5p2/2PP3Q/PP1R1NPP/4BP2/1R4K1 b - - bm e5; id "STS(v1.0) Undermine.002"; c0 "e5=10, Bd6=6, g6=4, h6=2";`
+
+	want := []stsPosition{
+		{
+			id:     "STS(v1.0) Undermine.001",
+			suite:  "STS(v1.0) Undermine",
+			fen:    "1kr5/3n4/q3p2p/p2n2p1/PppB1P2/5BP1/1P2Q2P/3R2K1 w - - 0 1",
+			points: map[string]int{"f4f5": 10, "d4e5": 2, "f3f2": 3, "f3g4": 2},
+		},
+		{
+			id:     "STS(v1.0) Undermine.002",
+			suite:  "STS(v1.0) Undermine",
+			fen:    "1q2rb1k/prp3pp/1pn1p3/5p2/2PP3Q/PP1R1NPP/4BP2/1R4K1 b - - 0 1",
+			points: map[string]int{"e6e5": 10, "f8d6": 6, "g7g6": 4, "h7h6": 2},
+		},
+	}
+
+	// act
+	got, err := readSTS(strings.NewReader(epd))
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %+v\ngot:  %+v", want, got)
+	}
+}
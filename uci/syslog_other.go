@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package uci
+
+import (
+	"errors"
+	"runtime"
+)
+
+// openSyslog fails, Windows and Plan 9 have no syslog. Windows' event log
+// would need a registered source.
+func openSyslog() (write func(LogLevel, string) error, close func() error, err error) {
+	return nil, nil, errors.New("syslog isn't available on " + runtime.GOOS)
+}
@@ -0,0 +1,31 @@
+//go:build !windows && !plan9
+
+package uci
+
+import "log/syslog"
+
+// openSyslog connects to the local syslog, which is journald on systemd
+// hosts.
+func openSyslog() (write func(LogLevel, string) error, close func() error, err error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "trollfish")
+	if err != nil {
+		return nil, nil, err
+	}
+	return syslogWriter(w), w.Close, nil
+}
+
+// syslogWriter drops the timestamp, syslog has its own.
+func syslogWriter(w *syslog.Writer) func(LogLevel, string) error {
+	return func(level LogLevel, line string) error {
+		line = stripTimestamp(line)
+		switch level {
+		case LogError:
+			return w.Err(line)
+		case LogWarn:
+			return w.Warning(line)
+		case LogDebug:
+			return w.Debug(line)
+		}
+		return w.Info(line)
+	}
+}
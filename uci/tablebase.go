@@ -0,0 +1,16 @@
+package uci
+
+// tbWinScore is the least centipawn score SF gives a tablebase win. It scores
+// them 20000 less the plies to the tablebase position, below a mate but above
+// any eval.
+const tbWinScore = 20_000 - 256
+
+// tablebaseWin reports whether SF's line is a win it read from the Syzygy
+// tables, a mate or a tablebase score backed by tbhits. Those are exact; the
+// selector's equality would only throw the win away.
+func tablebaseWin(move Info) bool {
+	if move.TBHits == 0 {
+		return false
+	}
+	return move.Mate > 0 || move.Score >= tbWinScore
+}
@@ -0,0 +1,45 @@
+package uci
+
+import (
+	"io"
+	"testing"
+)
+
+func TestTablebaseWin(t *testing.T) {
+	cases := []struct {
+		name string
+		move Info
+		want bool
+	}{
+		{name: "tablebase win", move: Info{Score: 19_950, TBHits: 12}, want: true},
+		{name: "tablebase mate", move: Info{Mate: 14, TBHits: 3}, want: true},
+		{name: "tablebase draw", move: Info{Score: 0, TBHits: 40}},
+		{name: "tablebase loss", move: Info{Score: -19_950, TBHits: 12}},
+		{name: "big eval, no tables", move: Info{Score: 19_950}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := tablebaseWin(c.move); got != c.want {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectMoveTablebaseWin(t *testing.T) {
+	u := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}, out: io.Discard, playBad: true}
+
+	moveList := []Info{
+		{MultiPV: 1, Score: 19_960, TBHits: 20, PV: "e1d2"},
+		{MultiPV: 2, Score: 0, TBHits: 20, PV: "e1f2"},
+		{MultiPV: 3, Score: -19_960, TBHits: 20, PV: "a7a8q"},
+	}
+	got := u.selectMove(moveList, moveList[0])
+	if got.PV != "e1d2" {
+		t.Errorf("want: e1d2 got: %s", got.PV)
+	}
+	if !u.gameAgro {
+		t.Error("want agro")
+	}
+}
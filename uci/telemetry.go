@@ -0,0 +1,183 @@
+package uci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	telemetryTimeout = 5 * time.Second
+	telemetryBuffer  = 64
+)
+
+// telemetry posts game summaries and error events as JSON to a user-configured
+// HTTP endpoint, for people running fleets of bots. It's off unless the
+// TelemetryURL option is set. Events are sent in the background and dropped
+// if the endpoint can't keep up; telemetry must never slow down a game.
+type telemetry struct {
+	mtx      sync.Mutex
+	url      string
+	engine   string
+	instance string
+	events   chan telemetryEvent
+	client   http.Client
+	logInfo  func(string)
+}
+
+type telemetryEvent struct {
+	Type     string      `json:"type"` // "game" or "error"
+	Time     time.Time   `json:"time"`
+	Engine   string      `json:"engine"`
+	Instance string      `json:"instance"`
+	Data     interface{} `json:"data"`
+}
+
+type gameTelemetry struct {
+	Moves      int     `json:"moves"`
+	ACPL       float64 `json:"acpl"`
+	Opening    float64 `json:"opening_acpl"`
+	Middlegame float64 `json:"middlegame_acpl"`
+	Endgame    float64 `json:"endgame_acpl"`
+	Agro       bool    `json:"agro"`
+	Result     string  `json:"result,omitempty"`
+	Reason     string  `json:"reason,omitempty"`
+}
+
+type errorTelemetry struct {
+	Reason string `json:"reason"`
+	FEN    string `json:"fen"`
+}
+
+func newGameTelemetry(game acplStats, agro bool, result GameResult) gameTelemetry {
+	return gameTelemetry{
+		Moves:      game.totalMoves(),
+		ACPL:       game.acpl(),
+		Opening:    game.phaseACPL(phaseOpening),
+		Middlegame: game.phaseACPL(phaseMiddlegame),
+		Endgame:    game.phaseACPL(phaseEndgame),
+		Agro:       agro,
+		Result:     result.Result,
+		Reason:     result.Reason,
+	}
+}
+
+// start runs the sender until ctx is done.
+func (t *telemetry) start(ctx context.Context, engine string, logInfo func(string)) {
+	instance, _ := os.Hostname()
+
+	t.mtx.Lock()
+	t.engine = engine
+	t.instance = instance
+	t.logInfo = logInfo
+	t.events = make(chan telemetryEvent, telemetryBuffer)
+	t.client.Timeout = telemetryTimeout
+	events := t.events
+	t.mtx.Unlock()
+
+	go func() {
+		for {
+			select {
+			case evt := <-events:
+				t.post(evt)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (t *telemetry) setURL(url string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.url = url
+}
+
+func (t *telemetry) newEvent(typ string, data interface{}) (telemetryEvent, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.url == "" || t.events == nil {
+		return telemetryEvent{}, false
+	}
+
+	return telemetryEvent{
+		Type:     typ,
+		Time:     time.Now().UTC(),
+		Engine:   t.engine,
+		Instance: t.instance,
+		Data:     data,
+	}, true
+}
+
+// send queues an event, dropping it if the queue is full.
+func (t *telemetry) send(typ string, data interface{}) {
+	evt, ok := t.newEvent(typ, data)
+	if !ok {
+		return
+	}
+
+	select {
+	case t.events <- evt:
+	default:
+		t.logInfo(fmt.Sprintf("telemetry: queue full, dropped %s event", typ))
+	}
+}
+
+// sendNow posts an event synchronously, for when the process is about to die.
+func (t *telemetry) sendNow(typ string, data interface{}) {
+	evt, ok := t.newEvent(typ, data)
+	if !ok {
+		return
+	}
+	t.post(evt)
+}
+
+// drain posts whatever is still queued. Called on quit so the last game's
+// summary isn't lost.
+func (t *telemetry) drain() {
+	t.mtx.Lock()
+	events := t.events
+	t.mtx.Unlock()
+
+	for {
+		select {
+		case evt := <-events:
+			t.post(evt)
+		default:
+			return
+		}
+	}
+}
+
+func (t *telemetry) post(evt telemetryEvent) {
+	t.mtx.Lock()
+	url := t.url
+	t.mtx.Unlock()
+
+	if url == "" {
+		return
+	}
+
+	b, err := json.Marshal(evt)
+	if err != nil {
+		t.logInfo(fmt.Sprintf("telemetry: %v", err))
+		return
+	}
+
+	resp, err := t.client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.logInfo(fmt.Sprintf("telemetry: %v", err))
+		return
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		t.logInfo(fmt.Sprintf("telemetry: %s returned %s", url, resp.Status))
+	}
+}
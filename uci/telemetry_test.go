@@ -0,0 +1,54 @@
+package uci
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTelemetrySend(t *testing.T) {
+	// arrange
+	received := make(chan telemetryEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var evt telemetryEvent
+		if err := json.NewDecoder(r.Body).Decode(&evt); err != nil {
+			t.Error(err)
+		}
+		received <- evt
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var tm telemetry
+	tm.start(ctx, "trollfish", func(string) {})
+
+	// act
+	tm.send("game", gameTelemetry{Moves: 1}) // not configured yet, dropped
+	tm.setURL(srv.URL)
+	tm.send("error", errorTelemetry{Reason: "engine: exit status 1", FEN: startPosFEN})
+
+	// assert
+	select {
+	case evt := <-received:
+		if evt.Type != "error" || evt.Engine != "trollfish" {
+			t.Errorf("want: error/trollfish got: %s/%s", evt.Type, evt.Engine)
+		}
+		data, _ := evt.Data.(map[string]interface{})
+		if data["reason"] != "engine: exit status 1" || data["fen"] != startPosFEN {
+			t.Errorf("data: %v", evt.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	select {
+	case evt := <-received:
+		t.Errorf("unexpected event: %+v", evt)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
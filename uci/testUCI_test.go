@@ -0,0 +1,55 @@
+package uci
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+)
+
+// newTestUCI returns a UCI for a test: no log, the GUI's side of stdout in
+// the returned buffer, crash reports in a temp dir and a context cancelled
+// when the test ends. There's no engine until the test sets one.
+func newTestUCI(t *testing.T) (*UCI, *syncBuffer) {
+	t.Helper()
+
+	u, err := New("test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out syncBuffer
+	u.log, u.out, u.crashDir = nopWriteCloser{}, &out, t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u.ctx, u.cancel = ctx, cancel
+	t.Cleanup(cancel)
+
+	return u, &out
+}
+
+// syncBuffer is the GUI's side of stdout, written and read from different
+// goroutines.
+type syncBuffer struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.String()
+}
+
+// Reset drops what's been written so far.
+func (b *syncBuffer) Reset() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.buf.Reset()
+}
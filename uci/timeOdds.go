@@ -0,0 +1,64 @@
+package uci
+
+import "fmt"
+
+const (
+	// timeOddsRatio is how far apart the starting clocks have to be before a
+	// game counts as time odds. Black's first clock is already down by White's
+	// first move, so there's some slack.
+	timeOddsRatio = 1.25
+
+	// timeOddsMovesLeft is how many more moves an odds game is budgeted for;
+	// it never drops below timeOddsMinMovesLeft.
+	timeOddsMovesLeft    = 40
+	timeOddsMinMovesLeft = 20
+)
+
+// gameClock is the clocks a game started with. The time manager compares our
+// clock to the opponent's, which only makes sense when they started equal.
+type gameClock struct {
+	ourStart int
+	oppStart int
+}
+
+// observe records the starting clocks. Only the first move of the game
+// counts, a clock seen mid-game says nothing about how it started.
+func (c *gameClock) observe(moveNumber, ourTime, oppTime int) {
+	if c.ourStart != 0 || moveNumber > 1 || ourTime <= 0 || oppTime <= 0 {
+		return
+	}
+	c.ourStart, c.oppStart = ourTime, oppTime
+}
+
+// odds reports whether the game started with unequal clocks.
+func (c gameClock) odds() bool {
+	if c.ourStart <= 0 || c.oppStart <= 0 {
+		return false
+	}
+	hi, lo := max(c.ourStart, c.oppStart), min(c.ourStart, c.oppStart)
+	return float64(hi) >= float64(lo)*timeOddsRatio
+}
+
+// parity scales the opponent's clock to ours, so in a 3+0 vs 1+0 game their
+// 60s left is 180s, the same as a full clock for us.
+func (c gameClock) parity(oppTime int) int {
+	if !c.odds() {
+		return oppTime
+	}
+	return int(int64(oppTime) * int64(c.ourStart) / int64(c.oppStart))
+}
+
+func (c gameClock) String() string {
+	if !c.odds() {
+		return "even"
+	}
+	return fmt.Sprintf("odds %d vs %d", c.ourStart, c.oppStart)
+}
+
+// timeOddsBudget is the most time to spend on a move in an odds game: an even
+// share of our own clock over the moves left. With less time we mustn't keep
+// up with the opponent, with more we shouldn't burn it just because it's there.
+func timeOddsBudget(ourTime, ourInc, moveNumber int) int {
+	movesLeft := max(timeOddsMovesLeft-moveNumber, timeOddsMinMovesLeft)
+	return ourTime/movesLeft + ourInc*3/4
+}
@@ -0,0 +1,45 @@
+package uci
+
+import "testing"
+
+func TestGameClock(t *testing.T) {
+	cases := []struct {
+		name       string
+		moveNumber int
+		ourTime    int
+		oppTime    int
+		odds       bool
+		opp        int // oppTime 60_000 after parity
+	}{
+		{name: "even", moveNumber: 1, ourTime: 180_000, oppTime: 180_000, odds: false, opp: 60_000},
+		{name: "black after white's first move", moveNumber: 1, ourTime: 180_000, oppTime: 172_000, odds: false, opp: 60_000},
+		{name: "we have more", moveNumber: 1, ourTime: 180_000, oppTime: 60_000, odds: true, opp: 180_000},
+		{name: "we have less", moveNumber: 1, ourTime: 60_000, oppTime: 180_000, odds: true, opp: 20_000},
+		{name: "mid-game clocks are ignored", moveNumber: 20, ourTime: 60_000, oppTime: 180_000, odds: false, opp: 60_000},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var clock gameClock
+			clock.observe(c.moveNumber, c.ourTime, c.oppTime)
+			// later moves don't change the starting clocks
+			clock.observe(c.moveNumber+1, 1000, 1000)
+
+			if got := clock.odds(); got != c.odds {
+				t.Errorf("odds: want %v got %v", c.odds, got)
+			}
+			if got := clock.parity(60_000); got != c.opp {
+				t.Errorf("parity: want %d got %d", c.opp, got)
+			}
+		})
+	}
+}
+
+func TestTimeOddsBudget(t *testing.T) {
+	if got, want := timeOddsBudget(60_000, 0, 10), 2000; got != want {
+		t.Errorf("move 10: want %d got %d", want, got)
+	}
+	if got, want := timeOddsBudget(60_000, 2000, 50), 4500; got != want {
+		t.Errorf("move 50: want %d got %d", want, got)
+	}
+}
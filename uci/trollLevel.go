@@ -0,0 +1,42 @@
+package uci
+
+const (
+	// defaultTrollLevel is the Troll Level the personality knobs are tuned
+	// for. 0 turns the theatrics off, maxTrollLevel doubles them.
+	defaultTrollLevel = 5
+	maxTrollLevel     = 10
+
+	// blunderTolerance is the most eval, in cp, the selector gives up for a
+	// line that keeps the game level, at the default Troll Level.
+	blunderTolerance = 250
+)
+
+// trollScale scales n, tuned for the default Troll Level, to level.
+func trollScale(n, level int) int {
+	return n * level / defaultTrollLevel
+}
+
+// blunderTolerance is how much eval the selector gives up to keep the game
+// level.
+func (c Config) blunderTolerance() int {
+	return trollScale(blunderTolerance, c.TrollLevel)
+}
+
+// agroEval is the eval that turns agro on. A bigger troll lets the game get
+// more lopsided before finishing it, from half the configured eval at 0 to
+// half again as much at the top.
+func (c Config) agroEval() int {
+	return c.Agro.Eval * (defaultTrollLevel + c.TrollLevel) / (2 * defaultTrollLevel)
+}
+
+// theatrics scales a dramatic pause: thinking on the first move out of book,
+// stopping to think when losing, taking time with an edge.
+func (c Config) theatrics(ms int) int {
+	return trollScale(ms, c.TrollLevel)
+}
+
+// scrambleTime is the clock both sides have to be under for a scramble,
+// where flagging the opponent comes before everything else.
+func (c Config) scrambleTime() int {
+	return trollScale(scrambleTime, c.TrollLevel)
+}
@@ -0,0 +1,56 @@
+package uci
+
+import (
+	"testing"
+)
+
+func TestTrollLevelKnobs(t *testing.T) {
+	cases := []struct {
+		level                                   int
+		tolerance, agroEval, exitTime, scramble int
+	}{
+		{level: 0, tolerance: 0, agroEval: 400, exitTime: 0, scramble: 0},
+		{level: 5, tolerance: 250, agroEval: 800, exitTime: 3000, scramble: 10_000},
+		{level: 10, tolerance: 500, agroEval: 1200, exitTime: 6000, scramble: 20_000},
+	}
+
+	for _, c := range cases {
+		cfg := defaultConfig()
+		cfg.TrollLevel = c.level
+
+		if got := cfg.blunderTolerance(); got != c.tolerance {
+			t.Errorf("level %d: blunder tolerance want: %d got: %d", c.level, c.tolerance, got)
+		}
+		if got := cfg.agroEval(); got != c.agroEval {
+			t.Errorf("level %d: agro eval want: %d got: %d", c.level, c.agroEval, got)
+		}
+		if got := cfg.theatrics(3000); got != c.exitTime {
+			t.Errorf("level %d: theatrics want: %d got: %d", c.level, c.exitTime, got)
+		}
+		if got := cfg.scrambleTime(); got != c.scramble {
+			t.Errorf("level %d: scramble time want: %d got: %d", c.level, c.scramble, got)
+		}
+	}
+}
+
+func TestTrollLevelOption(t *testing.T) {
+	u, _ := newTestUCI(t)
+
+	moveList := []Info{
+		{MultiPV: 1, Score: 120, PV: "e2e4"},
+		{MultiPV: 2, Score: 0, PV: "a2a3"},
+	}
+
+	// a 120cp sacrifice for equality is fine by default...
+	u.gameScore = Score{CP: 120}
+	if got := u.selectMove(moveList, moveList[0]); got.PV != "a2a3" {
+		t.Errorf("default level: want: a2a3 got: %s", got.PV)
+	}
+
+	// ...but not with the troll turned down
+	u.SetOption("Troll Level", "1")
+	u.gameScore = Score{CP: 120}
+	if got := u.selectMove(moveList, moveList[0]); got.PV != "e2e4" {
+		t.Errorf("level 1: want: e2e4 got: %s", got.PV)
+	}
+}
@@ -3,9 +3,9 @@ package uci
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math/rand"
 	"os"
 	"sort"
@@ -13,53 +13,124 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
-	"syscall"
 	"time"
-
-	"trollfish/stockfish"
 )
 
 const startPosFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
-const defaultThreads = 28
 
 //const hashMemory = 40960
 //const hashMemory = 3072 // 1024*3
 const hashMemory = 7168 // 256*28
+
+// defaultMoveOverhead is the ms kept off our clock for network and GUI
+// latency, the Move Overhead option's default.
+const defaultMoveOverhead = 500
+
 const defaultMultiPV = 5
 const agroMultiPV = 2
 
-// TODO: get path from config file
-const stockfishPath = "/home/jud/projects/trollfish/stockfish/stockfish"
-
 type UCI struct {
 	name    string
 	author  string
-	options []Option
+	options *Options
+	config  Config // guarded by moveListMtx
 
 	fen string
 
-	started int64
-	playBad bool
+	started       int64
+	sfInitialized int64
+	debug         int64 // 1 after "debug on"
+	quit          sync.Once
+	playBad       bool
 
 	moveListMtx     sync.Mutex
-	moveListNodes   int
+	moveListNodes   int64
 	moveList        []Info
+	refutations     map[string]string // this search's, by the refuted move
 	moveListPrinted bool
 	gameMoveCount   int
 	gameActiveColor string
 	gameMultiPV     int
-	gameMateIn      int
-	gameEval        int
+	sentMultiPV     int
+	gameScore       Score // our last move's, from our side
 	gameAgro        bool
+	gameHistory     []moveEval
+	gameClock       gameClock
+	gameResult      GameResult
+	opponent        opponent // the GUI's UCI_Opponent
+	gameOpponent    opponent // as of our first move this game
+	pounce          pounce
+	scramble        bool
+	imbalance       string
+	imbalanceLeft   int
+	imbalanceMoves  int
+	losing          losingPolicy
+	resignCount     int
+	history         *History
+	goMate          int
+	searchMoves     []string // the last go's searchmoves
+	goTiming        goTiming
+	goClock         [2]int // the last go's time left, ours and the opponent's
+	searchDone      chan struct{}
+	staleBestMoves  int
+	bookStop        chan struct{}
+	bookExit        bool // a book move was played; the next search is the first out of book
 	startAgro       bool
-
-	sf *stockfish.StockFish
+	jsonInfo        bool
+	ownBook         bool
+	chess960        bool // guarded by moveListMtx
+
+	// analysis is a search for an analysis GUI ("go infinite", or a depth or
+	// node limit without a clock): SF's lines are streamed and its bestmove
+	// passed on as is. untilStop is a "go infinite" or "go ponder", which
+	// owes its bestmove only after stop or ponderhit. analysisHeld is one of
+	// those without an engine, or whose engine died, answered by the built-in
+	// engine on stop or ponderhit. All three are guarded by moveListMtx.
+	analysis     bool
+	untilStop    bool
+	analysisHeld bool
+
+	analysisMultiPV int                      // the GUI's MultiPV, guarded by moveListMtx
+	threads         int                      // the GUI's Threads, 0 until it sets one; guarded by moveListMtx
+	contempt        int                      // cp against a 0.00 repetition, guarded by moveListMtx
+	showWDL         bool                     // guarded by moveListMtx
+	userOptions     map[string]string        // set by the GUI, kept for the next session; guarded by moveListMtx
+	engineOptions   engineOptions            // SF's own options the GUI set, guarded by moveListMtx
+	engineCaps      engineCaps               // the running engine's, guarded by moveListMtx
+	knownEngines    map[string]engineCaps    // by path, guarded by moveListMtx
+	position        string                   // the last position command sent to SF, guarded by moveListMtx
+	engineRestarts  int                      // this game, guarded by moveListMtx
+	engineReplies   map[string]chan struct{} // uciok and readyok being waited for, guarded by moveListMtx
+
+	session     sessionStats
+	sessionACPL acplStats
+	latency     moveLatency
+
+	sf           EngineBackend // through engine and setEngine once started
+	sfMtx        sync.RWMutex
+	startBackend EngineStarter
+	maia         *maia  // guarded by moveListMtx
+	enginePath   string // guarded by sfMtx, with sf
+	variant      Variant
+	currLine     currLine
+
+	antichessRepertoire *antichessRepertoire
+	book                *openingBook
+	repertoires         repertoires
+	polyglot            *polyglotBook
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	mtxStdout sync.Mutex
-	log       io.WriteCloser
+	in         io.Reader
+	mtxStdout  sync.Mutex
+	out        io.Writer
+	log        io.WriteCloser
+	logSpec    string
+	logger     Logger
+	transcript transcript
+	crashDir   string
+	telemetry  telemetry
 }
 
 type Info struct {
@@ -68,12 +139,22 @@ type Info struct {
 	MultiPV  int
 	Score    int
 	Mate     int
-	Nodes    int
-	NPS      int
+	Nodes    int64
+	NPS      int64
 	HashFull int
 	TBHits   int
 	Time     int
 	PV       string
+	WDL      WDL
+
+	// CurrLine is the line search thread CurrLineCPU is on, from engines
+	// that report currline
+	CurrLine    string
+	CurrLineCPU int
+
+	// Refutation is a move and the line that refutes it, from engines with
+	// UCI_ShowRefutations
+	Refutation string
 }
 
 func (m Info) String() string {
@@ -83,58 +164,117 @@ func (m Info) String() string {
 	} else {
 		score = fmt.Sprintf("mate %d", m.Mate)
 	}
+	if m.WDL.valid() {
+		score += " wdl " + m.WDL.String()
+	}
 	return fmt.Sprintf("depth %d seldepth %d multipv %d score %s nodes %d nps %d hashfull %d tbhits %d time %d pv %s",
 		m.Depth, m.SelDepth, m.MultiPV, score, m.Nodes, m.NPS, m.HashFull, m.TBHits, m.Time, m.PV,
 	)
 }
 
-func New(name, author string, options ...Option) *UCI {
-	return &UCI{
-		name:        name,
-		author:      author,
-		options:     options,
-		gameMultiPV: defaultMultiPV,
+func New(name, author string, options ...Option) (*UCI, error) {
+	opts, err := newOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := LoadConfig(configPath())
+	if err != nil {
+		return nil, err
+	}
+
+	u := &UCI{
+		name:            name,
+		author:          author,
+		options:         opts,
+		config:          cfg,
+		gameMultiPV:     cfg.MultiPV.Default,
+		analysisMultiPV: 1,
+		imbalanceMoves:  defaultImbalanceMoves,
+		losing:          defaultLosingPolicy,
+		book:            builtinBook(),
+		ownBook:         true,
+		in:              os.Stdin,
+		out:             os.Stdout,
+		crashDir:        ".",
+		sf:              noEngine{},
+		log:             nopWriteCloser{},
+	}
+
+	if err := u.registerOptions(); err != nil {
+		return nil, err
+	}
+	if err := u.checkConfigOptions(cfg); err != nil {
+		return nil, err
+	}
+	u.applyConfigOptions(cfg)
+	if err := u.restoreOptions(); err != nil {
+		return nil, err
 	}
+
+	return u, nil
+}
+
+// SetIO sets the streams the GUI's commands are read from and the engine's
+// replies are written to, replacing stdin and stdout, to embed the engine or
+// drive it over a socket. It must be called before Start.
+func (u *UCI) SetIO(in io.Reader, out io.Writer) {
+	u.in = in
+	u.setOutput(out)
+}
+
+// Options returns the option registry, for library users that want to read
+// values or subscribe to changes.
+func (u *UCI) Options() *Options {
+	return u.options
 }
 
 func (u *UCI) ResetGame() {
-	u.sf.Write("ucinewgame")
-	if u.startAgro {
-		u.gameMultiPV = agroMultiPV
-	} else {
-		u.gameMultiPV = defaultMultiPV
-	}
+	u.reportGame()
+
+	u.engine().Write("ucinewgame")
+	u.setLogContext(func(c *logContext) { *c = logContext{} })
+	u.clearSavedGame()
+
+	u.moveListMtx.Lock()
 	u.gameMoveCount = 0
 	u.gameActiveColor = "w"
-	u.gameMateIn = 0
-	u.gameEval = 0
+	u.gameScore = Score{}
 	u.gameAgro = u.startAgro
-	u.sf.Write(fmt.Sprintf("setoption name MultiPV value %d", u.gameMultiPV))
+	u.gameClock = gameClock{}
+	u.gameResult = GameResult{}
+	u.pounce = pounce{}
+	u.scramble = false
+	u.imbalance = ""
+	u.imbalanceLeft = 0
+	u.resignCount = 0
+	u.engineRestarts = 0
+	u.bookExit = false
+	u.history = nil
+	u.repertoires.rotate()
+	u.applyMultiPV()
+	u.moveListMtx.Unlock()
 }
 
-func (u *UCI) Start(ctx context.Context) (context.Context, context.CancelFunc) {
+// Start starts SF and the UCI loop on stdin and stdout, or the streams set by
+// SetIO. The returned context is done once the engine quits.
+func (u *UCI) Start(ctx context.Context) (context.Context, context.CancelFunc, error) {
 	if !atomic.CompareAndSwapInt64(&u.started, 0, 1) {
-		return u.ctx, u.cancel
+		return u.ctx, u.cancel, nil
 	}
 
-	fp, err := os.OpenFile("trollfish.log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatal(err)
+	// stderr only belongs to us when the GUI is on the process's stdio
+	stdio := u.in == io.Reader(os.Stdin) && u.out == io.Writer(os.Stdout)
+	if err := u.startEngine(ctx, stdio); err != nil {
+		atomic.StoreInt64(&u.started, 0)
+		return nil, nil, err
 	}
 
-	redirectStderr(fp)
-
-	u.log = fp
-
-	u.logInfo("=========================================")
-
-	u.ctx, u.cancel = context.WithCancel(ctx)
-
 	c := make(chan string, 512)
 
 	go func() {
 		defer close(c)
-		r := bufio.NewScanner(os.Stdin)
+		r := bufio.NewScanner(u.in)
 
 		for r.Scan() {
 			select {
@@ -151,30 +291,107 @@ func (u *UCI) Start(ctx context.Context) (context.Context, context.CancelFunc) {
 		}
 	}()
 
-	sf, err := stockfish.Start(u.ctx, stockfishPath, u.logInfo)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	u.sf = sf
-
-	go u.stockFishReadLoop()
-
 	go func() {
+		defer u.recoverCrash()
 		for line := range c {
 			u.parseLine(line)
 		}
 	}()
 
-	return u.ctx, u.cancel
+	return u.ctx, u.cancel, nil
+}
+
+// startEngine opens the log and starts SF. The caller feeds it commands;
+// stdio is true for the UCI loop on the process's stdin and stdout.
+func (u *UCI) startEngine(ctx context.Context, stdio bool) error {
+	fp, err := u.openLog(stdio)
+	if err != nil {
+		return err
+	}
+
+	// in UCI mode trollfish's own stderr (runtime panics and fatal errors)
+	// gets its own file; the engine's stderr is piped and tagged in the log
+	if stdio {
+		stderrFile, err := os.OpenFile("trollfish-stderr.log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			_ = fp.Close()
+			return fmt.Errorf("open stderr log: %w", err)
+		}
+		err = redirectStderr(stderrFile)
+		_ = stderrFile.Close() // stderr holds its own descriptor
+		if err != nil {
+			_ = fp.Close()
+			return err
+		}
+	}
+
+	u.log = newAsyncLog(fp)
+	u.setLogLevel(u.options.String("LogLevel"))
+
+	u.logInfo("=========================================")
+
+	// logged so the session can be replayed with the same choices
+	seed := time.Now().UnixNano()
+	rand.Seed(seed)
+	u.logInfo(fmt.Sprintf("seed: %d", seed))
+
+	u.ctx, u.cancel = context.WithCancel(ctx)
+
+	// without SF the built-in engine plays; a weak move beats no move
+	path := u.stockfishPath()
+	sf, err := u.engineStarter()(u.ctx, path, u.logInfo)
+	if err != nil {
+		u.logInfo(fmt.Sprintf("ERR: start engine: %v, using the built-in engine", err))
+	} else {
+		u.setEngine(sf, path)
+
+		go u.stockFishReadLoop(sf)
+		go u.watchEngine(sf)
+	}
+	go u.setMaia("")
+
+	u.resumeGame()
+
+	go buildEndgameTables()
+
+	u.telemetry.start(u.ctx, u.name, u.logInfo)
+
+	return nil
 }
 
+// logInfo logs s at the level its prefix gives it, see logLineLevel: info
+// unless it's an error or traffic.
 func (u *UCI) logInfo(s string) {
-	_, _ = u.log.Write([]byte(fmt.Sprintf("%s %s\n", ts(), s)))
+	u.logAt(logLineLevel(s), s)
+}
+
+// logDebug logs protocol traffic and other noise production logs leave out.
+func (u *UCI) logDebug(s string) {
+	u.logAt(LogDebug, s)
+}
+
+// logWarn logs something that's off but doesn't stop play.
+func (u *UCI) logWarn(s string) {
+	u.logAt(LogWarn, s)
+}
+
+func (u *UCI) logAt(level LogLevel, s string) {
+	line := fmt.Sprintf("%s %s", ts(), s)
+	u.transcript.add(line)
+	if w, ok := u.log.(interface{ write(LogLevel, string) }); ok {
+		w.write(level, line)
+		return
+	}
+	_, _ = u.log.Write([]byte(line + "\n"))
 }
 
-func (u *UCI) stockFishReadLoop() {
-	for line := range u.sf.Output {
+func (u *UCI) stockFishReadLoop(sf EngineBackend) {
+	defer u.recoverCrash()
+
+	// what the engine says about itself before its uciok
+	var caps engineCaps
+
+	for line := range sf.Output() {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
@@ -185,82 +402,79 @@ func (u *UCI) stockFishReadLoop() {
 			continue
 		}
 
+		u.logDebug(fmt.Sprintf("SF: <- %s", line))
+		u.debugEngineLine(line)
+
 		cmd := parts[0]
 
 		switch cmd {
 		case "readyok":
+			u.engineReplied("readyok")
 			u.WriteLine("readyok")
+		case "id", "option":
+			caps.parse(line)
 		case "uciok":
-			n := defaultThreads
-			u.sf.Write(fmt.Sprintf("setoption name Threads value %d", n))
-			u.sf.Write(fmt.Sprintf("setoption name Hash value %d", hashMemory))
-			u.sf.Write(fmt.Sprintf("setoption name MultiPV value %d", u.gameMultiPV))
-			u.sf.Write("setoption name Move Overhead value 200")
-			u.WriteLine("uciok")
+			u.engineAnswered(sf, caps)
+			caps = engineCaps{}
+			u.engineReplied("uciok")
+			// the GUI already has its uciok if the engine was restarted or switched
+			initialized := !atomic.CompareAndSwapInt64(&u.sfInitialized, 0, 1)
+			u.moveListMtx.Lock()
+			threads, hash := u.engineThreads(), u.engineHash()
+			u.moveListMtx.Unlock()
+			u.setEngineOption("Threads", strconv.Itoa(threads))
+			u.setEngineOption("Hash", strconv.Itoa(hash))
+			u.setEngineOption("Move Overhead", u.options.String("Move Overhead"))
+
+			// a new engine instance starts at MultiPV 1
+			u.moveListMtx.Lock()
+			u.sentMultiPV = 0
+			u.applyMultiPV()
+			u.moveListMtx.Unlock()
+			if !initialized {
+				u.WriteLine("uciok")
+			}
 		case "info":
+			if len(parts) < 2 {
+				u.engineLineError(line, errors.New("info line without keys"))
+				break
+			}
 			if parts[1] == "string" {
 				// debug info, ignore
 				break
 			}
 
-			var move Info
-		infoLoop:
-			for i := 1; i < len(parts); i += 2 {
-				if i == len(parts)-1 {
-					break
-				}
-
-				key := parts[i]
+			u.moveListMtx.Lock()
+			analysis := u.analysis
+			u.moveListMtx.Unlock()
+			if analysis {
+				// the GUI wants every line as SF sends it
+				u.WriteLine(line)
+				break
+			}
 
-				var n int
+			move, err := parseInfo(parts, u.logInfo)
+			if err != nil {
+				u.engineLineError(line, err)
+				break
+			}
 
-				if key == "score" {
-					if parts[i+1] == "cp" {
-						key = "score.cp"
-						n = atoi(parts[i+2])
-					}
-					if parts[i+1] == "mate" {
-						key = "score.mate"
-						n = atoi(parts[i+2])
-					}
-					i++
-					if i+2 < len(parts) && (parts[i+2] == "lowerbound" || parts[i+2] == "upperbound") {
-						// ignore
-						i++
-					}
-				} else {
-					n = atoi(parts[i+1])
+			if move.CurrLine != "" {
+				if s := u.currLine.engineLine(line); s != "" {
+					u.WriteLine(s)
 				}
-
-				switch key {
-				case "score.cp":
-					move.Score = n
-				case "score.mate":
-					move.Mate = n
-				case "depth":
-					move.Depth = n
-				case "seldepth":
-					move.SelDepth = n
-				case "multipv":
-					move.MultiPV = n
-				case "nodes":
-					move.Nodes = n
-				case "nps":
-					move.NPS = n
-				case "hashfull":
-					move.HashFull = n
-				case "tbhits":
-					move.TBHits = n
-				case "time":
-					move.Time = n
-				case "currmove", "currmovenumber":
-					// ignore
-				case "pv":
-					move.PV = strings.Join(parts[i+1:], " ")
-					break infoLoop
-				default:
-					u.logInfo(fmt.Sprintf("unknown key '%s': %s", key, strings.Join(parts, " ")))
+				break
+			}
+			if move.Refutation != "" {
+				u.moveListMtx.Lock()
+				if u.refutations == nil {
+					u.refutations = make(map[string]string)
 				}
+				u.refutations[pvMove(move.Refutation)] = move.Refutation
+				u.moveListMtx.Unlock()
+			}
+			if s := u.currLine.pvLine(move); s != "" {
+				u.WriteLine(s)
 			}
 
 			if move.PV == "" {
@@ -303,64 +517,109 @@ func (u *UCI) stockFishReadLoop() {
 			u.moveListMtx.Unlock()
 
 		case "bestmove":
-			if line == "bestmove (none)" {
-				u.WriteLine(line)
-				break
+			if len(parts) < 2 {
+				// truncated; the best line has the move SF meant
+				u.moveListMtx.Lock()
+				line = "bestmove (none)"
+				if len(u.moveList) > 0 {
+					line = "bestmove " + pvMove(u.moveList[0].PV)
+				}
+				u.moveListMtx.Unlock()
+				u.engineLineError("bestmove", fmt.Errorf("bestmove without a move, using '%s'", line))
+				parts = strings.Split(line, " ")
 			}
 
 			u.moveListMtx.Lock()
+			if u.staleBestMoves > 0 {
+				// the built-in engine already answered this search
+				u.staleBestMoves--
+				u.moveListMtx.Unlock()
+				break
+			}
+			u.goTiming.engineDone = time.Now()
+			u.moveListMtx.Unlock()
 
-			minDist := 1_000_000
+			if parts[1] == "(none)" || parts[1] == "0000" {
+				// no legal moves: nothing to troll, and nothing to ponder on
+				u.moveListMtx.Lock()
+				u.analysis = false
+				u.goMate = 0
+				u.moveList = nil
+				u.moveListPrinted = false
+				u.moveListNodes = 0
+				u.moveListMtx.Unlock()
 
-			var engineMove Info
-			if len(u.moveList) > 0 {
-				engineMove = u.moveList[0]
-			} else {
-				engineMove = Info{PV: strings.Join(parts[1:], " ")}
+				u.WriteLine("bestmove (none)")
+				u.finishSearch()
+				break
 			}
 
-			bestMove := engineMove
+			u.moveListMtx.Lock()
 
-			if u.gameAgro || engineMove.Score >= 2000 || engineMove.Mate > 0 {
-				u.gameAgro = true
-			} else {
-				u.gameMateIn = 0
+			if u.analysis {
+				// nothing to troll, SF's move is the answer
+				u.analysis = false
+				u.moveList = nil
+				u.moveListPrinted = false
+				u.moveListNodes = 0
+				u.moveListMtx.Unlock()
 
-				for i := 0; i < len(u.moveList); i++ {
-					move := u.moveList[i]
-					if move.Mate < 0 {
-						// don't get mated
-						break
-					}
+				u.WriteLine(line)
+				u.finishSearch()
+				break
+			}
 
-					// avoid gross blunders
-					if u.gameEval-move.Score > 250 {
-						continue
-					}
+			if u.goMate > 0 {
+				n := u.goMate
+				variant := u.variant
 
-					// attempt to maintain equality until we hit agro
-					dist := move.Score
-					if dist < 0 {
-						dist *= -1
-					}
-					if dist < minDist {
-						bestMove = move
-						minDist = dist
-					}
+				var best Info
+				if len(u.moveList) > 0 {
+					best = u.moveList[0]
 				}
+				u.printMoveList(false)
+				u.goMate = 0
+				u.moveList = nil
+				u.moveListPrinted = false
+				u.moveListNodes = 0
+				u.moveListMtx.Unlock()
+
+				if variant.isChess() {
+					u.verifyMate(n, parts[1], best)
+				} else {
+					// the prover only knows chess, SF's mate isn't claimed
+					u.WriteLine(fmt.Sprintf("info string mate %d not verified in %s", n, variant))
+					u.WriteLine("bestmove " + parts[1])
+				}
+
+				u.finishSearch()
+				break
+			}
+
+			// only the GUI's searchmoves are candidates, even from an engine
+			// that doesn't honor them
+			candidates := restrictToSearchMoves(u.moveList, u.searchMoves)
+
+			var engineMove Info
+			if len(candidates) > 0 {
+				engineMove = candidates[0]
+			} else {
+				engineMove = Info{PV: strings.Join(parts[1:], " ")}
 			}
 
+			bestMove := u.selectMove(candidates, engineMove)
+
 			u.printMoveList(false)
 			u.WriteLine(strings.ReplaceAll(line, "bestmove", "sfbm"))
 
-			if !u.gameAgro && u.playBad && len(u.moveList) > 0 {
-				bestMove = u.moveList[len(u.moveList)-1]
-				for i := len(u.moveList) - 2; i >= 0; i-- {
-					badMove := u.moveList[i]
-					if badMove.Score < 0 || badMove.Mate < 0 {
-						bestMove = badMove
-					}
+			if len(u.moveList) > 0 {
+				m := newMoveEval(u.fen, u.gameMoveCount, engineMove, bestMove)
+				m.color, m.agro = u.gameActiveColor, u.gameAgro
+				m.clock, m.oppClock = u.goClock[0], u.goClock[1]
+				if !u.goTiming.start.IsZero() {
+					m.moveTime = int(u.goTiming.engineDone.Sub(u.goTiming.start).Milliseconds())
 				}
+				u.recordMove(m)
 			}
 
 			u.moveList = nil
@@ -368,54 +627,306 @@ func (u *UCI) stockFishReadLoop() {
 			u.moveListNodes = 0
 
 			uciMove := strings.Split(bestMove.PV, " ")[0]
+			refutation := u.refutations[uciMove]
 
-			u.gameMateIn = bestMove.Mate
-			u.gameEval = bestMove.Score
+			u.gameScore = bestMove.Eval()
+			eval, agro := bestMove.Score, u.gameAgro
+			u.setLogContext(func(c *logContext) { c.eval, c.agro = &eval, agro })
 
 			u.moveListMtx.Unlock()
 
-			evalHuman := float64(bestMove.Score) / 100
-			if bestMove.Score != 0 && u.gameActiveColor == "b" {
-				evalHuman *= -1
-			}
-			evalString := fmt.Sprintf("%0.2f", evalHuman)
-
-			if bestMove.Mate != 0 {
-				mateHuman := bestMove.Mate
-				if u.gameActiveColor == "b" {
-					mateHuman *= -1
-				}
-				evalString = fmt.Sprintf("M%d", mateHuman)
-			}
-
-			addl := fmt.Sprintf("eval %s agro %v", evalString, u.gameAgro)
+			// GUIs show White's eval
+			addl := fmt.Sprintf("eval %s agro %v", bestMove.Eval().WhitePOV(u.gameActiveColor), u.gameAgro)
 			if uciMove == parts[1] {
 				u.WriteLine(line + " " + addl)
 			} else {
 				u.WriteLine(fmt.Sprintf("bestmove %s %s", uciMove, addl))
 
 				if u.gameAgro {
-					u.logInfo(fmt.Sprintf("!!! WARNING %s != %s", parts[1], uciMove))
+					u.logWarn(fmt.Sprintf("!!! WARNING %s != %s", parts[1], uciMove))
 				}
 			}
 
+			u.finishSearch()
+			u.saveGame()
+
 			u.logInfo(fmt.Sprintf("play_bad: %v agro: %v sf_move: %s sf_move_eval: %d played_move: %s eval: %d",
 				u.playBad, u.gameAgro,
 				strings.Split(engineMove.PV, " ")[0], engineMove.Score,
 				uciMove, bestMove.Score,
 			))
+			if refutation != "" {
+				// the engine's line against the move it didn't pick
+				u.logInfo(fmt.Sprintf("refutation: %s", refutation))
+			}
 
-		default:
-			u.logInfo(fmt.Sprintf("SF: <- %s", line))
-			// TODO
 		}
 	}
 
 	u.logInfo("stockfish read loop exited")
 }
 
+// selectMove applies the troll policy to the final MultiPV lines and returns
+// the line to play. engineMove is SF's choice. Callers must hold moveListMtx.
+func (u *UCI) selectMove(moveList []Info, engineMove Info) Info {
+	minDist := 1_000_000
+
+	bestMove := engineMove
+
+	// punish a blunder or play out an unbalanced position with SF's move,
+	// trolling can wait
+	u.checkPounce(engineMove)
+	u.checkImbalance()
+	fullStrength := u.pounce.active() || u.imbalanceLeft > 0
+	swindling := !fullStrength && u.losing.swindling(engineMove)
+	u.checkResign(engineMove)
+	defer func() {
+		u.pounce.played(bestMove)
+		if u.imbalanceLeft > 0 {
+			u.imbalanceLeft--
+		}
+	}()
+
+	ply := gamePly(u.gameMoveCount, u.gameActiveColor)
+	if tablebaseWin(engineMove) {
+		// the tables say it's won, there's nothing to troll
+		u.setAgro()
+		bestMove = engineMove
+	} else if u.gameAgro || winProbAtLeast(engineMove.Eval(), ply, u.config.Agro.SelectorEval) || u.kingAttack(engineMove) {
+		u.setAgro()
+		bestMove = u.avoidDraw(moveList, engineMove)
+	} else if u.scramble && !fullStrength {
+		// both flags are about to fall, keep it simple
+		bestMove = u.scrambleMove(moveList, engineMove)
+	} else if swindling {
+		// lost anyway, set some traps
+		bestMove = u.swindleMove(moveList, engineMove)
+	} else if !fullStrength {
+		u.gameScore.Mate = 0
+
+		useWDL := allHaveWDL(moveList)
+		var maiaWeight int
+		policy := u.maia.moveProbabilities(u.fen)
+		if policy != nil {
+			maiaWeight = u.options.Int("MaiaWeight")
+		}
+		for i := 0; i < len(moveList); i++ {
+			move := moveList[i]
+			if move.Mate < 0 {
+				// don't get mated
+				break
+			}
+
+			// avoid gross blunders
+			if winProbDrop(u.gameScore, move.Eval(), ply, u.config.blunderTolerance()) {
+				continue
+			}
+
+			// attempt to maintain equality until we hit agro, with a move a
+			// human might play
+			dist := equalityDist(move, useWDL) - humanBonus(policy, maiaWeight, move) + u.contemptBias(move)
+			if dist < minDist {
+				bestMove = move
+				minDist = dist
+			}
+		}
+	}
+
+	if !u.gameAgro && !fullStrength && !u.scramble && !swindling && u.playBad && len(moveList) > 0 {
+		bestMove = moveList[len(moveList)-1]
+		for i := len(moveList) - 2; i >= 0; i-- {
+			badMove := moveList[i]
+			if badMove.Score < 0 || badMove.Mate < 0 {
+				bestMove = badMove
+			}
+		}
+	}
+
+	return bestMove
+}
+
+// infoError is an engine "info" line parseInfo couldn't read: a key without
+// its value, or a value that isn't what the key takes.
+type infoError struct {
+	key   string
+	value string // empty when it's missing
+	want  string
+}
+
+func (e infoError) Error() string {
+	if e.value == "" {
+		return fmt.Sprintf("info line: '%s' without a value", e.key)
+	}
+	return fmt.Sprintf("info line: '%s' value '%s' isn't %s", e.key, e.value, e.want)
+}
+
+// infoKeys are the keys of an engine "info" line; one ends the moves of a
+// currline or refutation.
+var infoKeys = map[string]bool{
+	"depth": true, "seldepth": true, "time": true, "nodes": true, "pv": true,
+	"multipv": true, "score": true, "currmove": true, "currmovenumber": true,
+	"hashfull": true, "nps": true, "tbhits": true, "sbhits": true,
+	"cpuload": true, "string": true, "refutation": true, "currline": true,
+	"wdl": true, "movesleft": true,
+}
+
+// parseInfo parses an engine "info" line split on spaces. Lines without a PV
+// (currmove updates and the like) come back with an empty PV. A truncated
+// line or one with a value that isn't a number is an infoError, rather than
+// a zero the engine never sent.
+func parseInfo(parts []string, logInfo func(string)) (Info, error) {
+	var move Info
+
+	// node counts on long searches don't fit in 32 bits
+	num := func(key string, i int) (int64, error) {
+		if i >= len(parts) || parts[i] == "" {
+			return 0, infoError{key: key}
+		}
+		n, err := strconv.ParseInt(parts[i], 10, 64)
+		if err != nil {
+			return 0, infoError{key: key, value: parts[i], want: "a number"}
+		}
+		return n, nil
+	}
+	// the moves from i to the next key, and the next key's index
+	moves := func(i int) (string, int) {
+		j := i
+		for j < len(parts) && !infoKeys[parts[j]] {
+			j++
+		}
+		return strings.Join(parts[i:j], " "), j
+	}
+
+	for i := 1; i < len(parts); i += 2 {
+		key := parts[i]
+
+		switch key {
+		case "wdl":
+			var wdl [3]int
+			for j := range wdl {
+				n, err := num(key, i+1+j)
+				if err != nil {
+					return move, err
+				}
+				wdl[j] = int(n)
+			}
+			move.WDL = WDL{Win: wdl[0], Draw: wdl[1], Loss: wdl[2]}
+			i += 2
+			continue
+		case "score":
+			if i+1 >= len(parts) {
+				return move, infoError{key: key}
+			}
+			unit := parts[i+1]
+			if unit != "cp" && unit != "mate" {
+				return move, infoError{key: key, value: unit, want: "cp or mate"}
+			}
+			n, err := num("score "+unit, i+2)
+			if err != nil {
+				return move, err
+			}
+			if unit == "cp" {
+				move.Score = int(n)
+			} else {
+				move.Mate = int(n)
+			}
+			i++
+			if i+2 < len(parts) && (parts[i+2] == "lowerbound" || parts[i+2] == "upperbound") {
+				// ignore
+				i++
+			}
+			continue
+		case "pv":
+			if i+1 >= len(parts) {
+				return move, infoError{key: key}
+			}
+			move.PV = strings.Join(parts[i+1:], " ")
+			return move, nil
+		case "currline":
+			j := i + 1
+			if j < len(parts) {
+				// the CPU number is optional
+				if n, err := strconv.Atoi(parts[j]); err == nil {
+					move.CurrLineCPU = n
+					j++
+				}
+			}
+			line, next := moves(j)
+			if line == "" {
+				return move, infoError{key: key}
+			}
+			move.CurrLine = line
+			i = next - 2
+			continue
+		case "refutation":
+			line, next := moves(i + 1)
+			if line == "" {
+				return move, infoError{key: key}
+			}
+			move.Refutation = line
+			i = next - 2
+			continue
+		case "currmove":
+			// a move, not a number
+			if i+1 >= len(parts) {
+				return move, infoError{key: key}
+			}
+			continue
+		case "nodes", "nps":
+			n, err := num(key, i+1)
+			if err != nil {
+				return move, err
+			}
+			if key == "nodes" {
+				move.Nodes = n
+			} else {
+				move.NPS = n
+			}
+			continue
+		}
+
+		var dst *int
+		switch key {
+		case "depth":
+			dst = &move.Depth
+		case "seldepth":
+			dst = &move.SelDepth
+		case "multipv":
+			dst = &move.MultiPV
+		case "hashfull":
+			dst = &move.HashFull
+		case "tbhits":
+			dst = &move.TBHits
+		case "time":
+			dst = &move.Time
+		case "currmovenumber", "movesleft", "sbhits", "cpuload":
+			// ignore
+		default:
+			logInfo(fmt.Sprintf("unknown key '%s': %s", key, strings.Join(parts, " ")))
+			continue
+		}
+
+		n, err := num(key, i+1)
+		if err != nil {
+			return move, err
+		}
+		if dst != nil {
+			*dst = int(n)
+		}
+	}
+
+	return move, nil
+}
+
+// engineLineError tells the GUI about a line from the engine that's dropped
+// because it doesn't parse.
+func (u *UCI) engineLineError(line string, err error) {
+	u.logWarn(fmt.Sprintf("SF: dropped '%s': %v", line, err))
+	u.WriteLine(fmt.Sprintf("info string ERR: engine: %v", err))
+}
+
 func (u *UCI) parseLine(line string) {
-	u.logInfo(fmt.Sprintf("-> %s", line))
+	u.logDebug(fmt.Sprintf("-> %s", line))
 
 	parts := strings.Split(strings.TrimSpace(line), " ")
 	if len(parts) == 0 {
@@ -428,23 +939,43 @@ func (u *UCI) parseLine(line string) {
 	case "quit":
 		u.Quit()
 	case "isready":
-		u.sf.Write("isready")
+		if !u.engine().Running() {
+			u.WriteLine("readyok")
+			break
+		}
+		u.askEngine("isready", "readyok")
+	case "debug":
+		u.setDebug(len(parts) < 2 || parts[1] != "off")
 	case "ucinewgame":
 		u.ResetGame()
 	case "setoption":
-		if len(parts) > 4 {
-			key := parts[2] // TODO: ignores that a key can be more than one word
-			val := parts[4]
-			u.SetOption(key, val)
+		name, value, ok := parseSetOption(strings.Fields(line)[1:])
+		if !ok {
+			u.WriteLine(fmt.Sprintf("info string ERR: '%s': want setoption name <id> [value <x>]", line))
+			break
 		}
+		u.setUserOption(name, value)
 	case "position":
 		u.SetPosition(parts[1:]...)
 	case "stop":
-		u.sf.Write(line)
+		u.stopBookDelay()
+		u.stopAnalysis()
+
+		u.moveListMtx.Lock()
+		done := u.searchDone
+		u.moveListMtx.Unlock()
+
+		u.engine().Write(line)
+		if done != nil {
+			go u.awaitStop(done, stopTimeout)
+		}
 	case "ponderhit":
-		u.sf.Write("ponderhit")
+		u.stopAnalysis()
+		u.engine().Write("ponderhit")
 	case "go":
 		u.Go(parts[1:]...)
+	case "bench":
+		go u.bench(parts[1:]...)
 	case "":
 	// no-op
 	default:
@@ -453,20 +984,40 @@ func (u *UCI) parseLine(line string) {
 	}
 }
 
+// Quit reports the game, waits for the engine to exit and flushes the log
+// before cancelling the context. It's safe to call more than once.
 func (u *UCI) Quit() {
-	u.cancel()
-	u.sf.Quit()
+	u.quit.Do(func() {
+		u.saveOptions()
+		u.reportGame()
+		u.sessionSummary()
+		u.telemetry.drain()
+
+		u.engine().Quit()
+		u.moveListMtx.Lock()
+		m := u.maia
+		u.moveListMtx.Unlock()
+		m.quit()
+
+		u.logInfo("engine stopped")
+		u.syncLog()
+
+		u.cancel()
+	})
 }
 
 func (u *UCI) SetUCI() {
 	var opts []string
-	for _, o := range u.options {
+	for _, o := range u.options.All() {
 		switch o.Type {
 		case OptionTypeCheck:
+			opts = append(opts, fmt.Sprintf("option name %s type check default %s", o.Name, o.DefaultValue()))
 		case OptionTypeSpin:
 			opts = append(opts, fmt.Sprintf("option name %s type spin default %s min %d max %d", o.Name, o.DefaultValue(), o.Min, o.Max))
 		case OptionTypeCombo:
+			opts = append(opts, fmt.Sprintf("option name %s type combo default %s var %s", o.Name, o.DefaultValue(), strings.Join(o.Options, " var ")))
 		case OptionTypeButton:
+			opts = append(opts, fmt.Sprintf("option name %s type button", o.Name))
 		case OptionTypeString:
 			opts = append(opts, fmt.Sprintf("option name %s type string default %s", o.Name, o.DefaultValue()))
 		}
@@ -481,179 +1032,419 @@ func (u *UCI) SetUCI() {
 
 	u.WriteLines(lines...)
 
-	u.sf.Write("uci")
-}
-
-func (u *UCI) SetOption(name, value string) {
-	switch strings.ToLower(name) {
-	case "threads":
-		n, err := strconv.Atoi(value)
-		if err != nil || n < 1 {
-			u.WriteLine(fmt.Sprintf("info option thread value %s invalid", value))
-			return
-		}
+	// in server mode SF was set up by an earlier session; asking again would
+	// resize the hash and throw away what's in it
+	if atomic.LoadInt64(&u.sfInitialized) == 1 || !u.engine().Running() {
+		u.WriteLine("uciok")
+		return
+	}
 
-		u.sf.Write(fmt.Sprintf("setoption name Threads value %d", n))
-		u.sf.Write(fmt.Sprintf("setoption name Hash value %d", hashMemory))
-		u.sf.Write(fmt.Sprintf("setoption name MultiPV value %d", u.gameMultiPV))
-	case "multipv":
-		// ignore
-		//u.sf.Write(fmt.Sprintf("setoption name MultiPV value %s", value))
-	case "playbad":
-		u.playBad = value == "true"
-	case "startagro":
-		u.startAgro = value == "true"
-		u.gameAgro = true
-	case "syzygypath":
-		u.sf.Write(fmt.Sprintf("setoption name SyzygyPath value %s", value))
-	case "ponder":
-		u.sf.Write(fmt.Sprintf("setoption name Ponder value %s", value))
+	u.askEngine("uci", "uciok")
+}
 
-	default:
-		u.WriteLine(fmt.Sprintf("info option '%s' not found", name))
+// engineThreads is SF's Threads: the GUI's if it set one, otherwise the
+// config's. Callers must hold moveListMtx.
+func (u *UCI) engineThreads() int {
+	if u.threads > 0 {
+		return u.threads
 	}
+	return u.config.threads()
 }
 
-func (u *UCI) setOptionRaw(v ...string) {
-	if len(v) == 0 {
+// SetOption sets one of our options. Anything else is SF's and passed on to
+// it as is.
+func (u *UCI) SetOption(name, value string) {
+	if !u.options.has(name) {
+		if value == "" {
+			if u.engineSupports(name) {
+				u.engine().Write(fmt.Sprintf("setoption name %s", name))
+			}
+		} else {
+			// kept even if this engine doesn't have it, the next one might
+			u.moveListMtx.Lock()
+			u.engineOptions.set(name, value)
+			u.moveListMtx.Unlock()
+			u.setEngineOption(name, value)
+		}
 		return
 	}
-
-	if v[0] != "name" {
-		return
+	if err := u.options.Set(name, value); err != nil {
+		u.WriteLine(fmt.Sprintf("info %v", err))
 	}
+}
 
-	i := 1
-
-	var name string
-	for ; i < len(v); i++ {
-		if v[i] == "value" {
-			break
+// registerOptions adds the options trollfish handles itself, if the caller
+// didn't declare them, and subscribes the handlers for them.
+func (u *UCI) registerOptions() error {
+	builtin := []Option{
+		{Name: "Threads", Type: OptionTypeSpin, Default: strconv.Itoa(u.config.threads()), Min: 1, Max: 1024},
+		{Name: "MultiPV", Type: OptionTypeSpin, Default: "1", Min: 1, Max: 500},
+		{Name: "MultiPVDefault", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultMultiPV), Min: 1, Max: 500},
+		{Name: "MultiPVAgro", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", agroMultiPV), Min: 1, Max: 500},
+		{Name: "PlayBad", Type: OptionTypeString, Default: "false"},
+		{Name: "StartAgro", Type: OptionTypeString, Default: "false"},
+		{Name: "SyzygyPath", Type: OptionTypeString, Default: ""},
+		{Name: "SyzygyProbeLimit", Type: OptionTypeSpin, Default: "7", Min: 0, Max: 7},
+		{Name: "Ponder", Type: OptionTypeCheck, Default: "false"},
+		{Name: "Move Overhead", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultMoveOverhead), Min: 0, Max: 5000},
+		{Name: "OwnBook", Type: OptionTypeCheck, Default: "true"},
+		{Name: "JSONInfo", Type: OptionTypeString, Default: "false"},
+		{Name: "BookDelayMin", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultBookDelayMin), Min: 0, Max: 10_000},
+		{Name: "BookDelayMax", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultBookDelayMax), Min: 0, Max: 10_000},
+		{Name: "BookDepth", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultBookDepth), Min: 0, Max: 200},
+		{Name: "BookExitTime", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultBookExitTime), Min: 0, Max: 60_000},
+		{Name: "TelemetryURL", Type: OptionTypeString, Default: ""},
+		{Name: "GameExportDir", Type: OptionTypeString, Default: ""},
+		{Name: "GameArchive", Type: OptionTypeString, Default: ""},
+		{Name: "UCI_Opponent", Type: OptionTypeString, Default: ""},
+		{Name: "UCI_Variant", Type: OptionTypeCombo, Default: string(VariantChess), Options: variantNames()},
+		{Name: "EnginePath", Type: OptionTypeString, Default: ""},
+		{Name: "EngineType", Type: OptionTypeCombo, Default: "stockfish", Options: []string{"stockfish", "lc0"}},
+		{Name: "MaiaPath", Type: OptionTypeString, Default: ""},
+		{Name: "MaiaWeights", Type: OptionTypeString, Default: ""},
+		{Name: "MaiaWeight", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultMaiaWeight), Min: 0, Max: 1000},
+		{Name: "VariantEngine", Type: OptionTypeString, Default: ""},
+		{Name: "AntichessRepertoire", Type: OptionTypeString, Default: ""},
+		{Name: "OpeningBook", Type: OptionTypeString, Default: ""},
+		{Name: "WhiteRepertoire", Type: OptionTypeString, Default: ""},
+		{Name: "BlackRepertoire", Type: OptionTypeString, Default: ""},
+		{Name: "PolyglotBook", Type: OptionTypeString, Default: ""},
+		{Name: "UCI_ShowCurrLine", Type: OptionTypeCheck, Default: "false"},
+		{Name: "UCI_Chess960", Type: OptionTypeCheck, Default: "false"},
+		{Name: "UCI_ShowWDL", Type: OptionTypeCheck, Default: "false"},
+		{Name: "ReloadConfig", Type: OptionTypeButton},
+		{Name: "Contempt", Type: OptionTypeSpin, Default: "0", Min: -1000, Max: 1000},
+		{Name: "Troll Level", Type: OptionTypeSpin, Default: strconv.Itoa(u.config.TrollLevel), Min: 0, Max: maxTrollLevel},
+		{Name: "HashPercent", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultHashPercent), Min: 1, Max: 100},
+		{Name: "ImbalanceMoves", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultImbalanceMoves), Min: 0, Max: 100},
+		{Name: "LosingThinkEval", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultLosingThinkEval), Min: -10_000, Max: 0},
+		{Name: "LosingThinkTime", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultLosingThinkTime), Min: 0, Max: 60_000},
+		{Name: "LosingThinkClock", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultLosingThinkClock), Min: 0, Max: 1000},
+		{Name: "SwindleEval", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultSwindleEval), Min: -10_000, Max: 0},
+		{Name: "ResignEval", Type: OptionTypeSpin, Default: fmt.Sprintf("%d", defaultResignEval), Min: -10_000, Max: 0},
+		{Name: "LogLevel", Type: OptionTypeCombo, Default: "default", Options: []string{"default", "debug", "info", "warn", "error"}},
+	}
+	for _, o := range builtin {
+		if _, ok := u.options.Lookup(o.Name); ok {
+			continue
+		}
+		if err := u.options.Register(o); err != nil {
+			return err
 		}
+	}
 
-		if name != "" {
-			name += " "
+	handlers := map[string]func(value string){
+		"Threads": func(value string) {
+			u.moveListMtx.Lock()
+			u.threads = atoi(value)
+			hash := u.engineHash()
+			u.moveListMtx.Unlock()
+			u.setEngineOption("Threads", value)
+			u.setEngineOption("Hash", strconv.Itoa(hash))
+		},
+		"PlayBad": func(value string) {
+			u.moveListMtx.Lock()
+			u.playBad = value == "true"
+			u.moveListMtx.Unlock()
+		},
+		"StartAgro": func(value string) {
+			u.moveListMtx.Lock()
+			u.startAgro = value == "true"
+			u.gameAgro = u.startAgro
+			u.moveListMtx.Unlock()
+		},
+		"SyzygyPath": func(value string) {
+			u.setEngineOption("SyzygyPath", value)
+		},
+		"SyzygyProbeLimit": func(value string) {
+			u.setEngineOption("SyzygyProbeLimit", value)
+		},
+		"Move Overhead": func(value string) {
+			u.setEngineOption("Move Overhead", value)
+		},
+		"Ponder": func(value string) {
+			u.setEngineOption("Ponder", value)
+		},
+		"UCI_Variant":         u.setVariant,
+		"EnginePath":          u.setEnginePath,
+		"EngineType":          u.setEngineType,
+		"MaiaPath":            u.setMaia,
+		"MaiaWeights":         u.setMaia,
+		"AntichessRepertoire": u.setAntichessRepertoire,
+		"OpeningBook":         u.setOpeningBook,
+		"WhiteRepertoire":     u.setRepertoire("w"),
+		"BlackRepertoire":     u.setRepertoire("b"),
+		"PolyglotBook":        u.setPolyglotBook,
+		"UCI_Chess960": func(value string) {
+			u.moveListMtx.Lock()
+			u.chess960 = value == "true"
+			u.moveListMtx.Unlock()
+			u.setEngineOption("UCI_Chess960", value)
+		},
+		"UCI_ShowCurrLine": func(value string) {
+			u.currLine.setEnabled(value == "true")
+		},
+		"TelemetryURL": func(value string) {
+			u.telemetry.setURL(value)
+		},
+		"UCI_Opponent": func(value string) {
+			u.moveListMtx.Lock()
+			u.opponent = parseOpponent(value)
+			u.moveListMtx.Unlock()
+		},
+		"ImbalanceMoves": func(value string) {
+			u.moveListMtx.Lock()
+			u.imbalanceMoves = atoi(value)
+			u.moveListMtx.Unlock()
+		},
+		"LosingThinkEval":  u.setLosingPolicy(func(p *losingPolicy, n int) { p.thinkEval = n }),
+		"LosingThinkTime":  u.setLosingPolicy(func(p *losingPolicy, n int) { p.thinkTime = n }),
+		"LosingThinkClock": u.setLosingPolicy(func(p *losingPolicy, n int) { p.thinkClock = n }),
+		"SwindleEval":      u.setLosingPolicy(func(p *losingPolicy, n int) { p.swindleEval = n }),
+		"ResignEval":       u.setLosingPolicy(func(p *losingPolicy, n int) { p.resignEval = n }),
+		"HashPercent":      u.setHashPercent,
+		"LogLevel":         u.setLogLevel,
+		"UCI_ShowWDL": func(value string) {
+			u.moveListMtx.Lock()
+			u.showWDL = value == "true"
+			u.moveListMtx.Unlock()
+			u.setEngineOption("UCI_ShowWDL", value)
+		},
+		"Contempt": func(value string) {
+			u.moveListMtx.Lock()
+			u.contempt = atoi(value)
+			u.moveListMtx.Unlock()
+		},
+		"Troll Level": func(value string) {
+			u.moveListMtx.Lock()
+			u.config.TrollLevel = atoi(value)
+			u.moveListMtx.Unlock()
+		},
+		"OwnBook": func(value string) {
+			u.moveListMtx.Lock()
+			u.ownBook = value == "true"
+			u.moveListMtx.Unlock()
+		},
+		"JSONInfo": func(value string) {
+			u.moveListMtx.Lock()
+			u.jsonInfo = value == "true"
+			u.moveListMtx.Unlock()
+		},
+		// in games MultiPV is up to the troll policy, the GUI's value is for
+		// analysis
+		"MultiPV":        u.setMultiPV,
+		"MultiPVDefault": u.setMultiPVConfig(func(c *MultiPVConfig, n int) { c.Default = n }),
+		"MultiPVAgro":    u.setMultiPVConfig(func(c *MultiPVConfig, n int) { c.Agro = n }),
+	}
+	for name, fn := range handlers {
+		if err := u.options.OnChange(name, fn); err != nil {
+			return err
 		}
-		name += v[i]
 	}
 
-	if i == len(v) || v[i] != "value" {
-		// TODO: only valid for buttons
-		return
+	if err := u.options.OnPress("ReloadConfig", u.pressReloadConfig); err != nil {
+		return err
 	}
 
-	var value string
-	for ; i < len(v); i++ {
-		if value != "" {
-			value += " "
-		}
-		value += v[i]
+	return nil
+}
+
+// parseSetOption reads "name <name> value <value>", where both the name and
+// the value can be more than one word. A button has a name and no value.
+func parseSetOption(v []string) (name, value string, ok bool) {
+	if len(v) == 0 || v[0] != "name" {
+		return "", "", false
+	}
+
+	i := 1
+	for i < len(v) && v[i] != "value" {
+		i++
 	}
+	if i == 1 {
+		return "", "", false
+	}
+	name = strings.Join(v[1:i], " ")
+	if i < len(v) {
+		value = strings.Join(v[i+1:], " ")
+	}
+	return name, value, true
 }
 
 func (u *UCI) Go(v ...string) {
+	p, err := ParseGoParams(v)
+	bookDepth := u.options.Int("BookDepth")
+
 	u.moveListMtx.Lock()
 	u.moveList = nil
 	u.moveListPrinted = false
 	u.moveListNodes = 0
+	u.refutations = nil
+	u.goTiming = goTiming{}
+	if !p.Infinite && !p.Ponder {
+		u.goTiming.start = time.Now()
+	}
+	u.scramble = err == nil && isScramble(p, u.gameActiveColor, u.config.scrambleTime())
+	u.analysis = err == nil && p.isAnalysis()
+	u.untilStop = err == nil && (p.Infinite || p.Ponder)
+	u.searchMoves = p.SearchMoves
+	u.goClock[0], _, u.goClock[1], _ = p.Clock(u.gameActiveColor)
+	// the books are standard chess, and castle the standard way
+	ownBook := u.ownBook && !u.chess960 && (bookDepth == 0 || u.gameMoveCount <= bookDepth)
+	// the game as of this go; the read loop updates the score as SF searches
+	moveCount, color, score, gameAgro := u.gameMoveCount, u.gameActiveColor, u.gameScore, u.gameAgro
+	fen, variant, scramble := u.fen, u.variant, u.scramble
+	_, recapture := lastCapture(u.history)
 	u.moveListMtx.Unlock()
 
-	if u.fen == startPosFEN {
-		move := getFirstMove()
-		u.logInfo(fmt.Sprintf("book_move: %s", move))
-		u.WriteLine("bestmove " + move)
+	if err != nil {
+		// let SF make sense of it rather than leave the GUI without a bestmove
+		u.WriteLine(fmt.Sprintf("info string ERR: %v", err))
+		u.sendGo(strings.Join(v, " "))
 		return
 	}
 
-	// passthroughs
-	if len(v) <= 1 || u.gameAgro {
-		u.sf.Write(fmt.Sprintf("go %s", strings.Join(v, " ")))
+	// mated or stalemated: answered as SF would, without the selector or a
+	// book picking a move that isn't there. Ponder and infinite searches wait
+	// for "stop" before their bestmove, SF keeps to that.
+	if score, ok := u.terminalScore(); ok && !p.Ponder && !p.Infinite {
+		u.logInfo(fmt.Sprintf("no legal moves, score %s", score))
+		u.WriteLine("info depth 0 score " + score)
+		u.WriteLine("bestmove (none)")
+		u.finishSearch()
 		return
 	}
 
-	if v[0] != "wtime" {
-		u.sf.Write(fmt.Sprintf("go %s", strings.Join(v, " ")))
+	u.moveListMtx.Lock()
+	u.goMate = p.Mate
+	u.moveListMtx.Unlock()
+
+	if p.Mate > 0 {
+		u.sendGo(p.String())
 		return
 	}
 
-	var wtime, btime, winc, binc int
-	for i := 0; i < len(v); i += 2 {
-		switch v[i] {
-		case "wtime":
-			wtime = atoi(v[i+1])
-		case "winc":
-			winc = atoi(v[i+1])
-		case "btime":
-			btime = atoi(v[i+1])
-		case "binc":
-			binc = atoi(v[i+1])
-		default:
-			// no-op
+	chess := variant.isChess()
+
+	if ownBook && chess && fen == startPosFEN && !p.isAnalysis() {
+		if move := getFirstMove(); p.allows(move) {
+			u.playBookMove(move, p)
+			return
+		}
+	}
+
+	// trivial endings are played from the built-in tables without asking SF
+	if chess && fen != "" && !p.isAnalysis() && !p.Ponder {
+		if move, score, ok := EndgameMove(FENtoBoard(fen)); ok && p.allows(move) {
+			u.logInfo(fmt.Sprintf("endgame_move: %s score: %s", move, egScoreString(score)))
+			u.WriteLine(fmt.Sprintf("info depth 1 score %s pv %s", egScoreString(score), move))
+			u.WriteLine("bestmove " + move)
+			u.finishSearch()
+			return
 		}
 	}
 
-	if move := u.BookMove(); move != "" {
-		u.logInfo(fmt.Sprintf("book_move: %s", move))
-		u.WriteLine("bestmove " + move)
+	// passthroughs; a search the GUI limited itself keeps its limits rather
+	// than get a humanized move time
+	if gameAgro || !p.HasClock() || p.Ponder || p.Infinite || p.Limited() {
+		if !p.Ponder {
+			u.moveListMtx.Lock()
+			u.bookExit = false
+			u.moveListMtx.Unlock()
+		}
+		u.sendGo(p.String())
 		return
 	}
 
-	var ourTime, oppTime, ourInc, oppInc int
-	if u.gameActiveColor == "w" {
-		ourTime, ourInc = wtime, winc
-		oppTime, oppInc = btime, binc
-	} else {
-		oppTime, oppInc = wtime, winc
-		ourTime, ourInc = btime, binc
+	if ownBook && chess {
+		if move := u.BookMove(); move != "" && p.allows(move) {
+			u.playBookMove(move, p)
+			return
+		}
+	} else if ownBook && variant.isAntichess() {
+		if move := u.antichessBookMove(); move != "" && p.allows(move) {
+			u.playBookMove(move, p)
+			return
+		}
 	}
 
-	ourTime -= 500 // account for network latency
+	ourTime, ourInc, oppTime, oppInc := p.Clock(color)
+
+	u.moveListMtx.Lock()
+	bookExit := u.bookExit
+	u.bookExit = false
+	u.gameClock.observe(moveCount, ourTime, oppTime)
+	clock := u.gameClock
+	cfg := u.config
+	losing := u.losing
+	u.moveListMtx.Unlock()
+	odds := clock.odds()
+
+	// account for network and GUI latency
+	ourTime -= u.options.Int("Move Overhead")
 	if ourTime <= 0 {
 		ourTime = 1
 	}
 
+	// with time odds, compare against the opponent's clock as if it started
+	// the same as ours
+	oppClock := clock.parity(oppTime)
+
 	lowTime := ourTime < 15_000
 	veryLowTime := ourTime < 5_000
 
-	u.sf.Write(fmt.Sprintf("info string our_time: %d+%d opp_time: %d+%d active_color: %s %v low_time: %v very_low_time: %v",
-		ourTime, ourInc, oppTime, oppInc, u.gameActiveColor, v, lowTime, veryLowTime))
+	u.engine().Write(fmt.Sprintf("info string our_time: %d+%d opp_time: %d+%d active_color: %s %v low_time: %v very_low_time: %v scramble: %v clock: %v",
+		ourTime, ourInc, oppTime, oppInc, color, p, lowTime, veryLowTime, scramble, clock))
 
 	// don't tell SF we're in a time control
 	// TODO: improve time management
 	agro := false
 
-	moveTime := 1000 + rand.Intn(500)
+	moveTime := cfg.MoveTime.Default.pick()
 	mate := false
 
-	if u.gameMoveCount < 5 {
-		moveTime = 250 + rand.Intn(500)
-	} else if u.gameMateIn > 0 {
+	if moveCount < 5 {
+		moveTime = cfg.MoveTime.Opening.pick()
+	} else if score.Mate > 0 {
 		agro = true
 		mate = true
-		moveTime = max(250, 75*u.gameMateIn)
-	} else if u.gameEval > 800 {
+		moveTime = max(250, 75*score.Mate)
+	} else if winProbAtLeast(score, gamePly(moveCount, color), cfg.agroEval()) {
 		agro = true
-	} else if u.gameMoveCount >= 23 && u.gameMoveCount < 35 {
-		if u.gameEval < 150 {
+	} else if moveCount >= cfg.Agro.MiddlegameMove && moveCount < cfg.Agro.EndgameMove {
+		if score.cp() < cfg.Agro.MiddlegameEval {
 			agro = true
-			moveTime = 2000 + rand.Intn(1000)
+			moveTime = cfg.MoveTime.Middlegame.pick()
 		}
-	} else if u.gameMoveCount >= 35 {
+	} else if moveCount >= cfg.Agro.EndgameMove {
 		agro = true
-		if u.gameEval < 350 {
-			moveTime = 1500 + rand.Intn(1000)
+		if score.cp() < 350 {
+			moveTime = cfg.MoveTime.Endgame.pick()
 		}
 	}
 
+	// the first move out of book is where a human stops playing from memory
+	if exitTime := cfg.theatrics(u.options.Int("BookExitTime")); bookExit && moveTime < exitTime {
+		u.logInfo(fmt.Sprintf("book_exit: move time %d -> %d", moveTime, exitTime))
+		moveTime = exitTime + rand.Intn(500)
+	}
+
 	// we're losing, stop to think
-	ponderEval := u.gameEval < -60 || (u.gameEval > 60 && u.gameEval < 400)
-	if ponderEval && ourTime > (oppTime/2) {
-		moveTime = 3500 + rand.Intn(1000)
+	if thinkTime, ok := losing.moveTime(score, ourTime, oppClock); ok {
+		moveTime = max(moveTime, cfg.theatrics(thinkTime+rand.Intn(1000)))
+	} else if eval := score.cp(); eval > 60 && eval < 400 && ourTime > (oppClock/2) {
+		moveTime = max(moveTime, cfg.theatrics(cfg.MoveTime.Advantage.pick()))
 	}
 
-	maxTime1 := (ourTime - oppTime) / 2
+	u.moveListMtx.Lock()
+	pouncing := u.pounce.active()
+	u.moveListMtx.Unlock()
+	if pouncing {
+		moveTime *= pounceTimeFactor
+	}
+
+	maxTime1 := (ourTime - oppClock) / 2
 	var maxTime2 int
-	if maxTime1 < 0 && (oppTime*100 > ourTime*115 || ourTime <= 20_000) {
+	if maxTime1 < 0 && (oppClock*100 > ourTime*115 || ourTime <= 20_000) {
 		maxTime2 = ourTime / 100
 	} else {
 		maxTime2 = ourTime / 20
@@ -662,10 +1453,13 @@ func (u *UCI) Go(v ...string) {
 	minTimeBasedOnInc := min(ourInc*3/4, 5000)
 
 	maxTime := max(maxTime1, maxTime2)
+	if odds {
+		maxTime = min(maxTime, timeOddsBudget(ourTime, ourInc, moveCount))
+	}
 	origMoveTime := moveTime
 	moveTime = min(moveTime, maxTime)
 	moveTime = max(moveTime, minTimeBasedOnInc)
-	if u.gameEval > 2000 {
+	if score.cp() > 2000 {
 		if ourTime > 2500 {
 			moveTime = 2500
 		} else {
@@ -675,36 +1469,112 @@ func (u *UCI) Go(v ...string) {
 	if mate {
 		moveTime = 250
 	}
+	if scramble {
+		moveTime = scrambleMoveTime
+		if recapture {
+			moveTime = scrambleRecaptureTime
+		}
+	}
+	// a classical time control: make it to the control with time left
+	if p.MovesToGo > 0 {
+		moveTime = min(moveTime, movesToGoBudget(ourTime, ourInc, p.MovesToGo))
+	}
 	moveTime = min(moveTime, ourTime)
 	moveTime = max(moveTime, 5)
 
-	u.logInfo(fmt.Sprintf("ourTime: %d oppTime: %d maxTime1: %d maxTime2: %d maxTime: %d origMoveTime: %d finalMoveTime: %d",
-		ourTime, oppTime,
+	u.logInfo(fmt.Sprintf("ourTime: %d oppTime: %d oppClock: %d movesToGo: %d maxTime1: %d maxTime2: %d maxTime: %d origMoveTime: %d finalMoveTime: %d",
+		ourTime, oppTime, oppClock, p.MovesToGo,
 		maxTime1, maxTime2, maxTime,
 		origMoveTime, moveTime,
 	))
 
 	u.moveListMtx.Lock()
 	if agro || u.gameAgro {
-		u.gameAgro = true
-		if u.gameMultiPV != agroMultiPV {
-			u.gameMultiPV = agroMultiPV
-			u.sf.Write(fmt.Sprintf("setoption name MultiPV value %d", u.gameMultiPV))
-		}
+		u.setAgro()
 	}
 	u.moveListMtx.Unlock()
 
-	u.sf.Write(fmt.Sprintf("go movetime %d", moveTime))
+	u.setLogContext(func(c *logContext) { c.moveTime = moveTime })
+	u.sendGo(GoParams{MoveTime: moveTime, SearchMoves: p.SearchMoves}.String())
+}
+
+// sendGo forwards a search to SF, or has the built-in engine answer it if SF
+// isn't running.
+func (u *UCI) sendGo(args string) {
+	u.moveListMtx.Lock()
+	u.startEngineTiming()
+	u.applyMultiPV()
+	done := make(chan struct{})
+	u.searchDone = done
+	m, fen := u.maia, u.fen
+	u.moveListMtx.Unlock()
+
+	if !u.engine().Running() {
+		u.goWithoutEngine()
+		return
+	}
+
+	m.ask(fen)
+
+	u.engine().Write(strings.TrimSpace("go " + args))
+	go u.watchSearch(done, args)
+}
+
+// goWithoutEngine answers a search with the built-in engine, except a "go
+// infinite" or "go ponder", whose bestmove has to wait for stop or ponderhit.
+func (u *UCI) goWithoutEngine() {
+	u.moveListMtx.Lock()
+	held := u.untilStop
+	u.analysisHeld = held
+	u.moveListMtx.Unlock()
+	if held {
+		return
+	}
+	u.playFallbackMove("no engine")
+}
+
+// stopAnalysis answers a "go infinite" or "go ponder" that's been waiting for
+// stop or ponderhit with no engine to search it. Either way the search no
+// longer waits: ponderhit turns a ponder into a normal search.
+func (u *UCI) stopAnalysis() {
+	u.moveListMtx.Lock()
+	held := u.analysisHeld
+	if held {
+		u.analysisHeld, u.analysis = false, false
+	}
+	u.untilStop = false
+	u.moveListMtx.Unlock()
+
+	if held {
+		u.playFallbackMove("no engine")
+	}
 }
 
 func (u *UCI) SetPosition(v ...string) {
 	if len(v) == 0 {
+		u.WriteLine("info string ERR: position without startpos or fen")
 		return
 	}
 
 	cmd := v[0]
 
-	u.sf.Write(fmt.Sprintf("position %s", strings.Join(v, " ")))
+	if cmd == "fen" {
+		// checked before SF gets it, a FEN it can't read can crash it
+		fenEnd := 1
+		for fenEnd < len(v) && v[fenEnd] != "moves" {
+			fenEnd++
+		}
+		if err := checkFEN(strings.Join(v[1:fenEnd], " ")); err != nil {
+			u.WriteLine(fmt.Sprintf("info string ERR: position: %v", err))
+			return
+		}
+	}
+
+	position := fmt.Sprintf("position %s", strings.Join(v, " "))
+	u.engine().Write(position)
+	u.moveListMtx.Lock()
+	u.position = position
+	u.moveListMtx.Unlock()
 
 	if cmd == "fen" {
 		var fenEnd int
@@ -714,16 +1584,14 @@ func (u *UCI) SetPosition(v ...string) {
 			}
 		}
 		u.fen = strings.Join(v[1:fenEnd], " ")
-		b := FENtoBoard(u.fen)
+		start := FENtoBoard(u.fen)
+		start.Variant = u.variant
+		start.Chess960 = start.Chess960 || u.chess960
+		var moves []string
 		if len(v) != fenEnd && v[fenEnd] == "moves" {
-			moves := v[fenEnd+1:]
-			b.Moves(moves...)
+			moves = v[fenEnd+1:]
 		}
-		u.fen = b.FEN()
-		u.gameMoveCount = atoi(b.FullMove)
-		u.gameActiveColor = b.ActiveColor
-
-		u.WriteLine(fmt.Sprintf("info fen set to '%s' move %d, %s to play", u.fen, u.gameMoveCount, u.gameActiveColor))
+		u.setHistory(start, moves)
 		return
 	}
 
@@ -734,7 +1602,7 @@ func (u *UCI) SetPosition(v ...string) {
 	}
 
 	if len(v) == 1 {
-		u.fen = startPosFEN
+		u.fen = u.variant.startFEN()
 		u.WriteLine(fmt.Sprintf("info fen set to '%s', move 1, w to play", u.fen))
 		return
 	}
@@ -742,7 +1610,7 @@ func (u *UCI) SetPosition(v ...string) {
 	cmd = v[1]
 
 	if cmd != "moves" {
-		u.fen = startPosFEN
+		u.fen = u.variant.startFEN()
 		u.WriteLine(fmt.Sprintf("info fen set to '%s'", u.fen))
 		u.WriteLine(fmt.Sprintf("info ERR: position startpos '%s' command unknown", cmd))
 		return
@@ -750,13 +1618,34 @@ func (u *UCI) SetPosition(v ...string) {
 
 	moves := v[2:]
 
-	b := FENtoBoard(u.fen)
-	b.Moves(moves...)
+	start := FENtoBoard(u.variant.startFEN())
+	start.Variant = u.variant
+	start.Chess960 = u.chess960
+	u.setHistory(start, moves)
+}
+
+// setHistory brings the game's move history up to date with a "position"
+// command and makes its last position the current one.
+func (u *UCI) setHistory(start Board, moves []string) {
+	u.moveListMtx.Lock()
+	h, undone := u.history.sync(start, moves)
+	u.history = h
+	b := h.Board()
 	u.fen = b.FEN()
 	u.gameMoveCount = atoi(b.FullMove)
 	u.gameActiveColor = b.ActiveColor
+	fen, moveCount := u.fen, u.gameMoveCount
+	u.moveListMtx.Unlock()
+
+	if undone > 0 && h.Len() > 0 {
+		u.logInfo(fmt.Sprintf("takeback: %d moves", undone))
+	}
+
+	u.setLogContext(func(c *logContext) { c.fen = fen })
 
-	u.WriteLine(fmt.Sprintf("info fen set to '%s' move %d, %s to play", u.fen, u.gameMoveCount, u.gameActiveColor))
+	u.WriteLine(fmt.Sprintf("info fen set to '%s' move %d, %s to play", fen, moveCount, b.ActiveColor))
+	u.checkGameOver()
+	u.saveGame()
 }
 
 func (u *UCI) printMoveList(lock bool) {
@@ -769,10 +1658,38 @@ func (u *UCI) printMoveList(lock bool) {
 		return
 	}
 
-	pvs := make([]string, 0, len(u.moveList))
-	for _, move := range u.moveList {
+	lines := multiPVLines(u.moveList)
+	if u.goMate > 0 {
+		// SF's mate scores wait for verifyMate to confirm them
+		kept := lines[:0]
+		for _, move := range lines {
+			if move.Mate == 0 {
+				kept = append(kept, move)
+			}
+		}
+		lines = kept
+	}
+
+	pvs := make([]string, 0, len(lines))
+	for i, move := range lines {
+		// the GUI only gets a WDL if it asked for one, modeled if the engine
+		// didn't send it
+		switch {
+		case !u.showWDL:
+			lines[i].WDL = WDL{}
+		case !move.WDL.valid():
+			lines[i].WDL = modelWDL(move.Score, move.Mate)
+		}
+	}
+
+	for _, move := range lines {
 		pvs = append(pvs, fmt.Sprintf("info %s", move.String()))
 	}
+	if u.jsonInfo {
+		for _, move := range lines {
+			pvs = append(pvs, fmt.Sprintf("info string json %s", move.JSON()))
+		}
+	}
 	u.WriteLines(pvs...)
 
 	u.moveListPrinted = true
@@ -781,8 +1698,8 @@ func (u *UCI) printMoveList(lock bool) {
 func (u *UCI) WriteLine(s string) {
 	u.mtxStdout.Lock()
 	defer u.mtxStdout.Unlock()
-	u.logInfo(fmt.Sprintf("<- %s", s))
-	_, _ = fmt.Fprintln(os.Stdout, s)
+	u.logDebug(fmt.Sprintf("<- %s", s))
+	_, _ = fmt.Fprintln(u.out, s)
 }
 
 func (u *UCI) WriteLines(v ...string) {
@@ -791,19 +1708,28 @@ func (u *UCI) WriteLines(v ...string) {
 		w.WriteString(s)
 		w.WriteRune('\n')
 
-		u.logInfo("<- " + s)
+		u.logDebug("<- " + s)
 	}
 	s := w.String()
 
 	u.mtxStdout.Lock()
 	defer u.mtxStdout.Unlock()
-	_, _ = fmt.Fprint(os.Stdout, s)
+	_, _ = fmt.Fprint(u.out, s)
 }
 
 func ts() string {
 	return fmt.Sprintf("[%s]", time.Now().Format("2006-01-02 15:04:05"))
 }
 
+func hasToken(v []string, token string) bool {
+	for _, s := range v {
+		if s == token {
+			return true
+		}
+	}
+	return false
+}
+
 func atoi(s string) int {
 	n, err := strconv.Atoi(s)
 	if err != nil {
@@ -812,14 +1738,6 @@ func atoi(s string) int {
 	return n
 }
 
-// redirectStderr to the file passed in
-func redirectStderr(f *os.File) {
-	err := syscall.Dup2(int(f.Fd()), int(os.Stderr.Fd()))
-	if err != nil {
-		log.Fatalf("Failed to redirect stderr to file: %v", err)
-	}
-}
-
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -0,0 +1,187 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetIO(t *testing.T) {
+	u, _ := newTestUCI(t)
+	dir := t.TempDir()
+	u.crashDir = dir
+	if err := u.SetLogSinks("file=" + filepath.Join(dir, "trollfish.log")); err != nil {
+		t.Fatal(err)
+	}
+	u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+		return nil, errors.New("no engine")
+	})
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	u.SetIO(inR, outW)
+
+	_, cancel, err := u.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	defer outR.Close()
+
+	lines := make(chan string, 512)
+	go func() {
+		r := bufio.NewScanner(outR)
+		for r.Scan() {
+			lines <- r.Text()
+		}
+	}()
+
+	expect := func(want string) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case line := <-lines:
+				if strings.HasPrefix(line, want) {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("timed out waiting for '%s'", want)
+			}
+		}
+	}
+
+	_, _ = io.WriteString(inW, "uci\n")
+	expect("id name test")
+	_, _ = io.WriteString(inW, "isready\n")
+	expect("readyok")
+
+	_ = inW.Close()
+}
+
+func TestStartErrors(t *testing.T) {
+	u, _ := newTestUCI(t)
+	dir := t.TempDir()
+	u.crashDir = dir
+	u.SetIO(strings.NewReader(""), io.Discard)
+	if err := u.SetLogSinks("file=" + filepath.Join(dir, "missing", "trollfish.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := u.Start(context.Background()); err == nil {
+		t.Fatal("unwritable log: want an error")
+	}
+
+	// the caller can fix the log and try again; a missing engine isn't fatal,
+	// the built-in engine plays
+	if err := u.SetLogSinks("file=" + filepath.Join(dir, "trollfish.log")); err != nil {
+		t.Fatal(err)
+	}
+	var startErr error
+	u.SetEngineStarter(func(ctx context.Context, path string, logInfo func(string)) (EngineBackend, error) {
+		sf, err := StartStockfish(ctx, filepath.Join(dir, "stockfish"), logInfo)
+		startErr = err
+		return sf, err
+	})
+	_, cancel, err := u.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	var notFound *EngineNotFoundError
+	if !errors.As(startErr, &notFound) {
+		t.Errorf("want an EngineNotFoundError got: %v", startErr)
+	}
+	if u.sf.Running() {
+		t.Error("want the built-in engine")
+	}
+}
+
+func TestParseInfoErrors(t *testing.T) {
+	cases := []struct {
+		line string
+		want string
+	}{
+		{"info depth 18 seldepth 24 multipv 1 score cp 42 pv e1g1", ""},
+		{"info depth 18 score cp 12 lowerbound nodes 50 pv e1g1", ""},
+		{"info depth 18 currmove e2e4 currmovenumber 1", ""},
+		{"info depth", "info line: 'depth' without a value"},
+		{"info depth 18 nodes", "info line: 'nodes' without a value"},
+		{"info depth 18 score cp", "info line: 'score cp' without a value"},
+		{"info depth 18 score", "info line: 'score' without a value"},
+		{"info depth 18 score mate x", "info line: 'score mate' value 'x' isn't a number"},
+		{"info depth 18 score pawns 3", "info line: 'score' value 'pawns' isn't cp or mate"},
+		{"info depth 18 wdl 200 600", "info line: 'wdl' without a value"},
+		{"info depth 1e pv e2e4", "info line: 'depth' value '1e' isn't a number"},
+		{"info depth 18 pv", "info line: 'pv' without a value"},
+	}
+
+	for _, c := range cases {
+		info, err := parseInfo(strings.Split(c.line, " "), func(string) {})
+		var got string
+		if err != nil {
+			got = err.Error()
+			var ie infoError
+			if !errors.As(err, &ie) {
+				t.Errorf("%s: want an infoError got %T", c.line, err)
+			}
+		}
+		if got != c.want {
+			t.Errorf("%s\nwant: '%s'\ngot:  '%s'", c.line, c.want, got)
+		}
+		if err == nil && info.Depth != 18 {
+			t.Errorf("%s: depth want: 18 got: %d", c.line, info.Depth)
+		}
+	}
+}
+
+func TestParseInfoNodes64(t *testing.T) {
+	// a day on a big box: past 2^32 nodes
+	line := "info depth 60 seldepth 80 multipv 1 score cp 31 nodes 8589934592123 nps 99420539 hashfull 1000 tbhits 0 time 86400000 pv e2e4"
+	info, err := parseInfo(strings.Split(line, " "), func(s string) { t.Error(s) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Nodes != 8589934592123 || info.NPS != 99420539 {
+		t.Errorf("nodes want: 8589934592123 got: %d nps want: 99420539 got: %d", info.Nodes, info.NPS)
+	}
+	if !strings.Contains(info.String(), "nodes 8589934592123 nps 99420539") {
+		t.Errorf("String: %s", info.String())
+	}
+}
+
+func TestParseInfoLines(t *testing.T) {
+	cases := []struct {
+		line string
+		want Info
+	}{
+		{"info currline 2 e2e4 e7e5 g1f3", Info{CurrLine: "e2e4 e7e5 g1f3", CurrLineCPU: 2}},
+		{"info depth 9 currline e2e4 e7e5 nodes 1200", Info{Depth: 9, CurrLine: "e2e4 e7e5", Nodes: 1200}},
+		{"info refutation d1h5 g6h5", Info{Refutation: "d1h5 g6h5"}},
+		{"info refutation d1h5 g6h5 depth 7 pv e2e4", Info{Refutation: "d1h5 g6h5", Depth: 7, PV: "e2e4"}},
+		{"info depth 9 sbhits 3 cpuload 980 pv e2e4", Info{Depth: 9, PV: "e2e4"}},
+	}
+
+	for _, c := range cases {
+		got, err := parseInfo(strings.Split(c.line, " "), func(s string) { t.Error(s) })
+		if err != nil {
+			t.Errorf("%s: %v", c.line, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s\nwant: %+v\ngot:  %+v", c.line, c.want, got)
+		}
+	}
+
+	for _, line := range []string{"info currline", "info currline 1", "info refutation depth 3"} {
+		if _, err := parseInfo(strings.Split(line, " "), func(string) {}); err == nil {
+			t.Errorf("%s: want an error", line)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// Variant is a chess variant, named as Fairy-Stockfish's UCI_Variant values.
+type Variant string
+
+const (
+	VariantChess         Variant = "chess"
+	VariantCrazyhouse    Variant = "crazyhouse"
+	VariantAtomic        Variant = "atomic"
+	VariantKingOfTheHill Variant = "kingofthehill"
+	VariantHorde         Variant = "horde"
+	VariantAntichess     Variant = "antichess"
+	VariantGiveaway      Variant = "giveaway"
+)
+
+var variants = []Variant{VariantChess, VariantCrazyhouse, VariantAtomic, VariantKingOfTheHill, VariantHorde, VariantAntichess, VariantGiveaway}
+
+const hordeStartFEN = "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+
+func parseVariant(s string) (Variant, error) {
+	for _, v := range variants {
+		if strings.EqualFold(s, string(v)) {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unknown variant '%s'", s)
+}
+
+func variantNames() []string {
+	names := make([]string, 0, len(variants))
+	for _, v := range variants {
+		names = append(names, string(v))
+	}
+	return names
+}
+
+// startFEN is the variant's starting position.
+func (v Variant) startFEN() string {
+	switch v {
+	case VariantCrazyhouse:
+		return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"
+	case VariantHorde:
+		return hordeStartFEN
+	case VariantAntichess:
+		return antichessStartFEN
+	}
+	return startPosFEN
+}
+
+// isChess is true for standard chess, where the book, endgame tables and mate
+// prover apply.
+func (v Variant) isChess() bool {
+	return v == "" || v == VariantChess
+}
+
+var kingOfTheHillCenter = []int{27, 28, 35, 36} // d5, e5, d4, e4
+
+// VariantResult returns "1-0" or "0-1" if the game is over by a variant rule
+// (a king reaching the hill, a king exploding, the horde being wiped out, a
+// side giving away everything), or
+// "" if it isn't.
+func (b *Board) VariantResult() string {
+	switch b.Variant {
+	case VariantKingOfTheHill:
+		for _, sq := range kingOfTheHillCenter {
+			switch b.Pos[sq] {
+			case 'K':
+				return "1-0"
+			case 'k':
+				return "0-1"
+			}
+		}
+	case VariantAtomic:
+		if b.kingSquare(true) == -1 {
+			return "0-1"
+		}
+		if b.kingSquare(false) == -1 {
+			return "1-0"
+		}
+	case VariantHorde:
+		for _, c := range b.Pos {
+			if isWhitePiece(c) {
+				return ""
+			}
+		}
+		return "0-1"
+	case VariantAntichess, VariantGiveaway:
+		return b.antichessResult()
+	}
+	return ""
+}
+
+// dropMoves returns the crazyhouse drops for the side to move, "N@f3", one
+// per piece type in its pocket. Pawns can't be dropped on the first or last
+// rank.
+func (b *Board) dropMoves() []string {
+	white := b.ActiveColor == "w"
+
+	var moves []string
+	seen := make(map[rune]bool)
+	for _, p := range b.Pocket {
+		if isWhitePiece(p) != white || seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		piece := unicode.ToUpper(p)
+		for sq, c := range b.Pos {
+			if c != ' ' || (piece == 'P' && (sq < 8 || sq >= 56)) {
+				continue
+			}
+			moves = append(moves, fmt.Sprintf("%c@%s", piece, indexToUCI(sq)))
+		}
+	}
+	return moves
+}
+
+// atomicLegal reports whether a pseudo-legal move is legal in atomic. Kings
+// can't capture, since they'd explode too, and a move can't blow up its own
+// king. Blowing up the other king wins even out of check, and kings that
+// touch can't check each other, since taking one would explode both.
+func (b *Board) atomicLegal(move string) bool {
+	white := b.ActiveColor == "w"
+	from, to := uciToIndex(move[:2]), uciToIndex(move[2:4])
+	piece := b.Pos[from]
+	isKing := unicode.ToLower(piece) == 'k'
+
+	enPassant := unicode.ToLower(piece) == 'p' && move[2:4] == b.EnPassantSquare && b.Pos[to] == ' '
+	capture := b.Pos[to] != ' ' || enPassant
+	if isKing && capture {
+		return false
+	}
+
+	pos := b.Clone()
+	pos.movePieces(move)
+	if capture {
+		pos.explode(to)
+	}
+
+	own, opp := pos.kingSquare(white), pos.kingSquare(!white)
+	switch {
+	case own == -1:
+		return false
+	case opp == -1:
+		return true
+	case kingsTouch(own, opp):
+		return true
+	}
+	return !pos.IsAttacked(own, !white)
+}
+
+// drop plays a crazyhouse drop ("N@f3") for the side to move.
+func (b *Board) drop(move string, white bool) {
+	piece := rune(move[0])
+	if white {
+		piece = unicode.ToUpper(piece)
+	} else {
+		piece = unicode.ToLower(piece)
+	}
+
+	if idx := strings.IndexRune(b.Pocket, piece); idx != -1 {
+		b.Pocket = b.Pocket[:idx] + b.Pocket[idx+1:]
+	}
+	b.Pos[uciToIndex(move[2:4])] = piece
+}
+
+// pocketCapture adds a captured piece to the capturer's pocket. Promoted
+// pieces go back to being pawns.
+func (b *Board) pocketCapture(captured rune, promoted bool) {
+	if captured == ' ' {
+		return
+	}
+
+	// the capturer gets it as its own color
+	white := isWhitePiece(captured)
+	if promoted {
+		captured = 'p'
+	}
+	if white {
+		captured = unicode.ToLower(captured)
+	} else {
+		captured = unicode.ToUpper(captured)
+	}
+	b.Pocket += string(captured)
+}
+
+// explode removes the capturing piece and every piece but pawns next to the
+// square of an atomic capture.
+func (b *Board) explode(sq int) {
+	b.Pos[sq] = ' '
+	row, col := sq/8, sq%8
+	for _, o := range kingOffsets {
+		r, c := row+o[0], col+o[1]
+		if !onBoard(r, c) {
+			continue
+		}
+		if p := b.Pos[r*8+c]; p != 'P' && p != 'p' {
+			b.Pos[r*8+c] = ' '
+		}
+	}
+}
+
+// switchEngine replaces the running engine with the one at path, for example
+// Fairy-Stockfish for variants. User options that are forwarded to the engine
+// are sent again.
+func (u *UCI) switchEngine(path string) error {
+	sf, err := u.engineStarter()(u.ctx, path, u.logInfo)
+	if err != nil {
+		return fmt.Errorf("start engine '%s': %w", path, err)
+	}
+
+	old := u.setEngine(sf, path)
+	old.Quit()
+
+	u.moveListMtx.Lock()
+	u.staleBestMoves = 0
+	// what this engine has is known if it ran before, until it answers uci
+	u.engineCaps = u.knownEngines[path]
+	u.moveListMtx.Unlock()
+
+	go u.stockFishReadLoop(sf)
+	go u.watchEngine(sf)
+
+	u.logInfo(fmt.Sprintf("switched engine to %s", path))
+
+	// uciok sets Threads/Hash/MultiPV
+	sf.Write("uci")
+	if path := u.options.String("SyzygyPath"); path != "" {
+		u.setEngineOption("SyzygyPath", path)
+	}
+	u.setEngineOption("SyzygyProbeLimit", u.options.String("SyzygyProbeLimit"))
+	u.setEngineOption("Ponder", u.options.String("Ponder"))
+	u.setEngineOption("UCI_ShowWDL", u.options.String("UCI_ShowWDL"))
+	u.moveListMtx.Lock()
+	chess960, forwarded := u.chess960, u.engineOptions.all()
+	u.moveListMtx.Unlock()
+	if chess960 {
+		u.setEngineOption("UCI_Chess960", "true")
+	}
+	for _, o := range forwarded {
+		u.setEngineOption(o.name, o.value)
+	}
+
+	return nil
+}
+
+// stockfishPath returns SF's path: the EnginePath option, then
+// $TROLLFISH_ENGINE, then the config file's engine.
+func (u *UCI) stockfishPath() string {
+	if path := u.options.String("EnginePath"); path != "" {
+		return path
+	}
+	return u.config.enginePath()
+}
+
+// setEnginePath handles EnginePath, switching to the new engine unless a
+// variant engine is playing.
+func (u *UCI) setEnginePath(string) {
+	u.moveListMtx.Lock()
+	chess := u.variant.isChess()
+	u.moveListMtx.Unlock()
+
+	// before Start, the engine is started with the new path
+	path := u.stockfishPath()
+	if atomic.LoadInt64(&u.started) == 0 || !chess || u.runningEnginePath() == path {
+		return
+	}
+	if err := u.switchEngine(path); err != nil {
+		u.WriteLine(fmt.Sprintf("info string ERR: %v", err))
+	}
+}
+
+// setVariant handles UCI_Variant. Anything but chess needs a variant engine
+// (Fairy-Stockfish), which is started in place of SF the first time it's needed.
+func (u *UCI) setVariant(value string) {
+	v, err := parseVariant(value)
+	if err != nil {
+		u.WriteLine(fmt.Sprintf("info string ERR: %v", err))
+		return
+	}
+
+	u.moveListMtx.Lock()
+	u.variant = v
+	u.moveListMtx.Unlock()
+
+	variantEngine := u.options.String("VariantEngine")
+	if !v.isChess() && u.runningEnginePath() != variantEngine {
+		if variantEngine == "" {
+			u.WriteLine(fmt.Sprintf("info string ERR: variant %s needs VariantEngine set to a Fairy-Stockfish binary", v))
+			return
+		}
+		if err := u.switchEngine(variantEngine); err != nil {
+			u.WriteLine(fmt.Sprintf("info string ERR: %v", err))
+			return
+		}
+	}
+
+	if u.runningEnginePath() == variantEngine {
+		u.engine().Write(fmt.Sprintf("setoption name UCI_Variant value %s", v))
+	}
+}
@@ -0,0 +1,125 @@
+package uci
+
+import (
+	"testing"
+)
+
+func TestVariantMoves(t *testing.T) {
+	cases := []struct {
+		name    string
+		variant Variant
+		fen     string
+		moves   []string
+		want    string
+	}{
+		{
+			name:    "crazyhouse capture goes to the pocket",
+			variant: VariantCrazyhouse,
+			fen:     "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR[] w KQkq - 0 2",
+			moves:   []string{"e4d5"},
+			want:    "rnbqkbnr/ppp1pppp/8/3P4/8/8/PPPP1PPP/RNBQKBNR[P] b KQkq - 0 2",
+		},
+		{
+			name:    "crazyhouse drop",
+			variant: VariantCrazyhouse,
+			fen:     "rnbqkbnr/ppp1pppp/8/3P4/8/8/PPPP1PPP/RNBQKBNR[Pn] b KQkq - 0 2",
+			moves:   []string{"N@f3"},
+			want:    "rnbqkbnr/ppp1pppp/8/3P4/8/5n2/PPPP1PPP/RNBQKBNR[P] w KQkq - 1 3",
+		},
+		{
+			name:    "crazyhouse promoted piece is captured as a pawn",
+			variant: VariantCrazyhouse,
+			fen:     "4k3/8/8/8/8/8/8/Q~3K2r[] b - - 0 40",
+			moves:   []string{"h1a1"},
+			want:    "4k3/8/8/8/8/8/8/r3K3[p] w - - 0 41",
+		},
+		{
+			name:    "atomic capture explodes",
+			variant: VariantAtomic,
+			fen:     "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+			moves:   []string{"d1h5", "d8d6", "h5f7"},
+			want:    "rnb4r/ppp1p1pp/3q4/3p4/4P3/8/PPPP1PPP/RNB1KBNR b KQ - 0 3", // the e8 king goes too
+		},
+		{
+			name:    "horde first rank double step has no en passant",
+			variant: VariantHorde,
+			fen:     hordeStartFEN,
+			moves:   []string{"a4a5", "e7e5", "e1e3"},
+			want:    "rnbqkbnr/pppp1ppp/8/PPP1pPP1/1PPPPPPP/PPPPPPPP/PPPPPPPP/PPPP1PPP b kq - 0 2",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+			b.Variant = c.variant
+
+			b.Moves(c.moves...)
+
+			if got := b.FEN(); got != c.want {
+				t.Errorf("\nwant: %s\ngot:  %s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestVariantResult(t *testing.T) {
+	cases := []struct {
+		name    string
+		variant Variant
+		fen     string
+		want    string
+	}{
+		{name: "king of the hill", variant: VariantKingOfTheHill, fen: "8/8/8/3k4/8/8/8/4K3 w - - 0 40", want: "0-1"},
+		{name: "king of the hill, not yet", variant: VariantKingOfTheHill, fen: "8/8/2k5/8/8/8/8/4K3 w - - 0 40", want: ""},
+		{name: "atomic king exploded", variant: VariantAtomic, fen: "8/8/8/8/8/8/8/4K3 b - - 0 10", want: "1-0"},
+		{name: "horde wiped out", variant: VariantHorde, fen: "4k3/8/8/8/8/8/8/8 w - - 0 50", want: "0-1"},
+		{name: "chess", variant: VariantChess, fen: "8/8/8/3k4/8/8/8/4K3 w - - 0 40", want: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+			b.Variant = c.variant
+
+			if got := b.VariantResult(); got != c.want {
+				t.Errorf("want: '%s' got: '%s'", c.want, got)
+			}
+		})
+	}
+}
+
+func TestVariantLegal(t *testing.T) {
+	cases := []struct {
+		name    string
+		variant Variant
+		fen     string
+		move    string
+		want    bool
+	}{
+		{name: "crazyhouse drop", variant: VariantCrazyhouse, fen: "rnbqkbnr/ppp1pppp/8/3P4/8/8/PPPP1PPP/RNBQKBNR[Pn] b KQkq - 0 2", move: "N@f3", want: true},
+		{name: "crazyhouse drop from the other pocket", variant: VariantCrazyhouse, fen: "rnbqkbnr/ppp1pppp/8/3P4/8/8/PPPP1PPP/RNBQKBNR[Pn] b KQkq - 0 2", move: "P@e4", want: false},
+		{name: "crazyhouse pawn drop on the last rank", variant: VariantCrazyhouse, fen: "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", move: "P@a8", want: false},
+		{name: "crazyhouse pawn drop", variant: VariantCrazyhouse, fen: "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", move: "P@a2", want: true},
+		{name: "crazyhouse drop blocks check", variant: VariantCrazyhouse, fen: "4k3/8/8/8/8/8/8/r3K3[N] w - - 0 1", move: "N@d1", want: true},
+		{name: "crazyhouse drop ignores check", variant: VariantCrazyhouse, fen: "4k3/8/8/8/8/8/8/r3K3[N] w - - 0 1", move: "N@h5", want: false},
+		{name: "chess has no drops", variant: VariantChess, fen: "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", move: "P@a2", want: false},
+		{name: "atomic king can't capture", variant: VariantAtomic, fen: "4k3/8/8/8/8/8/4p3/4K3 w - - 0 1", move: "e1e2", want: false},
+		{name: "atomic capture next to own king", variant: VariantAtomic, fen: "4k3/8/8/8/8/8/3p4/3QK3 w - - 0 1", move: "d1d2", want: false},
+		{name: "atomic exploding the king beats check", variant: VariantAtomic, fen: "4k3/3p4/8/8/8/8/8/3QK2r w - - 0 1", move: "d1d7", want: true},
+		{name: "atomic check still has to be answered", variant: VariantAtomic, fen: "4k3/3p4/8/8/8/8/8/3QK2r w - - 0 1", move: "d1d2", want: false},
+		{name: "atomic kings can touch", variant: VariantAtomic, fen: "8/8/8/8/8/8/3k4/r3K3 w - - 0 1", move: "e1e2", want: true},
+		{name: "chess kings can't touch", variant: VariantChess, fen: "8/8/8/8/8/8/3k4/r3K3 w - - 0 1", move: "e1e2", want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+			b.Variant = c.variant
+
+			if got := b.IsLegal(c.move); got != c.want {
+				t.Errorf("%s: want: %v got: %v", c.move, c.want, got)
+			}
+		})
+	}
+}
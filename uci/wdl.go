@@ -0,0 +1,87 @@
+package uci
+
+import (
+	"fmt"
+	"math"
+)
+
+// WDL is an engine's win/draw/loss estimate in per mille, from the side to
+// move's point of view. lc0 reports it from its own search; SF only with
+// UCI_ShowWDL, from a model of its eval.
+type WDL struct {
+	Win, Draw, Loss int
+}
+
+// valid reports whether the engine sent a WDL.
+func (w WDL) valid() bool {
+	return w.Win+w.Draw+w.Loss > 0
+}
+
+func (w WDL) String() string {
+	return fmt.Sprintf("%d %d %d", w.Win, w.Draw, w.Loss)
+}
+
+const (
+	// wdlModelCenter and wdlModelScale shape the model used for WDL when the
+	// engine doesn't send one: a win is even money at +1.00, as SF
+	// normalizes its eval, and all but certain by +3.00.
+	wdlModelCenter = 100
+	wdlModelScale  = 50
+)
+
+// modelWDL estimates the WDL of a score from a logistic model of the eval.
+func modelWDL(score, mate int) WDL {
+	switch {
+	case mate > 0:
+		return WDL{Win: 1000}
+	case mate < 0:
+		return WDL{Loss: 1000}
+	}
+
+	winRate := func(cp int) int {
+		return int(math.Round(1000 * wdlModelWin(float64(cp))))
+	}
+	w, l := winRate(score), winRate(-score)
+	return WDL{Win: w, Draw: 1000 - w - l, Loss: l}
+}
+
+// wdlModelWin is the model's chance of a win at cp, 0 to 1.
+func wdlModelWin(cp float64) float64 {
+	return 1 / (1 + math.Exp((wdlModelCenter-cp)/wdlModelScale))
+}
+
+// imbalance is how far the position is from equal: 0 when the chances are
+// even, 1000 when one side is sure to win. Unlike centipawns it doesn't grow
+// with a big eval in a dead draw, or shrink in a sharp position that's
+// level on material.
+func (w WDL) imbalance() int {
+	d := w.Win - w.Loss
+	if d < 0 {
+		d = -d
+	}
+	return d
+}
+
+// equalityDist is how far a line is from keeping the game equal, the troll
+// policy's target until it turns agro. The WDL is used if the engine sent
+// one for every line.
+func equalityDist(move Info, useWDL bool) int {
+	if useWDL {
+		return move.WDL.imbalance()
+	}
+	dist := move.Score
+	if dist < 0 {
+		dist *= -1
+	}
+	return dist
+}
+
+// allHaveWDL reports whether every line has a WDL.
+func allHaveWDL(moveList []Info) bool {
+	for _, move := range moveList {
+		if !move.WDL.valid() {
+			return false
+		}
+	}
+	return len(moveList) > 0
+}
@@ -0,0 +1,118 @@
+package uci
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseInfoWDL(t *testing.T) {
+	line := "info depth 12 seldepth 30 time 1015 nodes 2401 score cp 21 wdl 214 634 152 hashfull 91 nps 2365 tbhits 0 multipv 1 movesleft 71 pv e2e4 e7e5"
+	info, err := parseInfo(strings.Split(line, " "), func(s string) { t.Error(s) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Info{Depth: 12, SelDepth: 30, Time: 1015, Nodes: 2401, Score: 21, WDL: WDL{214, 634, 152}, HashFull: 91, NPS: 2365, MultiPV: 1, PV: "e2e4 e7e5"}
+	if info != want {
+		t.Errorf("\nwant: %+v\ngot:  %+v", want, info)
+	}
+	if !strings.Contains(info.String(), "score cp 21 wdl 214 634 152 nodes") {
+		t.Errorf("String: %s", info.String())
+	}
+}
+
+func TestSelectMoveWDL(t *testing.T) {
+	// by centipawns g1f3 is closer to equal, by WDL d2d4 is: g1f3 is sharp
+	moveList := []Info{
+		{MultiPV: 1, Score: 40, WDL: WDL{60, 900, 40}, PV: "d2d4"},
+		{MultiPV: 2, Score: 5, WDL: WDL{300, 300, 400}, PV: "g1f3"},
+	}
+
+	for _, tt := range []struct {
+		name string
+		wdl  bool
+		want string
+	}{
+		{"wdl", true, "d2d4"},
+		{"cp", false, "g1f3"},
+	} {
+		lines := append([]Info(nil), moveList...)
+		if !tt.wdl {
+			lines[1].WDL = WDL{}
+		}
+		u := &UCI{sf: noEngine{}, config: defaultConfig(), log: nopWriteCloser{}, out: io.Discard}
+		if got := u.selectMove(lines, lines[0]); got.PV != tt.want {
+			t.Errorf("%s: want: %s got: %s", tt.name, tt.want, got.PV)
+		}
+	}
+}
+
+func TestLc0Backend(t *testing.T) {
+	fake := newFakeBackend()
+	b := lc0Backend{fake}
+
+	b.Write("uci")
+	b.Write("setoption name Hash value 7168")
+	b.Write("setoption name Move Overhead value 200")
+	b.Write("setoption name MultiPV value 5")
+	b.Write("setoption name UCI_ShowWDL value false")
+
+	want := []string{
+		"uci",
+		"setoption name UCI_ShowWDL value true",
+		"setoption name MoveOverheadMs value 200",
+		"setoption name MultiPV value 5",
+	}
+	if got := fake.sent(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("\nwant: %q\ngot:  %q", want, got)
+	}
+}
+
+func TestModelWDL(t *testing.T) {
+	cases := []struct {
+		score, mate int
+		want        WDL
+	}{
+		{score: 0, want: WDL{119, 762, 119}},
+		{score: 100, want: WDL{500, 482, 18}},
+		{score: -100, want: WDL{18, 482, 500}},
+		{mate: 3, want: WDL{Win: 1000}},
+		{mate: -2, want: WDL{Loss: 1000}},
+	}
+
+	for _, c := range cases {
+		if got := modelWDL(c.score, c.mate); got != c.want {
+			t.Errorf("cp %d mate %d: want: %v got: %v", c.score, c.mate, c.want, got)
+		}
+	}
+}
+
+func TestShowWDL(t *testing.T) {
+	u, out := newTestUCI(t)
+
+	print := func() string {
+		out.Reset()
+		u.moveListMtx.Lock()
+		u.moveList = []Info{
+			{Depth: 10, MultiPV: 1, Score: 100, PV: "e2e4"},
+			{Depth: 10, MultiPV: 2, Score: 20, WDL: WDL{100, 800, 100}, PV: "d2d4"},
+		}
+		u.moveListPrinted = false
+		u.printMoveList(false)
+		u.moveListMtx.Unlock()
+		return out.String()
+	}
+
+	if got := print(); strings.Contains(got, "wdl") {
+		t.Errorf("UCI_ShowWDL off:\n%s", got)
+	}
+
+	u.SetOption("UCI_ShowWDL", "true")
+	got := print()
+	for _, want := range []string{"score cp 100 wdl 500 482 18 ", "score cp 20 wdl 100 800 100 "} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in:\n%s", want, got)
+		}
+	}
+}
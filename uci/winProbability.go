@@ -0,0 +1,58 @@
+package uci
+
+const (
+	// evalMaxWeight is how much more a centipawn counts from
+	// evalMaxWeightPly on than at the start of the game: the same eval is
+	// worth more the fewer pieces are left to go wrong with.
+	evalMaxWeight    = 2
+	evalMaxWeightPly = 160
+
+	// winProbReferencePly is the middlegame the centipawn thresholds in the
+	// config are tuned for. They're compared as the win probability they
+	// give there, so they mean the same in an endgame.
+	winProbReferencePly = 60
+)
+
+// evalWeight is how much a centipawn at ply counts for, from 1 at the start
+// of the game up to evalMaxWeight.
+func evalWeight(ply int) float64 {
+	return 1 + (evalMaxWeight-1)*float64(min(max(ply, 0), evalMaxWeightPly))/evalMaxWeightPly
+}
+
+// expectedScore converts a score at ply to that side's expected result, 0 for
+// a sure loss to 1 for a sure win: the win and half the draw of the WDL
+// model, for the eval weighted by ply.
+func expectedScore(s Score, ply int) float64 {
+	switch {
+	case s.Mate > 0:
+		return 1
+	case s.Mate < 0:
+		return 0
+	}
+	cp := float64(s.CP) * evalWeight(ply)
+	win, loss := wdlModelWin(cp), wdlModelWin(-cp)
+	return win + (1-win-loss)/2
+}
+
+// winProbAtLeast reports whether a score at ply is at least as good as a
+// threshold in middlegame centipawns.
+func winProbAtLeast(s Score, ply, threshold int) bool {
+	return expectedScore(s, ply) >= expectedScore(Score{CP: threshold}, winProbReferencePly)
+}
+
+// winProbDrop reports whether going from eval to s at ply, both for the same
+// side, gives up more than tolerance middlegame centipawns from an equal
+// position would.
+func winProbDrop(eval, s Score, ply, tolerance int) bool {
+	drop := expectedScore(eval, ply) - expectedScore(s, ply)
+	return drop > expectedScore(Score{}, winProbReferencePly)-expectedScore(Score{CP: -tolerance}, winProbReferencePly)
+}
+
+// gamePly is the plies played, from the fullmove number and side to move.
+func gamePly(moveNumber int, color string) int {
+	ply := 2 * max(moveNumber-1, 0)
+	if color == "b" {
+		ply++
+	}
+	return ply
+}
@@ -0,0 +1,75 @@
+package uci
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExpectedScore(t *testing.T) {
+	// the WDL model's win and half its draw, per mille
+	model := func(cp int) float64 {
+		w := modelWDL(cp, 0)
+		return (float64(w.Win) + float64(w.Draw)/2) / 1000
+	}
+
+	cases := []struct {
+		name        string
+		score, mate int
+		ply         int
+		want        float64
+	}{
+		{name: "equal", score: 0, ply: 20, want: 0.5},
+		{name: "opening", score: 100, ply: 0, want: model(100)},
+		{name: "endgame, half the eval", score: 50, ply: 200, want: model(100)},
+		{name: "losing", score: -100, ply: 0, want: model(-100)},
+		{name: "mate", mate: 5, want: 1},
+		{name: "mated", mate: -5, want: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := expectedScore(Score{CP: c.score, Mate: c.mate}, c.ply); math.Abs(got-c.want) > 1e-3 {
+				t.Errorf("want: %.4f got: %.4f", c.want, got)
+			}
+		})
+	}
+}
+
+func TestWinProbThresholds(t *testing.T) {
+	// the same eval is closer to a win in an endgame
+	if winProbAtLeast(Score{CP: 700}, 10, 800) {
+		t.Error("+7 in the opening: want below the +8 threshold")
+	}
+	if !winProbAtLeast(Score{CP: 700}, 120, 800) {
+		t.Error("+7 in the endgame: want at least the +8 threshold")
+	}
+	if !winProbAtLeast(Score{Mate: 1}, 10, 2000) {
+		t.Error("mate: want at least the threshold")
+	}
+
+	// and giving up the same eval matters more
+	if winProbDrop(Score{}, Score{CP: -250}, 10, 250) {
+		t.Error("-2.50 in the opening: want within tolerance")
+	}
+	if !winProbDrop(Score{}, Score{CP: -250}, 120, 250) {
+		t.Error("-2.50 in the endgame: want a blunder")
+	}
+}
+
+func TestGamePly(t *testing.T) {
+	cases := []struct {
+		move  int
+		color string
+		want  int
+	}{
+		{move: 1, color: "w", want: 0},
+		{move: 1, color: "b", want: 1},
+		{move: 30, color: "w", want: 58},
+		{move: 0, color: "w", want: 0},
+	}
+	for _, c := range cases {
+		if got := gamePly(c.move, c.color); got != c.want {
+			t.Errorf("move %d %s: want: %d got: %d", c.move, c.color, c.want, got)
+		}
+	}
+}